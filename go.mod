@@ -6,3 +6,5 @@ require (
 	github.com/google/uuid v1.6.0
 	golang.org/x/sys v0.47.0
 )
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,268 @@
+package debugadmin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 运行时事件的 provider 与 EventID，见 ClrEtwAll.man。
+const (
+	dotnetRuntimeProvider = "Microsoft-Windows-DotNETRuntime"
+	dotnetRundownProvider = "Microsoft-Windows-DotNETRuntimeRundown"
+
+	gcAllocationTickEventID     = 10  // GCAllocationTick，每分配约 100KB 触发一次
+	methodLoadVerboseEventID    = 143 // 运行时的 MethodLoadVerbose 与 rundown 的 MethodDCStartVerbose
+	methodDCEndVerboseEventID   = 144 // rundown 的 MethodDCEndVerbose
+	moduleLoadEventID           = 152 // 运行时的 ModuleLoad 与 rundown 的 ModuleDCEnd
+	moduleDCStartEventID        = 151
+	allocTraceGCKeyword         = 0x1
+	allocTraceVerboseEventLevel = 5
+)
+
+// AllocSample 是一次 AllocationTick：自上一次 tick 以来在这个线程上分配的字节数，以及触发 tick 的那次分配的类型与调用栈。
+type AllocSample struct {
+	TypeName string
+	Bytes    uint64
+	Stack    []uint64 // 栈顶在前
+}
+
+// managedMethod 是 MethodLoadVerbose / MethodDCEndVerbose 中的一个方法。
+type managedMethod struct {
+	Start    uint64
+	Size     uint64
+	ModuleID uint64
+	Name     string
+}
+
+// managedCode 收集 JIT 与 rundown 事件中的方法地址和模块名，用来还原托管调用栈。
+type managedCode struct {
+	methods []managedMethod
+	modules map[uint64]string // module id -> 模块文件名
+}
+
+// handleEvent 处理方法与模块相关的事件，其余事件返回 false。
+func (c *managedCode) handleEvent(event *NetTraceEvent) bool {
+	switch event.Metadata.Provider {
+	case dotnetRuntimeProvider:
+		switch event.Metadata.EventID {
+		case methodLoadVerboseEventID:
+			c.addMethod(event.Payload)
+			return true
+		case moduleLoadEventID:
+			c.addModule(event.Payload)
+			return true
+		}
+	case dotnetRundownProvider:
+		switch event.Metadata.EventID {
+		case methodLoadVerboseEventID, methodDCEndVerboseEventID:
+			c.addMethod(event.Payload)
+			return true
+		case moduleDCStartEventID, moduleLoadEventID:
+			c.addModule(event.Payload)
+			return true
+		}
+	}
+	return false
+}
+
+// perfMap 把收集到的方法转换为按地址查找的表，方法名为 "模块!命名空间.方法"。
+func (c *managedCode) perfMap() *PerfMap {
+	entries := make([]PerfMapEntry, 0, len(c.methods))
+	for _, method := range c.methods {
+		name := method.Name
+		if module := c.modules[method.ModuleID]; module != "" {
+			name = module + "!" + name
+		}
+		entries = append(entries, PerfMapEntry{Start: method.Start, Size: method.Size, Name: name})
+	}
+	return newPerfMap(entries)
+}
+
+// AllocProfile 是从 nettrace 中解析出的分配采样，以及用来还原调用栈的方法与模块。
+type AllocProfile struct {
+	managedCode
+	PID         int
+	Start       time.Time
+	End         time.Time
+	Samples     []AllocSample
+	pointerSize int
+	firstQPC    int64
+	lastQPC     int64
+}
+
+// ParseAllocTrace 读取一个启用了 GC keyword（Verbose）的 nettrace，收集 AllocationTick 事件，
+// 以及 JIT / rundown 中的方法地址（dotnet-trace 结束时会请求 rundown）。
+func ParseAllocTrace(r io.Reader) (*AllocProfile, error) {
+	reader, err := NewNetTraceReader(r)
+	if err != nil {
+		return nil, err
+	}
+	profile := &AllocProfile{PID: reader.ProcessID, pointerSize: reader.PointerSize, managedCode: managedCode{modules: make(map[uint64]string)}}
+	err = reader.ReadEvents(func(event *NetTraceEvent) error {
+		if event.Metadata == nil {
+			return nil
+		}
+		if profile.firstQPC == 0 || event.Timestamp < profile.firstQPC {
+			profile.firstQPC = event.Timestamp
+		}
+		if event.Timestamp > profile.lastQPC {
+			profile.lastQPC = event.Timestamp
+		}
+		if !profile.handleEvent(event) && event.Metadata.Provider == dotnetRuntimeProvider && event.Metadata.EventID == gcAllocationTickEventID {
+			profile.addAllocationTick(event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	profile.Start = reader.Time(profile.firstQPC)
+	profile.End = reader.Time(profile.lastQPC)
+	return profile, nil
+}
+
+// addAllocationTick 解析 GCAllocationTick_V2 及以后版本：
+// AllocationAmount u32, AllocationKind u32, ClrInstanceID u16, AllocationAmount64 u64, TypeID ptr, TypeName string, ...
+func (p *AllocProfile) addAllocationTick(event *NetTraceEvent) {
+	data := netTraceCursor{data: event.Payload}
+	amount := uint64(data.uint32())
+	data.uint32()
+	data.uint16()
+	if event.Metadata.Version < 2 {
+		return // V1 之前没有类型名
+	}
+	if amount64 := data.uint64(); amount64 > 0 {
+		amount = amount64
+	}
+	data.bytes(p.pointerSize) // TypeID
+	typeName := data.utf16String()
+	if data.err != nil {
+		return
+	}
+	if typeName == "" {
+		typeName = "[unknown type]"
+	}
+	p.Samples = append(p.Samples, AllocSample{
+		TypeName: typeName,
+		Bytes:    amount,
+		Stack:    append([]uint64(nil), event.Stack...),
+	})
+}
+
+// addMethod 解析 MethodLoadVerbose / MethodDCEndVerbose：
+// MethodID u64, ModuleID u64, MethodStartAddress u64, MethodSize u32, MethodToken u32, MethodFlags u32,
+// MethodNamespace string, MethodName string, MethodSignature string, ...
+func (c *managedCode) addMethod(payload []byte) {
+	data := netTraceCursor{data: payload}
+	data.uint64()
+	method := managedMethod{ModuleID: data.uint64(), Start: data.uint64(), Size: uint64(data.uint32())}
+	data.uint32()
+	data.uint32()
+	namespace := data.utf16String()
+	name := data.utf16String()
+	if data.err != nil || method.Size == 0 {
+		return
+	}
+	method.Name = name
+	if namespace != "" {
+		method.Name = namespace + "." + name
+	}
+	c.methods = append(c.methods, method)
+}
+
+// addModule 解析 ModuleLoad / ModuleDCEnd：ModuleID u64, AssemblyID u64, ModuleFlags u32, Reserved1 u32, ModuleILPath string, ...
+func (c *managedCode) addModule(payload []byte) {
+	data := netTraceCursor{data: payload}
+	id := data.uint64()
+	data.uint64()
+	data.uint32()
+	data.uint32()
+	path := data.utf16String()
+	if data.err != nil || path == "" {
+		return
+	}
+	c.modules[id] = filepath.Base(path)
+}
+
+// allocSymbolizer 把托管代码地址还原为方法名。运行时的事件里没有的方法（例如没有开启 rundown）再查 perf map。
+type allocSymbolizer struct {
+	pid      int
+	methods  *PerfMap
+	perfMaps *PerfMapIndex
+}
+
+func newAllocSymbolizer(profile *AllocProfile, perfMaps *PerfMapIndex) *allocSymbolizer {
+	return &allocSymbolizer{pid: profile.PID, methods: profile.perfMap(), perfMaps: perfMaps}
+}
+
+func (s *allocSymbolizer) Symbolize(addr uint64) string {
+	if entry, ok := s.methods.Lookup(addr); ok {
+		return entry.Name
+	}
+	if entry, ok := s.perfMaps.Resolve(s.pid, addr); ok {
+		return entry.Name
+	}
+	return fmt.Sprintf("0x%x", addr)
+}
+
+// buildAllocSpeedscope 把分配采样转换为 speedscope 文件，单位是字节：
+// 第一个 profile 按调用栈聚合，分配的类型作为栈顶的一帧；第二个 profile 只按类型聚合。
+func buildAllocSpeedscope(profile *AllocProfile, symbolizer *allocSymbolizer) *speedscopeFile {
+	builder := newSpeedscopeBuilder(fmt.Sprintf("allocations of process %d", profile.PID))
+	var total uint64
+	byType := make(map[string]uint64)
+	byStack := speedscopeProfile{Type: "sampled", Name: "allocated bytes by stack", Unit: "bytes"}
+	for _, sample := range profile.Samples {
+		stack := make([]int, 0, len(sample.Stack)+1)
+		for i := len(sample.Stack) - 1; i >= 0; i-- {
+			stack = append(stack, builder.frame(symbolizer.Symbolize(sample.Stack[i]), ""))
+		}
+		stack = append(stack, builder.frame(sample.TypeName, "[type]"))
+		byStack.Samples = append(byStack.Samples, stack)
+		byStack.Weights = append(byStack.Weights, float64(sample.Bytes))
+		byType[sample.TypeName] += sample.Bytes
+		total += sample.Bytes
+	}
+	byStack.EndValue = float64(total)
+	builder.addProfile(byStack)
+
+	types := make([]string, 0, len(byType))
+	for typeName := range byType {
+		types = append(types, typeName)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if byType[types[i]] != byType[types[j]] {
+			return byType[types[i]] > byType[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	byTypeProfile := speedscopeProfile{Type: "sampled", Name: "allocated bytes by type", Unit: "bytes", EndValue: float64(total)}
+	for _, typeName := range types {
+		byTypeProfile.Samples = append(byTypeProfile.Samples, []int{builder.frame(typeName, "[type]")})
+		byTypeProfile.Weights = append(byTypeProfile.Weights, float64(byType[typeName]))
+	}
+	builder.addProfile(byTypeProfile)
+	return &builder.file
+}
+
+// convertAllocTrace 把 dotnet-trace 写出的 nettrace 转换为 speedscope 文件，返回采样数。
+func (h *AdminHandler) convertAllocTrace(nettracePath, outputPath string) (int, error) {
+	file, err := os.Open(nettracePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	profile, err := ParseAllocTrace(file)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", nettracePath, err)
+	}
+	speedscope := buildAllocSpeedscope(profile, newAllocSymbolizer(profile, h.perfMaps))
+	if err := writeSpeedscopeFile(outputPath, speedscope); err != nil {
+		return 0, err
+	}
+	return len(profile.Samples), nil
+}
@@ -0,0 +1,111 @@
+package debugadmin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func allocationTickPayload(amount uint64, typeName string) []byte {
+	payload := &netTracePayload{}
+	payload.put(uint32(amount)).put(uint32(0)).put(uint16(0)).put(amount).put(uint64(0x7f0000abc000))
+	payload.str(typeName).put(uint32(0))
+	return payload.Bytes()
+}
+
+func methodLoadPayload(moduleID, start uint64, size uint32, namespace, name string) []byte {
+	payload := &netTracePayload{}
+	payload.put(uint64(1)).put(moduleID).put(start).put(size).put(uint32(0x06000001)).put(uint32(0))
+	payload.str(namespace).str(name).str("void ()").put(uint16(0))
+	return payload.Bytes()
+}
+
+func moduleLoadPayload(moduleID uint64, path string) []byte {
+	payload := &netTracePayload{}
+	payload.put(moduleID).put(uint64(2)).put(uint32(0)).put(uint32(0))
+	payload.str(path).str("").put(uint16(0))
+	return payload.Bytes()
+}
+
+// sampleAllocTrace 模拟 dotnet-trace 的输出：两次在 Handle 中分配 String，一次在 Load 中分配 Byte[]，
+// 方法地址来自结束时的 rundown。
+func sampleAllocTrace() []byte {
+	w := newNetTraceWriter(4321, time.Now())
+	w.metadata(1, dotnetRuntimeProvider, gcAllocationTickEventID, "", 4)
+	w.metadata(2, dotnetRundownProvider, methodDCEndVerboseEventID, "", 0)
+	w.metadata(3, dotnetRundownProvider, moduleLoadEventID, "", 2)
+	w.stacks(1, []uint64{0x5010, 0x6010}, []uint64{0x5110, 0x6010})
+	w.events("EventBlock", []netTraceTestEvent{
+		{MetadataID: 1, ThreadID: 1, StackID: 1, Timestamp: 2000, Payload: allocationTickPayload(100000, "System.String")},
+		{MetadataID: 1, ThreadID: 1, StackID: 1, Timestamp: 3000, Payload: allocationTickPayload(110000, "System.String")},
+		{MetadataID: 1, ThreadID: 2, StackID: 2, Timestamp: 4000, Payload: allocationTickPayload(300000, "System.Byte[]")},
+	})
+	w.events("EventBlock", []netTraceTestEvent{
+		{MetadataID: 2, Timestamp: 5000, Payload: methodLoadPayload(9, 0x5000, 0x100, "MyApp.Server", "Handle")},
+		{MetadataID: 2, Timestamp: 5000, Payload: methodLoadPayload(9, 0x5100, 0x100, "MyApp.Cache", "Load")},
+		{MetadataID: 2, Timestamp: 5000, Payload: methodLoadPayload(9, 0x6000, 0x100, "MyApp.Program", "Main")},
+		{MetadataID: 3, Timestamp: 5000, Payload: moduleLoadPayload(9, "/app/MyApp.dll")},
+	})
+	return w.Bytes()
+}
+
+func TestParseAllocTrace(t *testing.T) {
+	profile, err := ParseAllocTrace(bytes.NewReader(sampleAllocTrace()))
+	if err != nil {
+		t.Fatalf("ParseAllocTrace() error = %v", err)
+	}
+	if profile.PID != 4321 || len(profile.Samples) != 3 || len(profile.methods) != 3 {
+		t.Fatalf("profile = pid %d, %d samples, %d methods", profile.PID, len(profile.Samples), len(profile.methods))
+	}
+	if sample := profile.Samples[2]; sample.TypeName != "System.Byte[]" || sample.Bytes != 300000 || len(sample.Stack) != 2 {
+		t.Errorf("sample = %+v", sample)
+	}
+
+	file := buildAllocSpeedscope(profile, newAllocSymbolizer(profile, nil))
+	if len(file.Profiles) != 2 || file.Profiles[0].Unit != "bytes" {
+		t.Fatalf("profiles = %+v", file.Profiles)
+	}
+	byStack := file.Profiles[0]
+	var names []string
+	for _, index := range byStack.Samples[0] {
+		names = append(names, file.Shared.Frames[index].Name)
+	}
+	if got := strings.Join(names, " > "); got != "MyApp.dll!MyApp.Program.Main > MyApp.dll!MyApp.Server.Handle > System.String" {
+		t.Errorf("first stack = %s", got)
+	}
+	byType := file.Profiles[1]
+	if len(byType.Samples) != 2 || file.Shared.Frames[byType.Samples[0][0]].Name != "System.Byte[]" ||
+		byType.Weights[0] != 300000 || byType.Weights[1] != 210000 || byType.EndValue != 510000 {
+		t.Errorf("by type profile = %+v", byType)
+	}
+}
+
+func TestConvertAllocTrace(t *testing.T) {
+	dir := t.TempDir()
+	nettracePath := filepath.Join(dir, "alloc.nettrace")
+	if err := os.WriteFile(nettracePath, sampleAllocTrace(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "alloc.speedscope.json")
+	h := &AdminHandler{}
+	samples, err := h.convertAllocTrace(nettracePath, outputPath)
+	if err != nil || samples != 3 {
+		t.Fatalf("convertAllocTrace() = %d, %v", samples, err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil || !strings.Contains(string(data), `"allocated bytes by type"`) {
+		t.Errorf("speedscope output = %s, %v", data, err)
+	}
+}
+
+func TestBuildAllocTraceCommand(t *testing.T) {
+	cmd := BuildAllocTraceCommand(123, 15, "/tmp/x.nettrace")
+	got := strings.Join(cmd.Args, " ")
+	want := "dotnet-trace collect --duration 00:00:00:15 --providers Microsoft-Windows-DotNETRuntime:0x1:5 -p 123 -o /tmp/x.nettrace"
+	if got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
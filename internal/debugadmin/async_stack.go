@@ -0,0 +1,260 @@
+package debugadmin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// asyncStackTimeout 限制一次异步调用栈采集（生成 heap dump 并运行 dumpasync）的总时长。
+const asyncStackTimeout = 90 * time.Second
+
+// AsyncFrame 是异步调用链中的一个状态机（或被等待的 Task）。
+type AsyncFrame struct {
+	Address     string `json:"address,omitempty"`
+	MethodTable string `json:"method_table,omitempty"`
+	State       *int   `json:"state,omitempty"` // 状态机的 <>1__state，-1 表示正在运行，>=0 表示停在第几个 await
+	TypeName    string `json:"type_name"`
+	Method      string `json:"method,omitempty"` // 从状态机类型名还原的方法名，例如 MyApp.Worker.RunAsync
+}
+
+// Function 返回可读的方法名，无法还原时返回类型名。
+func (f AsyncFrame) Function() string {
+	if f.Method != "" {
+		return f.Method
+	}
+	return f.TypeName
+}
+
+// AsyncStack 是一条逻辑异步调用链，Frames[0] 是最内层（正在等待的）状态机，最后一项是最外层的调用者。
+type AsyncStack struct {
+	Awaiting string       `json:"awaiting,omitempty"` // 链条最内层等待的对象类型，例如 System.Threading.Tasks.Task+DelayPromise
+	Frames   []AsyncFrame `json:"frames"`
+}
+
+// Signature 用于合并相同的异步调用链，只比较类型，不比较地址与状态。
+func (s AsyncStack) Signature() string {
+	parts := make([]string, 0, len(s.Frames)+1)
+	parts = append(parts, s.Awaiting)
+	for _, frame := range s.Frames {
+		parts = append(parts, frame.TypeName)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// AsyncStackReport 是一次 dumpasync 的解析结果，Misc 是无法识别的输出行。
+type AsyncStackReport struct {
+	Stacks []AsyncStack `json:"stacks"`
+	Misc   []string     `json:"misc,omitempty"`
+}
+
+// AsyncStackGroup 是相同的一组异步调用链。
+type AsyncStackGroup struct {
+	Count    int          `json:"count"`
+	Awaiting string       `json:"awaiting,omitempty"`
+	Frames   []AsyncFrame `json:"frames"`
+}
+
+var (
+	asyncStackHeaderPattern = regexp.MustCompile(`^\s*STACKS?\s+\d+\s*$`)
+	asyncAwaitingPattern    = regexp.MustCompile(`<<\s*Awaiting:\s*(?:(?:0x)?[0-9a-fA-F]+\s+(?:0x)?[0-9a-fA-F]+\s+)?(.*?)\s*>>`)
+	asyncFramePattern       = regexp.MustCompile(`^[\s.>]*((?:0x)?[0-9a-fA-F]{8,16})\s+((?:0x)?[0-9a-fA-F]{8,16})\s+(?:\((-?\d*)\)\s+)?(.+?)\s*$`)
+)
+
+// ParseAsyncStacks 解析 SOS dumpasync 的输出。每个 "STACK n" 开始一条调用链，
+// "<< Awaiting: ... >>" 是链条等待的对象，其余 "地址 MT (状态) 类型" 行是链上的状态机。
+func ParseAsyncStacks(output string) AsyncStackReport {
+	var report AsyncStackReport
+	var current *AsyncStack
+	flush := func() {
+		if current != nil && (len(current.Frames) > 0 || current.Awaiting != "") {
+			report.Stacks = append(report.Stacks, *current)
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if asyncStackHeaderPattern.MatchString(line) {
+			flush()
+			current = &AsyncStack{}
+			continue
+		}
+		if current != nil {
+			if match := asyncAwaitingPattern.FindStringSubmatch(line); match != nil {
+				current.Awaiting = match[1]
+				continue
+			}
+			if match := asyncFramePattern.FindStringSubmatch(line); match != nil {
+				frame := AsyncFrame{
+					Address:     match[1],
+					MethodTable: match[2],
+					TypeName:    match[4],
+					Method:      asyncStateMachineMethod(match[4]),
+				}
+				if state, err := strconv.Atoi(match[3]); err == nil {
+					frame.State = &state
+				}
+				current.Frames = append(current.Frames, frame)
+				continue
+			}
+		}
+		report.Misc = append(report.Misc, line)
+	}
+	flush()
+	return report
+}
+
+// asyncStateMachineMethod 从编译器生成的状态机类型名还原方法名，
+// 例如 "MyApp.Worker+<RunAsync>d__5" 为 "MyApp.Worker.RunAsync"；
+// 包在 AsyncStateMachineBox`1[[...]] 中的类型名同样可以识别。不是状态机时返回空字符串。
+func asyncStateMachineMethod(typeName string) string {
+	end := strings.LastIndex(typeName, ">d__")
+	if end < 0 {
+		return ""
+	}
+	depth := 0
+	start := -1
+	for i := end; i >= 0; i-- {
+		switch typeName[i] {
+		case '>':
+			depth++
+		case '<':
+			depth--
+		}
+		if depth == 0 {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	method := typeName[start+1 : end]
+	outer := strings.TrimSuffix(typeName[:start], "+")
+	if index := strings.LastIndexAny(outer, "[, "); index >= 0 {
+		outer = outer[index+1:]
+	}
+	outer = strings.ReplaceAll(outer, "+", ".")
+	if outer == "" {
+		return method
+	}
+	return outer + "." + method
+}
+
+// GroupAsyncStacks 合并相同的异步调用链，数量多的在前。
+func GroupAsyncStacks(stacks []AsyncStack) []AsyncStackGroup {
+	groups := make(map[string]*AsyncStackGroup)
+	order := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		signature := stack.Signature()
+		group := groups[signature]
+		if group == nil {
+			group = &AsyncStackGroup{Awaiting: stack.Awaiting, Frames: stack.Frames}
+			groups[signature] = group
+			order = append(order, signature)
+		}
+		group.Count++
+	}
+	result := make([]AsyncStackGroup, 0, len(order))
+	for _, signature := range order {
+		result = append(result, *groups[signature])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	return result
+}
+
+// collectAsyncStacks 用 dotnet-dump 生成一份 heap dump，在 dump 上运行 dumpasync 遍历堆中的异步状态机，
+// 返回解析结果与 dotnet-dump 的原始输出。dump 文件用完即删除。
+func collectAsyncStacks(ctx context.Context, pid int) (AsyncStackReport, string, error) {
+	if pid <= 0 {
+		return AsyncStackReport{}, "", errors.New("target process is not running")
+	}
+	dumpPath := filepath.Join("/tmp", fmt.Sprintf("async-stacks-%d-%s.dmp", pid, time.Now().Format(traceIDLayout)))
+	defer func() { _ = os.Remove(dumpPath) }()
+
+	var output bytes.Buffer
+	collect := BuildDumpCollectCommand(ctx, pid, "Heap", dumpPath)
+	collect.Stdout = &output
+	collect.Stderr = &output
+	if err := collect.Run(); err != nil {
+		return AsyncStackReport{}, output.String(), fmt.Errorf("dotnet-dump collect failed: %w", err)
+	}
+
+	output.Reset()
+	analyze := BuildDumpAnalyzeCommand(ctx, dumpPath, "dumpasync")
+	analyze.Stdout = &output
+	analyze.Stderr = &output
+	if err := analyze.Run(); err != nil {
+		return AsyncStackReport{}, output.String(), fmt.Errorf("dotnet-dump analyze failed: %w", err)
+	}
+	return ParseAsyncStacks(output.String()), output.String(), nil
+}
+
+// stackAsyncSection 是 /stack?view=async 页面中的异步调用链部分，所有字段都已转义。
+type stackAsyncSection struct {
+	Count  int // 异步调用链总数（合并前）
+	Groups []stackAsyncRow
+	Output string // 无法识别的 dotnet-dump 输出，或采集失败时的完整输出
+	Error  string
+}
+
+type stackAsyncRow struct {
+	Count    int
+	Awaiting string
+	Frames   []string
+}
+
+func (d *stackPageData) setAsyncStacks(report AsyncStackReport, output string, err error) {
+	section := &stackAsyncSection{Count: len(report.Stacks)}
+	if err != nil {
+		section.Error = html.EscapeString(err.Error())
+		section.Output = html.EscapeString(strings.TrimSpace(output))
+		d.Async = section
+		return
+	}
+	for _, group := range GroupAsyncStacks(report.Stacks) {
+		frames := make([]string, 0, len(group.Frames))
+		for _, frame := range group.Frames {
+			frames = append(frames, formatAsyncFrameHTML(frame))
+		}
+		section.Groups = append(section.Groups, stackAsyncRow{
+			Count:    group.Count,
+			Awaiting: html.EscapeString(group.Awaiting),
+			Frames:   frames,
+		})
+	}
+	section.Output = html.EscapeString(strings.Join(report.Misc, "\n"))
+	d.Async = section
+}
+
+func formatAsyncFrameHTML(frame AsyncFrame) string {
+	var b strings.Builder
+	b.WriteString(`<span class="frame-method">`)
+	b.WriteString(html.EscapeString(frame.Function()))
+	b.WriteString(`</span>`)
+	if frame.State != nil {
+		b.WriteString(` <span class="frame-il">state `)
+		b.WriteString(strconv.Itoa(*frame.State))
+		b.WriteString(`</span>`)
+	}
+	if frame.Method != "" {
+		b.WriteString(` <span class="frame-ns">`)
+		b.WriteString(html.EscapeString(frame.TypeName))
+		b.WriteString(`</span>`)
+	}
+	return b.String()
+}
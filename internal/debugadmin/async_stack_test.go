@@ -0,0 +1,78 @@
+package debugadmin
+
+import (
+	"strings"
+	"testing"
+)
+
+const dumpasyncOutput = `Loading core dump: /tmp/async.dmp ...
+Ready to process analysis commands. Type 'help' to list available commands or 'help [command]' to get detailed help on a command.
+Type 'quit' or 'exit' to exit the session.
+> dumpasync
+STACK 1
+<< Awaiting: 00007f5a2c0a1d20 00007f5a4e8b8440 System.Threading.Tasks.Task+DelayPromise >>
+  00007f5a2c0a1de8 00007f5a4e8bd3b8 (1) MyApp.Worker+<PollAsync>d__3
+    00007f5a2c0a1e78 00007f5a4e8bd5f8 (0) System.Runtime.CompilerServices.AsyncTaskMethodBuilder` + "`" + `1+AsyncStateMachineBox` + "`" + `1[[System.Int32, System.Private.CoreLib],[MyApp.Worker+<RunAsync>d__5, MyApp]]
+STACK 2
+<< Awaiting: 00007f5a2c0a2d20 00007f5a4e8b8440 System.Threading.Tasks.Task+DelayPromise >>
+  00007f5a2c0a2de8 00007f5a4e8bd3b8 (1) MyApp.Worker+<PollAsync>d__3
+    00007f5a2c0a2e78 00007f5a4e8bd5f8 (-1) System.Runtime.CompilerServices.AsyncTaskMethodBuilder` + "`" + `1+AsyncStateMachineBox` + "`" + `1[[System.Int32, System.Private.CoreLib],[MyApp.Worker+<RunAsync>d__5, MyApp]]
+STACK 3
+  00007f5a2c0a3de8 00007f5a4e8bd9a0 (0) MyApp.Program+<>c+<<Main>b__0_0>d
+  00007f5a2c0a3e00 00007f5a4e8bd9b0 (2) MyApp.Program+<Main>d__0
+`
+
+func TestParseAsyncStacks(t *testing.T) {
+	report := ParseAsyncStacks(dumpasyncOutput)
+	if len(report.Stacks) != 3 {
+		t.Fatalf("got %d stacks, want 3: %+v", len(report.Stacks), report.Stacks)
+	}
+	first := report.Stacks[0]
+	if first.Awaiting != "System.Threading.Tasks.Task+DelayPromise" || len(first.Frames) != 2 {
+		t.Fatalf("stack 1 = %+v", first)
+	}
+	if frame := first.Frames[0]; frame.Method != "MyApp.Worker.PollAsync" || frame.State == nil || *frame.State != 1 || frame.Address != "00007f5a2c0a1de8" {
+		t.Errorf("stack 1 frame 0 = %+v", frame)
+	}
+	if got := first.Frames[1].Function(); got != "MyApp.Worker.RunAsync" {
+		t.Errorf("boxed state machine method = %q, want MyApp.Worker.RunAsync", got)
+	}
+	if got := report.Stacks[2].Frames[1].Method; got != "MyApp.Program.Main" {
+		t.Errorf("stack 3 frame 1 method = %q", got)
+	}
+	if len(report.Misc) != 4 || !strings.HasPrefix(report.Misc[0], "Loading core dump") {
+		t.Errorf("misc = %q, want the 4 banner lines", report.Misc)
+	}
+
+	groups := GroupAsyncStacks(report.Stacks)
+	if len(groups) != 2 || groups[0].Count != 2 || groups[1].Count != 1 {
+		t.Errorf("groups = %+v, want stacks 1 and 2 merged", groups)
+	}
+}
+
+func TestAsyncStateMachineMethod(t *testing.T) {
+	for typeName, want := range map[string]string{
+		"MyApp.Worker+<RunAsync>d__5":                   "MyApp.Worker.RunAsync",
+		"MyApp.Outer+Inner+<Get>d__1`1[[System.Int32]]": "MyApp.Outer.Inner.Get",
+		"MyApp.Program+<>c+<<Main>b__0_0>d__1":          "MyApp.Program.<>c.<Main>b__0_0",
+		"System.Threading.Tasks.Task+DelayPromise":      "",
+	} {
+		if got := asyncStateMachineMethod(typeName); got != want {
+			t.Errorf("asyncStateMachineMethod(%q) = %q, want %q", typeName, got, want)
+		}
+	}
+}
+
+func TestSetAsyncStacks(t *testing.T) {
+	data := buildStackPageData(stackViewAsync, "", ParseStackDump(aggregateDump), "", nil)
+	if data.View != stackViewAsync || len(data.Groups) != 3 {
+		t.Fatalf("async view = %+v, want grouped thread stacks next to the async stacks", data)
+	}
+	data.setAsyncStacks(ParseAsyncStacks(dumpasyncOutput), dumpasyncOutput, nil)
+	if data.Async.Count != 3 || len(data.Async.Groups) != 2 || !strings.Contains(data.Async.Groups[0].Frames[0], "MyApp.Worker.PollAsync") {
+		t.Errorf("async section = %+v", data.Async)
+	}
+	if !strings.Contains(data.Async.Groups[0].Frames[0], "MyApp.Worker+&lt;PollAsync&gt;d__3") {
+		t.Errorf("frame html = %s, want the escaped state machine type", data.Async.Groups[0].Frames[0])
+	}
+}
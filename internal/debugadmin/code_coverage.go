@@ -12,7 +12,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,8 +20,6 @@ import (
 var reportUUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 var coberturaFileNamePattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\.cobertura\.xml$`)
 
-const maxCoverageHistory = 20
-
 // CoverageRecord 记录一次代码覆盖率采集的结果，用于在首页展示历史趋势。
 type CoverageRecord struct {
 	Timestamp     time.Time `json:"timestamp"`
@@ -33,35 +30,6 @@ type CoverageRecord struct {
 	LinesValid    int       `json:"lines_valid"`
 }
 
-var (
-	coverageHistoryMu sync.Mutex
-	coverageHistory   []CoverageRecord
-
-	// coverageRunMu 保证同一时刻只有一次采集在执行。
-	// handleCodeCoverage 用同一秒的时间戳生成临时文件名（coverageFile/coberturaFile），
-	// 如果两个请求并发执行，会互相覆盖对方的临时文件，导致 recordCoverageResult
-	// 读取/解析失败而静默丢弃这次采集结果，使得历史记录数少于 maxCoverageHistory。
-	coverageRunMu sync.Mutex
-)
-
-func appendCoverageHistory(rec CoverageRecord) {
-	coverageHistoryMu.Lock()
-	defer coverageHistoryMu.Unlock()
-	coverageHistory = append(coverageHistory, rec)
-	if len(coverageHistory) > maxCoverageHistory {
-		coverageHistory = coverageHistory[len(coverageHistory)-maxCoverageHistory:]
-	}
-}
-
-// SnapshotCoverageHistory 返回当前代码覆盖率历史记录的一份拷贝，供首页模板渲染。
-func SnapshotCoverageHistory() []CoverageRecord {
-	coverageHistoryMu.Lock()
-	defer coverageHistoryMu.Unlock()
-	out := make([]CoverageRecord, len(coverageHistory))
-	copy(out, coverageHistory)
-	return out
-}
-
 type coberturaCoverageXML struct {
 	XMLName      xml.Name `xml:"coverage"`
 	LineRate     float64  `xml:"line-rate,attr"`
@@ -70,8 +38,8 @@ type coberturaCoverageXML struct {
 }
 
 // recordCoverageResult 解析 cobertura xml 里的总体覆盖率数据（line-rate/lines-covered/lines-valid），
-// 追加到全局历史记录中，超过 maxCoverageHistory 条后丢弃最旧的一条。
-func recordCoverageResult(coberturaFile, reportID string, timestamp time.Time) {
+// 追加到目标进程的历史记录中，超过 maxCoverageHistory 条后丢弃最旧的一条。
+func recordCoverageResult(history *CoverageHistory, coberturaFile, reportID string, timestamp time.Time) {
 	data, err := os.ReadFile(coberturaFile)
 	if err != nil {
 		return
@@ -80,7 +48,7 @@ func recordCoverageResult(coberturaFile, reportID string, timestamp time.Time) {
 	if err := xml.Unmarshal(data, &cov); err != nil {
 		return
 	}
-	appendCoverageHistory(CoverageRecord{
+	history.Append(CoverageRecord{
 		Timestamp:     timestamp,
 		CoberturaFile: coberturaFile,
 		ReportID:      reportID,
@@ -91,7 +59,7 @@ func recordCoverageResult(coberturaFile, reportID string, timestamp time.Time) {
 }
 
 // handleCodeCoverage 采集目标进程当前的代码覆盖率数据并生成 HTML 报告：
-//  1. dotnet-coverage snapshot 从正在运行的目标进程（通过 CoverageName 对应的 session）抓取一份 .coverage 快照；
+//  1. dotnet-coverage snapshot 从正在运行的目标进程（通过 LaunchSpec.CoverageSession 对应的 session）抓取一份 .coverage 快照；
 //  2. dotnet-coverage merge 把快照转换为 cobertura xml；
 //  3. cleanCoberturaCompilerGeneratedClasses 把 cobertura xml 中编译器生成的类
 //     （async 状态机、lambda 缓存、闭包）合并进父类，修复 reportgenerator 对泛型类
@@ -103,16 +71,17 @@ func (h *AdminHandler) handleCodeCoverage(w http.ResponseWriter, r *http.Request
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !GlobalOptions.WithCoverage {
-		http.Error(w, "code coverage is not enabled (missing -with.coverage)", http.StatusNotFound)
+	if !h.coverageEnabled() {
+		http.Error(w, "code coverage is not enabled (the current run is not in coverage mode)", http.StatusNotFound)
 		return
 	}
 
-	if !coverageRunMu.TryLock() {
+	// 同一个目标进程同一时刻只允许一次采集，避免并发的 snapshot 互相干扰。
+	if !h.coverageRunMu.TryLock() {
 		http.Error(w, "another code coverage collection is already in progress, please retry later", http.StatusConflict)
 		return
 	}
-	defer coverageRunMu.Unlock()
+	defer h.coverageRunMu.Unlock()
 
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Minute)
 	defer cancel()
@@ -127,7 +96,7 @@ func (h *AdminHandler) handleCodeCoverage(w http.ResponseWriter, r *http.Request
 	// if settingsFile := GlobalOptions.CoverageOpts.CoverageXMLSettingsFile; settingsFile != "" {
 	// 	snapshotArgs = append(snapshotArgs, "--settings", settingsFile)
 	// }
-	snapshotArgs = append(snapshotArgs, GlobalOptions.CoverageOpts.CoverageName)
+	snapshotArgs = append(snapshotArgs, h.target.Load().LaunchSpec().coverageSession())
 
 	reportGeneratorArgs := []string{"-reports:" + coberturaFile, "-targetdir:" + htmlDir, "-reporttypes:Html"}
 	if sourceDirs := GlobalOptions.CoverageOpts.SourceDirs; sourceDirs != "" {
@@ -159,7 +128,7 @@ func (h *AdminHandler) handleCodeCoverage(w http.ResponseWriter, r *http.Request
 			}
 		}
 	}
-	recordCoverageResult(coberturaFile, reportID, time.Now())
+	recordCoverageResult(h.coverage, coberturaFile, reportID, time.Now())
 	http.Redirect(w, r, "/code_coverage_report/"+reportID+"/", http.StatusFound)
 }
 
@@ -171,12 +140,12 @@ func (h *AdminHandler) handleResetCoverageData(w http.ResponseWriter, r *http.Re
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !GlobalOptions.WithCoverage {
-		http.Error(w, "code coverage is not enabled (missing -with.coverage)", http.StatusNotFound)
+	if !h.coverageEnabled() {
+		http.Error(w, "code coverage is not enabled (the current run is not in coverage mode)", http.StatusNotFound)
 		return
 	}
 
-	cmd := exec.Command("dotnet-coverage", "snapshot", GlobalOptions.CoverageOpts.CoverageName, "--output", "/dev/null", "--reset", "true")
+	cmd := exec.Command("dotnet-coverage", "snapshot", h.target.Load().LaunchSpec().coverageSession(), "--output", "/dev/null", "--reset", "true")
 	if err := cmd.Start(); err != nil {
 		http.Error(w, fmt.Sprintf("start dotnet-coverage snapshot failed: %v", err), http.StatusInternalServerError)
 		return
@@ -186,6 +155,14 @@ func (h *AdminHandler) handleResetCoverageData(w http.ResponseWriter, r *http.Re
 	w.WriteHeader(http.StatusOK)
 }
 
+// coverageEnabled 返回当前这次运行是否处于 coverage 模式。
+// 运行方式是每次启动的状态，管理端可以在 plain / gdb / coverage 之间切换，
+// 因此不能再根据启动 DebugAdmin 时的 -with.coverage 判断。
+func (h *AdminHandler) coverageEnabled() bool {
+	target := h.target.Load()
+	return target != nil && target.Mode() == RunModeCoverage
+}
+
 func renderCodeCoverageErrorHTML(w http.ResponseWriter, step string, err error, output string) {
 	_, _ = fmt.Fprintf(w, `<!doctype html><html><head><meta charset="utf-8"><title>Code Coverage Error</title></head><body>
 <h2 style="color:#b91c1c">%s failed</h2>
@@ -241,12 +218,12 @@ func (h *AdminHandler) handleGetCodeCoverageList(w http.ResponseWriter, r *http.
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !GlobalOptions.WithCoverage {
-		http.Error(w, "code coverage is not enabled (missing -with.coverage)", http.StatusNotFound)
+	if !h.coverageEnabled() {
+		http.Error(w, "code coverage is not enabled (the current run is not in coverage mode)", http.StatusNotFound)
 		return
 	}
 
-	records := SnapshotCoverageHistory()
+	records := h.coverage.Snapshot()
 	list := make([]CoverageRecord, len(records))
 	for i, record := range records {
 		list[len(records)-1-i] = record
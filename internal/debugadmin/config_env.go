@@ -0,0 +1,123 @@
+package debugadmin
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// envPrefix 是所有 DebugAdmin 环境变量的前缀，参数名转大写并把 . 和 - 替换为 _，
+	// 例如 admin.port 对应 DEBUGADMIN_ADMIN_PORT。
+	envPrefix = "DEBUGADMIN_"
+	// targetCommandEnv 用于代替 -- 之后的目标进程命令行，按 shell 规则切分，支持引号。
+	targetCommandEnv = envPrefix + "TARGET"
+)
+
+// ConfigSourceEnv 表示配置项来自 DEBUGADMIN_* 环境变量。
+const ConfigSourceEnv = "env"
+
+// flagEnvName 返回参数对应的环境变量名。
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+// applyEnvOverrides 把 DEBUGADMIN_* 环境变量写入命令行没有显式指定的参数，并在 sources 中记录来源。
+// 值为空的环境变量视为未设置；可以重复指定的参数（例如 coverage.exclude.re）每行一个值。
+func applyEnvOverrides(flagSet *flag.FlagSet, sources map[string]string) error {
+	var errs []error
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if sources[f.Name] != "" {
+			return
+		}
+		envName := flagEnvName(f.Name)
+		raw, ok := os.LookupEnv(envName)
+		if !ok || strings.TrimSpace(raw) == "" {
+			return
+		}
+		values := []string{raw}
+		if _, repeatable := f.Value.(*stringSliceFlag); repeatable {
+			values = splitNonEmptyLines(raw)
+		}
+		for _, value := range values {
+			if err := flagSet.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q for %s: %w", envName, value, f.Name, err))
+				return
+			}
+		}
+		sources[f.Name] = ConfigSourceEnv
+	})
+	return errors.Join(errs...)
+}
+
+// targetGroupsFromEnv 读取 DEBUGADMIN_TARGET，格式与 -- 之后的参数相同，也支持 "-- name=xxx" 多个分组。
+func targetGroupsFromEnv() ([]TargetOptions, error) {
+	raw := strings.TrimSpace(os.Getenv(targetCommandEnv))
+	if raw == "" {
+		return nil, nil
+	}
+	params, err := splitCommandLine(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", targetCommandEnv, err)
+	}
+	groups, err := splitTargetGroups(params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", targetCommandEnv, err)
+	}
+	return groups, nil
+}
+
+// splitCommandLine 按 shell 的规则切分命令行：空白分隔，支持单引号、双引号和反斜杠转义，不做变量展开。
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("unterminated escape at end of command line")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command line", quote)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,242 @@
+package debugadmin
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置项的来源，优先级从高到低：命令行 > 环境变量(ConfigSourceEnv) > 配置文件 > 默认值。
+const (
+	ConfigSourceFlag    = "flag"
+	ConfigSourceFile    = "file"
+	ConfigSourceDefault = "default"
+)
+
+// configFileTarget 是配置文件 targets: 列表中的一项，对应命令行中的一个 "-- name=xxx" 分组。
+type configFileTarget struct {
+	Name        string   `yaml:"name"`
+	Args        []string `yaml:"args"`
+	Env         []string `yaml:"env"`
+	Mode        string   `yaml:"mode"`
+	AutoRestart *bool    `yaml:"auto_restart"`
+	Dir         string   `yaml:"dir"`
+}
+
+// configFileTrigger 是配置文件 triggers: 列表中的一项：满足 when 中的条件时依次执行 actions。
+type configFileTrigger struct {
+	Name     string                    `yaml:"name"`
+	Target   string                    `yaml:"target"`
+	When     configFileTriggerWhen     `yaml:"when"`
+	Actions  []configFileTriggerAction `yaml:"actions"`
+	Cooldown string                    `yaml:"cooldown"`
+	Limit    int                       `yaml:"limit"`
+}
+
+// configFileTriggerWhen 是触发条件，cpu / rss / log / exceptions_per_minute / thread_growth 只能指定一个。
+type configFileTriggerWhen struct {
+	CPU                 float64 `yaml:"cpu"`
+	RSS                 string  `yaml:"rss"`
+	Log                 string  `yaml:"log"`
+	ExceptionsPerMinute int64   `yaml:"exceptions_per_minute"`
+	ThreadGrowth        int     `yaml:"thread_growth"`
+	Window              string  `yaml:"window"`
+	For                 string  `yaml:"for"`
+}
+
+type configFileTriggerAction struct {
+	Type     string `yaml:"type"`
+	Kind     string `yaml:"kind"`
+	Duration string `yaml:"duration"`
+	DumpType string `yaml:"dump_type"`
+	URL      string `yaml:"url"`
+}
+
+// configFileNotifier 是配置文件 notifiers: 列表中的一项，比 -notify.webhook 多了名称、格式与订阅的事件。
+type configFileNotifier struct {
+	Name   string   `yaml:"name"`
+	URL    string   `yaml:"url"`
+	Format string   `yaml:"format"`
+	Events []string `yaml:"events"`
+}
+
+// configFile 是 init.config.yaml 解析后的内容。
+// 除 targets、triggers 与 notifiers 以外，顶层的 key 与命令行参数同名（例如 admin.port、coverage.exclude.re），
+// 可以重复指定的参数在配置文件中写成列表。
+type configFile struct {
+	values    map[string][]string
+	targets   []configFileTarget
+	triggers  []configFileTrigger
+	notifiers []configFileNotifier
+}
+
+// loadConfigFile 读取并严格校验配置文件：出现命令行参数中不存在的 key 时返回 error。
+func loadConfigFile(path string, flagSet *flag.FlagSet) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file %q failed: %w", path, err)
+	}
+	var root map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse config file %q failed: %w", path, err)
+	}
+	cfg := &configFile{values: make(map[string][]string, len(root))}
+	for key, node := range root {
+		if key == "targets" || key == "triggers" || key == "notifiers" {
+			switch key {
+			case "targets":
+				err = decodeConfigList(&node, &cfg.targets)
+			case "triggers":
+				err = decodeConfigList(&node, &cfg.triggers)
+			default:
+				err = decodeConfigList(&node, &cfg.notifiers)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("config file %q: %s: %w", path, key, err)
+			}
+			continue
+		}
+		if key == "config" || flagSet.Lookup(key) == nil {
+			return nil, fmt.Errorf("config file %q: unknown key %q (line %d)", path, key, node.Line)
+		}
+		values, err := configNodeValues(&node)
+		if err != nil {
+			return nil, fmt.Errorf("config file %q: key %q: %w", path, key, err)
+		}
+		cfg.values[key] = values
+	}
+	return cfg, nil
+}
+
+// configNodeValues 把标量或标量列表转换为可以传给 flag.Value.Set 的字符串。
+func configNodeValues(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: list items must be scalars", item.Line)
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("line %d: value must be a scalar or a list of scalars", node.Line)
+	}
+}
+
+// decodeConfigList 严格解析 targets、triggers、notifiers 这样的列表，出现未知的字段时返回 error。
+func decodeConfigList(node *yaml.Node, out any) error {
+	// yaml.Node.Decode 不支持 KnownFields，这里重新编码后用 Decoder 严格解析。
+	raw, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// apply 把配置文件中的值写入命令行和环境变量都没有指定的参数，并在 sources 中记录来源。
+func (c *configFile) apply(flagSet *flag.FlagSet, sources map[string]string) error {
+	for name, values := range c.values {
+		if sources[name] != "" {
+			continue
+		}
+		for _, value := range values {
+			if err := flagSet.Set(name, value); err != nil {
+				return fmt.Errorf("config file: invalid value %q for %s: %w", value, name, err)
+			}
+		}
+		sources[name] = ConfigSourceFile
+	}
+	return nil
+}
+
+// targetOptions 把配置文件中的 targets 转换为 TargetOptions，未指定 auto_restart 时使用 autoRestart。
+func (c *configFile) targetOptions(autoRestart bool) ([]TargetOptions, error) {
+	targets := make([]TargetOptions, 0, len(c.targets))
+	seen := make(map[string]struct{}, len(c.targets))
+	for i, item := range c.targets {
+		name := strings.TrimSpace(item.Name)
+		if name == "" && i == 0 {
+			name = defaultTargetName
+		}
+		if !targetNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("config file: invalid target name %q", item.Name)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("config file: duplicate target name %q", name)
+		}
+		seen[name] = struct{}{}
+		spec, err := parseLaunchSpec(item.Mode, strings.Join(item.Args, "\n"), strings.Join(item.Env, "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("config file: target %q: %w", name, err)
+		}
+		target := TargetOptions{Name: name, StartupParams: spec.Args, Env: spec.Env, AutoRestart: autoRestart, Dir: strings.TrimSpace(item.Dir)}
+		if target.Dir != "" {
+			if info, err := os.Stat(target.Dir); err != nil || !info.IsDir() {
+				return nil, fmt.Errorf("config file: target %q: dir %q is not a directory", name, target.Dir)
+			}
+		}
+		if item.Mode != "" {
+			target.Mode = spec.Mode
+		}
+		if item.AutoRestart != nil {
+			target.AutoRestart = *item.AutoRestart
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// triggerRules 把配置文件中的 triggers 转换为 TriggerRule，target 必须是 targetNames 之一。
+func (c *configFile) triggerRules(targetNames []string) ([]TriggerRule, error) {
+	rules := make([]TriggerRule, 0, len(c.triggers))
+	seen := make(map[string]struct{}, len(c.triggers))
+	for i, item := range c.triggers {
+		rule, err := newTriggerRule(item)
+		if err != nil {
+			return nil, fmt.Errorf("config file: triggers[%d]: %w", i, err)
+		}
+		if _, ok := seen[rule.Name]; ok {
+			return nil, fmt.Errorf("config file: duplicate trigger name %q", rule.Name)
+		}
+		seen[rule.Name] = struct{}{}
+		if rule.Target != "" && !slices.Contains(targetNames, rule.Target) {
+			return nil, fmt.Errorf("config file: trigger %q: unknown target %q", rule.Name, rule.Target)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// notificationEndpoints 把配置文件中的 notifiers 转换为 NotificationEndpoint。
+func (c *configFile) notificationEndpoints() ([]NotificationEndpoint, error) {
+	endpoints := make([]NotificationEndpoint, 0, len(c.notifiers))
+	seen := make(map[string]struct{}, len(c.notifiers))
+	for i, item := range c.notifiers {
+		endpoint, err := newNotificationEndpoint(item.Name, item.URL, item.Format, item.Events)
+		if err != nil {
+			return nil, fmt.Errorf("config file: notifiers[%d]: %w", i, err)
+		}
+		if _, ok := seen[endpoint.Name]; ok {
+			return nil, fmt.Errorf("config file: duplicate notifier name %q", endpoint.Name)
+		}
+		seen[endpoint.Name] = struct{}{}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,171 @@
+package debugadmin
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redactedValue = "REDACTED"
+
+// secretNameParts 是配置项/环境变量名中表示敏感信息的片段，名字按 . _ - 切分后逐段比较。
+var secretNameParts = map[string]struct{}{
+	"password": {}, "passwd": {}, "pwd": {}, "secret": {}, "token": {}, "apikey": {},
+	"key": {}, "auth": {}, "authorization": {}, "credential": {}, "credentials": {},
+	"signature": {}, "sig": {}, "accesskey": {}, "cookie": {}, "webhook": {},
+}
+
+// isSecretName 判断配置项或环境变量名是否表示敏感信息，例如 API_TOKEN、db.password。
+func isSecretName(name string) bool {
+	parts := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	for _, part := range parts {
+		if _, ok := secretNameParts[part]; ok {
+			return true
+		}
+		if strings.Contains(part, "password") || strings.Contains(part, "secret") || strings.Contains(part, "token") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConfigValue 隐藏配置值中的敏感信息：
+// 名字本身是敏感项时整体隐藏；值是 URL 时隐藏 userinfo 中的密码，以及名字敏感的 query 参数。
+func redactConfigValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if isSecretName(name) {
+		return redactedValue
+	}
+	return redactURL(value)
+}
+
+func redactURL(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return value
+	}
+	changed := false
+	if parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+			changed = true
+		}
+	}
+	query := parsed.Query()
+	for key := range query {
+		if isSecretName(key) {
+			query.Set(key, redactedValue)
+			changed = true
+		}
+	}
+	if !changed {
+		return value
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// redactEnv 隐藏 KEY=VALUE 形式的环境变量中的敏感值。
+func redactEnv(item string) string {
+	name, value, found := strings.Cut(item, "=")
+	if !found {
+		return item
+	}
+	return name + "=" + redactConfigValue(name, value)
+}
+
+// handleConfig 展示合并命令行与配置文件之后生效的全部配置，以及每一项的来源，敏感信息会被隐藏。
+func (h *AdminHandler) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = io.WriteString(w, `<!doctype html><html><head><meta charset="utf-8"><title>Effective Config</title>
+<style>body{font-family:Consolas,Monaco,monospace;font-size:12px;padding:24px;}table{border-collapse:collapse;}th,td{border:1px solid #d1d5db;padding:4px 8px;text-align:left;vertical-align:top;}th{background:#f9fafb;}.src-flag{color:#1d4ed8;}.src-file{color:#166534;}.src-env{color:#9333ea;}.src-default{color:#6b7280;}</style>
+</head><body>
+<h3>Effective Config</h3>
+<div>priority: flag &gt; env (DEBUGADMIN_*) &gt; file &gt; default</div>
+`)
+	if GlobalOptions.ConfigPath != "" {
+		_, _ = fmt.Fprintf(w, "<div>config file: %s</div>\n", html.EscapeString(GlobalOptions.ConfigPath))
+	}
+	_, _ = io.WriteString(w, "<table><tr><th>Name</th><th>Env</th><th>Value</th><th>Source</th></tr>\n")
+	for _, entry := range GlobalOptions.Effective {
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td class=\"src-%s\">%s</td></tr>\n",
+			html.EscapeString(entry.Name),
+			html.EscapeString(flagEnvName(entry.Name)),
+			html.EscapeString(redactConfigValue(entry.Name, entry.Value)),
+			html.EscapeString(entry.Source),
+			html.EscapeString(entry.Source))
+	}
+	_, _ = fmt.Fprintf(w, "</table>\n<h3>Targets</h3>\n<div>source: <span class=\"src-%s\">%s</span></div>\n",
+		html.EscapeString(GlobalOptions.TargetsSource), html.EscapeString(GlobalOptions.TargetsSource))
+	_, _ = io.WriteString(w, "<table><tr><th>Name</th><th>Mode</th><th>Args</th><th>Env</th><th>Auto Restart</th></tr>\n")
+	for _, target := range GlobalOptions.Targets {
+		env := make([]string, 0, len(target.Env))
+		for _, item := range target.Env {
+			env = append(env, redactEnv(item))
+		}
+		args := make([]string, 0, len(target.StartupParams))
+		for _, arg := range target.StartupParams {
+			args = append(args, redactURL(arg))
+		}
+		mode := string(target.Mode)
+		if mode == "" {
+			mode = "-"
+		}
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%t</td></tr>\n",
+			html.EscapeString(target.Name),
+			html.EscapeString(mode),
+			html.EscapeString(strings.Join(args, " ")),
+			html.EscapeString(strings.Join(env, " ")),
+			target.AutoRestart)
+	}
+	_, _ = io.WriteString(w, "</table>\n")
+	if len(GlobalOptions.Triggers) > 0 {
+		_, _ = io.WriteString(w, "<h3>Triggers</h3>\n<table><tr><th>Name</th><th>Target</th><th>When</th><th>Actions</th><th>Cooldown</th><th>Limit</th></tr>\n")
+		for _, rule := range GlobalOptions.Triggers {
+			actions := make([]string, 0, len(rule.Actions))
+			for _, action := range rule.Actions {
+				actions = append(actions, action.Describe())
+			}
+			target := rule.Target
+			if target == "" {
+				target = "*"
+			}
+			_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%d</td></tr>\n",
+				html.EscapeString(rule.Name),
+				html.EscapeString(target),
+				html.EscapeString(rule.Describe()),
+				html.EscapeString(strings.Join(actions, ", ")),
+				rule.Cooldown,
+				rule.Limit)
+		}
+		_, _ = io.WriteString(w, "</table>\n")
+	}
+	if len(GlobalOptions.Notify.Endpoints) > 0 {
+		_, _ = io.WriteString(w, "<h3>Notifiers</h3>\n<table><tr><th>Name</th><th>URL</th><th>Format</th><th>Events</th></tr>\n")
+		for _, endpoint := range GlobalOptions.Notify.Endpoints {
+			events := "all"
+			if len(endpoint.Events) > 0 {
+				events = strings.Join(endpoint.Events, ", ")
+			}
+			_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				html.EscapeString(endpoint.Name),
+				html.EscapeString(redactNotifyURL(endpoint)),
+				html.EscapeString(endpoint.Format),
+				html.EscapeString(events))
+		}
+		_, _ = io.WriteString(w, "</table>\n")
+	}
+	_, _ = io.WriteString(w, "</body></html>")
+}
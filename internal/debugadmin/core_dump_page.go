@@ -0,0 +1,223 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"text/template"
+)
+
+//go:embed coredumps.html.tpl
+var coreDumpsHTMLContent string
+
+var coreDumpsHTMLTemplate = template.Must(template.New("coredumps.html").Parse(coreDumpsHTMLContent))
+
+type coreDumpsPageData struct {
+	TargetQuery   string
+	TargetParam   string
+	PID           int
+	Running       bool
+	CorePattern   string
+	PatternNote   string
+	CreateDump    string
+	Dir           string
+	TotalSize     string
+	MaxSize       string
+	Dumps         []coreDumpView
+	CollectAction string
+}
+
+type coreDumpView struct {
+	ID          string
+	Created     string
+	PID         int
+	Source      string
+	Original    string
+	RawSize     string
+	Size        string
+	Status      string
+	Error       string
+	DownloadURL string
+	DeleteURL   string
+}
+
+// coreDumpsAPIResponse 是 /api/coredumps 的返回值。
+type coreDumpsAPIResponse struct {
+	CorePattern CorePattern `json:"core_pattern"`
+	Dir         string      `json:"dir"`
+	TotalSize   int64       `json:"total_size"` // 所有目标进程
+	MaxSize     int64       `json:"max_size"`
+	Dumps       []CoreDump  `json:"dumps"`
+}
+
+// coreDumpURL 返回下载 core dump 的链接。
+func (h *AdminHandler) coreDumpURL(id string) string {
+	return "/coredumps/download?id=" + url.QueryEscape(id) + h.targetQuery("&")
+}
+
+// collectCrashCoreDump 在目标进程崩溃后压缩保存找到的 core，并关联到这次运行的 RunRecord，
+// 开启 -coredump.analyze 时先在后台分析 core，分析完再压缩。
+// 由 TargetSupervisor.OnExit 调用，先于崩溃通知，这样通知中可以带上下载链接与崩溃报告的链接。
+func (h *AdminHandler) collectCrashCoreDump(exit TargetExit) {
+	if h.coreDumps == nil || exit.Target.reason != RunReasonCrash {
+		return
+	}
+	records := h.history.Snapshot()
+	if len(records) == 0 {
+		return
+	}
+	index := len(records) - 1
+	record := records[index]
+	if record.CoreDumpPath == "" {
+		return
+	}
+	if h.crashReports == nil {
+		dump := h.coreDumps.Import(h.name, record.PID, record.CoreDumpSource, record.CoreDumpPath)
+		h.history.SetCoreDump(index, dump.ID)
+		return
+	}
+	dump := h.coreDumps.Begin(h.name, record.PID, record.CoreDumpSource)
+	report := h.crashReports.Begin(h.name, index, record, dump.ID)
+	h.history.SetCoreDump(index, dump.ID)
+	h.history.SetCrashReport(index, report.ID)
+	h.crashReports.Analyze(report, func() {
+		h.coreDumps.Finish(dump, record.CoreDumpPath, nil)
+	})
+}
+
+// handleCoreDumps 展示 core_pattern、保存的 core dump，以及手动生成 core dump 的表单。
+func (h *AdminHandler) handleCoreDumps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	target := h.target.Load()
+	pattern := readCorePattern()
+	data := coreDumpsPageData{
+		TargetQuery:   h.targetQuery("?"),
+		TargetParam:   h.targetQuery("&"),
+		PID:           h.resolveTargetPID(),
+		Running:       target != nil && target.Running(),
+		CorePattern:   html.EscapeString(pattern.Raw),
+		PatternNote:   html.EscapeString(pattern.Describe()),
+		CreateDump:    "off",
+		Dir:           html.EscapeString(h.coreDumps.Dir()),
+		TotalSize:     formatBytes(uint64(h.coreDumps.TotalSize())),
+		MaxSize:       formatBytes(uint64(h.coreDumps.MaxSize())),
+		CollectAction: "/coredumps/collect" + h.targetQuery("?"),
+	}
+	if opts := GlobalOptions.CoreDump; opts.CreateDump {
+		data.CreateDump = fmt.Sprintf("on, %s dumps are written to %s", opts.CreateDumpType, createDumpIncomingDir(opts.Dir))
+	}
+	for _, dump := range h.coreDumps.List(h.name) {
+		view := coreDumpView{
+			ID:       html.EscapeString(dump.ID),
+			Created:  dump.Created.Format("2006-01-02 15:04:05"),
+			PID:      dump.PID,
+			Source:   html.EscapeString(dump.Source),
+			Original: html.EscapeString(dump.Original),
+			Status:   dump.Status,
+			Error:    html.EscapeString(dump.Error),
+		}
+		if dump.RawSize > 0 {
+			view.RawSize = formatBytes(uint64(dump.RawSize))
+		}
+		if dump.Status == CoreDumpReady {
+			view.Size = formatBytes(uint64(dump.Size))
+			view.DownloadURL = h.coreDumpURL(dump.ID)
+		}
+		if dump.Status == CoreDumpReady || dump.Status == CoreDumpFailed {
+			view.DeleteURL = "/coredumps/delete?id=" + url.QueryEscape(dump.ID) + h.targetQuery("&")
+		}
+		data.Dumps = append(data.Dumps, view)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := coreDumpsHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render core dumps page failed: %v\n", err)
+	}
+}
+
+// handleCoreDumpCollect 对运行中的目标进程生成一个 core dump：method=gcore 或 createdump（type=Mini/Heap/Triage/Full）。
+func (h *AdminHandler) handleCoreDumpCollect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	target := h.target.Load()
+	if target == nil || !target.Running() {
+		http.Error(w, "target process is not running", http.StatusConflict)
+		return
+	}
+	dumpType := r.FormValue("type")
+	if dumpType == "" {
+		dumpType = defaultCreateDumpType
+	}
+	if _, err := collectCoreDump(h.coreDumps, h.name, h.resolveTargetPID(), r.FormValue("method"), dumpType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "/coredumps"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleCoreDumpDownload 下载压缩后的 core dump，只能下载 store 中登记过的文件。
+func (h *AdminHandler) handleCoreDumpDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dump, ok := h.coreDumps.Get(r.URL.Query().Get("id"))
+	if !ok || dump.Status != CoreDumpReady {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(dump.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", dump.Target+"-"+dump.FileName()))
+	http.ServeContent(w, r, dump.FileName(), info.ModTime(), file)
+}
+
+// handleCoreDumpDelete 删除一个 core dump。
+func (h *AdminHandler) handleCoreDumpDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.coreDumps.Delete(r.URL.Query().Get("id")); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/coredumps"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleCoreDumpsAPI 以 JSON 返回 core_pattern 与这个目标进程的 core dump。
+func (h *AdminHandler) handleCoreDumpsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	response := coreDumpsAPIResponse{
+		CorePattern: readCorePattern(),
+		Dir:         h.coreDumps.Dir(),
+		TotalSize:   h.coreDumps.TotalSize(),
+		MaxSize:     h.coreDumps.MaxSize(),
+		Dumps:       h.coreDumps.List(h.name),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(response)
+}
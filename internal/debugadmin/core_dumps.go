@@ -0,0 +1,377 @@
+package debugadmin
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCoreDumpDir     = "/tmp/debugadmin-cores"
+	defaultCoreDumpMaxSize = "10GiB"
+	defaultCreateDumpType  = "Full"
+	coreDumpCollectTimeout = 10 * time.Minute
+)
+
+// core dump 的状态。
+const (
+	CoreDumpCollecting  = "collecting"  // 正在运行 gcore / createdump
+	CoreDumpCompressing = "compressing" // 正在压缩
+	CoreDumpReady       = "ready"
+	CoreDumpFailed      = "failed"
+)
+
+// CoreDumpOptions 是 core dump 的参数。
+type CoreDumpOptions struct {
+	Dir            string        // 压缩保存 core dump 的目录，-coredump.dir
+	MaxSize        int64         // 压缩后的 core dump 最多占用的空间，超出时删除最早的
+	CreateDump     bool          // 以 DOTNET_DbgEnableMiniDump=1 启动目标进程，崩溃时由运行时的 createdump 写 dump
+	CreateDumpType string        // Mini / Heap / Triage / Full
+	Analyze        bool          // 崩溃后压缩 core 之前先用 gdb 与 dotnet-dump 分析，生成崩溃报告
+	AnalyzeTimeout time.Duration // 一次分析的时间上限
+}
+
+// createDumpTypes 把 dump 类型转换为 DOTNET_DbgMiniDumpType 的值与 createdump 的参数。
+var createDumpTypes = map[string]struct {
+	env  string
+	flag string
+}{
+	"Mini":   {"1", "--normal"},
+	"Heap":   {"2", "--withheap"},
+	"Triage": {"3", "--triage"},
+	"Full":   {"4", "--full"},
+}
+
+// createDumpEnv 返回 -coredump.createdump 时目标进程的环境变量。
+func createDumpEnv(opts CoreDumpOptions) []string {
+	if !opts.CreateDump {
+		return nil
+	}
+	return []string{
+		"DOTNET_DbgEnableMiniDump=1",
+		"DOTNET_DbgMiniDumpType=" + createDumpTypes[opts.CreateDumpType].env,
+		"DOTNET_DbgMiniDumpName=" + filepath.Join(createDumpIncomingDir(opts.Dir), "createdump.%p.dmp"),
+	}
+}
+
+// CoreDump 是压缩保存的一个 core dump。
+type CoreDump struct {
+	ID       string    `json:"id"`
+	Target   string    `json:"target"`
+	PID      int       `json:"pid"`
+	Source   string    `json:"source"`             // CoreDumpSource*
+	Original string    `json:"original,omitempty"` // 压缩前的文件，压缩完成后删除
+	Path     string    `json:"-"`
+	RawSize  int64     `json:"raw_size"`
+	Size     int64     `json:"size"` // 压缩后的大小
+	Created  time.Time `json:"created"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// FileName 返回下载时的文件名。
+func (d CoreDump) FileName() string {
+	return filepath.Base(d.Path)
+}
+
+// CoreDumpStore 保存所有目标进程压缩后的 core dump，按 MaxSize 删除最早的文件。
+type CoreDumpStore struct {
+	dir     string
+	maxSize int64
+	now     func() time.Time
+
+	mu    sync.Mutex
+	dumps []*CoreDump // 按创建时间排序
+	wg    sync.WaitGroup
+}
+
+func NewCoreDumpStore(dir string, maxSize int64) *CoreDumpStore {
+	if err := os.MkdirAll(createDumpIncomingDir(dir), 0o755); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[coredump] create %s failed: %v\n", dir, err)
+	}
+	return &CoreDumpStore{dir: dir, maxSize: maxSize, now: time.Now}
+}
+
+// Dir 返回保存 core dump 的目录。
+func (s *CoreDumpStore) Dir() string {
+	return s.dir
+}
+
+// MaxSize 返回压缩后的 core dump 最多占用的空间。
+func (s *CoreDumpStore) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Begin 登记一个正在生成的 core dump，生成之后调用 Finish。
+func (s *CoreDumpStore) Begin(target string, pid int, source string) *CoreDump {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	id := fmt.Sprintf("%s-%d", now.Format("20060102-150405.000"), pid)
+	for suffix := 2; s.find(id) != nil; suffix++ {
+		id = fmt.Sprintf("%s-%d-%d", now.Format("20060102-150405.000"), pid, suffix)
+	}
+	dump := &CoreDump{
+		ID:      id,
+		Target:  target,
+		PID:     pid,
+		Source:  source,
+		Path:    filepath.Join(s.dir, target, id+".core.gz"),
+		Created: now,
+		Status:  CoreDumpCollecting,
+	}
+	s.dumps = append(s.dumps, dump)
+	return dump
+}
+
+// Finish 在后台压缩 raw 并删除它；err 不为空时表示生成失败。
+func (s *CoreDumpStore) Finish(dump *CoreDump, raw string, err error) {
+	s.mu.Lock()
+	dump.Original = raw
+	if err != nil {
+		dump.Status, dump.Error = CoreDumpFailed, err.Error()
+		s.mu.Unlock()
+		return
+	}
+	dump.Status = CoreDumpCompressing
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		rawSize, size, err := compressCoreDump(raw, dump.Path)
+		s.mu.Lock()
+		dump.RawSize, dump.Size = rawSize, size
+		if err != nil {
+			dump.Status, dump.Error = CoreDumpFailed, err.Error()
+		} else {
+			dump.Status = CoreDumpReady
+		}
+		s.mu.Unlock()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "[coredump] compress %s failed: %v\n", raw, err)
+			return
+		}
+		_ = os.Remove(raw)
+		_, _ = fmt.Fprintf(os.Stdout, "[coredump] saved %s (%s, %s before compression)\n", dump.Path, formatBytes(uint64(size)), formatBytes(uint64(rawSize)))
+		s.evict(dump)
+	}()
+}
+
+// Import 登记一个已经存在的 core 文件，例如崩溃时内核写出的 core。
+func (s *CoreDumpStore) Import(target string, pid int, source, raw string) *CoreDump {
+	dump := s.Begin(target, pid, source)
+	s.Finish(dump, raw, nil)
+	return dump
+}
+
+// Wait 等待所有的压缩完成，用于测试。
+func (s *CoreDumpStore) Wait() {
+	s.wg.Wait()
+}
+
+// evict 在压缩后的总大小超出 maxSize 时删除最早的 core dump，keep 总是保留。
+func (s *CoreDumpStore) evict(keep *CoreDump) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := int64(0)
+	for _, dump := range s.dumps {
+		total += dump.Size
+	}
+	for i := 0; total > s.maxSize && i < len(s.dumps); {
+		dump := s.dumps[i]
+		if dump == keep || dump.Status == CoreDumpCollecting || dump.Status == CoreDumpCompressing {
+			i++
+			continue
+		}
+		_ = os.Remove(dump.Path)
+		total -= dump.Size
+		_, _ = fmt.Fprintf(os.Stdout, "[coredump] removed %s to stay within %s\n", dump.Path, formatBytes(uint64(s.maxSize)))
+		s.dumps = append(s.dumps[:i], s.dumps[i+1:]...)
+	}
+}
+
+// Get 按 id 查找 core dump。
+func (s *CoreDumpStore) Get(id string) (CoreDump, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dump := s.find(id); dump != nil {
+		return *dump, true
+	}
+	return CoreDump{}, false
+}
+
+func (s *CoreDumpStore) find(id string) *CoreDump {
+	for _, dump := range s.dumps {
+		if dump.ID == id {
+			return dump
+		}
+	}
+	return nil
+}
+
+// List 返回 target 的 core dump，最近的在前；target 为空时返回全部。
+func (s *CoreDumpStore) List(target string) []CoreDump {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dumps := make([]CoreDump, 0, len(s.dumps))
+	for i := len(s.dumps) - 1; i >= 0; i-- {
+		if target == "" || s.dumps[i].Target == target {
+			dumps = append(dumps, *s.dumps[i])
+		}
+	}
+	return dumps
+}
+
+// TotalSize 返回所有目标进程的 core dump 压缩后的总大小。
+func (s *CoreDumpStore) TotalSize() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := int64(0)
+	for _, dump := range s.dumps {
+		total += dump.Size
+	}
+	return total
+}
+
+// Delete 删除一个已经完成的 core dump。
+func (s *CoreDumpStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, dump := range s.dumps {
+		if dump.ID != id {
+			continue
+		}
+		if dump.Status == CoreDumpCollecting || dump.Status == CoreDumpCompressing {
+			return errors.New("the core dump is still being collected")
+		}
+		s.dumps = append(s.dumps[:i], s.dumps[i+1:]...)
+		if err := os.Remove(dump.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("core dump %q not found", id)
+}
+
+// compressCoreDump 用 gzip 压缩 raw 到 path，返回压缩前后的大小。core 中大部分是零页，压缩比通常很高。
+func compressCoreDump(raw, path string) (rawSize, size int64, err error) {
+	in, err := os.Open(raw)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, 0, err
+	}
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmp)
+	zw, err := gzip.NewWriterLevel(out, gzip.BestSpeed)
+	if err != nil {
+		_ = out.Close()
+		return 0, 0, err
+	}
+	zw.Name = filepath.Base(raw)
+	rawSize, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return rawSize, 0, err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return rawSize, 0, err
+	}
+	return rawSize, info.Size(), os.Rename(tmp, path)
+}
+
+// collectCoreDump 对运行中的 pid 生成一个 core dump 并登记到 store：
+// method 为 gcore 时用 gdb 的 gcore，为 createdump 时用运行时自带的 createdump，dumpType 只用于 createdump。
+func collectCoreDump(store *CoreDumpStore, target string, pid int, method, dumpType string) (*CoreDump, error) {
+	var build func(ctx context.Context, raw string) (string, error)
+	switch method {
+	case CoreDumpSourceGCore:
+		// 一个进程只能被一个调试器 ptrace：-with.gdb 运行的目标进程，或者 netcoredbg 正在抓调用栈时，
+		// gcore 一定 attach 失败，提前给出明确的错误，而不是等到命令超时
+		if tracer := readProcessTracerPID(pid); tracer > 0 {
+			name := "pid " + strconv.Itoa(tracer)
+			if cmdline := readProcessCmdline(tracer); len(cmdline) > 0 {
+				name = filepath.Base(cmdline[0]) + " (" + name + ")"
+			}
+			return nil, fmt.Errorf("pid %d is already traced by %s, gcore cannot attach to it; use createdump instead", pid, name)
+		}
+		build = func(ctx context.Context, prefix string) (string, error) {
+			// gcore -o prefix 写出 prefix.pid
+			return prefix + "." + strconv.Itoa(pid), runCoreDumpCommand(BuildGCoreCommand(ctx, pid, prefix))
+		}
+	case CoreDumpSourceCreateDump:
+		flag, ok := createDumpTypes[dumpType]
+		if !ok {
+			return nil, fmt.Errorf("unknown dump type %q, expected Mini, Heap, Triage or Full", dumpType)
+		}
+		tool, err := findCreateDump(pid)
+		if err != nil {
+			return nil, err
+		}
+		build = func(ctx context.Context, raw string) (string, error) {
+			return raw, runCoreDumpCommand(BuildCreateDumpCommand(ctx, tool, pid, flag.flag, raw))
+		}
+	default:
+		return nil, fmt.Errorf("unknown method %q, expected gcore or createdump", method)
+	}
+	dump := store.Begin(target, pid, method)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), coreDumpCollectTimeout)
+		defer cancel()
+		raw, err := build(ctx, filepath.Join(createDumpIncomingDir(store.Dir()), dump.ID))
+		if err != nil {
+			_ = os.Remove(raw)
+		}
+		store.Finish(dump, raw, err)
+	}()
+	return dump, nil
+}
+
+func runCoreDumpCommand(cmd *exec.Cmd) error {
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, tailLines(strings.TrimSpace(string(output)), 12))
+	}
+	return nil
+}
+
+// findCreateDump 在目标进程加载的 libcoreclr.so 所在目录中查找运行时自带的 createdump。
+func findCreateDump(pid int) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || filepath.Base(fields[5]) != "libcoreclr.so" {
+			continue
+		}
+		tool := filepath.Join(filepath.Dir(fields[5]), "createdump")
+		if fileExists(tool) {
+			return tool, nil
+		}
+		return "", fmt.Errorf("createdump not found next to %s", fields[5])
+	}
+	return "", fmt.Errorf("process %d has not loaded libcoreclr.so; is it a .NET process?", pid)
+}
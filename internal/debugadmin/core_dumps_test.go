@@ -0,0 +1,314 @@
+package debugadmin
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// useCorePattern 把 core_pattern 与 core_uses_pid 替换为临时文件。
+func useCorePattern(t *testing.T, pattern string, usesPID bool) {
+	t.Helper()
+	dir := t.TempDir()
+	oldPattern, oldUsesPID := corePatternPath, coreUsesPIDPath
+	corePatternPath, coreUsesPIDPath = filepath.Join(dir, "core_pattern"), filepath.Join(dir, "core_uses_pid")
+	t.Cleanup(func() { corePatternPath, coreUsesPIDPath = oldPattern, oldUsesPID })
+	usesPIDValue := "0\n"
+	if usesPID {
+		usesPIDValue = "1\n"
+	}
+	if err := os.WriteFile(corePatternPath, []byte(pattern+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(coreUsesPIDPath, []byte(usesPIDValue), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCorePatternGlob(t *testing.T) {
+	tests := []struct {
+		raw     string
+		usesPID bool
+		want    string
+	}{
+		{raw: "core", want: "/app/core"},
+		{raw: "core", usesPID: true, want: "/app/core.42"},
+		{raw: "/var/crash/core.%e.%p.%t", want: "/var/crash/core.*.42.*"},
+		{raw: "/var/crash/%h/core-%p", usesPID: true, want: "/var/crash/web-1/core-42"},
+		{raw: "/cores/100%%-%s", want: "/cores/100%-*"},
+		{raw: "|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h", want: ""},
+	}
+	for _, tt := range tests {
+		pattern := CorePattern{Raw: tt.raw, UsesPID: tt.usesPID}
+		if got := pattern.Glob(42, "web-1", "/app"); got != tt.want {
+			t.Errorf("Glob(%q, %v) = %q, want %q", tt.raw, tt.usesPID, got, tt.want)
+		}
+	}
+	pipe := CorePattern{Raw: "|/usr/share/apport/apport -p%p"}
+	if !pipe.Pipe() || !strings.Contains(pipe.Describe(), "/usr/share/apport/apport") {
+		t.Errorf("Describe() = %q", pipe.Describe())
+	}
+	if got := (CorePattern{Raw: "/cores/core.%p"}).Describe(); got != "cores are written to /cores/core.*" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+func TestDetectCoreDump(t *testing.T) {
+	dumpDir := t.TempDir()
+	GlobalOptions = &Options{CoreDump: CoreDumpOptions{Dir: dumpDir}}
+	coreDir := t.TempDir()
+	useCorePattern(t, filepath.Join(coreDir, "core.%e.%p.%t"), false)
+	since := time.Now().Add(-time.Minute)
+
+	if path, source := detectCoreDump(4242, since, ""); path != "" || source != "" {
+		t.Fatalf("detectCoreDump() = %q, %q, want nothing", path, source)
+	}
+	old := filepath.Join(coreDir, "core.dotnet.4242.100")
+	newer := filepath.Join(coreDir, "core.dotnet.4242.200")
+	for _, path := range []string{old, newer, filepath.Join(coreDir, "core.dotnet.4243.300")} {
+		if err := os.WriteFile(path, []byte("core"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(old, since.Add(-time.Hour), since.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if path, source := detectCoreDump(4242, since, ""); path != newer || source != CoreDumpSourceKernel {
+		t.Errorf("detectCoreDump() = %q, %q, want %q", path, source, newer)
+	}
+	if err := os.Chtimes(newer, since.Add(-time.Hour), since.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := detectCoreDump(4242, since, ""); path != "" {
+		t.Errorf("detectCoreDump() = %q, cores written before since must be ignored", path)
+	}
+
+	createDump := createDumpPath(dumpDir, 4242)
+	if err := os.MkdirAll(filepath.Dir(createDump), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(createDump, []byte("dump"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if path, source := detectCoreDump(4242, since, ""); path != createDump || source != CoreDumpSourceCreateDump {
+		t.Errorf("detectCoreDump() = %q, %q, want createdump %q", path, source, createDump)
+	}
+}
+
+func TestDetectCoreDumpRelativePattern(t *testing.T) {
+	// 内核默认的 core_pattern 是相对路径，core 写在目标进程的工作目录下，而不是 DebugAdmin 的
+	GlobalOptions = &Options{}
+	useCorePattern(t, "core", true)
+	since := time.Now().Add(-time.Minute)
+	targetDir := t.TempDir()
+	if cwd, _ := os.Getwd(); cwd == targetDir {
+		t.Fatal("the target dir must differ from the process cwd")
+	}
+	core := filepath.Join(targetDir, "core.4242")
+	if err := os.WriteFile(core, []byte("core"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := LaunchSpec{Args: []string{"sleep", "30"}, Dir: targetDir}
+	if cmd, err := BuildLaunchCommand(spec); err != nil || cmd.Dir != targetDir || spec.WorkDir() != targetDir {
+		t.Fatalf("BuildLaunchCommand() dir = %q, WorkDir() = %q, err %v", cmd.Dir, spec.WorkDir(), err)
+	}
+	if path, source := detectCoreDump(4242, since, spec.WorkDir()); path != core || source != CoreDumpSourceKernel {
+		t.Errorf("detectCoreDump() = %q, %q, want %q", path, source, core)
+	}
+	if path, _ := detectCoreDump(4242, since, LaunchSpec{}.WorkDir()); path != "" {
+		t.Errorf("detectCoreDump() = %q, want nothing in the DebugAdmin cwd", path)
+	}
+}
+
+func TestCreateDumpEnv(t *testing.T) {
+	if env := createDumpEnv(CoreDumpOptions{Dir: "/cores"}); env != nil {
+		t.Errorf("createDumpEnv() = %q, want nil when disabled", env)
+	}
+	want := "DOTNET_DbgEnableMiniDump=1 DOTNET_DbgMiniDumpType=2 DOTNET_DbgMiniDumpName=/cores/incoming/createdump.%p.dmp"
+	if env := createDumpEnv(CoreDumpOptions{Dir: "/cores", CreateDump: true, CreateDumpType: "Heap"}); strings.Join(env, " ") != want {
+		t.Errorf("createDumpEnv() = %q", env)
+	}
+
+	GlobalOptions = &Options{CoreDump: CoreDumpOptions{Dir: "/cores", CreateDump: true, CreateDumpType: "Full"}}
+	env := LaunchSpec{Args: []string{"app.dll"}, Env: []string{"DOTNET_DbgMiniDumpType=1"}}.Environ()
+	if len(env) < 4 || env[len(env)-4] != "DOTNET_DbgEnableMiniDump=1" || env[len(env)-1] != "DOTNET_DbgMiniDumpType=1" {
+		t.Errorf("Environ() = %q, want createdump variables before the launch spec env", env)
+	}
+}
+
+func writeRawCore(t *testing.T, size int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "core")
+	if err := os.WriteFile(path, []byte(strings.Repeat("core", size/4)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCoreDumpStore(t *testing.T) {
+	store := NewCoreDumpStore(t.TempDir(), 1<<20)
+	clock := time.Date(2026, 5, 1, 10, 0, 0, 0, time.Local)
+	store.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	raw := writeRawCore(t, 1<<20)
+	dump := store.Import("api", 42, CoreDumpSourceKernel, raw)
+	store.Wait()
+	saved, ok := store.Get(dump.ID)
+	if !ok || saved.Status != CoreDumpReady || saved.RawSize != 1<<20 || saved.Size <= 0 || saved.Size >= saved.RawSize {
+		t.Fatalf("saved = %+v", saved)
+	}
+	if fileExists(raw) {
+		t.Error("the uncompressed core must be removed after compression")
+	}
+	if saved.ID != "20260501-100001.000-42" || saved.Path != filepath.Join(store.Dir(), "api", saved.ID+".core.gz") {
+		t.Errorf("id = %q, path = %q", saved.ID, saved.Path)
+	}
+	file, err := os.Open(saved.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(reader); err != nil || len(data) != 1<<20 || reader.Name != "core" {
+		t.Errorf("decompressed %d bytes, name %q, err %v", len(data), reader.Name, err)
+	}
+
+	failed := store.Begin("worker", 43, CoreDumpSourceGCore)
+	store.Finish(failed, "", os.ErrNotExist)
+	if list := store.List(""); len(list) != 2 || list[0].ID != failed.ID || list[0].Status != CoreDumpFailed {
+		t.Errorf("List() = %+v", list)
+	}
+	if list := store.List("api"); len(list) != 1 || list[0].ID != dump.ID {
+		t.Errorf("List(api) = %+v", list)
+	}
+
+	// 第二个 core 让总大小超出 maxSize，最早的 core 被删除
+	store.maxSize = saved.Size + saved.Size/2
+	second := store.Import("api", 44, CoreDumpSourceKernel, writeRawCore(t, 1<<20))
+	store.Wait()
+	if _, ok := store.Get(dump.ID); ok || fileExists(saved.Path) {
+		t.Error("the oldest core dump must be evicted")
+	}
+	if _, ok := store.Get(second.ID); !ok || store.TotalSize() <= 0 {
+		t.Errorf("the newest core dump must be kept, total = %d", store.TotalSize())
+	}
+
+	if err := store.Delete(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if store.TotalSize() != 0 || store.Delete(second.ID) == nil {
+		t.Errorf("Delete() left total = %d", store.TotalSize())
+	}
+	collecting := store.Begin("api", 45, CoreDumpSourceGCore)
+	if err := store.Delete(collecting.ID); err == nil {
+		t.Error("Delete() of a collecting core dump must fail")
+	}
+}
+
+func TestCoreDumpPages(t *testing.T) {
+	useCorePattern(t, "core.%p", false)
+	GlobalOptions = &Options{CoreDump: CoreDumpOptions{Dir: t.TempDir(), CreateDump: true, CreateDumpType: "Full"}}
+	store := NewCoreDumpStore(GlobalOptions.CoreDump.Dir, 1<<30)
+	history := NewRunHistory()
+	history.Add(RunRecord{PID: 41, Reason: RunReasonExit})
+	raw := writeRawCore(t, 4096)
+	history.Add(RunRecord{PID: 42, Reason: RunReasonCrash, Abnormal: true, CoreDumpPath: raw, CoreDumpSource: CoreDumpSourceKernel})
+	h := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, history: history, coreDumps: store, mux: http.NewServeMux()}
+	h.Register(h.mux)
+
+	h.collectCrashCoreDump(TargetExit{Target: &TargetProcess{reason: RunReasonExit}})
+	if len(store.List("")) != 0 {
+		t.Fatal("a normal exit must not collect a core dump")
+	}
+	h.collectCrashCoreDump(TargetExit{Target: &TargetProcess{reason: RunReasonCrash}})
+	store.Wait()
+	records := history.Snapshot()
+	dumps := store.List("api")
+	if len(dumps) != 1 || dumps[0].Status != CoreDumpReady || records[1].CoreDumpID != dumps[0].ID || records[0].CoreDumpID != "" {
+		t.Fatalf("dumps = %+v, records = %+v", dumps, records)
+	}
+	id := dumps[0].ID
+
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/coredumps?target=api", nil))
+	body := response.Body.String()
+	if response.Code != http.StatusOK || !strings.Contains(body, "cores are written to core.*") ||
+		!strings.Contains(body, "incoming") || !strings.Contains(body, "/coredumps/download?id="+id+"&target=api") ||
+		!strings.Contains(body, "The target process is not running") {
+		t.Errorf("page = %d %s", response.Code, body)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/coredumps/download?id="+id, nil))
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "application/gzip" ||
+		!strings.Contains(response.Header().Get("Content-Disposition"), "api-"+id+".core.gz") {
+		t.Fatalf("download = %d %v", response.Code, response.Header())
+	}
+	reader, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(reader); err != nil || len(data) != 4096 {
+		t.Errorf("downloaded %d bytes, err %v", len(data), err)
+	}
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/coredumps/download?id=../../etc/passwd", nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("download of an unknown id = %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/coredumps/collect?target=api", strings.NewReader("method=gcore")))
+	if response.Code != http.StatusConflict {
+		t.Errorf("collect without a running target = %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/api/coredumps", nil))
+	var api coreDumpsAPIResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &api); err != nil || api.CorePattern.Raw != "core.%p" || len(api.Dumps) != 1 || api.TotalSize <= 0 {
+		t.Errorf("api = %+v, err %v", api, err)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/coredumps/delete?id="+id+"&target=api", nil))
+	if response.Code != http.StatusSeeOther || len(store.List("")) != 0 {
+		t.Errorf("delete = %d, dumps = %+v", response.Code, store.List(""))
+	}
+}
+
+func TestCollectCoreDumpGCoreOnTracedProcess(t *testing.T) {
+	// 以 PTRACE_TRACEME 启动子进程，它的 TracerPid 就是测试进程，和 -with.gdb 下的目标进程一样
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("ptrace is not available: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	if tracer := readProcessTracerPID(cmd.Process.Pid); tracer != os.Getpid() {
+		t.Fatalf("readProcessTracerPID() = %d, want %d", tracer, os.Getpid())
+	}
+	store := NewCoreDumpStore(t.TempDir(), 1<<30)
+	_, err := collectCoreDump(store, "api", cmd.Process.Pid, CoreDumpSourceGCore, "")
+	if err == nil || !strings.Contains(err.Error(), "already traced") || len(store.List("")) != 0 {
+		t.Errorf("collectCoreDump() error = %v, dumps = %+v", err, store.List(""))
+	}
+}
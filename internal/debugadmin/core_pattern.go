@@ -0,0 +1,178 @@
+package debugadmin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 内核的 core dump 配置，测试中可以替换。
+var (
+	corePatternPath = "/proc/sys/kernel/core_pattern"
+	coreUsesPIDPath = "/proc/sys/kernel/core_uses_pid"
+)
+
+// CorePattern 是 /proc/sys/kernel/core_pattern 与 core_uses_pid 的内容。
+type CorePattern struct {
+	Raw     string `json:"raw"`
+	UsesPID bool   `json:"uses_pid"`
+	Err     string `json:"error,omitempty"`
+}
+
+func readCorePattern() CorePattern {
+	data, err := os.ReadFile(corePatternPath)
+	if err != nil {
+		return CorePattern{Err: err.Error()}
+	}
+	pattern := CorePattern{Raw: strings.TrimSpace(string(data))}
+	if usesPID, err := os.ReadFile(coreUsesPIDPath); err == nil {
+		pattern.UsesPID = strings.TrimSpace(string(usesPID)) == "1"
+	}
+	return pattern
+}
+
+// Pipe 判断 core 是否交给了一个程序处理（例如 systemd-coredump、apport），这时内核不会写文件。
+func (c CorePattern) Pipe() bool {
+	return strings.HasPrefix(c.Raw, "|")
+}
+
+// Describe 返回页面上展示的说明。
+func (c CorePattern) Describe() string {
+	switch {
+	case c.Err != "":
+		return "unknown: " + c.Err
+	case c.Raw == "":
+		return "empty, the kernel writes no core file"
+	case c.Pipe():
+		return fmt.Sprintf("piped to %s; DebugAdmin cannot locate these cores, enable -coredump.createdump or point core_pattern to a path", strings.Fields(c.Raw[1:])[0])
+	}
+	return "cores are written to " + c.Glob(0, "", "")
+}
+
+// Glob 把 core_pattern 中的模板转换为 glob：%p 替换为 pid，%h 替换为主机名，
+// 其他的 %e、%t、%s 等无法事先知道的值替换为 *。相对路径相对于目标进程的工作目录 cwd。
+// pid 为 0 时 %p 也替换为 *。
+func (c CorePattern) Glob(pid int, host, cwd string) string {
+	if c.Raw == "" || c.Pipe() {
+		return ""
+	}
+	var b strings.Builder
+	hasPID := false
+	for i := 0; i < len(c.Raw); i++ {
+		ch := c.Raw[i]
+		if ch != '%' || i == len(c.Raw)-1 {
+			b.WriteString(escapeGlob(string(ch)))
+			continue
+		}
+		i++
+		switch c.Raw[i] {
+		case '%':
+			b.WriteString("%")
+		case 'p':
+			hasPID = true
+			b.WriteString(globPID(pid))
+		case 'h':
+			if host == "" {
+				b.WriteString("*")
+			} else {
+				b.WriteString(escapeGlob(host))
+			}
+		default:
+			b.WriteString("*")
+		}
+	}
+	// core_uses_pid 为 1 且模板中没有 %p 时，内核在文件名后面加上 .pid
+	if c.UsesPID && !hasPID {
+		b.WriteString("." + globPID(pid))
+	}
+	glob := b.String()
+	if !filepath.IsAbs(glob) && cwd != "" {
+		glob = filepath.Join(escapeGlob(cwd), glob)
+	}
+	return glob
+}
+
+func globPID(pid int) string {
+	if pid <= 0 {
+		return "*"
+	}
+	return fmt.Sprintf("%d", pid)
+}
+
+// escapeGlob 转义 filepath.Match 中的特殊字符。
+func escapeGlob(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// createDumpIncomingDir 是 -coredump.createdump 时运行时写 dump 的目录，位于 -coredump.dir 之下。
+func createDumpIncomingDir(dir string) string {
+	return filepath.Join(dir, "incoming")
+}
+
+// createDumpPath 是运行时的 createdump 为 pid 写出的 dump，与 createDumpEnv 中的 DOTNET_DbgMiniDumpName 对应。
+func createDumpPath(dir string, pid int) string {
+	return filepath.Join(createDumpIncomingDir(dir), fmt.Sprintf("createdump.%d.dmp", pid))
+}
+
+// CoreDumpSource* 是 core dump 的来源。
+const (
+	CoreDumpSourceKernel     = "kernel"     // 内核按 core_pattern 写出
+	CoreDumpSourceCreateDump = "createdump" // 运行时崩溃时调用 createdump，或者在页面上手动运行 createdump
+	CoreDumpSourceGCore      = "gcore"      // 在页面上手动运行 gdb 的 gcore
+)
+
+// detectCoreDump 查找目标进程 pid 崩溃时产生的 core dump：
+// 先找运行时 createdump 写出的文件，再按 core_pattern 查找，最后检查目标进程的工作目录 cwd 与 /tmp 下常见的文件名。
+// 只接受 since 之后修改过的文件，避免把上一次运行留下的 core 当成这一次的。
+func detectCoreDump(pid int, since time.Time, cwd string) (path, source string) {
+	if GlobalOptions != nil && GlobalOptions.CoreDump.Dir != "" {
+		if candidate := createDumpPath(GlobalOptions.CoreDump.Dir, pid); coreFileSince(candidate, since) {
+			return candidate, CoreDumpSourceCreateDump
+		}
+	}
+	host, _ := os.Hostname()
+	if glob := readCorePattern().Glob(pid, host, cwd); glob != "" {
+		if matches, err := filepath.Glob(glob); err == nil {
+			newest, newestTime := "", time.Time{}
+			for _, match := range matches {
+				info, err := os.Stat(match)
+				if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(since) {
+					continue
+				}
+				if newest == "" || info.ModTime().After(newestTime) {
+					newest, newestTime = match, info.ModTime()
+				}
+			}
+			if newest != "" {
+				return absPath(newest), CoreDumpSourceKernel
+			}
+		}
+	}
+	for _, candidate := range []string{filepath.Join(cwd, fmt.Sprintf("core.%d", pid)), filepath.Join(cwd, "core"), fmt.Sprintf("/tmp/core.%d", pid), "/tmp/core"} {
+		if coreFileSince(candidate, since) {
+			return absPath(candidate), CoreDumpSourceKernel
+		}
+	}
+	return "", ""
+}
+
+func coreFileSince(path string, since time.Time) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && !info.ModTime().Before(since)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,50 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Core Dumps</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;margin:2px 0;}
+.collecting,.compressing{color:#b45309;font-weight:700;}
+.ready{color:#15803d;}
+.failed{color:#991b1b;font-weight:700;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:4px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.num{text-align:right;}
+.error{white-space:pre-wrap;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Core Dumps</h1>
+<div class="meta">core_pattern: <code>{{.CorePattern}}</code> — {{.PatternNote}}</div>
+<div class="meta">createdump on crash (<code>-coredump.createdump</code>): {{.CreateDump}}</div>
+<div class="meta">Cores found after a crash and on-demand dumps are gzip-compressed into <code>{{.Dir}}</code> and the uncompressed file is deleted. {{.TotalSize}} of {{.MaxSize}} used by all targets; the oldest dumps are deleted beyond it. Full data at <a href="/api/coredumps{{.TargetQuery}}" target="_blank">/api/coredumps</a>. <a href="/coredumps{{.TargetQuery}}">refresh</a></div>
+<h2>Dump the running process</h2>
+{{if .Running}}
+<form method="post" action="{{.CollectAction}}">
+pid {{.PID}}:
+<select name="method"><option value="createdump">createdump (the runtime's own dumper)</option><option value="gcore">gcore (gdb)</option></select>
+type <select name="type"><option>Full</option><option>Heap</option><option>Triage</option><option>Mini</option></select> <span class="meta">(createdump only)</span>
+<button type="submit">Generate core dump</button>
+</form>
+<div class="meta">The process is paused while its memory is written. gcore cannot attach while another debugger traces the target, e.g. when it runs under gdb (<code>-with.gdb</code>) or netcoredbg is collecting stacks; use createdump then.</div>
+{{else}}<div class="meta">The target process is not running.</div>{{end}}
+<h2>Dumps</h2>
+{{if not .Dumps}}<div class="meta">No core dump has been collected for this target.</div>{{else}}
+<table>
+<thead><tr><th>time</th><th>pid</th><th>source</th><th>original file</th><th>size</th><th>compressed</th><th>status</th><th></th></tr></thead>
+<tbody>
+{{range .Dumps}}<tr><td>{{.Created}}</td><td>{{.PID}}</td><td>{{.Source}}</td><td>{{if .Original}}{{.Original}}{{else}}-{{end}}</td><td class="num">{{.RawSize}}</td><td class="num">{{if .DownloadURL}}<a href="{{.DownloadURL}}">{{.Size}}</a>{{end}}</td><td><span class="{{.Status}}">{{.Status}}</span>{{if .Error}}<div class="error">{{.Error}}</div>{{end}}</td>
+<td>{{if .DeleteURL}}<form method="post" action="{{.DeleteURL}}" style="display:inline;"><button type="submit">Delete</button></form>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</div>
+</body>
+</html>
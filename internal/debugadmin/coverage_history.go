@@ -0,0 +1,38 @@
+package debugadmin
+
+import "sync"
+
+const maxCoverageHistory = 20
+
+// CoverageHistory 保存一个目标进程的代码覆盖率采集记录，用于在首页展示历史趋势。
+// 每个目标进程各自一份，最多保留 maxCoverageHistory 条。
+type CoverageHistory struct {
+	mu      sync.Mutex
+	records []CoverageRecord
+}
+
+func NewCoverageHistory() *CoverageHistory {
+	return &CoverageHistory{}
+}
+
+func (c *CoverageHistory) Append(rec CoverageRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, rec)
+	if len(c.records) > maxCoverageHistory {
+		c.records = c.records[len(c.records)-maxCoverageHistory:]
+	}
+}
+
+// Snapshot 返回当前代码覆盖率历史记录的一份拷贝，供首页模板渲染。
+// c 为 nil 时返回 nil，方便只关心其他功能的测试直接构造 AdminHandler。
+func (c *CoverageHistory) Snapshot() []CoverageRecord {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]CoverageRecord, len(c.records))
+	copy(out, c.records)
+	return out
+}
@@ -0,0 +1,100 @@
+package debugadmin
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcessCPUTime 解析 /proc/[pid]/stat 的第 14、15 个字段（utime、stime），返回进程累计使用的 CPU 时间。
+func readProcessCPUTime(pid int) (time.Duration, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	content := string(data)
+	lastParen := strings.LastIndex(content, ")")
+	if lastParen < 0 {
+		return 0, fmt.Errorf("invalid stat content for pid %d", pid)
+	}
+	fields := strings.Fields(content[lastParen+1:])
+	const utimeFieldIndex = 11 // overall field 14, minus pid+comm already consumed
+	if len(fields) <= utimeFieldIndex+1 {
+		return 0, fmt.Errorf("stat fields too short for pid %d", pid)
+	}
+	var ticks uint64
+	for _, field := range fields[utimeFieldIndex : utimeFieldIndex+2] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ticks += value
+	}
+	return time.Duration(ticks) * time.Second / clockTicksPerSecond, nil
+}
+
+// cpuUsageSampler 根据两次采样之间累计 CPU 时间的增量计算 CPU 使用率，单位为百分比，一个核跑满为 100。
+type cpuUsageSampler struct {
+	read func(pid int) (time.Duration, error)
+	pid  int
+	cpu  time.Duration
+	at   time.Time
+}
+
+func newCPUUsageSampler() *cpuUsageSampler {
+	return &cpuUsageSampler{read: readProcessCPUTime}
+}
+
+// Sample 返回自上一次采样以来 pid 的 CPU 使用率。第一次采样、pid 变化或读取失败时返回 false。
+func (s *cpuUsageSampler) Sample(pid int, now time.Time) (float64, bool) {
+	if pid <= 0 {
+		s.pid = 0
+		return 0, false
+	}
+	cpu, err := s.read(pid)
+	if err != nil {
+		s.pid = 0
+		return 0, false
+	}
+	previousPID, previousCPU, previousAt := s.pid, s.cpu, s.at
+	s.pid, s.cpu, s.at = pid, cpu, now
+	if previousPID != pid || !now.After(previousAt) {
+		return 0, false
+	}
+	return float64(cpu-previousCPU) / float64(now.Sub(previousAt)) * 100, true
+}
+
+// CPUTraceOptions 是 CPU 使用率持续过高时自动采集 trace 的参数。
+type CPUTraceOptions struct {
+	Threshold float64       // CPU 使用率阈值（百分比，一个核跑满为 100），0 表示不自动采集
+	For       time.Duration // 持续超过阈值多久之后开始采集
+	Kind      string        // trace 种类：cpu / perf / alloc
+	Duration  time.Duration // 采集时长
+	Cooldown  time.Duration // 两次自动采集之间的最短间隔
+}
+
+// cpuTraceTriggerName 是 -trace.cpu.* 对应的内置触发规则名，配置文件中的规则不能使用这个名字。
+const cpuTraceTriggerName = "trace.cpu"
+
+// TriggerRule 把 -trace.cpu.* 转换为一条内置的 cpu 触发规则。它和配置文件 triggers: 中的规则由同一个
+// TriggerEngine 采样、求值，在 /triggers 中一起展示；上一次采集还没有结束时不会再次触发。
+// Threshold 为 0 时返回 false。
+func (opts CPUTraceOptions) TriggerRule() (TriggerRule, bool) {
+	if opts.Threshold <= 0 {
+		return TriggerRule{}, false
+	}
+	trace := TraceRequest{Kind: opts.Kind, Duration: opts.Duration}
+	if opts.Kind == TraceKindPerf {
+		trace.Frequency = defaultPerfFrequency
+	}
+	return TriggerRule{
+		Name:      cpuTraceTriggerName,
+		Condition: TriggerConditionCPU,
+		Threshold: opts.Threshold,
+		For:       opts.For,
+		Actions:   []TriggerAction{{Type: TriggerActionTrace, Trace: trace}},
+		Cooldown:  opts.Cooldown,
+	}, true
+}
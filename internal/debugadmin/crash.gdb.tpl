@@ -0,0 +1,55 @@
+# DebugAdmin 生成的 gdb 崩溃采集脚本，与 {{.LogPath}} 一起保存，可以用 gdb -x 重放
+set pagination off
+set confirm off
+set print address off
+set print pretty on
+set print frame-arguments none
+set print entry-values no
+set print thread-events off
+set disable-randomization off
+
+set logging overwrite on
+set logging file {{.LogPath}}
+set logging enabled on
+
+{{range .StopSignals}}handle {{.}} stop print pass
+{{end}}{{range .PassSignals}}handle {{.}} nostop print pass
+{{end}}{{range .IgnoreSignals}}handle {{.}} nostop noprint pass
+{{end}}
+run
+{{- if .Hold}}
+
+# 进程已经退出（没有停在信号上）时直接结束，不等待 /gdb-console 的命令
+if !$_isvoid($_exitcode) || !$_isvoid($_exitsignal)
+  quit
+end
+echo \n{{.HoldMarker}}\n
+{{- end}}
+
+echo \n===== Program status =====\n
+info program
+echo \n===== Crashed thread =====\n
+thread
+echo \n===== Crash backtrace =====\n
+# JIT 帧没有符号，打印地址才能在查看日志时用 perf map 还原
+set print address on
+bt
+echo \n===== Registers =====\n
+info registers
+{{- if .Commands}}
+echo \n===== Extra commands =====\n
+{{- range .Commands}}
+{{.}}
+{{- end}}
+{{- end}}
+{{- if .ScriptFile}}
+echo \n===== {{.ScriptFile}} =====\n
+{{.Script}}
+{{- end}}
+{{- if .Hold}}
+echo \n===== Waiting for commands from /gdb-console for {{.Hold}} =====\n
+{{- else}}
+set logging enabled off
+kill
+quit 128
+{{- end}}
@@ -0,0 +1,467 @@
+package debugadmin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCrashAnalyzeTimeout = 5 * time.Minute
+	maxCrashReports            = 50
+	// maxCrashHeapTypes 是报告中保留的 dumpheap -stat 类型数，按总大小从大到小。
+	maxCrashHeapTypes = 30
+)
+
+// 崩溃报告的状态。
+const (
+	CrashReportAnalyzing = "analyzing"
+	CrashReportDone      = "done"
+)
+
+// crashSOSCommands 是在 core 上运行的 SOS 命令，每个命令单独运行一次 dotnet-dump analyze，
+// 原始输出分别保存为附件。
+var crashSOSCommands = []struct {
+	name    string
+	command string
+}{
+	{"clrstack", "clrstack -all"},
+	{"pe", "pe"},
+	{"dumpheap", "dumpheap -stat"},
+}
+
+// CrashReport 是对一次崩溃的 core 做事后分析的结果。
+type CrashReport struct {
+	ID             string                  `json:"id"`
+	Target         string                  `json:"target"`
+	PID            int                     `json:"pid"`
+	RunIndex       int                     `json:"run_index"` // RunHistory 中的下标
+	CoreDumpID     string                  `json:"core_dump_id,omitempty"`
+	Core           string                  `json:"core"` // 分析时的 core 文件，分析完成后被压缩保存
+	Program        string                  `json:"program,omitempty"`
+	Created        time.Time               `json:"created"`
+	Finished       time.Time               `json:"finished,omitzero"`
+	Status         string                  `json:"status"`
+	Signal         string                  `json:"signal,omitempty"` // gdb 的 "Program terminated with signal ..."
+	Exception      *CrashException         `json:"exception,omitempty"`
+	Backtrace      []string                `json:"backtrace,omitempty"` // 崩溃线程的原生调用栈
+	Threads        []CrashNativeThread     `json:"threads,omitempty"`
+	Registers      []CrashRegister         `json:"registers,omitempty"`
+	ManagedThreads []CrashManagedThread    `json:"managed_threads,omitempty"`
+	Heap           *CrashHeapStats         `json:"heap,omitempty"`
+	Attachments    []CrashReportAttachment `json:"attachments"`
+}
+
+// CrashException 是 SOS pe 输出的当前线程上的托管异常。
+type CrashException struct {
+	Type           string   `json:"type"`
+	Message        string   `json:"message,omitempty"`
+	InnerException string   `json:"inner_exception,omitempty"`
+	HResult        string   `json:"hresult,omitempty"`
+	Stack          []string `json:"stack,omitempty"`
+}
+
+// CrashNativeThread 是 gdb "info threads" 的一行。
+type CrashNativeThread struct {
+	ID      int    `json:"id"`
+	Target  string `json:"target"` // 例如 Thread 0x7f0c... (LWP 123) "dotnet"
+	Frame   string `json:"frame"`
+	Current bool   `json:"current,omitempty"` // 崩溃的线程
+}
+
+// CrashRegister 是 gdb "info registers" 的一行。
+type CrashRegister struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Natural string `json:"natural,omitempty"`
+}
+
+// CrashManagedThread 是 SOS "clrstack -all" 中一个线程的托管调用栈。
+type CrashManagedThread struct {
+	OSThreadID string   `json:"os_thread_id"`
+	Frames     []string `json:"frames"`
+}
+
+// CrashHeapStats 是 SOS "dumpheap -stat" 的结果。
+type CrashHeapStats struct {
+	Objects int64           `json:"objects"`
+	Bytes   int64           `json:"bytes"`
+	Types   []CrashHeapType `json:"types"`
+}
+
+// CrashHeapType 是 dumpheap -stat 的一行。
+type CrashHeapType struct {
+	MT        string `json:"mt"`
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+	Name      string `json:"name"`
+}
+
+// CrashReportAttachment 是一个分析命令的原始输出。
+type CrashReportAttachment struct {
+	Name     string        `json:"name"` // gdb / clrstack / pe / dumpheap
+	Command  string        `json:"command"`
+	Path     string        `json:"-"`
+	Size     int64         `json:"size"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Signature 返回报告的摘要，例如 "SIGSEGV | System.NullReferenceException | at raise"。
+func (r CrashReport) Signature() string {
+	var parts []string
+	if r.Signal != "" {
+		parts = append(parts, r.Signal)
+	}
+	if r.Exception != nil {
+		parts = append(parts, r.Exception.Type)
+	}
+	if len(r.Backtrace) > 0 {
+		if match := gdbFramePattern.FindStringSubmatch(r.Backtrace[0]); match != nil {
+			parts = append(parts, "at "+match[1])
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// CrashReportStore 保存所有目标进程的崩溃报告，附件写在 dir 下，只保留最近 maxCrashReports 份。
+type CrashReportStore struct {
+	dir     string
+	timeout time.Duration
+	now     func() time.Time
+	run     func(cmd *exec.Cmd) ([]byte, error) // 运行分析命令，测试中替换
+
+	mu      sync.Mutex
+	reports []*CrashReport
+	wg      sync.WaitGroup
+}
+
+func NewCrashReportStore(dir string, timeout time.Duration) *CrashReportStore {
+	return &CrashReportStore{
+		dir:     dir,
+		timeout: timeout,
+		now:     time.Now,
+		run:     func(cmd *exec.Cmd) ([]byte, error) { return cmd.CombinedOutput() },
+	}
+}
+
+// Begin 登记一份正在分析的报告。
+func (s *CrashReportStore) Begin(target string, runIndex int, record RunRecord, coreDumpID string) *CrashReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	id := fmt.Sprintf("%s-%d", now.Format("20060102-150405.000"), record.PID)
+	for suffix := 2; s.find(id) != nil; suffix++ {
+		id = fmt.Sprintf("%s-%d-%d", now.Format("20060102-150405.000"), record.PID, suffix)
+	}
+	report := &CrashReport{
+		ID:         id,
+		Target:     target,
+		PID:        record.PID,
+		RunIndex:   runIndex,
+		CoreDumpID: coreDumpID,
+		Core:       record.CoreDumpPath,
+		Program:    crashProgram(record.Args),
+		Created:    now,
+		Status:     CrashReportAnalyzing,
+	}
+	s.reports = append(s.reports, report)
+	s.trimLocked()
+	return report
+}
+
+// trimLocked 删除最旧的报告，只保留 maxCrashReports 份；还在分析中的报告不删除，
+// 它的 analyze 还在往报告目录里写文件。
+func (s *CrashReportStore) trimLocked() {
+	excess := len(s.reports) - maxCrashReports
+	kept := s.reports[:0]
+	for _, report := range s.reports {
+		if excess > 0 && report.Status != CrashReportAnalyzing {
+			_ = os.RemoveAll(s.reportDir(report))
+			excess--
+			continue
+		}
+		kept = append(kept, report)
+	}
+	clear(s.reports[len(kept):])
+	s.reports = kept
+}
+
+func (s *CrashReportStore) find(id string) *CrashReport {
+	for _, report := range s.reports {
+		if report.ID == id {
+			return report
+		}
+	}
+	return nil
+}
+
+func (s *CrashReportStore) reportDir(report *CrashReport) string {
+	return filepath.Join(s.dir, report.Target, report.ID)
+}
+
+// Analyze 在后台对 core 运行 gdb 与 dotnet-dump，解析输出并保存原始输出，完成后调用 then。
+// core 在 then 中才能压缩、删除。
+func (s *CrashReportStore) Analyze(report *CrashReport, then func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.analyze(report)
+		if then != nil {
+			then()
+		}
+	}()
+}
+
+func (s *CrashReportStore) analyze(report *CrashReport) {
+	dir := s.reportDir(report)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[crash-report] create %s failed: %v\n", dir, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	result := CrashReport{}
+	gdbOutput, gdbAttachment := s.runAttachment(dir, "gdb", BuildCoreAnalyzeCommand(ctx, report.Program, report.Core))
+	result.Attachments = append(result.Attachments, gdbAttachment)
+	analysis := ParseGDBCoreAnalysis(gdbOutput)
+	result.Signal, result.Backtrace, result.Threads, result.Registers = analysis.Signal, analysis.Backtrace, analysis.Threads, analysis.Registers
+
+	for _, sos := range crashSOSCommands {
+		output, attachment := s.runAttachment(dir, sos.name, BuildDumpAnalyzeCommand(ctx, report.Core, sos.command))
+		result.Attachments = append(result.Attachments, attachment)
+		switch sos.name {
+		case "clrstack":
+			result.ManagedThreads = ParseCLRStackAll(output)
+		case "pe":
+			result.Exception = ParsePrintException(output)
+		case "dumpheap":
+			result.Heap = ParseDumpHeapStat(output)
+		}
+	}
+
+	s.mu.Lock()
+	report.Signal, report.Backtrace, report.Threads, report.Registers = result.Signal, result.Backtrace, result.Threads, result.Registers
+	report.ManagedThreads, report.Exception, report.Heap = result.ManagedThreads, result.Exception, result.Heap
+	report.Attachments = result.Attachments
+	report.Finished = s.now()
+	report.Status = CrashReportDone
+	s.mu.Unlock()
+	_, _ = fmt.Fprintf(os.Stdout, "[crash-report] %s analyzed in %s: %s\n", report.Core, report.Finished.Sub(report.Created).Truncate(time.Millisecond), report.Signature())
+}
+
+// runAttachment 运行一个分析命令，把输出保存到 dir/name.txt。
+func (s *CrashReportStore) runAttachment(dir, name string, cmd *exec.Cmd) (string, CrashReportAttachment) {
+	attachment := CrashReportAttachment{
+		Name:    name,
+		Command: strings.Join(cmd.Args, " "),
+		Path:    filepath.Join(dir, name+".txt"),
+	}
+	start := s.now()
+	output, err := s.run(cmd)
+	attachment.Duration = s.now().Sub(start)
+	if err != nil {
+		attachment.Error = err.Error()
+	}
+	if writeErr := os.WriteFile(attachment.Path, output, 0o644); writeErr != nil {
+		attachment.Path = ""
+		if attachment.Error == "" {
+			attachment.Error = "save output: " + writeErr.Error()
+		}
+	}
+	attachment.Size = int64(len(output))
+	return string(output), attachment
+}
+
+// Wait 等待所有的分析完成，用于测试。
+func (s *CrashReportStore) Wait() {
+	s.wg.Wait()
+}
+
+// Get 按 id 查找报告。
+func (s *CrashReportStore) Get(id string) (CrashReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if report := s.find(id); report != nil {
+		return *report, true
+	}
+	return CrashReport{}, false
+}
+
+// crashProgram 返回崩溃的可执行文件，供 gdb 读取符号；.dll 由 dotnet 启动。
+func crashProgram(args []string) string {
+	program, _, err := resolveStartupProgram(args)
+	if err != nil {
+		return ""
+	}
+	if path, err := exec.LookPath(program); err == nil {
+		return path
+	}
+	return ""
+}
+
+var (
+	gdbSectionPattern     = regexp.MustCompile(`^===== (.+) =====$`)
+	gdbSignalPattern      = regexp.MustCompile(`^Program terminated with signal (\w+)`)
+	gdbThreadPattern      = regexp.MustCompile(`^(\*)?\s*(\d+)\s+((?:Thread 0x[0-9a-fA-F]+ \(LWP \d+\)|LWP \d+|process \d+)(?: "[^"]*")?)\s*(.*)$`)
+	gdbRegisterPattern    = regexp.MustCompile(`^([a-z][a-z0-9_]*)\s+(\S+)\s*(.*)$`)
+	clrThreadPattern      = regexp.MustCompile(`^OS Thread Id:\s*(0x[0-9a-fA-F]+)`)
+	clrFramePattern       = regexp.MustCompile(`^[0-9A-Fa-f]{8,16}\s+[0-9A-Fa-f]{8,16}\s+(.+)$`)
+	heapTypePattern       = regexp.MustCompile(`^([0-9a-fA-F]{8,16})\s+([\d,]+)\s+([\d,]+)\s+(.+)$`)
+	heapTotalPattern      = regexp.MustCompile(`^Total\s+([\d,]+)\s+objects(?:,\s*([\d,]+)\s+bytes)?`)
+	exceptionFieldPattern = regexp.MustCompile(`^(Exception type|Message|InnerException|HResult):\s*(.*)$`)
+)
+
+// GDBCoreAnalysis 是 BuildCoreAnalyzeCommand 输出的解析结果。
+type GDBCoreAnalysis struct {
+	Signal    string
+	Backtrace []string
+	Threads   []CrashNativeThread
+	Registers []CrashRegister
+}
+
+// ParseGDBCoreAnalysis 按 "===== command =====" 拆分 gdb 的输出并解析每一段。
+func ParseGDBCoreAnalysis(output string) GDBCoreAnalysis {
+	var analysis GDBCoreAnalysis
+	section := ""
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := gdbSectionPattern.FindStringSubmatch(trimmed); match != nil {
+			section = match[1]
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		switch section {
+		case "":
+			if match := gdbSignalPattern.FindStringSubmatch(trimmed); match != nil {
+				analysis.Signal = match[1]
+			}
+		case "info threads":
+			if match := gdbThreadPattern.FindStringSubmatch(line); match != nil {
+				id, _ := strconv.Atoi(match[2])
+				analysis.Threads = append(analysis.Threads, CrashNativeThread{ID: id, Target: match[3], Frame: match[4], Current: match[1] != ""})
+			}
+		case "bt":
+			if strings.HasPrefix(trimmed, "#") {
+				analysis.Backtrace = append(analysis.Backtrace, trimmed)
+			} else if n := len(analysis.Backtrace); n > 0 && line != trimmed {
+				// gdb 把过长的栈帧折行，续行以空白开头
+				analysis.Backtrace[n-1] += " " + trimmed
+			}
+		case "info registers":
+			if match := gdbRegisterPattern.FindStringSubmatch(trimmed); match != nil {
+				analysis.Registers = append(analysis.Registers, CrashRegister{Name: match[1], Value: match[2], Natural: match[3]})
+			}
+		}
+	}
+	return analysis
+}
+
+// ParseCLRStackAll 解析 SOS "clrstack -all" 的输出，只保留栈帧，忽略 "Child SP" 等标题行。
+func ParseCLRStackAll(output string) []CrashManagedThread {
+	var threads []CrashManagedThread
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := clrThreadPattern.FindStringSubmatch(trimmed); match != nil {
+			threads = append(threads, CrashManagedThread{OSThreadID: match[1], Frames: []string{}})
+			continue
+		}
+		if len(threads) == 0 {
+			continue
+		}
+		if match := clrFramePattern.FindStringSubmatch(trimmed); match != nil {
+			current := &threads[len(threads)-1]
+			current.Frames = append(current.Frames, strings.TrimSpace(match[1]))
+		}
+	}
+	return threads
+}
+
+// ParsePrintException 解析 SOS "pe" 的输出，当前线程上没有托管异常时返回 nil。
+func ParsePrintException(output string) *CrashException {
+	var exception CrashException
+	inStack := false
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := exceptionFieldPattern.FindStringSubmatch(trimmed); match != nil {
+			inStack = false
+			value := strings.TrimSpace(match[2])
+			if value == "<none>" {
+				value = ""
+			}
+			switch match[1] {
+			case "Exception type":
+				exception.Type = value
+			case "Message":
+				exception.Message = value
+			case "InnerException":
+				exception.InnerException = value
+			case "HResult":
+				exception.HResult = value
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "StackTrace (generated):"):
+			inStack = true
+		case trimmed == "" || strings.HasPrefix(trimmed, "StackTraceString:"):
+			inStack = false
+		case inStack:
+			if match := clrFramePattern.FindStringSubmatch(trimmed); match != nil {
+				exception.Stack = append(exception.Stack, strings.TrimSpace(match[1]))
+			}
+		}
+	}
+	if exception.Type == "" {
+		return nil
+	}
+	return &exception
+}
+
+// ParseDumpHeapStat 解析 SOS "dumpheap -stat" 的输出，类型按总大小从大到小排列，只保留前 maxCrashHeapTypes 个。
+// 新版本的 SOS 在数字中加入千位分隔符。
+func ParseDumpHeapStat(output string) *CrashHeapStats {
+	var stats CrashHeapStats
+	found := false
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := heapTotalPattern.FindStringSubmatch(trimmed); match != nil {
+			found = true
+			stats.Objects = parseGroupedInt(match[1])
+			stats.Bytes = parseGroupedInt(match[2])
+			continue
+		}
+		if match := heapTypePattern.FindStringSubmatch(trimmed); match != nil {
+			found = true
+			stats.Types = append(stats.Types, CrashHeapType{
+				MT:        match[1],
+				Count:     parseGroupedInt(match[2]),
+				TotalSize: parseGroupedInt(match[3]),
+				Name:      strings.TrimSpace(match[4]),
+			})
+		}
+	}
+	if !found {
+		return nil
+	}
+	sort.SliceStable(stats.Types, func(i, j int) bool { return stats.Types[i].TotalSize > stats.Types[j].TotalSize })
+	if len(stats.Types) > maxCrashHeapTypes {
+		stats.Types = stats.Types[:maxCrashHeapTypes]
+	}
+	return &stats
+}
+
+func parseGroupedInt(text string) int64 {
+	value, _ := strconv.ParseInt(strings.ReplaceAll(text, ",", ""), 10, 64)
+	return value
+}
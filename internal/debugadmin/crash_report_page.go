@@ -0,0 +1,242 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed crashreport.html.tpl
+var crashReportHTMLContent string
+
+var crashReportHTMLTemplate = template.Must(template.New("crashreport.html").Parse(crashReportHTMLContent))
+
+// crashReportPageData 是 /crash-report 页面的数据，所有字符串都已转义。
+type crashReportPageData struct {
+	TargetQuery    string
+	TargetParam    string
+	ID             string
+	Target         string
+	PID            int
+	RunIndex       int
+	Created        string
+	Finished       string
+	Analyzing      bool
+	Signature      string
+	Signal         string
+	Core           string
+	CoreDumpURL    string
+	Exception      *crashExceptionView
+	Backtrace      []string
+	Threads        []crashThreadView
+	Registers      []CrashRegister
+	ManagedThreads []crashManagedThreadView
+	Heap           *crashHeapView
+	Attachments    []crashAttachmentView
+}
+
+type crashExceptionView struct {
+	Type           string
+	Message        string
+	InnerException string
+	HResult        string
+	Stack          []string
+}
+
+type crashThreadView struct {
+	ID      int
+	Target  string
+	Frame   string
+	Current bool
+}
+
+type crashManagedThreadView struct {
+	OSThreadID string
+	Frames     []string
+}
+
+type crashHeapView struct {
+	Objects int64
+	Bytes   string
+	Types   []crashHeapTypeView
+}
+
+type crashHeapTypeView struct {
+	Count     int64
+	TotalSize string
+	Name      string
+}
+
+type crashAttachmentView struct {
+	Name     string
+	Command  string
+	Duration string
+	Size     string
+	Error    string
+	URL      string
+}
+
+// crashReportURL 返回崩溃报告页面的链接。
+func (h *AdminHandler) crashReportURL(id string) string {
+	return "/crash-report?id=" + url.QueryEscape(id) + h.targetQuery("&")
+}
+
+// lookupCrashReport 按 id 查找这个目标进程的崩溃报告。
+func (h *AdminHandler) lookupCrashReport(w http.ResponseWriter, r *http.Request) (CrashReport, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return CrashReport{}, false
+	}
+	if h.crashReports == nil {
+		http.Error(w, "crash analysis is disabled, see -coredump.analyze", http.StatusNotFound)
+		return CrashReport{}, false
+	}
+	report, ok := h.crashReports.Get(r.URL.Query().Get("id"))
+	if !ok || report.Target != h.name {
+		http.NotFound(w, r)
+		return CrashReport{}, false
+	}
+	return report, true
+}
+
+// handleCrashReport 展示一次崩溃的分析结果：信号、托管异常、崩溃线程的调用栈、线程、寄存器、托管调用栈与堆统计。
+func (h *AdminHandler) handleCrashReport(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.lookupCrashReport(w, r)
+	if !ok {
+		return
+	}
+	data := crashReportPageData{
+		TargetQuery: h.targetQuery("?"),
+		TargetParam: h.targetQuery("&"),
+		ID:          html.EscapeString(report.ID),
+		Target:      html.EscapeString(report.Target),
+		PID:         report.PID,
+		RunIndex:    report.RunIndex + 1,
+		Created:     report.Created.Format("2006-01-02 15:04:05"),
+		Analyzing:   report.Status == CrashReportAnalyzing,
+		Signature:   html.EscapeString(report.Signature()),
+		Signal:      html.EscapeString(report.Signal),
+		Core:        html.EscapeString(report.Core),
+		Backtrace:   escapeStrings(report.Backtrace),
+		Registers:   make([]CrashRegister, 0, len(report.Registers)),
+	}
+	if !report.Finished.IsZero() {
+		data.Finished = report.Finished.Format("2006-01-02 15:04:05")
+	}
+	if h.coreDumps != nil {
+		if dump, ok := h.coreDumps.Get(report.CoreDumpID); ok && dump.Status == CoreDumpReady {
+			data.CoreDumpURL = h.coreDumpURL(dump.ID)
+		}
+	}
+	if exception := report.Exception; exception != nil {
+		data.Exception = &crashExceptionView{
+			Type:           html.EscapeString(exception.Type),
+			Message:        html.EscapeString(exception.Message),
+			InnerException: html.EscapeString(exception.InnerException),
+			HResult:        html.EscapeString(exception.HResult),
+			Stack:          escapeStrings(exception.Stack),
+		}
+	}
+	for _, thread := range report.Threads {
+		data.Threads = append(data.Threads, crashThreadView{
+			ID:      thread.ID,
+			Target:  html.EscapeString(thread.Target),
+			Frame:   html.EscapeString(thread.Frame),
+			Current: thread.Current,
+		})
+	}
+	for _, register := range report.Registers {
+		data.Registers = append(data.Registers, CrashRegister{
+			Name:    html.EscapeString(register.Name),
+			Value:   html.EscapeString(register.Value),
+			Natural: html.EscapeString(register.Natural),
+		})
+	}
+	for _, thread := range report.ManagedThreads {
+		if len(thread.Frames) == 0 {
+			continue
+		}
+		data.ManagedThreads = append(data.ManagedThreads, crashManagedThreadView{
+			OSThreadID: html.EscapeString(thread.OSThreadID),
+			Frames:     escapeStrings(thread.Frames),
+		})
+	}
+	if heap := report.Heap; heap != nil {
+		view := &crashHeapView{Objects: heap.Objects, Bytes: formatBytes(uint64(heap.Bytes))}
+		for _, item := range heap.Types {
+			view.Types = append(view.Types, crashHeapTypeView{
+				Count:     item.Count,
+				TotalSize: formatBytes(uint64(item.TotalSize)),
+				Name:      html.EscapeString(item.Name),
+			})
+		}
+		data.Heap = view
+	}
+	for _, attachment := range report.Attachments {
+		view := crashAttachmentView{
+			Name:     html.EscapeString(attachment.Name),
+			Command:  html.EscapeString(attachment.Command),
+			Duration: attachment.Duration.Truncate(time.Millisecond).String(),
+			Size:     formatBytes(uint64(attachment.Size)),
+			Error:    html.EscapeString(attachment.Error),
+		}
+		if attachment.Path != "" {
+			view.URL = "/crash-report/raw?id=" + url.QueryEscape(report.ID) + "&name=" + url.QueryEscape(attachment.Name) + h.targetQuery("&")
+		}
+		data.Attachments = append(data.Attachments, view)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := crashReportHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render crash report page failed: %v\n", err)
+	}
+}
+
+// handleCrashReportRaw 返回一个分析命令的原始输出。
+func (h *AdminHandler) handleCrashReportRaw(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.lookupCrashReport(w, r)
+	if !ok {
+		return
+	}
+	name := r.URL.Query().Get("name")
+	for _, attachment := range report.Attachments {
+		if attachment.Name != name || attachment.Path == "" {
+			continue
+		}
+		content, err := os.ReadFile(attachment.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(content)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// handleCrashReportAPI 以 JSON 返回一份崩溃报告。
+func (h *AdminHandler) handleCrashReportAPI(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.lookupCrashReport(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+func escapeStrings(items []string) []string {
+	escaped := make([]string, 0, len(items))
+	for _, item := range items {
+		escaped = append(escaped, html.EscapeString(strings.TrimSpace(item)))
+	}
+	return escaped
+}
@@ -0,0 +1,253 @@
+package debugadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const gdbCoreAnalysisOutput = `[New LWP 4243]
+[New LWP 4242]
+[Thread debugging using libthread_db enabled]
+Core was generated by ` + "`dotnet /app/MyApp.dll'" + `.
+Program terminated with signal SIGSEGV, Segmentation fault.
+#0  0x00007f1c2d3e4f50 in CrashNative () from /app/libnative.so
+[Current thread is 1 (Thread 0x7f1c2c1ff640 (LWP 4243))]
+
+===== info threads =====
+  Id   Target Id                                   Frame
+* 1    Thread 0x7f1c2c1ff640 (LWP 4243) "MyApp"    0x00007f1c2d3e4f50 in CrashNative () from /app/libnative.so
+  2    Thread 0x7f1c2e5b8740 (LWP 4242) "dotnet"   0x00007f1c2e6a1234 in __futex_abstimed_wait_common () from /lib/x86_64-linux-gnu/libc.so.6
+
+===== bt =====
+#0  0x00007f1c2d3e4f50 in CrashNative () from /app/libnative.so
+#1  0x00007f1bfe3a1234 in ?? ()
+#2  0x00007f1c2d9a0011 in CallDescrWorkerInternal ()
+    from /usr/share/dotnet/shared/Microsoft.NETCore.App/8.0.0/libcoreclr.so
+
+===== info registers =====
+rax            0x0                 0
+rip            0x7f1c2d3e4f50      0x7f1c2d3e4f50 <CrashNative+16>
+eflags         0x10246             [ PF ZF IF RF ]
+`
+
+const clrStackAllOutput = `Loading core dump: /cores/core.4242 ...
+Ready to process analysis commands. Type 'help' to list available commands or 'help [command]' to get detailed help on a command.
+Type 'quit' or 'exit' to exit the session.
+> clrstack -all
+OS Thread Id: 0x1093
+        Child SP               IP Call Site
+00007FFD7B6E5F28 00007f1c2d3e4f50 [InlinedCallFrame: 00007ffd7b6e5f28] MyApp.Native.CrashNative()
+00007FFD7B6E6010 00007F1BFE3A1234 MyApp.Program.Main(System.String[]) [/src/Program.cs @ 12]
+OS Thread Id: 0x1092
+        Child SP               IP Call Site
+Failed to start stack walk: 80004005
+> exit
+`
+
+const printExceptionOutput = `> pe
+Exception object: 00007f1bd8012345
+Exception type:   System.NullReferenceException
+Message:          Object reference not set to an instance of an object.
+InnerException:   <none>
+StackTrace (generated):
+    SP               IP               Function
+    00007FFD7B6E6010 00007F1BFE3A1234 MyApp.dll!MyApp.Program.Main(System.String[])+0x1a
+
+StackTraceString: <none>
+HResult: 80004003
+`
+
+const dumpHeapStatOutput = `> dumpheap -stat
+Statistics:
+          MT    Count    TotalSize Class Name
+7f1bfe3a1234        1           24 System.Object
+7f1bfe3a5678    1,024       65,536 System.String
+7f1bfe3a9abc       10        4,096 System.Byte[]
+Total 1,035 objects, 69,656 bytes
+`
+
+func TestParseGDBCoreAnalysis(t *testing.T) {
+	analysis := ParseGDBCoreAnalysis(gdbCoreAnalysisOutput)
+	if analysis.Signal != "SIGSEGV" {
+		t.Errorf("Signal = %q", analysis.Signal)
+	}
+	if len(analysis.Backtrace) != 3 || !strings.HasPrefix(analysis.Backtrace[0], "#0  0x00007f1c2d3e4f50 in CrashNative ()") ||
+		!strings.HasSuffix(analysis.Backtrace[2], "CallDescrWorkerInternal () from /usr/share/dotnet/shared/Microsoft.NETCore.App/8.0.0/libcoreclr.so") {
+		t.Errorf("Backtrace = %q", analysis.Backtrace)
+	}
+	if len(analysis.Threads) != 2 || !analysis.Threads[0].Current || analysis.Threads[1].Current ||
+		analysis.Threads[0].Target != `Thread 0x7f1c2c1ff640 (LWP 4243) "MyApp"` ||
+		analysis.Threads[1].Frame != "0x00007f1c2e6a1234 in __futex_abstimed_wait_common () from /lib/x86_64-linux-gnu/libc.so.6" {
+		t.Errorf("Threads = %+v", analysis.Threads)
+	}
+	if len(analysis.Registers) != 3 || analysis.Registers[1] != (CrashRegister{Name: "rip", Value: "0x7f1c2d3e4f50", Natural: "0x7f1c2d3e4f50 <CrashNative+16>"}) {
+		t.Errorf("Registers = %+v", analysis.Registers)
+	}
+}
+
+func TestParseSOSOutputs(t *testing.T) {
+	threads := ParseCLRStackAll(clrStackAllOutput)
+	if len(threads) != 2 || threads[0].OSThreadID != "0x1093" || len(threads[0].Frames) != 2 ||
+		threads[0].Frames[1] != "MyApp.Program.Main(System.String[]) [/src/Program.cs @ 12]" || len(threads[1].Frames) != 0 {
+		t.Errorf("ParseCLRStackAll() = %+v", threads)
+	}
+
+	exception := ParsePrintException(printExceptionOutput)
+	if exception == nil || exception.Type != "System.NullReferenceException" || exception.InnerException != "" ||
+		exception.HResult != "80004003" || len(exception.Stack) != 1 || exception.Stack[0] != "MyApp.dll!MyApp.Program.Main(System.String[])+0x1a" {
+		t.Errorf("ParsePrintException() = %+v", exception)
+	}
+	if exception := ParsePrintException("There is no current managed exception on this thread\n"); exception != nil {
+		t.Errorf("ParsePrintException() = %+v, want nil", exception)
+	}
+
+	heap := ParseDumpHeapStat(dumpHeapStatOutput)
+	if heap == nil || heap.Objects != 1035 || heap.Bytes != 69656 || len(heap.Types) != 3 ||
+		heap.Types[0].Name != "System.String" || heap.Types[0].Count != 1024 || heap.Types[2].Name != "System.Object" {
+		t.Errorf("ParseDumpHeapStat() = %+v", heap)
+	}
+	if heap := ParseDumpHeapStat("Failed to load data access module\n"); heap != nil {
+		t.Errorf("ParseDumpHeapStat() = %+v, want nil", heap)
+	}
+}
+
+func TestBuildCoreAnalyzeCommand(t *testing.T) {
+	cmd := BuildCoreAnalyzeCommand(t.Context(), "/usr/bin/dotnet", "/cores/core.42")
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, "-batch -nx") || !strings.Contains(args, "/usr/bin/dotnet /cores/core.42") ||
+		!strings.Contains(args, `-ex echo \n===== bt =====\n -ex bt`) || !strings.HasSuffix(args, "-ex info registers") {
+		t.Errorf("args = %q", args)
+	}
+	if cmd := BuildCoreAnalyzeCommand(t.Context(), "", "/cores/core.42"); !strings.Contains(strings.Join(cmd.Args, " "), "-c /cores/core.42") {
+		t.Errorf("args = %q", cmd.Args)
+	}
+}
+
+func TestCrashReportForCrashCore(t *testing.T) {
+	GlobalOptions = &Options{CoreDump: CoreDumpOptions{Dir: t.TempDir()}}
+	coreDumps := NewCoreDumpStore(GlobalOptions.CoreDump.Dir, 1<<30)
+	reports := NewCrashReportStore(filepath.Join(GlobalOptions.CoreDump.Dir, "reports"), time.Minute)
+	raw := writeRawCore(t, 4096)
+	var analyzed []string
+	reports.run = func(cmd *exec.Cmd) ([]byte, error) {
+		if !fileExists(raw) {
+			t.Error("the core must not be compressed before the analysis")
+		}
+		args := strings.Join(cmd.Args, " ")
+		analyzed = append(analyzed, args)
+		switch {
+		case cmd.Args[0] == "gdb":
+			return []byte(gdbCoreAnalysisOutput), nil
+		case strings.Contains(args, "clrstack -all"):
+			return []byte(clrStackAllOutput), nil
+		case strings.Contains(args, "-c pe"):
+			return []byte(printExceptionOutput), nil
+		}
+		return []byte("dotnet-dump: command not found\n"), errors.New("exit status 127")
+	}
+
+	history := NewRunHistory()
+	history.Add(RunRecord{PID: 4242, Reason: RunReasonCrash, Abnormal: true, Args: []string{"/bin/sh"}, CoreDumpPath: raw, CoreDumpSource: CoreDumpSourceKernel})
+	h := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, history: history, coreDumps: coreDumps, crashReports: reports, mux: http.NewServeMux()}
+	h.Register(h.mux)
+	h.collectCrashCoreDump(TargetExit{Target: &TargetProcess{reason: RunReasonCrash}})
+
+	record := history.Snapshot()[0]
+	if record.CrashReportID == "" || record.CoreDumpID == "" {
+		t.Fatalf("record = %+v", record)
+	}
+	reports.Wait()
+	coreDumps.Wait()
+	if len(analyzed) != 4 || !strings.HasPrefix(analyzed[0], "gdb -batch -nx -ex set pagination off /bin/sh "+raw) {
+		t.Errorf("analyzed = %q", analyzed)
+	}
+	if dump, ok := coreDumps.Get(record.CoreDumpID); !ok || dump.Status != CoreDumpReady || fileExists(raw) {
+		t.Errorf("dump = %+v", dump)
+	}
+	report, ok := reports.Get(record.CrashReportID)
+	if !ok || report.Status != CrashReportDone || report.Signature() != "SIGSEGV | System.NullReferenceException | at CrashNative" ||
+		len(report.ManagedThreads) != 2 || report.Heap != nil || len(report.Attachments) != 4 || report.Attachments[3].Error != "exit status 127" {
+		t.Fatalf("report = %+v", report)
+	}
+
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", h.crashReportURL(record.CrashReportID), nil))
+	body := response.Body.String()
+	for _, want := range []string{
+		"SIGSEGV | System.NullReferenceException | at CrashNative",
+		"Object reference not set to an instance of an object.",
+		`<tr class="current"><td>* 1</td>`,
+		"MyApp.Program.Main(System.String[]) [/src/Program.cs @ 12]",
+		"/coredumps/download?id=" + record.CoreDumpID + "&target=api",
+		"/crash-report/raw?id=" + record.CrashReportID + "&name=pe&target=api",
+		"exit status 127",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Contains(body, "http-equiv") {
+		t.Error("a finished report must not refresh")
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/crash-report/raw?id="+record.CrashReportID+"&name=gdb", nil))
+	if response.Code != http.StatusOK || response.Body.String() != gdbCoreAnalysisOutput {
+		t.Errorf("raw = %d %q", response.Code, response.Body.String())
+	}
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/crash-report/raw?id="+record.CrashReportID+"&name=../../etc/passwd", nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("raw of an unknown attachment = %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/api/crash-report?id="+record.CrashReportID, nil))
+	var api CrashReport
+	if err := json.Unmarshal(response.Body.Bytes(), &api); err != nil || api.Exception == nil || api.Signal != "SIGSEGV" || len(api.Threads) != 2 {
+		t.Errorf("api = %+v, err %v", api, err)
+	}
+
+	worker := &AdminHandler{name: "worker", history: NewRunHistory(), crashReports: reports, mux: http.NewServeMux()}
+	worker.Register(worker.mux)
+	response = httptest.NewRecorder()
+	worker.mux.ServeHTTP(response, httptest.NewRequest("GET", "/crash-report?id="+record.CrashReportID, nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("report of another target = %d", response.Code)
+	}
+}
+
+func TestCrashReportStoreBegin(t *testing.T) {
+	reports := NewCrashReportStore(t.TempDir(), time.Minute)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	reports.now = func() time.Time { return now }
+	first := reports.Begin("api", 0, RunRecord{PID: 42}, "")
+	second := reports.Begin("api", 1, RunRecord{PID: 42}, "")
+	if first.ID != "20260102-030405.000-42" || second.ID != "20260102-030405.000-42-2" {
+		t.Fatalf("ids = %q %q", first.ID, second.ID)
+	}
+
+	// first 还在分析中，不能被删除；其余的旧报告分析完成，超出上限时从最旧的开始删除。
+	reports.reports[1].Status = CrashReportDone
+	for i := 0; i < maxCrashReports; i++ {
+		now = now.Add(time.Second)
+		report := reports.Begin("api", i+2, RunRecord{PID: 42}, "")
+		report.Status = CrashReportDone
+	}
+	if len(reports.reports) != maxCrashReports {
+		t.Fatalf("len = %d", len(reports.reports))
+	}
+	if _, ok := reports.Get(first.ID); !ok {
+		t.Error("a report still being analyzed must not be trimmed")
+	}
+	if _, ok := reports.Get(second.ID); ok {
+		t.Error("the oldest finished report must be trimmed")
+	}
+}
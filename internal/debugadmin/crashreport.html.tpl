@@ -0,0 +1,90 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+{{if .Analyzing}}<meta http-equiv="refresh" content="5"/>{{end}}
+<title>Crash Report {{.ID}}</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;margin:2px 0;}
+.signature{font-size:14px;font-weight:700;color:#991b1b;margin:8px 0;}
+.analyzing{color:#b45309;font-weight:700;}
+.exception{padding:10px;border-radius:8px;background:#fee2e2;border:1px solid #fecaca;}
+.exception-type{font-weight:700;color:#991b1b;}
+.frames{margin:6px 0 0 16px;padding-left:10px;border-left:2px solid #d1d5db;font-size:12px;}
+.frame{white-space:pre-wrap;line-height:1.4;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:4px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.num{text-align:right;}
+tr.current td{background:#fef2f2;font-weight:700;}
+.error{white-space:pre-wrap;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Crash Report</h1>
+<div class="meta">target {{.Target}}, pid {{.PID}}, run #{{.RunIndex}}, crashed at {{.Created}}{{if .Finished}}, analyzed at {{.Finished}}{{end}}. <a href="/{{.TargetQuery}}">run history</a> · <a href="/api/crash-report?id={{.ID}}{{.TargetParam}}" target="_blank">/api/crash-report</a></div>
+<div class="meta">core: <code>{{.Core}}</code>{{if .CoreDumpURL}} · <a href="{{.CoreDumpURL}}">download the compressed core</a>{{end}}</div>
+{{if .Analyzing}}<div class="analyzing">Analyzing the core with gdb and dotnet-dump, this page refreshes every 5 seconds...</div>{{end}}
+{{if .Signature}}<div class="signature">{{.Signature}}</div>{{end}}
+{{with .Exception}}
+<h2>Managed exception (pe)</h2>
+<div class="exception">
+<div><span class="exception-type">{{.Type}}</span>{{if .HResult}} <span class="meta">HResult {{.HResult}}</span>{{end}}</div>
+{{if .Message}}<div>{{.Message}}</div>{{end}}
+{{if .InnerException}}<div class="meta">inner exception: {{.InnerException}}</div>{{end}}
+{{if .Stack}}<div class="frames">{{range .Stack}}<div class="frame">{{.}}</div>{{end}}</div>{{end}}
+</div>
+{{end}}
+{{if .Backtrace}}
+<h2>Crashed thread (gdb bt)</h2>
+<div class="frames">{{range .Backtrace}}<div class="frame">{{.}}</div>{{end}}</div>
+{{end}}
+{{if .ManagedThreads}}
+<h2>Managed stacks (clrstack -all), {{len .ManagedThreads}} thread(s)</h2>
+{{range .ManagedThreads}}<details><summary>OS thread {{.OSThreadID}}: {{index .Frames 0}}</summary><div class="frames">{{range .Frames}}<div class="frame">{{.}}</div>{{end}}</div></details>
+{{end}}
+{{end}}
+{{if .Threads}}
+<h2>Threads (gdb info threads)</h2>
+<table>
+<thead><tr><th>id</th><th>thread</th><th>frame</th></tr></thead>
+<tbody>
+{{range .Threads}}<tr{{if .Current}} class="current"{{end}}><td>{{if .Current}}* {{end}}{{.ID}}</td><td>{{.Target}}</td><td>{{.Frame}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Registers}}
+<h2>Registers of the crashed thread (gdb info registers)</h2>
+<table>
+<thead><tr><th>register</th><th>value</th><th></th></tr></thead>
+<tbody>
+{{range .Registers}}<tr><td>{{.Name}}</td><td>{{.Value}}</td><td>{{.Natural}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{with .Heap}}
+<h2>Managed heap (dumpheap -stat): {{.Objects}} objects, {{.Bytes}}</h2>
+<table>
+<thead><tr><th>count</th><th>total size</th><th>type</th></tr></thead>
+<tbody>
+{{range .Types}}<tr><td class="num">{{.Count}}</td><td class="num">{{.TotalSize}}</td><td>{{.Name}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{if .Attachments}}
+<h2>Raw outputs</h2>
+<table>
+<thead><tr><th>name</th><th>command</th><th>time</th><th>size</th><th>error</th></tr></thead>
+<tbody>
+{{range .Attachments}}<tr><td>{{if .URL}}<a href="{{.URL}}" target="_blank">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td>{{.Command}}</td><td>{{.Duration}}</td><td class="num">{{.Size}}</td><td>{{if .Error}}<div class="error">{{.Error}}</div>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</div>
+</body>
+</html>
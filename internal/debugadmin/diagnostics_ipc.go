@@ -0,0 +1,251 @@
+package debugadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// .NET 运行时的诊断端口协议，dotnet-trace / dotnet-counters 都通过它与目标进程通信，
+// 说明见 https://github.com/dotnet/diagnostics/blob/main/documentation/design-docs/ipc-protocol.md
+const (
+	diagnosticsMagic          = "DOTNET_IPC_V1\x00"
+	diagnosticsHeaderSize     = 20
+	diagnosticsEventPipeSet   = 0x02
+	diagnosticsStopTracing    = 0x01
+	diagnosticsCollectTracing = 0x03 // CollectTracing2，可以选择是否在结束时 rundown
+	diagnosticsServerSet      = 0xFF
+	diagnosticsServerOK       = 0x00
+	diagnosticsServerError    = 0xFF
+	diagnosticsFormatNetTrace = 1
+	eventPipeBufferSizeMB     = 256
+	diagnosticsConnectTimeout = 3 * time.Second
+)
+
+// diagnosticSocketDir 是运行时创建诊断端口的目录，即目标进程的 TMPDIR。
+var diagnosticSocketDir = os.TempDir()
+
+// EventPipeProvider 是一个 EventPipe provider 的配置，与 dotnet-trace --providers 的含义相同。
+type EventPipeProvider struct {
+	Name       string `json:"name"`
+	Keywords   uint64 `json:"keywords"`
+	Level      uint32 `json:"level"`
+	FilterData string `json:"filter_data,omitempty"` // 例如 EventCounterIntervalSec=1
+}
+
+// String 按 dotnet-trace 的语法格式化：Name:0xKeywords:Level[:FilterData]。
+func (p EventPipeProvider) String() string {
+	text := fmt.Sprintf("%s:0x%x:%d", p.Name, p.Keywords, p.Level)
+	if p.FilterData != "" {
+		text += ":" + p.FilterData
+	}
+	return text
+}
+
+var eventPipeLevels = map[string]uint32{
+	"logalways":     0,
+	"critical":      1,
+	"error":         2,
+	"warning":       3,
+	"informational": 4,
+	"verbose":       5,
+}
+
+// ParseEventPipeProviders 解析 dotnet-trace 语法的 provider 列表，每行或每个逗号分隔一个：
+// Name[:Keywords[:Level[:FilterData]]]。Keywords 省略时为全部，Level 省略时为 Verbose，Level 也可以写名字。
+func ParseEventPipeProviders(text string) ([]EventPipeProvider, error) {
+	var providers []EventPipeProvider
+	for _, line := range strings.Split(text, "\n") {
+		for _, item := range strings.Split(line, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" || strings.HasPrefix(item, "#") {
+				continue
+			}
+			parts := strings.SplitN(item, ":", 4)
+			provider := EventPipeProvider{Name: strings.TrimSpace(parts[0]), Keywords: ^uint64(0), Level: 5}
+			if provider.Name == "" {
+				return nil, fmt.Errorf("provider name is empty in %q", item)
+			}
+			if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" && parts[1] != "*" {
+				keywords, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(parts[1])), "0x"), 16, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid keywords in %q", item)
+				}
+				provider.Keywords = keywords
+			}
+			if len(parts) > 2 && strings.TrimSpace(parts[2]) != "" {
+				raw := strings.ToLower(strings.TrimSpace(parts[2]))
+				if level, ok := eventPipeLevels[raw]; ok {
+					provider.Level = level
+				} else if level, err := strconv.ParseUint(raw, 10, 32); err == nil && level <= 5 {
+					provider.Level = uint32(level)
+				} else {
+					return nil, fmt.Errorf("invalid level in %q", item)
+				}
+			}
+			if len(parts) > 3 {
+				provider.FilterData = strings.Trim(strings.TrimSpace(parts[3]), `"`)
+			}
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("no provider configured")
+	}
+	return providers, nil
+}
+
+// diagnosticSocketPath 找到 pid 的诊断端口：$TMPDIR/dotnet-diagnostic-{pid}-{key}-socket，有多个时取最新的一个。
+func diagnosticSocketPath(pid int) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(diagnosticSocketDir, fmt.Sprintf("dotnet-diagnostic-%d-*-socket", pid)))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("diagnostic port of process %d not found in %s", pid, diagnosticSocketDir)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		left, _ := os.Stat(matches[i])
+		right, _ := os.Stat(matches[j])
+		if left == nil || right == nil {
+			return matches[i] > matches[j]
+		}
+		return left.ModTime().After(right.ModTime())
+	})
+	return matches[0], nil
+}
+
+func dialDiagnostics(ctx context.Context, pid int) (net.Conn, error) {
+	if pid <= 0 {
+		return nil, errors.New("target process is not running")
+	}
+	path, err := diagnosticSocketPath(pid)
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.Dialer{Timeout: diagnosticsConnectTimeout}
+	return dialer.DialContext(ctx, "unix", path)
+}
+
+// writeDiagnosticsRequest 写出请求头与 payload。
+func writeDiagnosticsRequest(w io.Writer, commandSet, commandID byte, payload []byte) error {
+	if len(payload)+diagnosticsHeaderSize > 0xFFFF {
+		return errors.New("diagnostics request is too large")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(diagnosticsMagic)
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(diagnosticsHeaderSize+len(payload)))
+	buf.WriteByte(commandSet)
+	buf.WriteByte(commandID)
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(0))
+	buf.Write(payload)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readDiagnosticsResponse 读取响应头与 payload，错误响应转换为 error。
+func readDiagnosticsResponse(r io.Reader) ([]byte, error) {
+	header := make([]byte, diagnosticsHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("read diagnostics response: %w", err)
+	}
+	if string(header[:len(diagnosticsMagic)]) != diagnosticsMagic {
+		return nil, errors.New("invalid diagnostics response magic")
+	}
+	size := int(binary.LittleEndian.Uint16(header[14:]))
+	if size < diagnosticsHeaderSize {
+		return nil, fmt.Errorf("invalid diagnostics response size %d", size)
+	}
+	payload := make([]byte, size-diagnosticsHeaderSize)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("read diagnostics response: %w", err)
+	}
+	if header[16] != diagnosticsServerSet {
+		return nil, fmt.Errorf("unexpected diagnostics response command set 0x%x", header[16])
+	}
+	if header[17] == diagnosticsServerError {
+		if len(payload) >= 4 {
+			return nil, fmt.Errorf("diagnostics request failed with HRESULT 0x%08x", binary.LittleEndian.Uint32(payload))
+		}
+		return nil, errors.New("diagnostics request failed")
+	}
+	return payload, nil
+}
+
+// diagnosticsString 编码为协议中的字符串：u32 字符数（含结尾的 0）+ UTF-16LE，空字符串只写长度 0。
+func diagnosticsString(buf *bytes.Buffer, s string) {
+	if s == "" {
+		_ = binary.Write(buf, binary.LittleEndian, uint32(0))
+		return
+	}
+	units := append(utf16.Encode([]rune(s)), 0)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(units)))
+	_ = binary.Write(buf, binary.LittleEndian, units)
+}
+
+// StartEventPipeSession 通过诊断端口开始一个 EventPipe 会话，返回会话 id 与 nettrace 流。
+// 流在 StopEventPipeSession 之后由运行时写完 rundown 并关闭；目标进程退出时也会结束。
+func StartEventPipeSession(ctx context.Context, pid int, providers []EventPipeProvider, rundown bool) (uint64, io.ReadCloser, error) {
+	conn, err := dialDiagnostics(ctx, pid)
+	if err != nil {
+		return 0, nil, err
+	}
+	var payload bytes.Buffer
+	_ = binary.Write(&payload, binary.LittleEndian, uint32(eventPipeBufferSizeMB))
+	_ = binary.Write(&payload, binary.LittleEndian, uint32(diagnosticsFormatNetTrace))
+	if rundown {
+		payload.WriteByte(1)
+	} else {
+		payload.WriteByte(0)
+	}
+	_ = binary.Write(&payload, binary.LittleEndian, uint32(len(providers)))
+	for _, provider := range providers {
+		_ = binary.Write(&payload, binary.LittleEndian, provider.Keywords)
+		_ = binary.Write(&payload, binary.LittleEndian, provider.Level)
+		diagnosticsString(&payload, provider.Name)
+		diagnosticsString(&payload, provider.FilterData)
+	}
+	_ = conn.SetDeadline(time.Now().Add(diagnosticsConnectTimeout))
+	if err := writeDiagnosticsRequest(conn, diagnosticsEventPipeSet, diagnosticsCollectTracing, payload.Bytes()); err != nil {
+		_ = conn.Close()
+		return 0, nil, err
+	}
+	response, err := readDiagnosticsResponse(conn)
+	if err != nil {
+		_ = conn.Close()
+		return 0, nil, err
+	}
+	if len(response) < 8 {
+		_ = conn.Close()
+		return 0, nil, errors.New("diagnostics response has no session id")
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return binary.LittleEndian.Uint64(response), conn, nil
+}
+
+// StopEventPipeSession 通过新的连接停止会话。
+func StopEventPipeSession(ctx context.Context, pid int, sessionID uint64) error {
+	conn, err := dialDiagnostics(ctx, pid)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(diagnosticsConnectTimeout))
+	payload := binary.LittleEndian.AppendUint64(nil, sessionID)
+	if err := writeDiagnosticsRequest(conn, diagnosticsEventPipeSet, diagnosticsStopTracing, payload); err != nil {
+		return err
+	}
+	_, err = readDiagnosticsResponse(conn)
+	return err
+}
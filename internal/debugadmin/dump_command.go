@@ -0,0 +1,48 @@
+package debugadmin
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+// BuildDumpCollectCommand 用 dotnet-dump 通过诊断端口让运行时生成 dump，dumpType 为 Mini / Heap / Triage / Full。
+func BuildDumpCollectCommand(ctx context.Context, pid int, dumpType string, outputPath string) *exec.Cmd {
+	return exec.CommandContext(
+		ctx,
+		"dotnet-dump",
+		"collect",
+		"-p",
+		strconv.Itoa(pid),
+		"--type",
+		dumpType,
+		"-o",
+		outputPath,
+	)
+}
+
+// BuildDumpAnalyzeCommand 对 dump 文件依次执行 SOS 命令，最后执行 exit，避免进入交互模式。
+func BuildDumpAnalyzeCommand(ctx context.Context, dumpPath string, commands ...string) *exec.Cmd {
+	args := []string{"analyze", dumpPath}
+	for _, command := range commands {
+		args = append(args, "-c", command)
+	}
+	args = append(args, "-c", "exit")
+	return exec.CommandContext(ctx, "dotnet-dump", args...)
+}
+
+// BuildGCDumpCommand 用 dotnet-gcdump 通过诊断端口采集托管堆的对象图，采集时会触发一次完整 GC。
+func BuildGCDumpCommand(ctx context.Context, pid int, outputPath string) *exec.Cmd {
+	return exec.CommandContext(ctx, "dotnet-gcdump", "collect", "-p", strconv.Itoa(pid), "-o", outputPath)
+}
+
+// BuildGCoreCommand 用 gdb 的 gcore 为运行中的进程生成 core 文件，写到 prefix.pid。
+// 目标进程已经被 gdb 调试（-with.gdb）时无法再 attach。
+func BuildGCoreCommand(ctx context.Context, pid int, prefix string) *exec.Cmd {
+	return exec.CommandContext(ctx, "gcore", "-o", prefix, strconv.Itoa(pid))
+}
+
+// BuildCreateDumpCommand 用运行时自带的 createdump 生成 dump，typeFlag 为 --normal / --withheap / --triage / --full。
+func BuildCreateDumpCommand(ctx context.Context, createDump string, pid int, typeFlag string, outputPath string) *exec.Cmd {
+	return exec.CommandContext(ctx, createDump, "-f", outputPath, typeFlag, strconv.Itoa(pid))
+}
@@ -0,0 +1,374 @@
+package debugadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxEventSessions        = 20   // 保留的会话数，更早的会话连同 nettrace 文件一起删除
+	maxRunningEventSessions = 4    // 同时运行的会话数
+	eventSessionBacklog     = 2000 // 每个会话在内存中保留的最近事件数，新打开的页面先显示这些
+	eventSubscriberBuffer   = 1024
+	eventSessionStopTimeout = 15 * time.Second // 停止后等待运行时写完 rundown 的时间
+)
+
+// EventRecord 是解码后的一个事件。
+type EventRecord struct {
+	Seq         int64           `json:"seq"`
+	Time        time.Time       `json:"time"`
+	Provider    string          `json:"provider"`
+	EventID     uint32          `json:"event_id"`
+	Name        string          `json:"name"`
+	ThreadID    uint64          `json:"thread_id"`
+	Fields      []NetTraceValue `json:"fields,omitempty"`
+	PayloadSize int             `json:"payload_size"`
+}
+
+// Matches 判断事件是否包含 filter（不区分大小写），匹配 provider、事件名与字段值。
+func (e EventRecord) Matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	if strings.Contains(strings.ToLower(e.Provider), filter) || strings.Contains(strings.ToLower(e.Name), filter) {
+		return true
+	}
+	for _, field := range e.Fields {
+		if strings.Contains(strings.ToLower(field.Value), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeEventSchema 是运行时内置事件的名字与 payload 布局。这些事件的 metadata 中没有字段定义，
+// 只能按 ClrEtwAll.man 解码；后续版本只在末尾追加字段，所以按较早版本的布局解码前面的字段。
+type runtimeEventSchema struct {
+	Name   string
+	Fields []NetTraceField
+}
+
+var runtimeEventSchemas = map[uint32]runtimeEventSchema{
+	1: {Name: "GCStart", Fields: []NetTraceField{
+		{Name: "Count", TypeCode: netTraceTypeUInt32}, {Name: "Depth", TypeCode: netTraceTypeUInt32},
+		{Name: "Reason", TypeCode: netTraceTypeUInt32}, {Name: "Type", TypeCode: netTraceTypeUInt32},
+	}},
+	2: {Name: "GCEnd", Fields: []NetTraceField{
+		{Name: "Count", TypeCode: netTraceTypeUInt32}, {Name: "Depth", TypeCode: netTraceTypeUInt32},
+	}},
+	gcAllocationTickEventID: {Name: "GCAllocationTick", Fields: []NetTraceField{
+		{Name: "AllocationAmount", TypeCode: netTraceTypeUInt32}, {Name: "AllocationKind", TypeCode: netTraceTypeUInt32},
+		{Name: "ClrInstanceID", TypeCode: netTraceTypeUInt16}, {Name: "AllocationAmount64", TypeCode: netTraceTypeUInt64},
+		{Name: "TypeID", TypeCode: netTraceTypeUInt64}, {Name: "TypeName", TypeCode: netTraceTypeString},
+	}},
+	35: {Name: "GCTriggered", Fields: []NetTraceField{{Name: "Reason", TypeCode: netTraceTypeUInt32}}},
+	exceptionThrownEventID: {Name: "ExceptionThrown", Fields: []NetTraceField{
+		{Name: "ExceptionType", TypeCode: netTraceTypeString}, {Name: "ExceptionMessage", TypeCode: netTraceTypeString},
+		{Name: "ExceptionEIP", TypeCode: netTraceTypeUInt64}, {Name: "ExceptionHRESULT", TypeCode: netTraceTypeUInt32},
+		{Name: "ExceptionFlags", TypeCode: netTraceTypeUInt16},
+	}},
+	81:                       {Name: "ContentionStart"},
+	91:                       {Name: "ContentionStop"},
+	methodLoadVerboseEventID: {Name: "MethodLoadVerbose"},
+	145:                      {Name: "MethodJittingStarted"},
+	moduleLoadEventID:        {Name: "ModuleLoad"},
+	250:                      {Name: "ExceptionCatchStart"},
+}
+
+// exceptionThrownEventID 是运行时的 ExceptionThrown_V1 事件，属于 Exception keyword。
+const exceptionThrownEventID = 80
+
+// decodeEventRecord 把 nettrace 事件转换为 EventRecord：EventSource 事件按自带的字段定义解码，运行时事件按内置布局解码。
+func decodeEventRecord(event *NetTraceEvent, at time.Time) EventRecord {
+	record := EventRecord{Time: at, ThreadID: event.ThreadID, PayloadSize: len(event.Payload)}
+	metadata := event.Metadata
+	if metadata == nil {
+		record.Name = "[unknown metadata]"
+		return record
+	}
+	record.Provider = metadata.Provider
+	record.EventID = metadata.EventID
+	record.Name = metadata.Name
+	if fields, ok := metadata.DecodePayload(event.Payload); ok {
+		record.Fields = fields
+	}
+	if metadata.Provider == dotnetRuntimeProvider {
+		if schema, ok := runtimeEventSchemas[metadata.EventID]; ok {
+			if record.Name == "" {
+				record.Name = schema.Name
+			}
+			if record.Fields == nil && schema.Fields != nil {
+				record.Fields, _ = (&NetTraceMetadata{Fields: schema.Fields}).DecodePayload(event.Payload)
+			}
+		}
+	}
+	if record.Name == "" {
+		record.Name = fmt.Sprintf("Event%d", metadata.EventID)
+	}
+	return record
+}
+
+// EventSession 是一个通过诊断端口开始的 EventPipe 会话。原始 nettrace 流保存到 Path，解码后的事件推送给订阅者。
+type EventSession struct {
+	ID        string
+	PID       int
+	Providers []EventPipeProvider
+	Rundown   bool
+	Started   time.Time
+	Path      string
+
+	sessionID uint64
+	stream    io.ReadCloser
+	done      chan struct{}
+
+	mu            sync.Mutex
+	running       bool
+	stopRequested bool
+	ended         time.Time
+	err           error
+	total         int64
+	bytes         int64
+	backlog       []EventRecord
+	nextClient    int
+	clients       map[int]chan EventRecord
+}
+
+// EventSessionStatus 是会话状态的快照。
+type EventSessionStatus struct {
+	ID        string              `json:"id"`
+	PID       int                 `json:"pid"`
+	Providers []EventPipeProvider `json:"providers"`
+	Started   time.Time           `json:"started"`
+	Ended     time.Time           `json:"ended,omitzero"`
+	Running   bool                `json:"running"`
+	Events    int64               `json:"events"`
+	Bytes     int64               `json:"bytes"`
+	Error     string              `json:"error,omitempty"`
+}
+
+func (s *EventSession) Status() EventSessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := EventSessionStatus{
+		ID:        s.ID,
+		PID:       s.PID,
+		Providers: s.Providers,
+		Started:   s.Started,
+		Ended:     s.ended,
+		Running:   s.running,
+		Events:    s.total,
+		Bytes:     s.bytes,
+	}
+	if s.err != nil {
+		status.Error = s.err.Error()
+	}
+	return status
+}
+
+// Subscribe 返回内存中最近的事件与之后的新事件。会话结束时 channel 被关闭。
+func (s *EventSession) Subscribe() ([]EventRecord, <-chan EventRecord, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backlog := append([]EventRecord(nil), s.backlog...)
+	ch := make(chan EventRecord, eventSubscriberBuffer)
+	if !s.running {
+		close(ch)
+		return backlog, ch, func() {}
+	}
+	id := s.nextClient
+	s.nextClient++
+	s.clients[id] = ch
+	cancel := func() {
+		s.mu.Lock()
+		if client, ok := s.clients[id]; ok {
+			delete(s.clients, id)
+			close(client)
+		}
+		s.mu.Unlock()
+	}
+	return backlog, ch, cancel
+}
+
+func (s *EventSession) publish(record EventRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	record.Seq = s.total
+	if len(s.backlog) >= eventSessionBacklog {
+		s.backlog = append(s.backlog[:0], s.backlog[len(s.backlog)/2:]...)
+	}
+	s.backlog = append(s.backlog, record)
+	for _, ch := range s.clients {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
+
+// run 读取 nettrace 流直到运行时关闭它，同时把原始数据写到 Path。
+func (s *EventSession) run() {
+	defer close(s.done)
+	err := s.consume()
+	_ = s.stream.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && !(s.stopRequested && errors.Is(err, io.ErrUnexpectedEOF)) {
+		s.err = err
+	}
+	s.running = false
+	s.ended = time.Now()
+	for id, ch := range s.clients {
+		delete(s.clients, id)
+		close(ch)
+	}
+}
+
+func (s *EventSession) consume() error {
+	file, err := os.Create(s.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	counter := &countingWriter{w: file, count: func(n int) {
+		s.mu.Lock()
+		s.bytes += int64(n)
+		s.mu.Unlock()
+	}}
+	reader, err := NewNetTraceReader(io.TeeReader(s.stream, counter))
+	if err != nil {
+		return err
+	}
+	return reader.ReadEvents(func(event *NetTraceEvent) error {
+		s.publish(decodeEventRecord(event, reader.Time(event.Timestamp)))
+		return nil
+	})
+}
+
+// Stop 请求运行时结束会话，等待它写完剩余的事件；超时后直接断开连接。
+func (s *EventSession) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopRequested = true
+	s.mu.Unlock()
+
+	stopErr := StopEventPipeSession(ctx, s.PID, s.sessionID)
+	timer := time.NewTimer(eventSessionStopTimeout)
+	defer timer.Stop()
+	if stopErr != nil {
+		// 诊断端口已经不可用（例如进程已经退出），直接断开
+		_ = s.stream.Close()
+	}
+	select {
+	case <-s.done:
+	case <-timer.C:
+		_ = s.stream.Close()
+		<-s.done
+	}
+	return stopErr
+}
+
+type countingWriter struct {
+	w     io.Writer
+	count func(int)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count(n)
+	return n, err
+}
+
+// EventSessionManager 管理一个目标进程的 EventPipe 会话。
+type EventSessionManager struct {
+	resolvePID func() int
+	mu         sync.Mutex
+	sessions   []*EventSession // 按开始时间排序
+}
+
+func NewEventSessionManager(resolvePID func() int) *EventSessionManager {
+	return &EventSessionManager{resolvePID: resolvePID}
+}
+
+// Start 对当前目标进程开始一个会话。
+func (m *EventSessionManager) Start(ctx context.Context, providers []EventPipeProvider, rundown bool) (*EventSession, error) {
+	m.mu.Lock()
+	running := 0
+	for _, session := range m.sessions {
+		if session.Status().Running {
+			running++
+		}
+	}
+	m.mu.Unlock()
+	if running >= maxRunningEventSessions {
+		return nil, fmt.Errorf("at most %d sessions can run at the same time", maxRunningEventSessions)
+	}
+
+	pid := m.resolvePID()
+	sessionID, stream, err := StartEventPipeSession(ctx, pid, providers, rundown)
+	if err != nil {
+		return nil, err
+	}
+	id := time.Now().Format(traceIDLayout)
+	session := &EventSession{
+		ID:        id,
+		PID:       pid,
+		Providers: providers,
+		Rundown:   rundown,
+		Started:   time.Now(),
+		Path:      filepath.Join("/tmp", "eventpipe-"+id+".nettrace"),
+		sessionID: sessionID,
+		stream:    stream,
+		done:      make(chan struct{}),
+		running:   true,
+		clients:   make(map[int]chan EventRecord),
+	}
+	go session.run()
+
+	m.mu.Lock()
+	m.sessions = append(m.sessions, session)
+	var removed []*EventSession
+	for len(m.sessions) > maxEventSessions && !m.sessions[0].Status().Running {
+		removed = append(removed, m.sessions[0])
+		m.sessions = m.sessions[1:]
+	}
+	m.mu.Unlock()
+	for _, old := range removed {
+		_ = os.Remove(old.Path)
+	}
+	return session, nil
+}
+
+func (m *EventSessionManager) Get(id string) *EventSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, session := range m.sessions {
+		if session.ID == id {
+			return session
+		}
+	}
+	return nil
+}
+
+// List 返回所有会话，最近开始的在前。
+func (m *EventSessionManager) List() []*EventSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*EventSession, 0, len(m.sessions))
+	for i := len(m.sessions) - 1; i >= 0; i-- {
+		result = append(result, m.sessions[i])
+	}
+	return result
+}
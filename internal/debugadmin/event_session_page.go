@@ -0,0 +1,250 @@
+package debugadmin
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed eventpipe.html.tpl
+var eventPipeHTMLContent string
+
+var eventPipeHTMLTemplate = template.Must(template.New("eventpipe.html").Parse(eventPipeHTMLContent))
+
+// defaultEventPipeProviders 是页面上 provider 输入框的初始内容。
+const defaultEventPipeProviders = "Microsoft.AspNetCore.Hosting\nSystem.Net.Http"
+
+type eventPipePageData struct {
+	TargetQuery string
+	Providers   string
+	Error       string
+	Sessions    []eventSessionLink
+	Session     *eventSessionView
+}
+
+type eventSessionLink struct {
+	URL      string
+	ID       string
+	Running  bool
+	Events   int64
+	Selected bool
+}
+
+type eventSessionView struct {
+	ID          string
+	PID         int
+	Started     string
+	Ended       string
+	Events      int64
+	Size        string
+	Providers   string
+	Running     bool
+	Error       string
+	StopURL     string
+	DownloadURL string
+	StreamURL   string
+}
+
+// handleEventPipe 展示 EventPipe 会话页面，id 选择要查看的会话，省略时显示最近的一个。
+func (h *AdminHandler) handleEventPipe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.renderEventPipePage(w, r.URL.Query().Get("id"), defaultEventPipeProviders, "", http.StatusOK)
+}
+
+// handleEventPipeStart 按表单中的 provider 开始一个会话，成功后跳转到会话页面。
+func (h *AdminHandler) handleEventPipeStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	text := r.FormValue("providers")
+	providers, err := ParseEventPipeProviders(text)
+	if err != nil {
+		h.renderEventPipePage(w, "", text, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	session, err := h.eventSessions.Start(ctx, providers, r.FormValue("rundown") == "1")
+	if err != nil {
+		h.renderEventPipePage(w, "", text, "start session failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/eventpipe?id="+url.QueryEscape(session.ID)+h.targetQuery("&"), http.StatusSeeOther)
+}
+
+func (h *AdminHandler) handleEventPipeStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	session := h.eventSessions.Get(id)
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := session.Stop(r.Context()); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[eventpipe] stop session %s: %v\n", id, err)
+	}
+	http.Redirect(w, r, "/eventpipe?id="+url.QueryEscape(id)+h.targetQuery("&"), http.StatusSeeOther)
+}
+
+// handleEventPipeStream 以 server-sent events 推送会话中的事件：先推送内存中最近的事件，再推送新事件。
+// filter 参数只推送 provider、事件名或字段值包含它的事件。会话结束时发送 "end" 事件。
+func (h *AdminHandler) handleEventPipeStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	session := h.eventSessions.Get(r.URL.Query().Get("id"))
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+	filter := strings.TrimSpace(r.URL.Query().Get("filter"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	backlog, ch, cancel := session.Subscribe()
+	defer cancel()
+	send := func(record EventRecord) bool {
+		if !record.Matches(filter) {
+			return true
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return true
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		return err == nil
+	}
+	for _, record := range backlog {
+		if !send(record) {
+			return
+		}
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case record, ok := <-ch:
+			if !ok {
+				_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+			if !send(record) {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleEventPipeDownload 下载会话保存的 nettrace 文件，可以用 PerfView 或 TraceEvent 打开。
+func (h *AdminHandler) handleEventPipeDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session := h.eventSessions.Get(r.URL.Query().Get("id"))
+	if session == nil || !fileExists(session.Path) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "eventpipe-"+session.ID+".nettrace"))
+	http.ServeFile(w, r, session.Path)
+}
+
+// handleEventPipeAPI 以 JSON 返回所有会话的状态。
+func (h *AdminHandler) handleEventPipeAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessions := h.eventSessions.List()
+	statuses := make([]EventSessionStatus, 0, len(sessions))
+	for _, session := range sessions {
+		statuses = append(statuses, session.Status())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(statuses)
+}
+
+func (h *AdminHandler) renderEventPipePage(w http.ResponseWriter, id, providers, errMsg string, status int) {
+	data := eventPipePageData{
+		TargetQuery: h.targetQuery("?"),
+		Providers:   html.EscapeString(providers),
+		Error:       html.EscapeString(errMsg),
+	}
+	sessions := h.eventSessions.List()
+	if id == "" && len(sessions) > 0 {
+		id = sessions[0].ID
+	}
+	targetParam := h.targetQuery("&")
+	for _, session := range sessions {
+		current := session.Status()
+		data.Sessions = append(data.Sessions, eventSessionLink{
+			URL:      "/eventpipe?id=" + url.QueryEscape(current.ID) + targetParam,
+			ID:       current.ID,
+			Running:  current.Running,
+			Events:   current.Events,
+			Selected: current.ID == id,
+		})
+		if current.ID == id {
+			data.Session = buildEventSessionView(current, targetParam)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	if err := eventPipeHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render eventpipe page failed: %v\n", err)
+	}
+}
+
+func buildEventSessionView(status EventSessionStatus, targetParam string) *eventSessionView {
+	providers := make([]string, 0, len(status.Providers))
+	for _, provider := range status.Providers {
+		providers = append(providers, provider.String())
+	}
+	query := "?id=" + url.QueryEscape(status.ID) + targetParam
+	view := &eventSessionView{
+		ID:          status.ID,
+		PID:         status.PID,
+		Started:     status.Started.Format(time.RFC3339),
+		Events:      status.Events,
+		Size:        formatBytes(uint64(status.Bytes)),
+		Providers:   html.EscapeString(strings.Join(providers, ", ")),
+		Running:     status.Running,
+		Error:       html.EscapeString(status.Error),
+		StopURL:     "/eventpipe/stop" + query,
+		DownloadURL: "/eventpipe/download" + query,
+		StreamURL:   "/eventpipe/stream" + query,
+	}
+	if !status.Ended.IsZero() {
+		view.Ended = status.Ended.Format(time.RFC3339)
+	}
+	return view
+}
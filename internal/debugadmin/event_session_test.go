@@ -0,0 +1,187 @@
+package debugadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEventPipeProviders(t *testing.T) {
+	providers, err := ParseEventPipeProviders("Microsoft-Windows-DotNETRuntime:0x8000:4\n  System.Net.Http, MyApp:*:warning:\"Key=Value\"\n# comment\n")
+	if err != nil {
+		t.Fatalf("ParseEventPipeProviders() error = %v", err)
+	}
+	var got []string
+	for _, provider := range providers {
+		got = append(got, provider.String())
+	}
+	want := "Microsoft-Windows-DotNETRuntime:0x8000:4 System.Net.Http:0xffffffffffffffff:5 MyApp:0xffffffffffffffff:3:Key=Value"
+	if strings.Join(got, " ") != want {
+		t.Errorf("providers = %q, want %q", strings.Join(got, " "), want)
+	}
+	for _, text := range []string{"", "MyApp:xyz", "MyApp:0x1:9", ":0x1"} {
+		if _, err := ParseEventPipeProviders(text); err == nil {
+			t.Errorf("ParseEventPipeProviders(%q) expected error", text)
+		}
+	}
+}
+
+func TestDecodeEventRecord(t *testing.T) {
+	metadata := &NetTraceMetadata{Provider: "MyApp", EventID: 3, Name: "RequestStop", Fields: []NetTraceField{
+		{Name: "Path", TypeCode: netTraceTypeString},
+		{Name: "StatusCode", TypeCode: netTraceTypeInt32},
+		{Name: "Elapsed", TypeCode: netTraceTypeDouble},
+	}}
+	payload := (&netTracePayload{}).str("/api/orders").put(int32(500)).put(12.5).Bytes()
+	record := decodeEventRecord(&NetTraceEvent{Metadata: metadata, ThreadID: 9, Payload: payload}, time.Now())
+	if record.Name != "RequestStop" || len(record.Fields) != 3 || record.Fields[0].Value != "/api/orders" ||
+		record.Fields[1].Value != "500" || record.Fields[2].Value != "12.5" {
+		t.Fatalf("record = %+v", record)
+	}
+	if !record.Matches("ORDERS") || record.Matches("health") {
+		t.Errorf("Matches() on %+v", record)
+	}
+
+	// 运行时事件没有字段定义，按内置布局解码
+	exception := (&netTracePayload{}).str("System.InvalidOperationException").str("boom").put(uint64(0)).put(uint32(0x80131509)).put(uint16(1)).Bytes()
+	runtime := &NetTraceMetadata{Provider: dotnetRuntimeProvider, EventID: exceptionThrownEventID}
+	record = decodeEventRecord(&NetTraceEvent{Metadata: runtime, Payload: exception}, time.Now())
+	if record.Name != "ExceptionThrown" || len(record.Fields) != 5 || record.Fields[0].Value != "System.InvalidOperationException" {
+		t.Errorf("runtime record = %+v", record)
+	}
+}
+
+// fakeDiagnosticServer 模拟运行时的诊断端口：CollectTracing 时先发送 head，StopTracing 后发送 tail 并关闭流。
+type fakeDiagnosticServer struct {
+	listener  net.Listener
+	head      []byte
+	tail      []byte
+	requests  chan []byte
+	streaming chan net.Conn
+}
+
+func startFakeDiagnosticServer(t *testing.T, pid int, trace []byte) *fakeDiagnosticServer {
+	t.Helper()
+	dir := t.TempDir()
+	old := diagnosticSocketDir
+	diagnosticSocketDir = dir
+	t.Cleanup(func() { diagnosticSocketDir = old })
+	listener, err := net.Listen("unix", filepath.Join(dir, "dotnet-diagnostic-"+strconv.Itoa(pid)+"-12345-socket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	server := &fakeDiagnosticServer{
+		listener:  listener,
+		head:      trace[:len(trace)-1],
+		tail:      trace[len(trace)-1:],
+		requests:  make(chan []byte, 4),
+		streaming: make(chan net.Conn, 1),
+	}
+	go server.serve()
+	return server
+}
+
+func (s *fakeDiagnosticServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		header := make([]byte, diagnosticsHeaderSize)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			_ = conn.Close()
+			continue
+		}
+		payload := make([]byte, int(binary.LittleEndian.Uint16(header[14:]))-diagnosticsHeaderSize)
+		_, _ = io.ReadFull(conn, payload)
+		s.requests <- append(header, payload...)
+		switch header[17] {
+		case diagnosticsCollectTracing:
+			_ = writeDiagnosticsResponseForTest(conn, binary.LittleEndian.AppendUint64(nil, 7))
+			_, _ = conn.Write(s.head)
+			s.streaming <- conn
+		case diagnosticsStopTracing:
+			_ = writeDiagnosticsResponseForTest(conn, payload)
+			_ = conn.Close()
+			stream := <-s.streaming
+			_, _ = stream.Write(s.tail)
+			_ = stream.Close()
+		}
+	}
+}
+
+func writeDiagnosticsResponseForTest(w io.Writer, payload []byte) error {
+	return writeDiagnosticsRequest(w, diagnosticsServerSet, diagnosticsServerOK, payload)
+}
+
+func TestEventSessionManager(t *testing.T) {
+	const pid = 424242
+	w := newNetTraceWriter(pid, time.Now())
+	w.metadata(1, "MyApp", 3, "RequestStop", 0,
+		NetTraceField{Name: "Path", TypeCode: netTraceTypeString},
+		NetTraceField{Name: "StatusCode", TypeCode: netTraceTypeInt32})
+	w.events("EventBlock", []netTraceTestEvent{
+		{MetadataID: 1, ThreadID: 5, Timestamp: 2000, Payload: (&netTracePayload{}).str("/api/orders").put(int32(200)).Bytes()},
+		{MetadataID: 1, ThreadID: 5, Timestamp: 3000, Payload: (&netTracePayload{}).str("/api/users").put(int32(500)).Bytes()},
+	})
+	trace := w.Bytes()
+	server := startFakeDiagnosticServer(t, pid, trace)
+
+	manager := NewEventSessionManager(func() int { return pid })
+	providers, _ := ParseEventPipeProviders("MyApp:0x1:4")
+	session, err := manager.Start(context.Background(), providers, false)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = os.Remove(session.Path) })
+	request := <-server.requests
+	if request[16] != diagnosticsEventPipeSet || request[17] != diagnosticsCollectTracing {
+		t.Fatalf("request header = %x", request[:diagnosticsHeaderSize])
+	}
+
+	backlog, ch, cancel := session.Subscribe()
+	defer cancel()
+	var records []EventRecord
+	records = append(records, backlog...)
+	for len(records) < 2 {
+		select {
+		case record := <-ch:
+			records = append(records, record)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d events", len(records))
+		}
+	}
+	if records[1].Seq != 2 || records[1].Name != "RequestStop" || records[1].Fields[1].Value != "500" {
+		t.Errorf("records = %+v", records)
+	}
+	if manager.Get(session.ID) != session || len(manager.List()) != 1 {
+		t.Errorf("manager does not list session %s", session.ID)
+	}
+
+	if err := session.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if stop := <-server.requests; stop[17] != diagnosticsStopTracing || binary.LittleEndian.Uint64(stop[diagnosticsHeaderSize:]) != 7 {
+		t.Errorf("stop request = %x", stop)
+	}
+	status := session.Status()
+	if status.Running || status.Events != 2 || status.Bytes != int64(len(trace)) || status.Error != "" || status.Ended.IsZero() {
+		t.Errorf("status = %+v", status)
+	}
+	saved, err := os.ReadFile(session.Path)
+	if err != nil || !bytes.Equal(saved, trace) {
+		t.Errorf("saved nettrace has %d bytes, %v", len(saved), err)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("subscriber channel is still open")
+	}
+}
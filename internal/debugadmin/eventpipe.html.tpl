@@ -0,0 +1,121 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>EventPipe Sessions</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+form{margin:8px 0;}
+textarea,input{font-family:inherit;}
+.presets button{margin:0 6px 6px 0;}
+.sessions a{margin-right:12px;}
+.running{color:#15803d;font-weight:700;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:3px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.fields{white-space:pre-wrap;word-break:break-all;}
+.field-name{color:#6b7280;}
+.error{margin-top:16px;white-space:pre-wrap;padding:10px;border-radius:8px;background:#fee2e2;border:1px solid #fecaca;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>EventPipe Sessions</h1>
+<div class="meta">Starts an EventPipe session through the target's diagnostic port. Providers use the dotnet-trace syntax, one per line: <code>Name[:Keywords[:Level[:FilterData]]]</code>; omitted keywords mean all, omitted level means Verbose.</div>
+<form method="post" action="/eventpipe/start{{.TargetQuery}}">
+<div class="presets">
+<button type="button" onclick="addProvider('Microsoft.AspNetCore.Hosting')">ASP.NET Core requests</button>
+<button type="button" onclick="addProvider('System.Net.Http')">HttpClient</button>
+<button type="button" onclick="addProvider('Microsoft.Data.SqlClient.EventSource:0x1:4')">SqlClient</button>
+<button type="button" onclick="addProvider('Microsoft-Windows-DotNETRuntime:0x8000:4')">Exceptions</button>
+<button type="button" onclick="addProvider('Microsoft-Windows-DotNETRuntime:0x1:4')">GC</button>
+</div>
+<textarea name="providers" id="providers" rows="5" cols="100">{{.Providers}}</textarea><br/>
+<label><input type="checkbox" name="rundown" value="1"/> rundown at stop (method names for PerfView, makes stopping slower)</label>
+<button type="submit">Start Session</button>
+</form>
+<script>
+function addProvider(text){
+	var box = document.getElementById("providers");
+	box.value = box.value.replace(/\s+$/, "") + (box.value.trim() ? "\n" : "") + text;
+}
+</script>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Sessions}}<h2>Sessions</h2><div class="sessions">{{range .Sessions}}<a href="{{.URL}}"{{if .Selected}} style="font-weight:700;"{{end}}>{{.ID}}{{if .Running}} <span class="running">running</span>{{end}} ({{.Events}} events)</a>{{end}}</div>{{end}}
+{{with .Session}}
+<h2>Session {{.ID}}</h2>
+<div class="meta">pid={{.PID}} started={{.Started}}{{if .Ended}} ended={{.Ended}}{{end}} events=<span id="total">{{.Events}}</span> size={{.Size}}</div>
+<div class="meta">providers: {{.Providers}}</div>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+<div>
+{{if .Running}}<form method="post" action="{{.StopURL}}" style="display:inline;"><button type="submit">Stop</button></form>{{end}}
+<a href="{{.DownloadURL}}">download .nettrace</a>{{if .Running}} <span class="meta">(complete after the session stops)</span>{{end}}
+</div>
+<div style="margin-top:8px;">filter <input id="filter" size="40" placeholder="provider, event name or field value"/> <label><input type="checkbox" id="paused"/> pause</label> <span class="meta" id="state"></span></div>
+<table>
+<thead><tr><th>#</th><th>time</th><th>provider</th><th>event</th><th>thread</th><th>payload</th></tr></thead>
+<tbody id="events"></tbody>
+</table>
+<script>
+var streamURL = "{{.StreamURL}}";
+var maxRows = 1000;
+var source = null;
+function cell(row, text, className){
+	var td = document.createElement("td");
+	td.textContent = text;
+	if(className){ td.className = className; }
+	row.appendChild(td);
+	return td;
+}
+function appendEvent(e){
+	if(document.getElementById("paused").checked){ return; }
+	var body = document.getElementById("events");
+	var row = document.createElement("tr");
+	cell(row, e.seq);
+	cell(row, e.time.replace("T", " ").replace(/\+.*|Z$/, ""));
+	cell(row, e.provider);
+	cell(row, e.name + " (" + e.event_id + ")");
+	cell(row, e.thread_id);
+	var fields = cell(row, "", "fields");
+	if(e.fields){
+		e.fields.forEach(function(f){
+			var name = document.createElement("span");
+			name.className = "field-name";
+			name.textContent = f.name + "=";
+			fields.appendChild(name);
+			fields.appendChild(document.createTextNode(f.value + "  "));
+		});
+	}else{
+		fields.textContent = e.payload_size + " bytes";
+	}
+	body.insertBefore(row, body.firstChild);
+	while(body.rows.length > maxRows){ body.deleteRow(body.rows.length - 1); }
+	document.getElementById("total").textContent = e.seq;
+}
+function connect(){
+	if(source){ source.close(); }
+	document.getElementById("events").innerHTML = "";
+	var filter = document.getElementById("filter").value;
+	source = new EventSource(streamURL + "&filter=" + encodeURIComponent(filter));
+	source.onmessage = function(msg){ appendEvent(JSON.parse(msg.data)); };
+	source.addEventListener("end", function(){
+		document.getElementById("state").textContent = "session ended";
+		source.close();
+	});
+	source.onerror = function(){ document.getElementById("state").textContent = "disconnected"; };
+}
+var filterTimer = null;
+document.getElementById("filter").addEventListener("input", function(){
+	clearTimeout(filterTimer);
+	filterTimer = setTimeout(connect, 400);
+});
+connect();
+</script>
+{{end}}
+</div>
+</body>
+</html>
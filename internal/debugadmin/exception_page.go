@@ -0,0 +1,149 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed exceptions.html.tpl
+var exceptionsHTMLContent string
+
+var exceptionsHTMLTemplate = template.Must(template.New("exceptions.html").Parse(exceptionsHTMLContent))
+
+// maxExceptionPageSites 是页面上显示的抛出位置数，完整列表见 /api/exceptions。
+const maxExceptionPageSites = 200
+
+type exceptionsPageData struct {
+	TargetQuery string
+	Enabled     bool
+	Connected   bool
+	PID         int
+	Error       string
+	Since       string
+	Total       int64
+	LastMinute  int64
+	Bars        []exceptionRateBar
+	Types       []ExceptionTypeCount
+	Sites       []exceptionSiteView
+	Hidden      int
+}
+
+type exceptionRateBar struct {
+	Height int // 像素
+	Title  string
+}
+
+type exceptionSiteView struct {
+	Type       string
+	Site       string
+	HResult    string
+	Count      int64
+	LastMinute int64
+	First      string
+	Last       string
+	Samples    []exceptionSampleView
+}
+
+type exceptionSampleView struct {
+	Time     string
+	ThreadID uint64
+	Message  string
+	Stack    string
+}
+
+// handleExceptions 展示 first-chance 异常的统计。
+func (h *AdminHandler) handleExceptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data := buildExceptionsPageData(h.exceptions.Stats(), time.Now())
+	data.TargetQuery = h.targetQuery("?")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := exceptionsHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render exceptions page failed: %v\n", err)
+	}
+}
+
+// handleExceptionsReset 清空异常统计后回到 /exceptions。
+func (h *AdminHandler) handleExceptionsReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.exceptions.Reset()
+	http.Redirect(w, r, "/exceptions"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleExceptionsAPI 以 JSON 返回完整的异常统计。
+func (h *AdminHandler) handleExceptionsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(h.exceptions.Stats())
+}
+
+func buildExceptionsPageData(stats ExceptionStats, now time.Time) exceptionsPageData {
+	data := exceptionsPageData{
+		Enabled:    stats.Enabled,
+		Connected:  stats.Connected,
+		PID:        stats.PID,
+		Error:      html.EscapeString(stats.Error),
+		Since:      stats.Since.Format("2006-01-02 15:04:05"),
+		Total:      stats.Total,
+		LastMinute: stats.LastMinute,
+	}
+	var peak int64
+	for _, count := range stats.PerMinute {
+		peak = max(peak, count)
+	}
+	start := now.Truncate(time.Minute).Add(-time.Duration(len(stats.PerMinute)-1) * time.Minute)
+	for i, count := range stats.PerMinute {
+		bar := exceptionRateBar{Title: fmt.Sprintf("%s: %d", start.Add(time.Duration(i)*time.Minute).Format("15:04"), count)}
+		if peak > 0 {
+			bar.Height = int(count * 60 / peak)
+		}
+		data.Bars = append(data.Bars, bar)
+	}
+	for _, item := range stats.Types {
+		data.Types = append(data.Types, ExceptionTypeCount{Type: html.EscapeString(item.Type), Count: item.Count})
+	}
+	sites := stats.Sites
+	if len(sites) > maxExceptionPageSites {
+		data.Hidden = len(sites) - maxExceptionPageSites
+		sites = sites[:maxExceptionPageSites]
+	}
+	for _, site := range sites {
+		view := exceptionSiteView{
+			Type:       html.EscapeString(site.Type),
+			Site:       html.EscapeString(site.Site),
+			HResult:    fmt.Sprintf("0x%08x", site.HResult),
+			Count:      site.Count,
+			LastMinute: site.LastMinute,
+			First:      site.First.Format("2006-01-02 15:04:05"),
+			Last:       site.Last.Format("2006-01-02 15:04:05"),
+		}
+		for i := len(site.Samples) - 1; i >= 0; i-- {
+			sample := site.Samples[i]
+			view.Samples = append(view.Samples, exceptionSampleView{
+				Time:     sample.Time.Format("15:04:05.000"),
+				ThreadID: sample.ThreadID,
+				Message:  html.EscapeString(sample.Message),
+				Stack:    html.EscapeString(strings.Join(sample.Stack, "\n")),
+			})
+		}
+		data.Sites = append(data.Sites, view)
+	}
+	return data
+}
@@ -0,0 +1,363 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// 运行时 provider 的 Exception keyword；同时开启 Loader 与 JIT 的 Verbose 事件，用新编译方法的地址还原抛出位置。
+	exceptionTrackerKeywords = 0x8000 | 0x10 | 0x8
+	exceptionTrackerRetry    = 5 * time.Second
+	maxExceptionSites        = 500 // 超过之后新的抛出位置合并到 exceptionOtherSites
+	exceptionSiteSamples     = 5   // 每个抛出位置保留的最近样本数
+	exceptionSampleFrames    = 32
+	maxExceptionMessage      = 1024
+	exceptionRateMinutes     = 60 // 按分钟统计的速率保留的分钟数
+)
+
+// exceptionOtherSites 是抛出位置过多时合并计数使用的位置名。
+const exceptionOtherSites = "[other sites]"
+
+// rateSeries 统计最近 exceptionRateMinutes 分钟内每分钟的次数。
+type rateSeries struct {
+	counts  [exceptionRateMinutes]int64
+	minutes [exceptionRateMinutes]int64 // 每个槽位对应的分钟数（unix 秒 / 60）
+}
+
+func (s *rateSeries) add(at time.Time) {
+	minute := at.Unix() / 60
+	slot := minute % exceptionRateMinutes
+	if s.minutes[slot] != minute {
+		s.minutes[slot] = minute
+		s.counts[slot] = 0
+	}
+	s.counts[slot]++
+}
+
+// perMinute 返回截至 now 的每分钟次数，最早的在前，最后一项是当前这一分钟。
+func (s *rateSeries) perMinute(now time.Time) []int64 {
+	current := now.Unix() / 60
+	result := make([]int64, exceptionRateMinutes)
+	for i := range result {
+		minute := current - int64(exceptionRateMinutes-1-i)
+		if slot := minute % exceptionRateMinutes; s.minutes[slot] == minute {
+			result[i] = s.counts[slot]
+		}
+	}
+	return result
+}
+
+// lastMinute 返回最近 60 秒内的次数（当前分钟与上一分钟）。
+func (s *rateSeries) lastMinute(now time.Time) int64 {
+	perMinute := s.perMinute(now)
+	return perMinute[len(perMinute)-1] + perMinute[len(perMinute)-2]
+}
+
+// ExceptionSample 是一次抛出的异常。
+type ExceptionSample struct {
+	Time     time.Time `json:"time"`
+	ThreadID uint64    `json:"thread_id"`
+	Message  string    `json:"message"`
+	Stack    []string  `json:"stack"` // 栈顶在前
+}
+
+// ExceptionSite 是同一类型的异常在同一个位置抛出的统计。
+type ExceptionSite struct {
+	Type       string            `json:"type"`
+	Site       string            `json:"site"`
+	HResult    uint32            `json:"hresult"`
+	Count      int64             `json:"count"`
+	LastMinute int64             `json:"last_minute"`
+	First      time.Time         `json:"first"`
+	Last       time.Time         `json:"last"`
+	Samples    []ExceptionSample `json:"samples"`
+	rate       rateSeries
+}
+
+// ExceptionTypeCount 是按类型合计的次数。
+type ExceptionTypeCount struct {
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+// ExceptionStats 是异常统计的快照。
+type ExceptionStats struct {
+	Enabled     bool                 `json:"enabled"`
+	Connected   bool                 `json:"connected"`
+	PID         int                  `json:"pid"`
+	Error       string               `json:"error,omitempty"`
+	Since       time.Time            `json:"since"` // 开始统计或上一次重置的时间
+	Total       int64                `json:"total"`
+	LastMinute  int64                `json:"last_minute"`
+	PerMinute   []int64              `json:"per_minute"` // 最近 60 分钟每分钟的次数，最早的在前
+	Types       []ExceptionTypeCount `json:"types"`
+	Sites       []ExceptionSite      `json:"sites"` // 按次数从多到少
+	ConnectedAt time.Time            `json:"connected_at,omitzero"`
+}
+
+type exceptionSiteKey struct {
+	Type string
+	Site string
+}
+
+// ExceptionTracker 在后台通过 EventPipe 订阅目标进程的 first-chance 异常，按类型与抛出位置计数。
+// 目标进程重启后自动重新订阅，计数一直累计到 Reset。
+type ExceptionTracker struct {
+	pid      func() int
+	perfMaps *PerfMapIndex
+
+	mu          sync.Mutex
+	enabled     bool
+	connected   bool
+	currentPID  int
+	connectedAt time.Time
+	lastErr     string
+	since       time.Time
+	total       int64
+	rate        rateSeries
+	sites       map[exceptionSiteKey]*ExceptionSite
+	types       map[string]int64
+}
+
+func NewExceptionTracker(pid func() int, perfMaps *PerfMapIndex) *ExceptionTracker {
+	return &ExceptionTracker{
+		pid:      pid,
+		perfMaps: perfMaps,
+		since:    time.Now(),
+		sites:    make(map[exceptionSiteKey]*ExceptionSite),
+		types:    make(map[string]int64),
+	}
+}
+
+// Run 持续订阅目标进程的异常事件，会话结束（例如目标进程退出）后每隔 exceptionTrackerRetry 重新连接，直到 ctx 结束。
+func (t *ExceptionTracker) Run(ctx context.Context) {
+	t.mu.Lock()
+	t.enabled = true
+	t.mu.Unlock()
+	for {
+		if pid := t.pid(); pid > 0 {
+			err := t.track(ctx, pid)
+			t.mu.Lock()
+			t.connected = false
+			message := ""
+			if err != nil {
+				message = err.Error()
+			}
+			changed := message != t.lastErr
+			t.lastErr = message
+			t.mu.Unlock()
+			if err != nil && changed && ctx.Err() == nil {
+				_, _ = fmt.Fprintf(os.Stderr, "[exceptions] tracking process %d: %v\n", pid, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(exceptionTrackerRetry):
+		}
+	}
+}
+
+// track 对 pid 开始一个 EventPipe 会话并处理其中的事件，直到会话结束。
+func (t *ExceptionTracker) track(ctx context.Context, pid int) error {
+	providers := []EventPipeProvider{{Name: dotnetRuntimeProvider, Keywords: exceptionTrackerKeywords, Level: 5}}
+	_, stream, err := StartEventPipeSession(ctx, pid, providers, false)
+	if err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stream.Close()
+		case <-stop:
+		}
+	}()
+	defer stream.Close()
+
+	reader, err := NewNetTraceReader(stream)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.connected = true
+	t.currentPID = pid
+	t.connectedAt = time.Now()
+	t.lastErr = ""
+	t.mu.Unlock()
+
+	symbolizer := &exceptionSymbolizer{pid: pid, perfMaps: t.perfMaps, code: managedCode{modules: make(map[uint64]string)}}
+	err = reader.ReadEvents(func(event *NetTraceEvent) error {
+		if event.Metadata == nil || symbolizer.code.handleEvent(event) {
+			return nil
+		}
+		if event.Metadata.Provider == dotnetRuntimeProvider && event.Metadata.EventID == exceptionThrownEventID {
+			t.addException(event, reader.Time(event.Timestamp), reader.PointerSize, symbolizer)
+		}
+		return nil
+	})
+	// 目标进程退出时运行时直接关闭连接，流在任意位置结束都视为正常
+	if err == nil || errors.Is(err, io.ErrUnexpectedEOF) || ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// addException 解析 ExceptionThrown_V1：ExceptionType string, ExceptionMessage string, ExceptionEIP ptr,
+// ExceptionHRESULT u32, ExceptionFlags u16, ClrInstanceID u16。
+func (t *ExceptionTracker) addException(event *NetTraceEvent, at time.Time, pointerSize int, symbolizer *exceptionSymbolizer) {
+	data := netTraceCursor{data: event.Payload}
+	typeName := data.utf16String()
+	message := data.utf16String()
+	var eip uint64
+	if raw := data.bytes(pointerSize); len(raw) == 8 {
+		eip = binary.LittleEndian.Uint64(raw)
+	} else if len(raw) == 4 {
+		eip = uint64(binary.LittleEndian.Uint32(raw))
+	}
+	hresult := data.uint32()
+	if data.err != nil {
+		return
+	}
+	if typeName == "" {
+		typeName = "[unknown type]"
+	}
+	if len(message) > maxExceptionMessage {
+		message = message[:maxExceptionMessage] + "..."
+	}
+	frames := event.Stack
+	if len(frames) > exceptionSampleFrames {
+		frames = frames[:exceptionSampleFrames]
+	}
+	stack := make([]string, 0, len(frames))
+	for _, addr := range frames {
+		stack = append(stack, symbolizer.Symbolize(addr))
+	}
+	site := "[unknown site]"
+	switch {
+	case len(stack) > 0:
+		site = stack[0]
+	case eip != 0:
+		site = symbolizer.Symbolize(eip)
+	}
+	t.record(typeName, site, hresult, ExceptionSample{Time: at, ThreadID: event.ThreadID, Message: message, Stack: stack})
+}
+
+func (t *ExceptionTracker) record(typeName, site string, hresult uint32, sample ExceptionSample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := exceptionSiteKey{Type: typeName, Site: site}
+	entry, ok := t.sites[key]
+	if !ok && len(t.sites) >= maxExceptionSites {
+		key.Site = exceptionOtherSites
+		entry, ok = t.sites[key]
+	}
+	if !ok {
+		entry = &ExceptionSite{Type: key.Type, Site: key.Site, HResult: hresult, First: sample.Time}
+		t.sites[key] = entry
+	}
+	entry.Count++
+	entry.Last = sample.Time
+	entry.rate.add(sample.Time)
+	if len(entry.Samples) >= exceptionSiteSamples {
+		entry.Samples = append(entry.Samples[:0], entry.Samples[1:]...)
+	}
+	entry.Samples = append(entry.Samples, sample)
+	t.total++
+	t.types[typeName]++
+	t.rate.add(sample.Time)
+}
+
+// Reset 清空所有计数。
+func (t *ExceptionTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.since = time.Now()
+	t.total = 0
+	t.rate = rateSeries{}
+	t.sites = make(map[exceptionSiteKey]*ExceptionSite)
+	t.types = make(map[string]int64)
+}
+
+// LastMinute 返回最近一分钟的异常数。
+func (t *ExceptionTracker) LastMinute() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rate.lastMinute(time.Now())
+}
+
+// Stats 返回当前统计的快照。
+func (t *ExceptionTracker) Stats() ExceptionStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	stats := ExceptionStats{
+		Enabled:    t.enabled,
+		Connected:  t.connected,
+		PID:        t.currentPID,
+		Error:      t.lastErr,
+		Since:      t.since,
+		Total:      t.total,
+		LastMinute: t.rate.lastMinute(now),
+		PerMinute:  t.rate.perMinute(now),
+		Types:      make([]ExceptionTypeCount, 0, len(t.types)),
+		Sites:      make([]ExceptionSite, 0, len(t.sites)),
+	}
+	if t.connected {
+		stats.ConnectedAt = t.connectedAt
+	}
+	for typeName, count := range t.types {
+		stats.Types = append(stats.Types, ExceptionTypeCount{Type: typeName, Count: count})
+	}
+	sort.Slice(stats.Types, func(i, j int) bool {
+		if stats.Types[i].Count != stats.Types[j].Count {
+			return stats.Types[i].Count > stats.Types[j].Count
+		}
+		return stats.Types[i].Type < stats.Types[j].Type
+	})
+	for _, entry := range t.sites {
+		site := *entry
+		site.LastMinute = entry.rate.lastMinute(now)
+		site.Samples = append([]ExceptionSample(nil), entry.Samples...)
+		stats.Sites = append(stats.Sites, site)
+	}
+	sort.Slice(stats.Sites, func(i, j int) bool {
+		if stats.Sites[i].Count != stats.Sites[j].Count {
+			return stats.Sites[i].Count > stats.Sites[j].Count
+		}
+		return stats.Sites[i].Last.After(stats.Sites[j].Last)
+	})
+	return stats
+}
+
+// exceptionSymbolizer 用会话中收到的 JIT 事件还原托管代码地址，会话开始前编译的方法再查 perf map。
+type exceptionSymbolizer struct {
+	pid      int
+	perfMaps *PerfMapIndex
+	code     managedCode
+	table    *PerfMap
+	built    int // table 中包含的方法数
+}
+
+func (s *exceptionSymbolizer) Symbolize(addr uint64) string {
+	if s.table == nil || s.built != len(s.code.methods) {
+		s.table = s.code.perfMap()
+		s.built = len(s.code.methods)
+	}
+	if entry, ok := s.table.Lookup(addr); ok {
+		return entry.Name
+	}
+	if entry, ok := s.perfMaps.Resolve(s.pid, addr); ok {
+		return entry.Name
+	}
+	return fmt.Sprintf("0x%x", addr)
+}
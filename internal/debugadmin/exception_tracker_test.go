@@ -0,0 +1,142 @@
+package debugadmin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exceptionThrownPayload(typeName, message string) []byte {
+	return (&netTracePayload{}).str(typeName).str(message).put(uint64(0x5010)).put(uint32(0x80131509)).put(uint16(1)).put(uint16(0)).Bytes()
+}
+
+// sampleExceptionTrace 模拟异常跟踪会话：先 JIT 了 Parse，之后 Parse 抛出两次 FormatException、Load 抛出一次 IOException。
+func sampleExceptionTrace(pid int) []byte {
+	w := newNetTraceWriter(pid, time.Now())
+	w.metadata(1, dotnetRuntimeProvider, methodLoadVerboseEventID, "", 1)
+	w.metadata(2, dotnetRuntimeProvider, moduleLoadEventID, "", 2)
+	w.metadata(3, dotnetRuntimeProvider, exceptionThrownEventID, "", 1)
+	w.stacks(1, []uint64{0x5010, 0x6010}, []uint64{0x5110, 0x6010})
+	w.events("EventBlock", []netTraceTestEvent{
+		{MetadataID: 2, Timestamp: 1000, Payload: moduleLoadPayload(9, "/app/MyApp.dll")},
+		{MetadataID: 1, Timestamp: 1000, Payload: methodLoadPayload(9, 0x5000, 0x100, "MyApp.Orders", "Parse")},
+		{MetadataID: 1, Timestamp: 1000, Payload: methodLoadPayload(9, 0x6000, 0x100, "MyApp.Program", "Main")},
+		{MetadataID: 3, ThreadID: 7, StackID: 1, Timestamp: 2000, Payload: exceptionThrownPayload("System.FormatException", "bad input 1")},
+		{MetadataID: 3, ThreadID: 7, StackID: 1, Timestamp: 3000, Payload: exceptionThrownPayload("System.FormatException", "bad input 2")},
+		{MetadataID: 3, ThreadID: 8, StackID: 2, Timestamp: 4000, Payload: exceptionThrownPayload("System.IO.IOException", "disk")},
+	})
+	return w.Bytes()
+}
+
+func TestExceptionTracker(t *testing.T) {
+	const pid = 434343
+	startFakeDiagnosticServer(t, pid, sampleExceptionTrace(pid))
+	tracker := NewExceptionTracker(func() int { return pid }, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tracker.track(ctx, pid) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tracker.Stats().Total < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := tracker.Stats()
+	if !stats.Connected || stats.PID != pid || stats.Total != 3 || stats.LastMinute != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if len(stats.Sites) != 2 {
+		t.Fatalf("sites = %+v", stats.Sites)
+	}
+	top := stats.Sites[0]
+	if top.Type != "System.FormatException" || top.Site != "MyApp.dll!MyApp.Orders.Parse" || top.Count != 2 ||
+		len(top.Samples) != 2 || top.Samples[1].Message != "bad input 2" || top.HResult != 0x80131509 {
+		t.Errorf("top site = %+v", top)
+	}
+	if got := strings.Join(top.Samples[0].Stack, " < "); got != "MyApp.dll!MyApp.Orders.Parse < MyApp.dll!MyApp.Program.Main" {
+		t.Errorf("sample stack = %s", got)
+	}
+	if stats.Sites[1].Site != "0x5110" {
+		t.Errorf("unresolved site = %q", stats.Sites[1].Site)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("track() error = %v", err)
+	}
+	tracker.Reset()
+	if stats := tracker.Stats(); stats.Total != 0 || len(stats.Sites) != 0 || len(stats.Types) != 0 {
+		t.Errorf("stats after reset = %+v", stats)
+	}
+}
+
+func TestExceptionTrackerLimitsSites(t *testing.T) {
+	tracker := NewExceptionTracker(func() int { return 0 }, nil)
+	now := time.Now()
+	for i := 0; i < maxExceptionSites+10; i++ {
+		tracker.record("System.Exception", "site"+strings.Repeat("x", i), 0, ExceptionSample{Time: now})
+	}
+	stats := tracker.Stats()
+	if len(stats.Sites) != maxExceptionSites+1 || stats.Sites[0].Site != exceptionOtherSites || stats.Sites[0].Count != 10 {
+		t.Errorf("%d sites, top = %+v", len(stats.Sites), stats.Sites[0])
+	}
+}
+
+func TestRateSeries(t *testing.T) {
+	var series rateSeries
+	base := time.Unix(1_800_000_000, 0).Truncate(time.Minute)
+	series.add(base)
+	series.add(base.Add(30 * time.Second))
+	series.add(base.Add(2 * time.Minute))
+	series.add(base.Add(-90 * time.Minute)) // 超出保留范围，之后会被覆盖
+	perMinute := series.perMinute(base.Add(2 * time.Minute))
+	if len(perMinute) != exceptionRateMinutes || perMinute[exceptionRateMinutes-1] != 1 || perMinute[exceptionRateMinutes-3] != 2 {
+		t.Errorf("per minute = %v", perMinute)
+	}
+	if got := series.lastMinute(base.Add(time.Minute)); got != 2 {
+		t.Errorf("last minute = %d, want 2", got)
+	}
+	if got := series.lastMinute(base.Add(time.Hour)); got != 0 {
+		t.Errorf("last minute an hour later = %d, want 0", got)
+	}
+}
+
+func TestExceptionsPageAndMetrics(t *testing.T) {
+	api := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, traces: NewTraceStore(), mux: http.NewServeMux()}
+	worker := &AdminHandler{name: "worker", targetNames: []string{"api", "worker"}, traces: NewTraceStore(), mux: http.NewServeMux()}
+	for _, h := range []*AdminHandler{api, worker} {
+		h.exceptions = NewExceptionTracker(func() int { return 0 }, nil)
+		h.Register(h.mux)
+	}
+	api.exceptions.enabled = true
+	api.exceptions.record(`My"Exception`, "<site>", 0, ExceptionSample{Time: time.Now(), Message: "<b>boom</b>"})
+	router := NewTargetRouter([]*AdminHandler{api, worker})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	metrics := response.Body.String()
+	if !strings.Contains(metrics, `debugadmin_exceptions_total{target="api",type="My\"Exception"} 1`) ||
+		!strings.Contains(metrics, `debugadmin_exception_tracker_connected{target="api"} 0`) || strings.Contains(metrics, `target="worker"`) {
+		t.Errorf("metrics = %s", metrics)
+	}
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/exceptions?target=api", nil))
+	page := response.Body.String()
+	if !strings.Contains(page, "&lt;b&gt;boom&lt;/b&gt;") || !strings.Contains(page, "&lt;site&gt;") || strings.Contains(page, "<b>boom") {
+		t.Errorf("exceptions page does not show the escaped sample:\n%s", page)
+	}
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/exceptions?target=worker", nil))
+	if !strings.Contains(response.Body.String(), "-exceptions.track") {
+		t.Errorf("disabled tracker page = %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("POST", "/exceptions/reset?target=api", nil))
+	if response.Code != http.StatusSeeOther || api.exceptions.Stats().Total != 0 {
+		t.Errorf("reset status = %d, total = %d", response.Code, api.exceptions.Stats().Total)
+	}
+}
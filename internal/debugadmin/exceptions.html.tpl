@@ -0,0 +1,59 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Exceptions</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+.connected{color:#15803d;font-weight:700;}
+.disconnected{color:#b45309;font-weight:700;}
+.chart{display:flex;align-items:flex-end;gap:2px;height:64px;padding:4px 0;border-bottom:1px solid #d1d5db;}
+.bar{flex:1;background:#dc2626;min-height:1px;}
+.bar.empty{background:#e5e7eb;}
+.types span{display:inline-block;margin:0 14px 4px 0;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:4px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.num{text-align:right;}
+.type{font-weight:700;color:#991b1b;}
+.site{color:#1d4ed8;}
+.sample{margin:6px 0 6px 12px;}
+.message{white-space:pre-wrap;word-break:break-all;}
+.stack{white-space:pre;color:#374151;margin:4px 0 0 12px;padding-left:8px;border-left:2px solid #d1d5db;}
+.error{margin-top:16px;white-space:pre-wrap;padding:10px;border-radius:8px;background:#fee2e2;border:1px solid #fecaca;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>First-chance Exceptions</h1>
+{{if .Enabled}}
+<div class="meta">Counts every exception thrown in the target process, including the ones that are caught, through a background EventPipe session. Exported at <a href="/metrics">/metrics</a>; full data at <a href="/api/exceptions{{.TargetQuery}}" target="_blank">/api/exceptions</a>.</div>
+<div class="meta">{{if .Connected}}<span class="connected">connected</span> to pid {{.PID}}{{else}}<span class="disconnected">not connected</span>, retrying{{end}} · counting since {{.Since}} · total {{.Total}} · last minute {{.LastMinute}}
+<form method="post" action="/exceptions/reset{{.TargetQuery}}" style="display:inline;"><button type="submit">Reset</button></form> <a href="/exceptions{{.TargetQuery}}">refresh</a></div>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+<h2>Exceptions per minute (last hour)</h2>
+<div class="chart">{{range .Bars}}<div class="bar{{if not .Height}} empty{{end}}" style="height:{{.Height}}px;" title="{{.Title}}"></div>{{end}}</div>
+{{if .Types}}<h2>By type</h2><div class="types">{{range .Types}}<span><span class="type">{{.Type}}</span> {{.Count}}</span>{{end}}</div>{{end}}
+<h2>By type and throw site</h2>
+{{if not .Sites}}<div class="meta">No exception has been thrown since {{.Since}}.</div>{{else}}
+<table>
+<thead><tr><th>type</th><th>throw site</th><th>count</th><th>last minute</th><th>first</th><th>last</th></tr></thead>
+<tbody>
+{{range .Sites}}<tr><td class="type">{{.Type}}</td><td><span class="site">{{.Site}}</span>
+<details><summary class="meta">{{len .Samples}} recent sample(s), HRESULT {{.HResult}}</summary>
+{{range .Samples}}<div class="sample"><span class="meta">{{.Time}} thread {{.ThreadID}}</span> <span class="message">{{.Message}}</span>{{if .Stack}}<div class="stack">{{.Stack}}</div>{{end}}</div>
+{{end}}</details></td><td class="num">{{.Count}}</td><td class="num">{{.LastMinute}}</td><td>{{.First}}</td><td>{{.Last}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Hidden}}<div class="meta">{{.Hidden}} less frequent site(s) not shown.</div>{{end}}
+{{end}}
+{{else}}
+<div class="meta">Exception tracking is disabled. Start DebugAdmin with <code>-exceptions.track</code> to count first-chance exceptions in the background.</div>
+{{end}}
+</div>
+</body>
+</html>
@@ -0,0 +1,209 @@
+package debugadmin
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed frame_inspect.html.tpl
+var frameInspectHTMLContent string
+
+var frameInspectHTMLTemplate = template.Must(template.New("frame_inspect.html").Parse(frameInspectHTMLContent))
+
+const (
+	// inspectTimeout 是一次查看栈帧的总时间上限，超时后会 detach 并结束调试器，目标进程继续运行。
+	inspectTimeout      = 15 * time.Second
+	defaultInspectDepth = 2
+	maxInspectDepth     = 5
+	// maxInspectChildren 限制每个对象展开的子节点数，maxInspectNodes 限制一次查看的节点总数。
+	maxInspectChildren = 50
+	maxInspectNodes    = 1000
+)
+
+// VariableNode 是参数或局部变量，以及按深度展开的字段。
+type VariableNode struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type,omitempty"`
+	Value     string         `json:"value"`
+	Children  []VariableNode `json:"children,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"` // 还有子节点因深度或数量限制没有展开
+}
+
+// frameInspector 在一次查看中展开变量，并统计已经展开的节点数。
+type frameInspector struct {
+	client   *MIClient
+	maxDepth int
+	nodes    int
+}
+
+// InspectFrame 读取线程某一帧的参数与局部变量，对象最多展开 maxDepth 层。调用方负责暂停目标进程。
+func (c *MIClient) InspectFrame(ctx context.Context, threadID, frameLevel, maxDepth int) ([]VariableNode, error) {
+	variables, err := c.Variables(ctx, threadID, frameLevel)
+	if err != nil {
+		return nil, err
+	}
+	inspector := &frameInspector{client: c, maxDepth: maxDepth}
+	nodes := make([]VariableNode, 0, len(variables))
+	for _, variable := range variables {
+		node := VariableNode{Name: variable.Name, Type: variable.Type, Value: variable.Value}
+		record, err := c.Exec(ctx, fmt.Sprintf("-var-create --thread %d --frame %d - * %s", threadID, frameLevel, miQuote(variable.Name)))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nodes, err
+			}
+			nodes = append(nodes, node)
+			continue
+		}
+		if node.Type == "" {
+			node.Type = record.Results.String("type")
+		}
+		if err := inspector.expand(ctx, &node, record.Results, 1); err != nil {
+			return append(nodes, node), err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func (f *frameInspector) expand(ctx context.Context, node *VariableNode, variable MIValue, depth int) error {
+	f.nodes++
+	numChild, _ := strconv.Atoi(variable.String("numchild"))
+	if numChild == 0 {
+		return nil
+	}
+	if depth > f.maxDepth || f.nodes >= maxInspectNodes {
+		node.Truncated = true
+		return nil
+	}
+	record, err := f.client.Exec(ctx, fmt.Sprintf("-var-list-children --all-values %s 0 %d", miQuote(variable.String("name")), maxInspectChildren))
+	if err != nil {
+		var miErr *MIError
+		if errors.As(err, &miErr) {
+			node.Truncated = true
+			return nil
+		}
+		return err
+	}
+	children := record.Results.Get("children").Items
+	node.Truncated = numChild > len(children) || record.Results.String("has_more") == "1"
+	for _, child := range children {
+		childNode := VariableNode{Name: child.String("exp"), Type: child.String("type"), Value: child.String("value")}
+		if f.nodes >= maxInspectNodes {
+			node.Truncated = true
+			break
+		}
+		if err := f.expand(ctx, &childNode, child, depth+1); err != nil {
+			node.Children = append(node.Children, childNode)
+			return err
+		}
+		node.Children = append(node.Children, childNode)
+	}
+	return nil
+}
+
+type frameInspectPageData struct {
+	PID         int
+	ThreadID    int
+	FrameLevel  int
+	Depth       int
+	Frame       string
+	Variables   string
+	DebuggerLog string
+	Stderr      string
+	Error       string
+}
+
+// handleStackInspect attach 到目标进程，选中 thread/frame 后读取参数与局部变量并以可折叠的树展示。
+// 整个过程有硬超时，结束时总会 detach，目标进程继续运行。
+func (h *AdminHandler) handleStackInspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	threadID, err := strconv.Atoi(query.Get("thread"))
+	if err != nil || threadID <= 0 {
+		http.Error(w, "invalid thread", http.StatusBadRequest)
+		return
+	}
+	frameLevel, err := strconv.Atoi(query.Get("frame"))
+	if err != nil || frameLevel < 0 {
+		http.Error(w, "invalid frame", http.StatusBadRequest)
+		return
+	}
+	depth := defaultInspectDepth
+	if raw := query.Get("depth"); raw != "" {
+		if depth, err = strconv.Atoi(raw); err != nil || depth < 0 || depth > maxInspectDepth {
+			http.Error(w, fmt.Sprintf("depth should be between 0 and %d", maxInspectDepth), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), inspectTimeout)
+	defer cancel()
+
+	pid := h.resolveTargetPID()
+	data := frameInspectPageData{PID: pid, ThreadID: threadID, FrameLevel: frameLevel, Depth: depth}
+	var variables []VariableNode
+	session, err := startDebuggerSession(ctx, pid)
+	if err == nil {
+		err = session.client.WhileStopped(ctx, func() error {
+			frames, err := session.client.Frames(ctx, threadID)
+			if err != nil {
+				return err
+			}
+			if frameLevel >= len(frames) {
+				return fmt.Errorf("thread %d has %d frames, frame %d does not exist any more", threadID, len(frames), frameLevel)
+			}
+			data.Frame = formatStackFrameHTML(frames[frameLevel])
+			variables, err = session.client.InspectFrame(ctx, threadID, frameLevel, depth)
+			return err
+		})
+		session.Close()
+	}
+	data.Variables = renderVariableTree(variables)
+	data.DebuggerLog = html.EscapeString(strings.TrimSpace(session.Log()))
+	data.Stderr = html.EscapeString(strings.TrimSpace(session.Stderr()))
+	if err != nil {
+		data.Error = html.EscapeString(err.Error())
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = frameInspectHTMLTemplate.Execute(w, data)
+}
+
+// renderVariableTree 把变量树渲染为嵌套的 <details>，所有文本都经过转义。
+func renderVariableTree(nodes []VariableNode) string {
+	var b strings.Builder
+	writeVariableNodes(&b, nodes)
+	return b.String()
+}
+
+func writeVariableNodes(b *strings.Builder, nodes []VariableNode) {
+	for _, node := range nodes {
+		label := `<span class="var-name">` + html.EscapeString(node.Name) + `</span>`
+		if node.Type != "" {
+			label += ` <span class="var-type">` + html.EscapeString(node.Type) + `</span>`
+		}
+		label += ` = <span class="var-value">` + html.EscapeString(node.Value) + `</span>`
+		if node.Truncated {
+			label += ` <span class="var-more">…</span>`
+		}
+		if len(node.Children) == 0 {
+			b.WriteString(`<div class="var-leaf">` + label + "</div>\n")
+			continue
+		}
+		b.WriteString(`<details><summary>` + label + "</summary>\n<div class=\"var-children\">\n")
+		writeVariableNodes(b, node.Children)
+		b.WriteString("</div></details>\n")
+	}
+}
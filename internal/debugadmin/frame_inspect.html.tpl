@@ -0,0 +1,45 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Inspect Frame</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1200px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+.frame{margin-top:8px;white-space:pre-wrap;}
+.frame-idx{color:#374151;}
+.frame-ptr,.frame-il{color:#9ca3af;}
+.frame-dll,.frame-sep,.frame-at,.frame-ns{color:#6b7280;}
+.frame-method{font-weight:700;}
+.frame-path{color:#0f766e;}
+.frame-file{color:#1d4ed8;font-weight:700;}
+.vars{margin-top:8px;line-height:1.5;}
+.vars summary{cursor:pointer;}
+.var-leaf{margin-left:16px;}
+.var-children{margin-left:16px;padding-left:10px;border-left:2px solid #e5e7eb;}
+.var-name{color:#7c2d12;font-weight:700;}
+.var-type{color:#6b7280;}
+.var-value{color:#1d4ed8;white-space:pre-wrap;}
+.var-more{color:#9ca3af;}
+.startup,.stderr,.error{margin-top:16px;white-space:pre-wrap;padding:10px;border-radius:8px;font-size:12px;}
+.startup{background:#f9fafb;border:1px solid #e5e7eb;color:#6b7280;}
+.stderr{background:#fff7ed;border:1px solid #fed7aa;color:#7c2d12;}
+.error{background:#fee2e2;border:1px solid #fecaca;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Inspect Frame</h1>
+<div class="meta">pid={{.PID}} thread={{.ThreadID}} frame={{.FrameLevel}} depth={{.Depth}}</div>
+{{if .Frame}}<div class="frame">{{.Frame}}</div>{{end}}
+<h2>Arguments &amp; Locals</h2>
+<div class="vars">{{if .Variables}}{{.Variables}}{{else}}<div class="meta">No variables.</div>{{end}}</div>
+{{if .Error}}<div class="error">inspect error: {{.Error}}</div>{{end}}
+{{if .DebuggerLog}}<h2>netcoredbg output</h2><pre class="startup">{{.DebuggerLog}}</pre>{{end}}
+{{if .Stderr}}<h2>netcoredbg stderr</h2><pre class="stderr">{{.Stderr}}</pre>{{end}}
+</div>
+</body>
+</html>
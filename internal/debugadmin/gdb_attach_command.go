@@ -18,3 +18,25 @@ func BuildThreadDumpCommand(ctx context.Context, pid int) *exec.Cmd {
 		"-ex", "thread apply all bt",
 	)
 }
+
+// crashCoreGDBSections are the gdb commands run against a crash core. Each
+// command's output is preceded by a "===== command =====" marker so that
+// ParseGDBCoreAnalysis can split the combined output again.
+var crashCoreGDBSections = []string{"info threads", "bt", "info registers"}
+
+// BuildCoreAnalyzeCommand builds a gdb batch invocation that loads a core file
+// and prints the thread list, the crashing thread's backtrace and its registers.
+// program is the crashed executable; without it gdb still reads the core but
+// cannot resolve symbols of the main binary.
+func BuildCoreAnalyzeCommand(ctx context.Context, program, corePath string) *exec.Cmd {
+	args := []string{"-batch", "-nx", "-ex", "set pagination off"}
+	if program != "" {
+		args = append(args, program, corePath)
+	} else {
+		args = append(args, "-c", corePath)
+	}
+	for _, section := range crashCoreGDBSections {
+		args = append(args, "-ex", `echo \n===== `+section+` =====\n`, "-ex", section)
+	}
+	return exec.CommandContext(ctx, "gdb", args...)
+}
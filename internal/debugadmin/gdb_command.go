@@ -1,10 +1,17 @@
 package debugadmin
 
 import (
+	_ "embed"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
 	"time"
 )
 
@@ -13,78 +20,155 @@ const gdbLogTimeLayout = "20060102-150405"
 // BuildGDBStartupCommand creates the gdb invocation after all debugger commands
 // have been persisted to a script. GDB reads the script before it starts the target.
 func BuildGDBStartupCommand(scriptPath string) (*exec.Cmd, error) {
-	program, args, err := resolveStartupProgram()
+	return BuildGDBLaunchCommand(DefaultLaunchSpec(), scriptPath)
+}
+
+// BuildGDBLaunchCommand 与 BuildGDBStartupCommand 相同，但使用 spec 中的参数和环境变量。
+func BuildGDBLaunchCommand(spec LaunchSpec, scriptPath string) (*exec.Cmd, error) {
+	program, args, err := resolveStartupProgram(spec.Args)
 	if err != nil {
 		return nil, err
 	}
 	gdbArgs := append([]string{"-q", "-x", scriptPath, "--args", program}, args...)
-	return exec.Command("gdb", gdbArgs...), nil
+	cmd := exec.Command("gdb", gdbArgs...)
+	cmd.Env = spec.Environ()
+	cmd.Dir = spec.Dir
+	return cmd, nil
 }
 
-// WriteGDBCommandScript writes the complete non-interactive debugging sequence.
+// WriteGDBCommandScript renders the crash capture script from opts and saves it
+// next to the gdb log (YYYYMMDD-HHMMSS.gdb beside YYYYMMDD-HHMMSS.log), so the
+// capture of every run can be reproduced with "gdb -x".
 // The timestamp is generated here rather than by a shell so it can be recorded in run history.
+// Targets started in the same second get a -2, -3... suffix, so every run has its own log and script.
 // 启动 gdb 命令行
-func WriteGDBCommandScript(now time.Time) (scriptPath, logPath string, err error) {
-	logPath = filepath.Join(os.TempDir(), now.Format(gdbLogTimeLayout)+".log")
-	// 把调试命令写到一个 *.gdb 文件
-	script, err := os.CreateTemp(os.TempDir(), "debugadmin-gdb-*.gdb")
-	if err != nil {
-		return "", "", fmt.Errorf("create gdb command script: %w", err)
+func WriteGDBCommandScript(now time.Time, opts GDBScriptOptions) (scriptPath, logPath string, err error) {
+	name := now.Format(gdbLogTimeLayout)
+	var file *os.File
+	for suffix := 2; ; suffix++ {
+		logPath = filepath.Join(os.TempDir(), name+".log")
+		scriptPath = gdbScriptPathForLog(logPath)
+		// 以 O_EXCL 创建脚本占用这个名字，日志由使用这个脚本的 gdb 创建
+		if !fileExists(logPath) {
+			file, err = os.OpenFile(scriptPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, fs.ErrExist) {
+				return "", "", fmt.Errorf("write gdb command script: %w", err)
+			}
+		}
+		name = fmt.Sprintf("%s-%d", now.Format(gdbLogTimeLayout), suffix)
 	}
-	scriptPath = script.Name()
-	if err := script.Chmod(0o600); err != nil {
-		_ = script.Close()
+	defer file.Close()
+	script, err := renderGDBCommandScript(logPath, opts)
+	if err != nil {
 		_ = os.Remove(scriptPath)
-		return "", "", fmt.Errorf("set gdb command script permissions: %w", err)
+		return "", "", err
 	}
 	// 写入多行 gdb 调试命令到 *.gdb 文件中
-	if _, err := script.WriteString(gdbCommandScript(logPath)); err != nil {
-		_ = script.Close()
+	if _, err := file.WriteString(script); err != nil {
 		_ = os.Remove(scriptPath)
 		return "", "", fmt.Errorf("write gdb command script: %w", err)
 	}
-	if err := script.Close(); err != nil {
-		_ = os.Remove(scriptPath)
-		return "", "", fmt.Errorf("close gdb command script: %w", err)
-	}
 	return scriptPath, logPath, nil
 }
 
-// 一系列的 gdb 调试命令
-func gdbCommandScript(logPath string) string {
-	return fmt.Sprintf(`set pagination off
-set confirm off
-set print address off
-set print pretty on
-set print frame-arguments none
-set print entry-values no
-set print thread-events off
-set disable-randomization off
+// gdbScriptPathForLog 返回与 gdb 日志放在一起的脚本路径。
+func gdbScriptPathForLog(logPath string) string {
+	return strings.TrimSuffix(logPath, ".log") + ".gdb"
+}
+
+//go:embed crash.gdb.tpl
+var gdbCrashScriptContent string
+
+var gdbCrashScriptTemplate = template.Must(template.New("crash.gdb").Parse(gdbCrashScriptContent))
+
+// defaultGDBStopSignals 是默认让 gdb 停下来采集崩溃现场的信号，也是 gdb 默认就会停下的致命信号。
+// 不在 -gdb.stop.signals 中的这些信号改为 nostop，直接交给目标进程。
+var defaultGDBStopSignals = []string{"SIGSEGV", "SIGABRT", "SIGBUS", "SIGILL", "SIGFPE"}
+
+// gdbIgnoredSignals 是运行时正常使用的信号，默认不停止也不打印。
+var gdbIgnoredSignals = []string{"SIG34", "SIGPIPE"}
 
-set logging overwrite on
-set logging file %s
-set logging enabled on
+var gdbSignalNamePattern = regexp.MustCompile(`^SIG[A-Z0-9]+$`)
 
-handle SIGSEGV stop print pass
-handle SIGABRT stop print pass
-handle SIGBUS  stop print pass
-handle SIGILL  stop print pass
-handle SIGFPE  stop print pass
-handle SIG34   nostop noprint pass
-handle SIGPIPE nostop noprint pass
+// GDBScriptOptions 是 -with.gdb 时崩溃采集脚本的参数。
+type GDBScriptOptions struct {
+	StopSignals []string // 停下来采集崩溃现场的信号，-gdb.stop.signals
+	Commands    []string // 打印寄存器之后额外执行的命令，-gdb.commands
+	ScriptFile  string   // 在 Commands 之后执行的用户脚本，每次启动时读取并写入生成的脚本，-gdb.script
+	// Hold 大于 0 时，停下来的进程在采集之后不会被立即结束，gdb 改为从 stdin 读取 /gdb-console 发送的命令，
+	// 直到 Hold 到期或者在页面上结束，-gdb.hold
+	Hold time.Duration
+	// holdMarker 是这次运行的 gdb 停下后打印的一行，见 newGDBHoldMarker；为空时使用 gdbHoldMarker
+	holdMarker string
+}
 
-run
+type gdbScriptData struct {
+	LogPath       string
+	StopSignals   []string
+	PassSignals   []string
+	IgnoreSignals []string
+	Commands      []string
+	ScriptFile    string
+	Script        string
+	Hold          time.Duration
+	HoldMarker    string
+}
 
-echo \n===== Program status =====\n
-info program
-echo \n===== Crashed thread =====\n
-thread
-echo \n===== Crash backtrace =====\n
-bt
-echo \n===== Registers =====\n
-info registers
-set logging enabled off
-kill
-quit 128
-`, logPath)
+// renderGDBCommandScript 按 opts 生成崩溃采集脚本。
+func renderGDBCommandScript(logPath string, opts GDBScriptOptions) (string, error) {
+	data := gdbScriptData{LogPath: logPath, StopSignals: opts.StopSignals, Commands: opts.Commands, ScriptFile: opts.ScriptFile, Hold: opts.Hold, HoldMarker: opts.holdMarker}
+	if data.HoldMarker == "" {
+		data.HoldMarker = gdbHoldMarker
+	}
+	if len(data.StopSignals) == 0 {
+		data.StopSignals = defaultGDBStopSignals
+	}
+	for _, signal := range defaultGDBStopSignals {
+		if !slices.Contains(data.StopSignals, signal) {
+			data.PassSignals = append(data.PassSignals, signal)
+		}
+	}
+	for _, signal := range gdbIgnoredSignals {
+		if !slices.Contains(data.StopSignals, signal) {
+			data.IgnoreSignals = append(data.IgnoreSignals, signal)
+		}
+	}
+	if opts.ScriptFile != "" {
+		content, err := os.ReadFile(opts.ScriptFile)
+		if err != nil {
+			return "", fmt.Errorf("read gdb script: %w", err)
+		}
+		data.Script = strings.TrimRight(string(content), " \t\r\n")
+	}
+	var b strings.Builder
+	if err := gdbCrashScriptTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render gdb command script: %w", err)
+	}
+	return b.String(), nil
+}
+
+// parseGDBSignals 解析 -gdb.stop.signals，每一项可以用逗号分隔多个信号，SEGV 与 SIGSEGV 等价。
+func parseGDBSignals(values []string) ([]string, error) {
+	var signals []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			signal := strings.ToUpper(strings.TrimSpace(item))
+			if signal == "" {
+				continue
+			}
+			if !strings.HasPrefix(signal, "SIG") {
+				signal = "SIG" + signal
+			}
+			if !gdbSignalNamePattern.MatchString(signal) {
+				return nil, fmt.Errorf("invalid signal %q", item)
+			}
+			if !slices.Contains(signals, signal) {
+				signals = append(signals, signal)
+			}
+		}
+	}
+	return signals, nil
 }
@@ -0,0 +1,114 @@
+package debugadmin
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// gdbHoldMarker 是 -gdb.hold 时崩溃采集脚本在进程停下后打印的一行的前缀，
+// 每次运行在后面加上随机串（newGDBHoldMarker），目标进程自己的输出和 gdb 共用一个管道，不会被误认。
+const gdbHoldMarker = "===== DebugAdmin: the target is stopped, holding it for /gdb-console ====="
+
+// gdbReleaseCommands 结束保留的现场：结束被调试进程，gdb 以 128 退出，和不保留现场时一样按崩溃处理。
+const gdbReleaseCommands = "kill\nquit 128\n"
+
+var errGDBNotHolding = errors.New("the target is not held by gdb, see -gdb.hold")
+
+// newGDBHoldMarker 返回这次运行的 gdb 停下后打印的一行。
+func newGDBHoldMarker() string {
+	return gdbHoldMarker + " " + rand.Text()
+}
+
+// checkGDBHoldMarker 在 gdb 打印这次运行的 holdMarker 之后确认被调试进程确实停在 gdb 下，然后开始保留现场；
+// 没有停下时结束 gdb，不保留现场。
+func (p *TargetProcess) checkGDBHoldMarker(line string) {
+	if p.holdMarker == "" || strings.TrimSpace(line) != p.holdMarker {
+		return
+	}
+	if inferior := p.resolvePID(); inferior == p.pid || !isProcessStoppedByTracer(inferior, p.pid) {
+		message := fmt.Sprintf("[gdb hold] pid=%d: the target is not stopped under gdb, not holding it\n", p.pid)
+		_, _ = os.Stdout.WriteString(message)
+		p.broker.Broadcast(message)
+		p.holdMu.Lock()
+		defer p.holdMu.Unlock()
+		if !p.holdReleased {
+			p.holdReleased = true
+			_, _ = io.WriteString(p.gdbStdin, gdbReleaseCommands)
+			_ = p.gdbStdin.Close()
+		}
+		return
+	}
+	p.startGDBHold()
+}
+
+// GDBHold 返回 gdb 保留现场的截止时间；进程没有被保留时返回 false。
+func (p *TargetProcess) GDBHold() (time.Time, bool) {
+	p.holdMu.Lock()
+	defer p.holdMu.Unlock()
+	return p.holdUntil, p.holdingLocked()
+}
+
+func (p *TargetProcess) holdingLocked() bool {
+	return p.gdbStdin != nil && !p.holdUntil.IsZero() && !p.holdReleased
+}
+
+// startGDBHold 开始保留现场，到期后自动结束。
+func (p *TargetProcess) startGDBHold() {
+	p.holdMu.Lock()
+	defer p.holdMu.Unlock()
+	if p.gdbStdin == nil || p.gdbHold <= 0 || !p.holdUntil.IsZero() {
+		return
+	}
+	p.holdUntil = time.Now().Add(p.gdbHold)
+	p.holdTimer = time.AfterFunc(p.gdbHold, func() {
+		_ = p.ReleaseGDBHold()
+	})
+	message := fmt.Sprintf("[gdb hold] pid=%d is stopped, inspect it from /gdb-console until %s\n", p.pid, p.holdUntil.Format("15:04:05"))
+	_, _ = os.Stdout.WriteString(message)
+	p.broker.Broadcast(message)
+}
+
+// SendGDBCommand 把一条命令交给保留现场的 gdb 执行。命令本身会先用 echo 写入 gdb 日志，
+// 输出跟在后面，/gdb-console/stream 从日志中读取。
+func (p *TargetProcess) SendGDBCommand(command string) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return errors.New("empty gdb command")
+	}
+	if strings.ContainsAny(command, "\r\n") {
+		return errors.New("gdb command must be a single line")
+	}
+	p.holdMu.Lock()
+	defer p.holdMu.Unlock()
+	if !p.holdingLocked() {
+		return errGDBNotHolding
+	}
+	echo := strings.ReplaceAll(command, `\`, `\\`)
+	if _, err := io.WriteString(p.gdbStdin, "echo \\n(gdb) "+echo+"\\n\n"+command+"\n"); err != nil {
+		return fmt.Errorf("write gdb command: %w", err)
+	}
+	return nil
+}
+
+// ReleaseGDBHold 提前结束保留的现场：gdb 结束被调试进程后退出，之后由重启策略决定是否重新启动。
+func (p *TargetProcess) ReleaseGDBHold() error {
+	p.holdMu.Lock()
+	defer p.holdMu.Unlock()
+	if !p.holdingLocked() {
+		return errGDBNotHolding
+	}
+	p.holdReleased = true
+	p.holdTimer.Stop()
+	_, err := io.WriteString(p.gdbStdin, gdbReleaseCommands)
+	// 关闭 stdin 后 gdb 读到 EOF 也会退出，写入失败时同样能结束现场
+	_ = p.gdbStdin.Close()
+	message := fmt.Sprintf("[gdb hold] pid=%d released\n", p.pid)
+	_, _ = os.Stdout.WriteString(message)
+	p.broker.Broadcast(message)
+	return err
+}
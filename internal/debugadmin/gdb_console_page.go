@@ -0,0 +1,164 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+)
+
+//go:embed gdbconsole.html.tpl
+var gdbConsoleHTMLContent string
+
+var gdbConsoleHTMLTemplate = template.Must(template.New("gdbconsole.html").Parse(gdbConsoleHTMLContent))
+
+// gdbConsolePollInterval 是 /gdb-console/stream 检查 gdb 日志是否有新内容的间隔。
+const gdbConsolePollInterval = 200 * time.Millisecond
+
+type gdbConsolePageData struct {
+	TargetQuery string
+	TargetParam string
+	PID         int
+	GDBMode     bool
+	Holding     bool
+	HoldUntil   string
+	HoldUntilMS int64
+	LogPath     string
+}
+
+// handleGDBConsole 展示 -gdb.hold 保留现场时的 gdb 控制台：gdb 日志的实时输出、命令输入框，
+// 以及 generate-core-file 与结束现场的按钮。
+func (h *AdminHandler) handleGDBConsole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data := gdbConsolePageData{TargetQuery: h.targetQuery("?"), TargetParam: h.targetQuery("&")}
+	if target := h.target.Load(); target != nil {
+		data.PID = target.resolvePID()
+		data.GDBMode = target.Mode() == RunModeGDB
+		data.LogPath = html.EscapeString(target.GDBLogPath())
+		if until, ok := target.GDBHold(); ok {
+			data.Holding = true
+			data.HoldUntil = until.Format("2006-01-02 15:04:05")
+			data.HoldUntilMS = until.UnixMilli()
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := gdbConsoleHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render gdb console page failed: %v\n", err)
+	}
+}
+
+// handleGDBConsoleExec 把表单中的 command 交给保留现场的 gdb 执行，输出从 /gdb-console/stream 读取。
+func (h *AdminHandler) handleGDBConsoleExec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	target := h.target.Load()
+	if target == nil {
+		http.Error(w, errGDBNotHolding.Error(), http.StatusConflict)
+		return
+	}
+	if err := target.SendGDBCommand(r.FormValue("command")); err != nil {
+		status := http.StatusBadRequest
+		if err == errGDBNotHolding {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGDBConsoleRelease 提前结束保留的现场，之后按 -auto.restart 决定是否重新启动。
+func (h *AdminHandler) handleGDBConsoleRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	target := h.target.Load()
+	if target == nil {
+		http.Error(w, errGDBNotHolding.Error(), http.StatusConflict)
+		return
+	}
+	if err := target.ReleaseGDBHold(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/gdb-console"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleGDBConsoleStream 以 server-sent events 推送当前 gdb 日志：先推送已有内容，再推送新写入的内容，
+// 每个事件的 data 是一段 JSON 字符串。gdb 退出时发送 "end" 事件。
+func (h *AdminHandler) handleGDBConsoleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	target := h.target.Load()
+	if target == nil || !isGDBLogPath(target.GDBLogPath()) {
+		http.Error(w, "current gdb log not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	var offset int64
+	send := func() bool {
+		chunk, err := readFileFrom(target.GDBLogPath(), offset)
+		if err != nil || len(chunk) == 0 {
+			return true
+		}
+		offset += int64(len(chunk))
+		data, _ := json.Marshal(string(chunk))
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	ticker := time.NewTicker(gdbConsolePollInterval)
+	defer ticker.Stop()
+	for {
+		if !send() {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-target.exitedCh:
+			send()
+			_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readFileFrom 读取文件从 offset 开始的新内容。
+func readFileFrom(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
@@ -0,0 +1,251 @@
+package debugadmin
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRenderGDBCommandScriptHold(t *testing.T) {
+	script, err := renderGDBCommandScript("/tmp/20260721-123456.log", GDBScriptOptions{Hold: 2 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{
+		"run\n\n# ",
+		"if !$_isvoid($_exitcode) || !$_isvoid($_exitsignal)\n  quit\nend\necho \\n" + gdbHoldMarker + "\\n\n",
+		"info registers\necho \\n===== Waiting for commands from /gdb-console for 2m0s =====\\n\n",
+	} {
+		if !strings.Contains(script, command) {
+			t.Errorf("command script does not contain %q:\n%s", command, script)
+		}
+	}
+	if strings.Contains(script, "kill\nquit 128") {
+		t.Error("a held target must not be killed by the script")
+	}
+	marker := newGDBHoldMarker()
+	if marker == newGDBHoldMarker() || !strings.HasPrefix(marker, gdbHoldMarker+" ") {
+		t.Errorf("newGDBHoldMarker() = %q, want a marker per run", marker)
+	}
+	script, err = renderGDBCommandScript("/tmp/20260721-123456.log", GDBScriptOptions{Hold: time.Minute, holdMarker: marker})
+	if err != nil || !strings.Contains(script, "echo \\n"+marker+"\\n\n") {
+		t.Errorf("script does not print the marker of this run: %v\n%s", err, script)
+	}
+
+	opts, err := loadOptions([]string{"-with.gdb", "-gdb.hold=90s", "--", "app.dll"})
+	if err != nil || opts.GDBScript.Hold != 90*time.Second {
+		t.Errorf("GDBScript = %+v, err %v", opts.GDBScript, err)
+	}
+	if _, err := loadOptions([]string{"-gdb.hold=-1s", "--", "app.dll"}); err == nil {
+		t.Error("loadOptions() should reject a negative gdb.hold")
+	}
+}
+
+// newHeldTarget 返回一个 gdb stdin 接到管道上的目标进程，从 reader 读取发给 gdb 的命令。
+func newHeldTarget(t *testing.T, hold time.Duration) (*TargetProcess, *bufio.Reader) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = reader.Close()
+		_ = writer.Close()
+	})
+	target := &TargetProcess{pid: 4242, broker: NewLogBroker(), gdbStdin: writer, gdbHold: hold, exitedCh: make(chan struct{})}
+	return target, bufio.NewReader(reader)
+}
+
+func TestGDBHold(t *testing.T) {
+	target, commands := newHeldTarget(t, time.Minute)
+	if _, ok := target.GDBHold(); ok {
+		t.Fatal("the target must not be held before gdb prints the marker")
+	}
+	if err := target.SendGDBCommand("bt"); err != errGDBNotHolding {
+		t.Errorf("SendGDBCommand() before the hold = %v", err)
+	}
+	target.startGDBHold()
+	if until, ok := target.GDBHold(); !ok || time.Until(until) < 50*time.Second {
+		t.Fatalf("GDBHold() = %v, %v", until, ok)
+	}
+
+	if err := target.SendGDBCommand(`  echo a\b  `); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"echo \\n(gdb) echo a\\\\b\\n\n", "echo a\\b\n"} {
+		if line, _ := commands.ReadString('\n'); line != want {
+			t.Errorf("gdb stdin = %q, want %q", line, want)
+		}
+	}
+	if err := target.SendGDBCommand("bt\nkill"); err == nil {
+		t.Error("SendGDBCommand() should reject multiple lines")
+	}
+
+	if err := target.ReleaseGDBHold(); err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := commands.ReadString('\n'); line != "kill\n" {
+		t.Errorf("gdb stdin = %q, want kill", line)
+	}
+	if _, ok := target.GDBHold(); ok {
+		t.Error("the hold must end after the release")
+	}
+	if err := target.ReleaseGDBHold(); err != errGDBNotHolding {
+		t.Errorf("second ReleaseGDBHold() = %v", err)
+	}
+}
+
+func TestGDBHoldExpires(t *testing.T) {
+	target, commands := newHeldTarget(t, 20*time.Millisecond)
+	target.startGDBHold()
+	if line, _ := commands.ReadString('\n'); line != "kill\n" {
+		t.Errorf("gdb stdin = %q, want kill when the hold expires", line)
+	}
+	if _, ok := target.GDBHold(); ok {
+		t.Error("the hold must end when it expires")
+	}
+}
+
+func TestCheckGDBHoldMarker(t *testing.T) {
+	// gdb 没有把目标进程停下时（这里 pid 4242 不是 gdb），即使看到了标记也不保留现场，直接结束 gdb
+	target, commands := newHeldTarget(t, time.Minute)
+	target.holdMarker = newGDBHoldMarker()
+	target.checkGDBHoldMarker(gdbHoldMarker)
+	target.checkGDBHoldMarker("  " + target.holdMarker + "  ")
+	if _, ok := target.GDBHold(); ok {
+		t.Error("the target must not be held when it is not stopped under gdb")
+	}
+	if line, _ := commands.ReadString('\n'); line != "kill\n" {
+		t.Errorf("gdb stdin = %q, want kill", line)
+	}
+
+	// 以 PTRACE_TRACEME 启动的子进程停在 exec 上，和 gdb 下停在崩溃信号上的目标进程一样处于 tracing stop
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("ptrace is not available: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	// 子进程在 exec 之后才停下，Start() 返回时可能还没有进入 tracing stop
+	for deadline := time.Now().Add(time.Second); !isProcessStoppedByTracer(cmd.Process.Pid, os.Getpid()); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the traced child did not stop")
+		}
+	}
+	target, _ = newHeldTarget(t, time.Minute)
+	target.pid = os.Getpid()
+	target.spec = LaunchSpec{Mode: RunModeGDB, Args: []string{"sleep", "30"}}
+	target.holdMarker = newGDBHoldMarker()
+	target.checkGDBHoldMarker(gdbHoldMarker)
+	if _, ok := target.GDBHold(); ok {
+		t.Fatal("the marker of another run must not start the hold")
+	}
+	target.checkGDBHoldMarker(target.holdMarker)
+	if _, ok := target.GDBHold(); !ok {
+		t.Fatal("the target stopped under the tracer must be held")
+	}
+	target.holdTimer.Stop()
+}
+
+func TestStopHeldTarget(t *testing.T) {
+	GlobalOptions = &Options{}
+	// gdb 读到 stdin 的 EOF 后以 128 退出，用 sh 模拟
+	cmd := exec.Command("sh", "-c", "cat >/dev/null; exit 128")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	supervisor := NewTargetSupervisor(TargetOptions{Name: "main", AutoRestart: true}, nil, false, 2*time.Second)
+	target := &TargetProcess{pid: cmd.Process.Pid, cmd: cmd, broker: supervisor.broker, history: supervisor.history, startTime: time.Now(),
+		gdbStdin: stdin, gdbHold: time.Minute, done: make(chan error, 1), exitedCh: make(chan struct{})}
+	go target.waitForExit()
+	target.startGDBHold()
+	supervisor.current.Store(target)
+	go supervisor.watch(target)
+
+	if err := supervisor.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	records := supervisor.History().Snapshot()
+	if len(records) != 1 || records[0].Reason != RunReasonCrash || !records[0].Abnormal {
+		t.Errorf("records = %+v, stopping a held target must keep the crash", records)
+	}
+	select {
+	case exit := <-supervisor.Exits():
+		t.Errorf("exit %+v reported to Run(), a stopped held target must not be restarted", exit)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if supervisor.Finished() {
+		t.Error("a manually stopped target must not count as finished")
+	}
+}
+
+func TestHandleGDBConsole(t *testing.T) {
+	target, commands := newHeldTarget(t, time.Minute)
+	target.spec = LaunchSpec{Mode: RunModeGDB}
+	target.gdbLogPath = writeTempGDBLog(t, "20260721-123457.log", "===== Crash backtrace =====\n#0  CrashNative ()\n")
+	h := &AdminHandler{history: NewRunHistory(), mux: http.NewServeMux()}
+	h.target.Store(target)
+	h.Register(h.mux)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		response := httptest.NewRecorder()
+		h.mux.ServeHTTP(response, request)
+		return response
+	}
+	if response := post("/gdb-console/exec", "command=bt"); response.Code != http.StatusConflict {
+		t.Errorf("exec before the hold = %d", response.Code)
+	}
+	target.startGDBHold()
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/gdb-console", nil))
+	if body := response.Body.String(); !strings.Contains(body, "The target is stopped under gdb until") || !strings.Contains(body, "generate-core-file") {
+		t.Errorf("page = %s", body)
+	}
+	if response := post("/gdb-console/exec", "command=generate-core-file"); response.Code != http.StatusNoContent {
+		t.Errorf("exec = %d %s", response.Code, response.Body.String())
+	}
+	_, _ = commands.ReadString('\n')
+	if line, _ := commands.ReadString('\n'); line != "generate-core-file\n" {
+		t.Errorf("gdb stdin = %q", line)
+	}
+	if response := post("/gdb-console/release", ""); response.Code != http.StatusSeeOther {
+		t.Errorf("release = %d %s", response.Code, response.Body.String())
+	}
+
+	close(target.exitedCh)
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/gdb-console/stream", nil))
+	if body := response.Body.String(); !strings.Contains(body, `data: "===== Crash backtrace =====\n#0  CrashNative ()\n"`) || !strings.HasSuffix(body, "event: end\ndata: {}\n\n") {
+		t.Errorf("stream = %q", body)
+	}
+}
+
+// writeTempGDBLog 在 os.TempDir() 下写一份 gdb 日志，只有这里的日志能通过 isGDBLogPath。
+func writeTempGDBLog(t *testing.T, name, content string) string {
+	path := filepath.Join(os.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(path)
+	})
+	return path
+}
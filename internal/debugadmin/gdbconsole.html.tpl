@@ -0,0 +1,81 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>GDB Console</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+.meta{color:#6b7280;font-size:12px;margin:2px 0;}
+.holding{color:#b45309;font-weight:700;}
+.error{white-space:pre-wrap;color:#991b1b;}
+#output{margin:10px 0;padding:10px;height:60vh;overflow:auto;background:#111827;color:#e5e7eb;border-radius:8px;font-size:12px;white-space:pre-wrap;}
+#command{width:60%;font-family:inherit;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>GDB Console</h1>
+<div class="meta">pid {{.PID}}{{if .LogPath}}, gdb log <code>{{.LogPath}}</code>{{end}}. <a href="/{{.TargetQuery}}">run history</a> · <a href="/coredumps{{.TargetQuery}}">core dumps</a></div>
+{{if .Holding}}
+<div class="holding">The target is stopped under gdb until {{.HoldUntil}} (<span id="remaining"></span> left). It is killed when the time is up or when you release it, then the restart policy continues.</div>
+<form id="console" style="margin-top:8px;">
+<input id="command" name="command" autocomplete="off" placeholder="thread apply all bt" autofocus/>
+<button type="submit">Run</button>
+<button type="button" onclick="send('generate-core-file')">generate-core-file</button>
+</form>
+<form method="post" action="/gdb-console/release{{.TargetQuery}}" style="margin-top:8px;" onsubmit="return confirm('Kill the stopped target now?');"><button type="submit">Release now</button></form>
+<div id="error" class="error"></div>
+{{else if .GDBMode}}
+<div class="meta">The target is not held. With <code>-gdb.hold</code>, gdb keeps the target stopped after a stop signal and this page accepts gdb commands until the hold ends.</div>
+{{else}}
+<div class="meta">The target is not running under gdb (<code>-with.gdb</code>).</div>
+{{end}}
+{{if .LogPath}}<pre id="output"></pre>{{end}}
+</div>
+{{if .LogPath}}
+<script>
+var output = document.getElementById("output");
+var source = new EventSource("/gdb-console/stream{{.TargetQuery}}");
+source.onmessage = function(msg){
+	var stick = output.scrollTop + output.clientHeight >= output.scrollHeight - 4;
+	output.textContent += JSON.parse(msg.data);
+	if (stick) { output.scrollTop = output.scrollHeight; }
+};
+source.addEventListener("end", function(){
+	output.textContent += "\n[gdb exited]\n";
+	output.scrollTop = output.scrollHeight;
+	source.close();
+	var form = document.getElementById("console");
+	if (form) { form.querySelectorAll("input,button").forEach(function(item){ item.disabled = true; }); }
+});
+</script>
+{{end}}
+{{if .Holding}}
+<script>
+var holdUntil = {{.HoldUntilMS}};
+function tick(){
+	var left = Math.max(0, Math.round((holdUntil - Date.now()) / 1000));
+	document.getElementById("remaining").textContent = left + "s";
+}
+tick();
+setInterval(tick, 1000);
+function send(command){
+	var body = new URLSearchParams();
+	body.set("command", command);
+	fetch("/gdb-console/exec{{.TargetQuery}}", {method: "POST", body: body}).then(function(response){
+		if (response.ok) { document.getElementById("error").textContent = ""; return; }
+		return response.text().then(function(text){ document.getElementById("error").textContent = text; });
+	});
+}
+document.getElementById("console").addEventListener("submit", function(event){
+	event.preventDefault();
+	var box = document.getElementById("command");
+	if (box.value.trim()) { send(box.value); }
+	box.value = "";
+});
+</script>
+{{end}}
+</body>
+</html>
@@ -0,0 +1,53 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Hang Detection</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1200px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+form{margin:8px 0;}
+input{font-family:inherit;width:80px;}
+.reports a{margin-right:12px;}
+.group{margin-top:14px;}
+.group-title{font-weight:700;color:#b91c1c;}
+.group.idle .group-title{color:#6b7280;}
+.group-threads{color:#374151;font-size:12px;margin-top:2px;}
+.group-stack{margin-top:6px;margin-left:16px;padding-left:10px;border-left:2px solid #d1d5db;}
+.frame{white-space:pre-wrap;line-height:1.4;}
+.frame-idx{color:#374151;}
+.frame-ptr,.frame-il{color:#9ca3af;}
+.frame-dll,.frame-sep,.frame-at,.frame-ns{color:#6b7280;}
+.frame-method{font-weight:700;}
+.frame-path{color:#0f766e;}
+.frame-file{color:#1d4ed8;font-weight:700;}
+.error{margin-top:16px;white-space:pre-wrap;padding:10px;border-radius:8px;background:#fee2e2;border:1px solid #fecaca;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Hang Detection</h1>
+<div class="meta">Takes several stack samples and reports threads whose stacks are identical in every sample.{{if .LogSilence}} Runs automatically when the target writes no log for {{.LogSilence}}.{{end}}</div>
+<form method="post" action="/hang{{.TargetQuery}}">
+samples <input name="samples" value="{{.Samples}}"/> interval <input name="interval" value="{{.Interval}}"/>
+<button type="submit"{{if .Running}} disabled{{end}}>{{if .Running}}Running...{{else}}Run Now{{end}}</button>
+</form>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Reports}}<h2>Reports</h2><div class="reports">{{range .Reports}}<a href="{{.URL}}"{{if .Selected}} style="font-weight:700;"{{end}}>{{.ID}} ({{.Trigger}}, {{.Stuck}} stuck)</a>{{end}}</div>{{end}}
+{{with .Report}}
+<h2>Report {{.ID}}</h2>
+<div class="meta">pid={{.PID}} trigger={{.Trigger}} started={{.StartedAt}} took={{.Duration}} samples={{.Samples}} interval={{.Interval}} threads={{.TotalThreads}} <a href="{{.JSONURL}}" target="_blank">json</a></div>
+{{range .Errors}}<div class="error">{{.}}</div>{{end}}
+{{if not .Groups}}<div class="meta">No thread kept the same stack across all samples.</div>{{end}}
+{{range .Groups}}<div class="group{{if .Idle}} idle{{end}}"><div class="group-title">[{{.Category}}] {{.Count}} thread(s)</div>
+<div class="group-threads">threads: {{.Threads}}</div>
+<div class="group-stack">{{range .Frames}}<div class="frame">{{.}}</div>
+{{end}}</div></div>
+{{end}}
+{{end}}
+</div>
+</body>
+</html>
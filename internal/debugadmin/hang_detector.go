@@ -0,0 +1,299 @@
+package debugadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 卡住线程的分类，按阻塞原因区分。
+const (
+	HangCategoryMonitorEnter  = "Monitor.Enter"
+	HangCategorySemaphoreWait = "SemaphoreSlim.Wait"
+	HangCategoryTaskWait      = "Task.Wait"
+	HangCategorySocketRead    = "Socket read"
+	HangCategoryIdle          = "idle" // 线程池、定时器等空闲等待的线程，通常不是问题
+	HangCategoryOther         = "other"
+)
+
+// 触发一次检测的原因。
+const (
+	HangTriggerManual     = "manual"
+	HangTriggerLogSilence = "log-silence"
+)
+
+const (
+	maxHangReports = 10
+	// maxHangDuration 限制一次检测的总时长（采样次数 × 间隔）。
+	maxHangDuration = 2 * time.Minute
+)
+
+// hangCategoryOrder 决定报告中分组的排列顺序，越靠前越可能是问题。
+var hangCategoryOrder = map[string]int{
+	HangCategoryMonitorEnter:  0,
+	HangCategorySemaphoreWait: 1,
+	HangCategoryTaskWait:      2,
+	HangCategorySocketRead:    3,
+	HangCategoryOther:         4,
+	HangCategoryIdle:          5,
+}
+
+// HangGroup 是调用栈完全相同的一组卡住的线程。
+type HangGroup struct {
+	Category string       `json:"category"`
+	Threads  []ThreadRef  `json:"threads"`
+	Frames   []StackFrame `json:"frames"`
+}
+
+// HangReport 是一次卡死检测的结果。
+type HangReport struct {
+	ID           string        `json:"id"`
+	PID          int           `json:"pid"`
+	Trigger      string        `json:"trigger"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   time.Time     `json:"finished_at"`
+	Samples      int           `json:"samples"`
+	Interval     time.Duration `json:"interval"`
+	TotalThreads int           `json:"total_threads"` // 最后一次采样中的线程数
+	Groups       []HangGroup   `json:"groups"`
+	Errors       []string      `json:"errors,omitempty"`
+}
+
+// StuckThreads 返回不属于 idle 分类的卡住线程数。
+func (r *HangReport) StuckThreads() int {
+	count := 0
+	for _, group := range r.Groups {
+		if group.Category != HangCategoryIdle {
+			count += len(group.Threads)
+		}
+	}
+	return count
+}
+
+// AnalyzeHang 找出在所有采样中都存在、且调用栈完全相同的线程，按调用栈分组并分类。
+func AnalyzeHang(samples []StackDump) []HangGroup {
+	if len(samples) < 2 {
+		return nil
+	}
+	stable := make(map[int]string) // thread id -> signature
+	for _, thread := range samples[0].Threads {
+		if len(thread.Frames) > 0 {
+			stable[thread.ID] = thread.Signature()
+		}
+	}
+	for _, sample := range samples[1:] {
+		current := make(map[int]string, len(sample.Threads))
+		for _, thread := range sample.Threads {
+			current[thread.ID] = thread.Signature()
+		}
+		for id, signature := range stable {
+			if current[id] != signature {
+				delete(stable, id)
+			}
+		}
+	}
+
+	last := samples[len(samples)-1]
+	stuck := make([]StackThread, 0, len(stable))
+	for _, thread := range last.Threads {
+		if _, ok := stable[thread.ID]; ok {
+			stuck = append(stuck, thread)
+		}
+	}
+	result := make([]HangGroup, 0, len(stuck))
+	for _, group := range GroupStacks(stuck) {
+		result = append(result, HangGroup{Category: classifyHangStack(group.Frames), Threads: group.Threads, Frames: group.Frames})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if hangCategoryOrder[result[i].Category] != hangCategoryOrder[result[j].Category] {
+			return hangCategoryOrder[result[i].Category] < hangCategoryOrder[result[j].Category]
+		}
+		return len(result[i].Threads) > len(result[j].Threads)
+	})
+	return result
+}
+
+// classifyHangStack 从栈顶开始查找第一个能说明阻塞原因的帧。
+func classifyHangStack(frames []StackFrame) string {
+	for _, frame := range frames {
+		typeName := frame.Type
+		if index := strings.LastIndex(typeName, "+"); index >= 0 {
+			typeName = typeName[index+1:]
+		}
+		switch {
+		case frame.Namespace == "System.Threading" && typeName == "Monitor" &&
+			(strings.HasPrefix(frame.Method, "Enter") || strings.HasPrefix(frame.Method, "ReliableEnter") || strings.HasPrefix(frame.Method, "TryEnter")):
+			return HangCategoryMonitorEnter
+		case typeName == "SemaphoreSlim" && strings.HasPrefix(frame.Method, "Wait") && frame.Method != "WaitAsync":
+			return HangCategorySemaphoreWait
+		case frame.Namespace == "System.Threading.Tasks" && typeName == "Task" &&
+			(strings.HasPrefix(frame.Method, "Wait") || strings.HasPrefix(frame.Method, "InternalWait") || frame.Method == "GetResultCore"):
+			return HangCategoryTaskWait
+		case frame.Namespace == "System.Runtime.CompilerServices" && strings.HasPrefix(typeName, "TaskAwaiter") && frame.Method == "GetResult":
+			return HangCategoryTaskWait
+		case strings.HasPrefix(frame.Namespace, "System.Net") && (typeName == "Socket" || typeName == "NetworkStream" || typeName == "SocketPal") &&
+			(strings.HasPrefix(frame.Method, "Receive") || strings.HasPrefix(frame.Method, "Read") || frame.Method == "Poll"):
+			return HangCategorySocketRead
+		case typeName == "LowLevelLifoSemaphore" || typeName == "GateThread" || (typeName == "TimerQueue" && frame.Method == "TimerThread"):
+			return HangCategoryIdle
+		}
+	}
+	return HangCategoryOther
+}
+
+// stackSampler 采集一次目标进程的调用栈，测试中可以替换。
+type stackSampler func(ctx context.Context, pid int) (StackDump, error)
+
+func collectStackSample(ctx context.Context, pid int) (StackDump, error) {
+	dump, _, _, err := collectStack(ctx, pid)
+	return dump, err
+}
+
+// HangDetector 对一个目标进程做卡死检测，保存最近的检测报告。同一时间只运行一次检测。
+type HangDetector struct {
+	pid    func() int
+	sample stackSampler
+
+	mu      sync.Mutex
+	running bool
+	reports []*HangReport
+}
+
+func NewHangDetector(pid func() int) *HangDetector {
+	return &HangDetector{pid: pid, sample: collectStackSample}
+}
+
+// ErrHangDetectorBusy 表示已经有一次检测正在运行。
+var ErrHangDetectorBusy = errors.New("hang detection is already running")
+
+// Run 每隔 interval 采集一次调用栈，共 samples 次，然后分析并保存报告。
+// 个别采样失败时记录在报告中，成功的采样少于 2 次时返回 error。
+func (d *HangDetector) Run(ctx context.Context, trigger string, samples int, interval time.Duration) (*HangReport, error) {
+	if samples < 2 {
+		return nil, fmt.Errorf("hang detection needs at least 2 samples, got %d", samples)
+	}
+	if interval <= 0 || time.Duration(samples-1)*interval > maxHangDuration {
+		return nil, fmt.Errorf("samples × interval should be positive and at most %s", maxHangDuration)
+	}
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return nil, ErrHangDetectorBusy
+	}
+	d.running = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.running = false
+		d.mu.Unlock()
+	}()
+
+	report := &HangReport{PID: d.pid(), Trigger: trigger, StartedAt: time.Now(), Samples: samples, Interval: interval}
+	report.ID = report.StartedAt.Format("20060102-150405.000")
+	dumps := make([]StackDump, 0, samples)
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		dump, err := d.sample(ctx, report.PID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("sample %d: %v", i+1, err))
+			continue
+		}
+		dumps = append(dumps, dump)
+	}
+	if len(dumps) < 2 {
+		return nil, fmt.Errorf("hang detection got %d successful samples: %s", len(dumps), strings.Join(report.Errors, "; "))
+	}
+	report.FinishedAt = time.Now()
+	report.TotalThreads = len(dumps[len(dumps)-1].Threads)
+	report.Groups = AnalyzeHang(dumps)
+
+	d.mu.Lock()
+	d.reports = append(d.reports, report)
+	if len(d.reports) > maxHangReports {
+		d.reports = d.reports[len(d.reports)-maxHangReports:]
+	}
+	d.mu.Unlock()
+	return report, nil
+}
+
+// Running 判断是否有检测正在运行。
+func (d *HangDetector) Running() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.running
+}
+
+// Reports 返回保存的报告，最近的在前。
+func (d *HangDetector) Reports() []*HangReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	reports := make([]*HangReport, 0, len(d.reports))
+	for i := len(d.reports) - 1; i >= 0; i-- {
+		reports = append(reports, d.reports[i])
+	}
+	return reports
+}
+
+// Report 按 id 查找报告，id 为空时返回最近的一次。
+func (d *HangDetector) Report(id string) *HangReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.reports) - 1; i >= 0; i-- {
+		if id == "" || d.reports[i].ID == id {
+			return d.reports[i]
+		}
+	}
+	return nil
+}
+
+// WatchLogSilence 在目标进程运行中且超过 opts.LogSilence 没有输出日志时自动运行一次检测。
+// 触发之后，要等到出现新的日志才会再次触发。
+func (d *HangDetector) WatchLogSilence(ctx context.Context, broker *LogBroker, running func() (bool, time.Time), opts HangOptions) {
+	if opts.LogSilence <= 0 {
+		return
+	}
+	tick := max(opts.LogSilence/4, time.Second)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	var triggeredAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		alive, startedAt := running()
+		if !alive {
+			continue
+		}
+		lastActivity := broker.LastBroadcast()
+		if startedAt.After(lastActivity) {
+			lastActivity = startedAt
+		}
+		if !triggeredAt.IsZero() && !lastActivity.After(triggeredAt) {
+			continue
+		}
+		if time.Since(lastActivity) < opts.LogSilence {
+			continue
+		}
+		triggeredAt = time.Now()
+		report, err := d.Run(ctx, HangTriggerLogSilence, opts.Samples, opts.Interval)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "hang detection after %s of log silence failed: %v\n", opts.LogSilence, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "hang detection after %s of log silence: %d stuck threads in %d groups, report %s\n",
+			opts.LogSilence, report.StuckThreads(), len(report.Groups), report.ID)
+	}
+}
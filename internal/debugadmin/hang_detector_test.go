@@ -0,0 +1,117 @@
+package debugadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hangSample(lines ...string) StackDump {
+	return ParseStackDump(strings.Join(lines, "\n"))
+}
+
+const (
+	hangMonitorThread = "Thread 11, name=\"worker-a\"\n" +
+		"#0: [Native Frames]\n" +
+		"#1: 0x1 System.Private.CoreLib.dll` System.Threading.Monitor.ReliableEnter(object, ref bool)\n" +
+		"#2: 0x2 MyApp.dll` MyApp.Cache.Get(string) at /src/Cache.cs:20"
+	hangTaskThread = "Thread 13\n" +
+		"#0: 0x1 System.Private.CoreLib.dll` System.Threading.ManualResetEventSlim.Wait(int, System.Threading.CancellationToken)\n" +
+		"#1: 0x2 System.Private.CoreLib.dll` System.Threading.Tasks.Task.InternalWaitCore(int, System.Threading.CancellationToken)\n" +
+		"#2: 0x3 MyApp.dll` MyApp.Sync.Run() at /src/Sync.cs:5"
+	hangIdleThread = "Thread 14, name=\".NET ThreadPool Worker\"\n" +
+		"#0: 0x1 System.Private.CoreLib.dll` System.Threading.LowLevelLifoSemaphore.WaitForSignal(int)\n" +
+		"#1: 0x2 System.Private.CoreLib.dll` System.Threading.PortableThreadPool.WorkerThread.WorkerThreadStart()"
+)
+
+func TestAnalyzeHang(t *testing.T) {
+	moving := func(line int) string {
+		return fmt.Sprintf("Thread 15\n#0: 0x1 MyApp.dll` MyApp.Loop.Spin() at /src/Loop.cs:%d", line)
+	}
+	samples := []StackDump{
+		hangSample(hangIdleThread, hangMonitorThread, strings.Replace(hangMonitorThread, "11, name=\"worker-a\"", "12", 1), hangTaskThread, moving(1)),
+		hangSample(hangIdleThread, hangMonitorThread, strings.Replace(hangMonitorThread, "11, name=\"worker-a\"", "12", 1), hangTaskThread, moving(2)),
+		// 线程 13 在最后一次采样中消失，不算卡住
+		hangSample(hangIdleThread, hangMonitorThread, strings.Replace(hangMonitorThread, "11, name=\"worker-a\"", "12", 1), moving(3)),
+	}
+	groups := AnalyzeHang(samples)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(groups), groups)
+	}
+	if groups[0].Category != HangCategoryMonitorEnter || len(groups[0].Threads) != 2 || groups[0].Threads[0].Name != "worker-a" {
+		t.Errorf("group 0 = %+v, want the two Monitor.Enter threads", groups[0])
+	}
+	if groups[1].Category != HangCategoryIdle {
+		t.Errorf("group 1 category = %s, want idle last", groups[1].Category)
+	}
+
+	for stack, want := range map[string]string{
+		hangTaskThread: HangCategoryTaskWait,
+		"Thread 1\n#0: 0x1 System.Private.CoreLib.dll` System.Threading.SemaphoreSlim.Wait(int, System.Threading.CancellationToken)":     HangCategorySemaphoreWait,
+		"Thread 1\n#0: 0x1 System.Net.Sockets.dll` System.Net.Sockets.Socket.Receive(System.Span<byte>, System.Net.Sockets.SocketFlags)": HangCategorySocketRead,
+		"Thread 1\n#0: 0x1 MyApp.dll` MyApp.Loop.Spin()": HangCategoryOther,
+	} {
+		if got := classifyHangStack(hangSample(stack).Threads[0].Frames); got != want {
+			t.Errorf("classifyHangStack(%q) = %s, want %s", stack, got, want)
+		}
+	}
+}
+
+func TestHangDetectorRun(t *testing.T) {
+	detector := NewHangDetector(func() int { return 42 })
+	calls := 0
+	detector.sample = func(_ context.Context, pid int) (StackDump, error) {
+		calls++
+		if pid != 42 {
+			t.Errorf("sample pid = %d, want 42", pid)
+		}
+		if calls == 2 {
+			return StackDump{}, errors.New("attach failed")
+		}
+		return hangSample(hangMonitorThread), nil
+	}
+	report, err := detector.Run(context.Background(), HangTriggerManual, 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if calls != 3 || len(report.Errors) != 1 || report.StuckThreads() != 1 {
+		t.Errorf("calls = %d report = %+v, want 3 samples, 1 error and 1 stuck thread", calls, report)
+	}
+	if got := detector.Report(""); got != report {
+		t.Errorf("Report(\"\") = %v, want the latest report", got)
+	}
+	if _, err := detector.Run(context.Background(), HangTriggerManual, 1, time.Millisecond); err == nil {
+		t.Error("Run() with 1 sample error = nil, want error")
+	}
+
+	detector.sample = func(context.Context, int) (StackDump, error) { return StackDump{}, errors.New("not running") }
+	if _, err := detector.Run(context.Background(), HangTriggerManual, 2, time.Millisecond); err == nil {
+		t.Error("Run() with failing samples error = nil, want error")
+	}
+}
+
+func TestHangDetectorWatchLogSilence(t *testing.T) {
+	detector := NewHangDetector(func() int { return 42 })
+	detector.sample = func(context.Context, int) (StackDump, error) { return hangSample(hangMonitorThread), nil }
+	broker := NewLogBroker()
+	broker.Broadcast("started")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := time.Now().Add(-time.Hour)
+	go detector.WatchLogSilence(ctx, broker, func() (bool, time.Time) { return true, started },
+		HangOptions{LogSilence: 10 * time.Millisecond, Samples: 2, Interval: time.Millisecond})
+	deadline := time.After(5 * time.Second)
+	for detector.Report("") == nil {
+		select {
+		case <-deadline:
+			t.Fatal("no hang report after the log went silent")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if report := detector.Report(""); report.Trigger != HangTriggerLogSilence {
+		t.Errorf("report trigger = %s, want %s", report.Trigger, HangTriggerLogSilence)
+	}
+}
@@ -0,0 +1,169 @@
+package debugadmin
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+//go:embed hang.html.tpl
+var hangHTMLContent string
+
+var hangHTMLTemplate = template.Must(template.New("hang.html").Parse(hangHTMLContent))
+
+type hangPageData struct {
+	TargetQuery string
+	TargetParam string
+	Samples     int
+	Interval    string
+	LogSilence  string
+	Running     bool
+	Error       string
+	Reports     []hangReportLink
+	Report      *hangReportView
+}
+
+type hangReportLink struct {
+	URL      string
+	ID       string
+	Trigger  string
+	Stuck    int
+	Selected bool
+}
+
+type hangReportView struct {
+	ID           string
+	PID          int
+	Trigger      string
+	StartedAt    string
+	Duration     string
+	Samples      int
+	Interval     string
+	TotalThreads int
+	Groups       []hangGroupView
+	Errors       []string
+	JSONURL      string
+}
+
+type hangGroupView struct {
+	Category string
+	Idle     bool
+	Count    int
+	Threads  string
+	Frames   []string
+}
+
+// handleHang GET 展示卡死检测的报告，POST 按表单中的参数运行一次检测，完成后跳转到报告。
+func (h *AdminHandler) handleHang(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.renderHangPage(w, r.URL.Query().Get("id"), "", http.StatusOK)
+	case http.MethodPost:
+		samples, err := strconv.Atoi(r.FormValue("samples"))
+		if err != nil {
+			h.renderHangPage(w, "", "invalid samples", http.StatusBadRequest)
+			return
+		}
+		interval, err := time.ParseDuration(r.FormValue("interval"))
+		if err != nil {
+			h.renderHangPage(w, "", "invalid interval: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), maxHangDuration+time.Minute)
+		defer cancel()
+		report, err := h.hangs.Run(ctx, HangTriggerManual, samples, interval)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrHangDetectorBusy) {
+				status = http.StatusConflict
+			}
+			h.renderHangPage(w, "", err.Error(), status)
+			return
+		}
+		http.Redirect(w, r, "/hang?id="+url.QueryEscape(report.ID)+h.targetQuery("&"), http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleHangAPI 以 JSON 返回一次检测报告，id 为空时返回最近的一次。
+func (h *AdminHandler) handleHangAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := h.hangs.Report(r.URL.Query().Get("id"))
+	if report == nil {
+		http.Error(w, "hang report not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+func (h *AdminHandler) renderHangPage(w http.ResponseWriter, id, errMsg string, status int) {
+	opts := GlobalOptions.Hang
+	data := hangPageData{
+		TargetQuery: h.targetQuery("?"),
+		TargetParam: h.targetQuery("&"),
+		Samples:     opts.Samples,
+		Interval:    opts.Interval.String(),
+		Running:     h.hangs.Running(),
+		Error:       html.EscapeString(errMsg),
+	}
+	if opts.LogSilence > 0 {
+		data.LogSilence = opts.LogSilence.String()
+	}
+	selected := h.hangs.Report(id)
+	for _, report := range h.hangs.Reports() {
+		data.Reports = append(data.Reports, hangReportLink{
+			URL:      "/hang?id=" + url.QueryEscape(report.ID) + data.TargetParam,
+			ID:       html.EscapeString(report.ID),
+			Trigger:  html.EscapeString(report.Trigger),
+			Stuck:    report.StuckThreads(),
+			Selected: report == selected,
+		})
+	}
+	if selected != nil {
+		data.Report = buildHangReportView(selected, data.TargetParam)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	_ = hangHTMLTemplate.Execute(w, data)
+}
+
+func buildHangReportView(report *HangReport, targetParam string) *hangReportView {
+	view := &hangReportView{
+		ID:           html.EscapeString(report.ID),
+		PID:          report.PID,
+		Trigger:      html.EscapeString(report.Trigger),
+		StartedAt:    report.StartedAt.Format("2006-01-02 15:04:05"),
+		Duration:     report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond).String(),
+		Samples:      report.Samples,
+		Interval:     report.Interval.String(),
+		TotalThreads: report.TotalThreads,
+		JSONURL:      "/api/hang?id=" + url.QueryEscape(report.ID) + targetParam,
+	}
+	for _, message := range report.Errors {
+		view.Errors = append(view.Errors, html.EscapeString(message))
+	}
+	for _, group := range report.Groups {
+		view.Groups = append(view.Groups, hangGroupView{
+			Category: html.EscapeString(group.Category),
+			Idle:     group.Category == HangCategoryIdle,
+			Count:    len(group.Threads),
+			Threads:  html.EscapeString(formatThreadRefs(group.Threads)),
+			Frames:   formatStackFramesHTML(group.Frames),
+		})
+	}
+	return view
+}
@@ -5,19 +5,21 @@ import (
 	"compress/gzip"
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"text/template"
 	"time"
@@ -41,39 +43,92 @@ var stackInfoHTMLTemplate = template.Must(template.New("stack_info.html").Parse(
 const traceIDLayout = "20060102150405.000"
 
 var traceIDPattern = regexp.MustCompile(`^\d{14}\.\d{3}$`)
-var gdbLogNamePattern = regexp.MustCompile(`^\d{8}-\d{6}\.log$`)
+var gdbLogNamePattern = regexp.MustCompile(`^\d{8}-\d{6}(-\d+)?\.log$`)
 
+// AdminHandler 为一个目标进程提供 http 接口，每个目标进程各有一个实例，
+// 由 TargetRouter 根据请求中的 target= 参数选择。
 type AdminHandler struct {
+	name               string   // 目标进程名，对应 target= 参数
+	targetNames        []string // 所有目标进程名，用于页面上的目标进程选择
 	traces             *TraceStore
 	broker             *LogBroker
 	target             atomic.Pointer[TargetProcess]
+	supervisor         *TargetSupervisor
 	history            *RunHistory
+	coverage           *CoverageHistory
+	coverageRunMu      sync.Mutex
+	hangs              *HangDetector
+	eventSessions      *EventSessionManager
+	exceptions         *ExceptionTracker
+	traceJobs          *TraceJobManager
+	triggers           *TriggerEngine
+	notifier           *Notifier         // 所有目标进程共用
+	coreDumps          *CoreDumpStore    // 所有目标进程共用
+	crashReports       *CrashReportStore // 所有目标进程共用，-coredump.analyze=false 时为 nil
+	perfMaps           *PerfMapIndex     // 所有目标进程共用，按 pid 区分
 	speedscope         fs.FS
 	vectorTOMLTemplate *template.Template
-	targetLabel        string
+	mux                *http.ServeMux
 }
 
-// NewHTTPServer 启动 http 服务器
-func NewHTTPServer(staticFS fs.FS, vectorTOMLTemplate *template.Template, broker *LogBroker, target *TargetProcess, history *RunHistory) (*http.Server, *AdminHandler, error) {
+// NewHTTPServer 启动 http 服务器，每个目标进程对应一个 AdminHandler。
+func NewHTTPServer(staticFS fs.FS, vectorTOMLTemplate *template.Template, supervisors []*TargetSupervisor) (*http.Server, error) {
 	speedscopeFS, err := fs.Sub(staticFS, "build/speedscope")
 	if err != nil {
-		return nil, nil, fmt.Errorf("load embedded speedscope files: %w", err)
-	}
-	handler := &AdminHandler{
-		traces:             NewTraceStore(),
-		broker:             broker,
-		history:            history,
-		speedscope:         speedscopeFS,
-		vectorTOMLTemplate: vectorTOMLTemplate,
-		targetLabel:        strings.Join(GlobalOptions.StartupParams, " "),
-	}
-	handler.target.Store(target)
-	mux := http.NewServeMux()
-	handler.Register(mux)
+		return nil, fmt.Errorf("load embedded speedscope files: %w", err)
+	}
+	names := make([]string, 0, len(supervisors))
+	for _, supervisor := range supervisors {
+		names = append(names, supervisor.Name())
+	}
+	handlers := make([]*AdminHandler, 0, len(supervisors))
+	perfMaps := NewPerfMapIndex()
+	notifier := NewNotifier(GlobalOptions.Notify)
+	go notifier.Run(context.Background())
+	coreDumps := NewCoreDumpStore(GlobalOptions.CoreDump.Dir, GlobalOptions.CoreDump.MaxSize)
+	var crashReports *CrashReportStore
+	if GlobalOptions.CoreDump.Analyze {
+		crashReports = NewCrashReportStore(filepath.Join(GlobalOptions.CoreDump.Dir, "reports"), GlobalOptions.CoreDump.AnalyzeTimeout)
+	}
+	for _, supervisor := range supervisors {
+		handler := &AdminHandler{
+			name:               supervisor.Name(),
+			targetNames:        names,
+			traces:             NewTraceStore(),
+			broker:             supervisor.Broker(),
+			supervisor:         supervisor,
+			history:            supervisor.History(),
+			coverage:           NewCoverageHistory(),
+			notifier:           notifier,
+			coreDumps:          coreDumps,
+			crashReports:       crashReports,
+			perfMaps:           perfMaps,
+			speedscope:         speedscopeFS,
+			vectorTOMLTemplate: vectorTOMLTemplate,
+			mux:                http.NewServeMux(),
+		}
+		handler.hangs = NewHangDetector(handler.resolveTargetPID)
+		handler.eventSessions = NewEventSessionManager(handler.resolveTargetPID)
+		handler.exceptions = NewExceptionTracker(handler.resolveTargetPID, perfMaps)
+		handler.traceJobs = NewTraceJobManager(handler.resolveTargetPID, handler.traces, handler.collectTrace)
+		handler.triggers = NewTriggerEngine(handler.name, GlobalOptions.Triggers, handler.resolveTargetPID, handler.broker, handler.exceptions.LastMinute, handler.runTriggerAction)
+		handler.target.Store(supervisor.Current())
+		supervisor.OnStart(handler.SetTarget)
+		supervisor.OnExit(handler.collectCrashCoreDump)
+		supervisor.OnExit(handler.notifyExit)
+		handler.triggers.OnFired(handler.notifyTriggerFired)
+		go handler.hangs.WatchLogSilence(context.Background(), handler.broker, handler.targetRunningSince, GlobalOptions.Hang)
+		go handler.triggers.Run(context.Background())
+		if GlobalOptions.TrackExceptions || handler.triggers.NeedsExceptions() {
+			go handler.exceptions.Run(context.Background())
+		}
+		handler.Register(handler.mux)
+		handlers = append(handlers, handler)
+	}
 	return &http.Server{
 		Addr:    fmt.Sprintf(":%d", GlobalOptions.AdminPort),
-		Handler: mux,
-	}, handler, nil
+		Handler: NewTargetRouter(handlers),
+	}, nil
 }
 
 // SetTarget 在子进程被重启后，切换 AdminHandler 指向的目标进程。
@@ -85,25 +140,78 @@ func (h *AdminHandler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/", h.handleRoot)
 	mux.HandleFunc("/log", h.handleLog)
 	mux.HandleFunc("/stack", h.handleStack)
+	mux.HandleFunc("/api/stack", h.handleStackAPI)
+	mux.HandleFunc("/stack/inspect", h.handleStackInspect)
+	mux.HandleFunc("/hang", h.handleHang)
+	mux.HandleFunc("/api/hang", h.handleHangAPI)
 	mux.HandleFunc("/show_threads", h.handleShowThreads)
+	mux.HandleFunc("/eventpipe", h.handleEventPipe)
+	mux.HandleFunc("/eventpipe/start", h.handleEventPipeStart)
+	mux.HandleFunc("/eventpipe/stop", h.handleEventPipeStop)
+	mux.HandleFunc("/eventpipe/stream", h.handleEventPipeStream)
+	mux.HandleFunc("/eventpipe/download", h.handleEventPipeDownload)
+	mux.HandleFunc("/api/eventpipe/sessions", h.handleEventPipeAPI)
+	mux.HandleFunc("/exceptions", h.handleExceptions)
+	mux.HandleFunc("/exceptions/reset", h.handleExceptionsReset)
+	mux.HandleFunc("/api/exceptions", h.handleExceptionsAPI)
+	mux.HandleFunc("/triggers", h.handleTriggers)
+	mux.HandleFunc("/triggers/fire", h.handleTriggerFire)
+	mux.HandleFunc("/triggers/artifact", h.handleTriggerArtifact)
+	mux.HandleFunc("/api/triggers", h.handleTriggersAPI)
+	mux.HandleFunc("/notifications", h.handleNotifications)
+	mux.HandleFunc("/notifications/test", h.handleNotificationsTest)
+	mux.HandleFunc("/api/notifications", h.handleNotificationsAPI)
+	mux.HandleFunc("/coredumps", h.handleCoreDumps)
+	mux.HandleFunc("/coredumps/collect", h.handleCoreDumpCollect)
+	mux.HandleFunc("/coredumps/download", h.handleCoreDumpDownload)
+	mux.HandleFunc("/coredumps/delete", h.handleCoreDumpDelete)
+	mux.HandleFunc("/api/coredumps", h.handleCoreDumpsAPI)
+	mux.HandleFunc("/crash-report", h.handleCrashReport)
+	mux.HandleFunc("/crash-report/raw", h.handleCrashReportRaw)
+	mux.HandleFunc("/api/crash-report", h.handleCrashReportAPI)
 	mux.HandleFunc("/trace", h.handleTrace)
+	mux.HandleFunc("/trace/start", h.handleTraceStart)
+	mux.HandleFunc("/trace/stop", h.handleTraceStop)
+	mux.HandleFunc("/api/trace/jobs", h.handleTraceJobsAPI)
 	mux.HandleFunc("/profile_list", h.handleProfileList)
 	mux.HandleFunc("/profile/", h.handleProfile)
 	mux.HandleFunc("/gdb-log", h.handleGDBLog)
 	mux.HandleFunc("/current-gdb-log", h.handleCurrentGDBLog)
+	mux.HandleFunc("/gdb-console", h.handleGDBConsole)
+	mux.HandleFunc("/gdb-console/exec", h.handleGDBConsoleExec)
+	mux.HandleFunc("/gdb-console/release", h.handleGDBConsoleRelease)
+	mux.HandleFunc("/gdb-console/stream", h.handleGDBConsoleStream)
+	mux.HandleFunc("/perf-map", h.handlePerfMap)
 	mux.HandleFunc("/code_coverage/", h.handleCodeCoverage)
 	mux.HandleFunc("/reset_coverage_data", h.handleResetCoverageData)
 	mux.HandleFunc("/code_coverage_report/{uuid}/", h.handleCodeCoverageReport)
 	mux.HandleFunc("/code_coverage_xml/{name}", h.handleCodeCoverageXML)
 	mux.HandleFunc("/get_code_coverage_list", h.handleGetCodeCoverageList)
+	mux.HandleFunc("/config", h.handleConfig)
+	mux.HandleFunc("/api/target/stop", h.handleTargetStop)
+	mux.HandleFunc("/api/target/start", h.handleTargetStart)
+	mux.HandleFunc("/api/target/restart", h.handleTargetRestart)
+	mux.HandleFunc("/api/target/signal", h.handleTargetSignal)
+	mux.HandleFunc("/api/target/launch", h.handleTargetLaunch)
+	mux.HandleFunc("/api/target/revert", h.handleTargetRevert)
 	mux.Handle("/speedscope/", http.StripPrefix("/speedscope/", http.FileServer(http.FS(h.speedscope))))
 }
 
 type indexPageData struct {
 	TargetLabel       string
 	PID               int
+	Running           bool
+	LaunchMode        string // 下一次启动使用的运行方式
+	LaunchArgs        string // 下一次启动使用的参数，每行一个
+	LaunchEnv         string // 下一次启动额外设置的环境变量，每行一个 KEY=VALUE
+	OriginalLabel     string // 命令行指定的原始启动参数
+	TargetQuery       string // "?target=xxx"，只有一个未命名目标进程时为空
+	TargetParam       string // "&target=xxx"，用于已经带有其他参数的链接
+	Targets           []targetLink
 	CWD               string
 	ShowCurrentGDBLog bool
+	GDBHoldUntil      string // 目标进程停在 gdb 中等待 /gdb-console 命令时的截止时间
+	ShowPerfMap       bool   // 当前目标进程已经写出 perf map
 	WithCoverage      bool
 	Processes         []ProcessInfo
 	RunHistory        []runHistoryRow
@@ -154,24 +262,56 @@ type runHistoryRow struct {
 	Abnormal     bool
 	ErrMsg       string
 	CoreDumpPath string
+	CoreDumpID   string
+	ReportID     string
 	GDBLogPath   string
+	GDBScript    bool
 	GDBLogIndex  int
+	PerfMap      bool
+	PerfInfo     bool
 	LastLogs     string
+	StartReason  string
+	Manual       bool
+	Command      string
 }
 
 func (h *AdminHandler) handleRoot(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Add("Content-Type", "text/html")
 	target := h.target.Load()
 	pid := h.resolveTargetPID()
+	spec := DefaultLaunchSpec()
+	if target != nil {
+		spec = target.LaunchSpec()
+	}
+	nextSpec, originalSpec := spec, DefaultLaunchSpec()
+	if h.supervisor != nil {
+		nextSpec, originalSpec = h.supervisor.LaunchSpecs()
+	}
+	holdUntil := ""
+	if target != nil {
+		if until, ok := target.GDBHold(); ok {
+			holdUntil = until.Format("15:04:05")
+		}
+	}
 	_ = indexHTMLTemplate.Execute(w, indexPageData{
-		TargetLabel:       h.targetLabel,
+		TargetLabel:       html.EscapeString(spec.Label()),
 		PID:               pid,
+		Running:           target != nil && target.Running(),
 		CWD:               html.EscapeString(readProcessCwd(pid)),
 		ShowCurrentGDBLog: target != nil && target.GDBLogPath() != "",
-		WithCoverage:      GlobalOptions.WithCoverage,
-		Processes:         listContainerProcesses(GlobalOptions.StartupParams),
+		GDBHoldUntil:      holdUntil,
+		ShowPerfMap:       target != nil && target.Running() && fileExists(perfMapPath(pid)),
+		WithCoverage:      spec.Mode == RunModeCoverage,
+		LaunchMode:        string(nextSpec.Mode),
+		Processes:         listContainerProcesses(spec.Args),
+		LaunchArgs:        html.EscapeString(strings.Join(nextSpec.Args, "\n")),
+		LaunchEnv:         html.EscapeString(strings.Join(nextSpec.Env, "\n")),
+		OriginalLabel:     html.EscapeString(originalSpec.Label()),
 		RunHistory:        buildRunHistoryRows(h.history.Snapshot()),
-		CoverageHistory:   buildCoverageHistoryRows(SnapshotCoverageHistory()),
+		CoverageHistory:   buildCoverageHistoryRows(h.coverage.Snapshot()),
+		TargetQuery:       h.targetQuery("?"),
+		TargetParam:       h.targetQuery("&"),
+		Targets:           h.buildTargetLinks(),
 	})
 }
 
@@ -193,9 +333,17 @@ func buildRunHistoryRows(records []RunRecord) []runHistoryRow {
 			Abnormal:     record.Abnormal,
 			ErrMsg:       html.EscapeString(record.Err),
 			CoreDumpPath: html.EscapeString(record.CoreDumpPath),
+			CoreDumpID:   url.QueryEscape(record.CoreDumpID),
+			ReportID:     url.QueryEscape(record.CrashReportID),
 			GDBLogPath:   html.EscapeString(record.GDBLogPath),
+			GDBScript:    record.GDBScriptPath != "",
 			GDBLogIndex:  i,
+			PerfMap:      record.PerfMapPath != "",
+			PerfInfo:     record.PerfInfoPath != "",
 			LastLogs:     html.EscapeString(strings.Join(record.LastLogs, "\n")),
+			StartReason:  html.EscapeString(record.StartReason),
+			Manual:       record.Reason == RunReasonManual,
+			Command:      html.EscapeString(LaunchSpec{Args: record.Args, Env: record.Env}.Label()),
 		})
 	}
 	return rows
@@ -216,6 +364,18 @@ func (h *AdminHandler) handleGDBLog(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "gdb log not found", http.StatusNotFound)
 		return
 	}
+	// kind=script 返回生成这份日志的崩溃采集脚本
+	if r.URL.Query().Get("kind") == "script" {
+		scriptPath := records[index].GDBScriptPath
+		data, err := os.ReadFile(scriptPath)
+		if scriptPath != gdbScriptPathForLog(records[index].GDBLogPath) || err != nil {
+			http.Error(w, "gdb script not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(data)
+		return
+	}
 	h.writeGDBLog(w, r, records[index].GDBLogPath)
 }
 
@@ -239,6 +399,10 @@ func (h *AdminHandler) writeGDBLog(w http.ResponseWriter, r *http.Request, path
 		http.Error(w, "read gdb log failed", http.StatusNotFound)
 		return
 	}
+	// 默认用 perf map 还原崩溃 backtrace 中的 JIT 帧，raw=1 时返回原始日志
+	if r.URL.Query().Get("raw") != "1" {
+		data = h.perfMaps.SymbolizeGDBLog(data)
+	}
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Vary", "Accept-Encoding")
 	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
@@ -253,6 +417,54 @@ func (h *AdminHandler) writeGDBLog(w http.ResponseWriter, r *http.Request, path
 	_, _ = w.Write(data)
 }
 
+// perfMapFilePattern 限制 /perf-map 只能下载运行时写出的 perf map 与 perfinfo 文件。
+var perfMapFilePattern = regexp.MustCompile(`^/tmp/perf(info)?-\d+\.map$`)
+
+// handlePerfMap 下载目标进程的 perf map，复制到分析机器的 /tmp 下即可离线 perf report。
+// index= 指定 Run History 中的一次运行，省略时为当前运行的目标进程；kind=info 下载 perfinfo。
+func (h *AdminHandler) handlePerfMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	info := r.URL.Query().Get("kind") == "info"
+	var path string
+	if raw := r.URL.Query().Get("index"); raw != "" {
+		index, err := strconv.Atoi(raw)
+		records := h.history.Snapshot()
+		if err != nil || index < 0 || index >= len(records) {
+			http.Error(w, "invalid run index", http.StatusBadRequest)
+			return
+		}
+		path = records[index].PerfMapPath
+		if info {
+			path = records[index].PerfInfoPath
+		}
+	} else {
+		pid := h.resolveTargetPID()
+		if pid <= 0 {
+			http.Error(w, "target process is not running", http.StatusNotFound)
+			return
+		}
+		path = perfMapPath(pid)
+		if info {
+			path = perfInfoPath(pid)
+		}
+	}
+	if !perfMapFilePattern.MatchString(path) || !fileExists(path) {
+		http.Error(w, "perf map not found; start the target with -perf.map", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+	http.ServeFile(w, r, path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func isGDBLogPath(path string) bool {
 	return filepath.Dir(path) == os.TempDir() && gdbLogNamePattern.MatchString(filepath.Base(path))
 }
@@ -317,14 +529,94 @@ func (h *AdminHandler) handleStack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	view := r.URL.Query().Get("view")
+	pid := h.resolveTargetPID()
+	// 异步调用栈要先生成 heap dump，放在 attach netcoredbg 之前完成，两者不同时操作目标进程
+	var asyncReport AsyncStackReport
+	var asyncOutput string
+	var asyncErr error
+	if view == stackViewAsync {
+		asyncCtx, asyncCancel := context.WithTimeout(r.Context(), asyncStackTimeout)
+		asyncReport, asyncOutput, asyncErr = collectAsyncStacks(asyncCtx, pid)
+		asyncCancel()
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
 	defer cancel()
 
-	startupOutput, stackOutput, stderrOutput, err := collectStackOutput(ctx, h.resolveTargetPID())
+	dump, debuggerLog, stderrOutput, err := collectStack(ctx, pid)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
-	renderStackHTML(w, startupOutput, stackOutput, stderrOutput, err)
+	data := buildStackPageData(view, debuggerLog, dump, stderrOutput, err)
+	if data.View == stackViewAsync {
+		data.setAsyncStacks(asyncReport, asyncOutput, asyncErr)
+	}
+	data.TargetParam = h.targetQuery("&")
+	data.Views = buildStackViewLinks(data.View, data.TargetParam)
+	_ = stackInfoHTMLTemplate.Execute(w, data)
+}
+
+// stackAPIResponse 是 /api/stack 的返回值。
+type stackAPIResponse struct {
+	PID        int               `json:"pid"`
+	Time       time.Time         `json:"time"`
+	Stack      StackDump         `json:"stack"`
+	Groups     []StackGroup      `json:"groups,omitempty"`
+	Tree       *CallTreeNode     `json:"tree,omitempty"`
+	Async      *AsyncStackReport `json:"async,omitempty"`
+	AsyncError string            `json:"async_error,omitempty"`
+	Stderr     string            `json:"stderr,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// handleStackAPI 与 /stack 相同地采集调用栈，以 JSON 返回解析后的 StackDump。
+// view=grouped 时附带按调用栈合并的线程分组，view=tree 时附带合并后的调用树，
+// view=async 时附带 dumpasync 还原的异步调用链。
+func (h *AdminHandler) handleStackAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pid := h.resolveTargetPID()
+	var asyncReport *AsyncStackReport
+	var asyncErr string
+	if r.URL.Query().Get("view") == stackViewAsync {
+		asyncCtx, asyncCancel := context.WithTimeout(r.Context(), asyncStackTimeout)
+		report, _, err := collectAsyncStacks(asyncCtx, pid)
+		asyncCancel()
+		if err != nil {
+			asyncErr = err.Error()
+		} else {
+			asyncReport = &report
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	dump, _, stderrOutput, err := collectStack(ctx, pid)
+	response := stackAPIResponse{
+		PID:        pid,
+		Time:       time.Now(),
+		Stack:      dump,
+		Stderr:     strings.TrimSpace(stderrOutput),
+		Async:      asyncReport,
+		AsyncError: asyncErr,
+	}
+	switch r.URL.Query().Get("view") {
+	case stackViewGrouped:
+		response.Groups = GroupStacks(dump.Threads)
+	case stackViewTree:
+		response.Tree = BuildCallTree(dump.Threads)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(response)
 }
 
 // handleShowThreads attaches gdb to an arbitrary pid, runs "thread apply all bt",
@@ -349,13 +641,31 @@ func (h *AdminHandler) handleShowThreads(w http.ResponseWriter, r *http.Request)
 	cmd.Stderr = &stderr
 	runErr := cmd.Run()
 
+	output, perfMapNote := h.symbolizeThreadDump(pid, stdout.String())
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
-	_ = threadInfoHTMLTemplate.Execute(w, buildThreadInfoPageData(pid, stdout.String(), stderr.String(), runErr))
+	data := buildThreadInfoPageData(pid, output, stderr.String(), runErr)
+	data.PerfMap = html.EscapeString(perfMapNote)
+	_ = threadInfoHTMLTemplate.Execute(w, data)
+}
+
+// symbolizeThreadDump 用 pid 的 perf map 还原 gdb 输出中的 JIT 帧，同时返回一行说明显示在页面上。
+func (h *AdminHandler) symbolizeThreadDump(pid int, output string) (string, string) {
+	m, err := h.perfMaps.Get(pid)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return output, "no " + perfMapPath(pid) + ", JIT frames stay as ??; start the target with DOTNET_PerfMapEnabled=1 to resolve them"
+		}
+		return output, "read perf map failed: " + err.Error()
+	}
+	symbolized, resolved := SymbolizeGDBBacktrace(output, m)
+	return symbolized, fmt.Sprintf("%d frame(s) resolved with %s (%d entries)", resolved, perfMapPath(pid), m.Len())
 }
 
 type threadInfoPageData struct {
 	PID     int
+	PerfMap string // perf map 符号还原的结果说明
 	Threads []threadInfoRow
 	Misc    string
 	Stderr  string
@@ -371,15 +681,18 @@ type threadInfoRow struct {
 // buildThreadInfoPageData turns raw "thread apply all bt" output into rows the
 // template can render directly, with all text pre-escaped.
 func buildThreadInfoPageData(pid int, stdout, stderrOutput string, runErr error) threadInfoPageData {
-	threads, misc := parseStackBlocks(stdout)
+	dump := ParseStackDump(stdout)
+	threads := sortThreadPoolWorkersFirst(dump.Threads)
 	rows := make([]threadInfoRow, 0, len(threads))
 	for i, thread := range threads {
-		lines := make([]string, 0, len(thread.frames)+len(thread.extra))
-		lines = append(lines, thread.frames...)
-		lines = append(lines, thread.extra...)
+		lines := make([]string, 0, len(thread.Frames)+len(thread.Extra))
+		for _, frame := range thread.Frames {
+			lines = append(lines, frame.Raw)
+		}
+		lines = append(lines, thread.Extra...)
 		rows = append(rows, threadInfoRow{
 			ID:    i,
-			Label: html.EscapeString(thread.header),
+			Label: html.EscapeString(thread.Header),
 			Stack: html.EscapeString(strings.Join(lines, "\n")),
 		})
 	}
@@ -390,327 +703,243 @@ func buildThreadInfoPageData(pid int, stdout, stderrOutput string, runErr error)
 	return threadInfoPageData{
 		PID:     pid,
 		Threads: rows,
-		Misc:    html.EscapeString(strings.Join(misc, "\n")),
+		Misc:    html.EscapeString(strings.Join(dump.Misc, "\n")),
 		Stderr:  html.EscapeString(strings.TrimSpace(stderrOutput)),
 		Error:   html.EscapeString(errMsg),
 	}
 }
 
-type stackThreadBlock struct {
-	header string
-	frames []string
-	extra  []string
-}
-
-func collectStackOutput(ctx context.Context, pid int) (string, string, string, error) {
-	cmd := BuildStackCommand(ctx, pid)
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", "", "", fmt.Errorf("read stack stdout failed: %w", err)
-	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return "", "", "", fmt.Errorf("read stack stderr failed: %w", err)
-	}
-	stdinPipe, err := cmd.StdinPipe()
-	if err != nil {
-		return "", "", "", fmt.Errorf("open stack stdin failed: %w", err)
-	}
-	if err := cmd.Start(); err != nil {
-		return "", "", "", fmt.Errorf("start stack command failed: %w", err)
-	}
-
-	stdoutCh := make(chan []byte, 32)
-	stdoutErrCh := make(chan error, 1)
-	go readPipeChunks(ctx, stdoutPipe, stdoutCh, stdoutErrCh)
-
-	stderrCh := make(chan string, 1)
-	go func() {
-		data, _ := io.ReadAll(stderrPipe)
-		stderrCh <- string(data)
-	}()
-
-	var startupStdout bytes.Buffer
-	var allStdout bytes.Buffer
-	idleTimer := time.NewTimer(100 * time.Millisecond)
-	defer idleTimer.Stop()
-
-	sendCommand := false
-	commandIssued := false
-	preCommandClosed := false
-
-	for !sendCommand {
-		select {
-		case chunk, ok := <-stdoutCh:
-			if !ok {
-				preCommandClosed = true
-				sendCommand = true
-				break
-			}
-			allStdout.Write(chunk)
-			startupStdout.Write(chunk)
-			if !idleTimer.Stop() {
-				select {
-				case <-idleTimer.C:
-				default:
-				}
-			}
-			idleTimer.Reset(100 * time.Millisecond)
-		case <-idleTimer.C:
-			commandIssued = true
-			sendCommand = true
-		case <-ctx.Done():
-			if cmd.Process != nil {
-				_ = cmd.Process.Kill()
-			}
-			_ = cmd.Wait()
-			return startupStdout.String(), "", <-stderrCh, ctx.Err()
-		}
-	}
-
-	var runErr error
-	if commandIssued {
-		if _, err := io.WriteString(stdinPipe, "bt all\n"); err != nil {
-			runErr = fmt.Errorf("send bt all command failed: %w", err)
-		}
-	}
-	if !preCommandClosed {
-		if err := stdinPipe.Close(); err != nil && runErr == nil {
-			runErr = fmt.Errorf("close stack command stdin failed: %w", err)
-		}
-	}
-
-	for chunk := range stdoutCh {
-		allStdout.Write(chunk)
-	}
-	stdoutErr := <-stdoutErrCh
-	waitErr := cmd.Wait()
-	stderrOutput := <-stderrCh
-
-	if runErr == nil && stdoutErr != nil && !errors.Is(stdoutErr, context.Canceled) {
-		runErr = fmt.Errorf("read stack stdout failed: %w", stdoutErr)
-	}
-	if runErr == nil && waitErr != nil {
-		runErr = waitErr
-	}
-
-	startupLen := startupStdout.Len()
-	allBytes := allStdout.Bytes()
-	stackBytes := []byte{}
-	if startupLen < len(allBytes) {
-		stackBytes = allBytes[startupLen:]
-	}
-	return startupStdout.String(), string(stackBytes), stderrOutput, runErr
-}
-
-func readPipeChunks(ctx context.Context, pipe io.Reader, chunkCh chan<- []byte, errCh chan<- error) {
-	defer close(chunkCh)
-	buffer := make([]byte, 4096)
-	for {
-		n, err := pipe.Read(buffer)
-		if n > 0 {
-			chunk := make([]byte, n)
-			copy(chunk, buffer[:n])
-			select {
-			case chunkCh <- chunk:
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			}
-		}
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				errCh <- nil
-			} else {
-				errCh <- err
-			}
-			return
-		}
-	}
-}
-
-func parseStackBlocks(raw string) ([]stackThreadBlock, []string) {
-	normalized := strings.ReplaceAll(raw, "\r\n", "\n")
-	normalized = strings.ReplaceAll(normalized, "\r", "\n")
-	lines := strings.Split(normalized, "\n")
-
-	threads := make([]stackThreadBlock, 0, 16)
-	misc := make([]string, 0, 8)
-	var current *stackThreadBlock
-
-	flush := func() {
-		if current != nil {
-			threads = append(threads, *current)
-			current = nil
-		}
-	}
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "Thread ") {
-			flush()
-			current = &stackThreadBlock{header: trimmed}
-			continue
-		}
-		if trimmed == "" {
-			continue
-		}
-		if current == nil {
-			misc = append(misc, trimmed)
-			continue
-		}
-		if strings.HasPrefix(trimmed, "#") {
-			current.frames = append(current.frames, trimmed)
-		} else {
-			current.extra = append(current.extra, trimmed)
-		}
-	}
-	flush()
-
-	sort.SliceStable(threads, func(i, j int) bool {
-		left := strings.Contains(threads[i].header, `name=".NET ThreadPool Worker"`)
-		right := strings.Contains(threads[j].header, `name=".NET ThreadPool Worker"`)
+// sortThreadPoolWorkersFirst 把 .NET ThreadPool Worker 线程排在前面，其余线程保持原来的顺序。
+func sortThreadPoolWorkersFirst(threads []StackThread) []StackThread {
+	sorted := append([]StackThread(nil), threads...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		left := sorted[i].Name == ".NET ThreadPool Worker"
+		right := sorted[j].Name == ".NET ThreadPool Worker"
 		if left == right {
 			return false
 		}
 		return left
 	})
-
-	return threads, misc
+	return sorted
 }
 
+// /stack 的视图：逐个线程、按相同调用栈合并、合并后的调用树，以及异步调用链（附带合并后的线程调用栈）。
+const (
+	stackViewThreads = "threads"
+	stackViewGrouped = "grouped"
+	stackViewTree    = "tree"
+	stackViewAsync   = "async"
+)
+
 type stackPageData struct {
-	StartupOutput string
-	NoData        bool
-	Threads       []stackThreadRow
-	Misc          string
-	StderrOutput  string
-	Error         string
+	DebuggerLog  string
+	NoData       bool
+	View         string
+	Views        []stackViewLink
+	ThreadCount  int
+	Threads      []stackThreadRow
+	Groups       []stackGroupRow
+	Tree         string
+	Async        *stackAsyncSection
+	TargetParam  string // "&target=xxx"，用于 inspect 链接
+	Misc         string
+	StderrOutput string
+	Error        string
 }
 
 type stackThreadRow struct {
+	ID     int
 	Header string
-	Frames []string
+	Frames []stackFrameRow
 	Extra  []string
 }
 
-// buildStackPageData turns raw "bt all" output into rows the template can render
-// directly. thread.frames are rendered to HTML via formatStackFrameHTML (safe markup
-// built entirely from escaped fragments); everything else is escaped plain text.
-func buildStackPageData(startupOutput, stackOutput, stderrOutput string, runErr error) stackPageData {
-	threads, misc := parseStackBlocks(stackOutput)
-
-	rows := make([]stackThreadRow, 0, len(threads))
-	for _, thread := range threads {
-		frames := make([]string, 0, len(thread.frames))
-		for _, frame := range thread.frames {
-			frames = append(frames, formatStackFrameHTML(frame))
-		}
-		extra := make([]string, 0, len(thread.extra))
-		for _, detail := range thread.extra {
-			extra = append(extra, html.EscapeString(detail))
-		}
-		rows = append(rows, stackThreadRow{
-			Header: html.EscapeString(thread.header),
-			Frames: frames,
-			Extra:  extra,
-		})
-	}
+// stackGroupRow 是调用栈相同的一组线程，Frames 是渲染后的 HTML。
+type stackGroupRow struct {
+	Count   int
+	Threads string
+	Frames  []string
+}
 
-	errMsg := ""
-	if runErr != nil {
-		errMsg = html.EscapeString(runErr.Error())
-	}
+type stackViewLink struct {
+	Name     string
+	URL      string
+	Selected bool
+}
 
-	return stackPageData{
-		StartupOutput: html.EscapeString(strings.TrimSpace(startupOutput)),
-		NoData:        len(threads) == 0 && strings.TrimSpace(stackOutput) == "",
-		Threads:       rows,
-		Misc:          html.EscapeString(strings.Join(misc, "\n")),
-		StderrOutput:  html.EscapeString(strings.TrimSpace(stderrOutput)),
-		Error:         errMsg,
+func buildStackViewLinks(view, targetParam string) []stackViewLink {
+	links := make([]stackViewLink, 0, 4)
+	for _, name := range []string{stackViewThreads, stackViewGrouped, stackViewTree, stackViewAsync} {
+		links = append(links, stackViewLink{
+			Name:     name,
+			URL:      "/stack?view=" + name + targetParam,
+			Selected: name == view,
+		})
 	}
+	return links
 }
 
-func renderStackHTML(w http.ResponseWriter, startupOutput, stackOutput, stderrOutput string, runErr error) {
-	_ = stackInfoHTMLTemplate.Execute(w, buildStackPageData(startupOutput, stackOutput, stderrOutput, runErr))
+// stackFrameRow 是一帧渲染后的 HTML，托管帧可以通过 /stack/inspect 查看参数与局部变量。
+type stackFrameRow struct {
+	HTML        string
+	Level       int
+	Inspectable bool
 }
 
-func formatStackFrameHTML(frame string) string {
-	line := strings.TrimSpace(frame)
-	if !strings.HasPrefix(line, "#") {
-		return html.EscapeString(line)
+// buildStackPageData turns a StackDump into rows the template can render directly.
+// Frames are rendered to HTML via formatStackFrameHTML (safe markup built entirely
+// from escaped fragments); everything else is escaped plain text.
+// Only the rows of the selected view are built; an unknown view falls back to threads.
+func buildStackPageData(view, debuggerLog string, dump StackDump, stderrOutput string, runErr error) stackPageData {
+	threads := sortThreadPoolWorkersFirst(dump.Threads)
+	data := stackPageData{
+		DebuggerLog:  html.EscapeString(strings.TrimSpace(debuggerLog)),
+		NoData:       len(threads) == 0 && len(dump.Misc) == 0,
+		View:         view,
+		ThreadCount:  len(threads),
+		Misc:         html.EscapeString(strings.Join(dump.Misc, "\n")),
+		StderrOutput: html.EscapeString(strings.TrimSpace(stderrOutput)),
 	}
-	colon := strings.Index(line, ":")
-	if colon < 0 {
-		return html.EscapeString(line)
+	if runErr != nil {
+		data.Error = html.EscapeString(runErr.Error())
 	}
 
-	indexPart := strings.TrimSpace(line[:colon+1])
-	rest := strings.TrimSpace(line[colon+1:])
-	if rest == "" {
-		return fmt.Sprintf(`<span class="frame-idx">%s</span>`, html.EscapeString(indexPart))
+	switch view {
+	case stackViewGrouped, stackViewAsync:
+		data.Groups = buildStackGroupRows(threads)
+		return data
+	case stackViewTree:
+		data.Tree = renderCallTreeHTML(BuildCallTree(threads))
+		return data
+	}
+	data.View = stackViewThreads
+	data.Threads = make([]stackThreadRow, 0, len(threads))
+	for _, thread := range threads {
+		frames := make([]stackFrameRow, 0, len(thread.Frames))
+		for _, frame := range thread.Frames {
+			frames = append(frames, stackFrameRow{
+				HTML:        formatStackFrameHTML(frame),
+				Level:       frame.Index,
+				Inspectable: thread.ID > 0 && frame.Index >= 0 && frame.Method != "",
+			})
+		}
+		extra := make([]string, 0, len(thread.Extra))
+		for _, detail := range thread.Extra {
+			extra = append(extra, html.EscapeString(detail))
+		}
+		data.Threads = append(data.Threads, stackThreadRow{
+			ID:     thread.ID,
+			Header: html.EscapeString(thread.Header),
+			Frames: frames,
+			Extra:  extra,
+		})
 	}
+	return data
+}
 
-	addrPart := ""
-	if field := strings.Fields(rest); len(field) > 0 && strings.HasPrefix(field[0], "0x") {
-		addrPart = field[0]
-		rest = strings.TrimSpace(rest[len(field[0]):])
+func buildStackGroupRows(threads []StackThread) []stackGroupRow {
+	groups := GroupStacks(threads)
+	rows := make([]stackGroupRow, 0, len(groups))
+	for _, group := range groups {
+		rows = append(rows, stackGroupRow{
+			Count:   len(group.Threads),
+			Threads: html.EscapeString(formatThreadRefs(group.Threads)),
+			Frames:  formatStackFramesHTML(group.Frames),
+		})
 	}
+	return rows
+}
 
-	symbolPart := rest
-	sourcePart := ""
-	if at := strings.LastIndex(rest, " at "); at >= 0 {
-		symbolPart = strings.TrimSpace(rest[:at])
-		sourcePart = strings.TrimSpace(rest[at+4:])
+// formatThreadRefs 把线程列为 "11 (worker-a), 12"。
+func formatThreadRefs(threads []ThreadRef) string {
+	labels := make([]string, 0, len(threads))
+	for _, thread := range threads {
+		label := strconv.Itoa(thread.ID)
+		if thread.Name != "" {
+			label += " (" + thread.Name + ")"
+		}
+		labels = append(labels, label)
 	}
+	return strings.Join(labels, ", ")
+}
 
-	dllPart := ""
-	funcPart := symbolPart
-	if sep := strings.Index(symbolPart, "`"); sep >= 0 {
-		dllPart = strings.TrimSpace(symbolPart[:sep])
-		funcPart = strings.TrimSpace(symbolPart[sep+1:])
+func formatStackFramesHTML(frames []StackFrame) []string {
+	rendered := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		rendered = append(rendered, formatStackFrameHTML(frame))
 	}
+	return rendered
+}
 
-	sourcePath := ""
-	sourceFile := ""
-	if sourcePart != "" {
-		slash := strings.LastIndex(sourcePart, "/")
-		if slash < 0 {
-			slash = strings.LastIndex(sourcePart, `\`)
-		}
-		if slash >= 0 {
-			sourcePath = sourcePart[:slash+1]
-			sourceFile = sourcePart[slash+1:]
-		} else {
-			sourceFile = sourcePart
-		}
+func formatStackFrameHTML(frame StackFrame) string {
+	if frame.Index < 0 {
+		return html.EscapeString(frame.Raw)
 	}
 
 	var b strings.Builder
-	b.WriteString(`<span class="frame-idx">`)
-	b.WriteString(html.EscapeString(indexPart))
-	b.WriteString(`</span>`)
+	b.WriteString(`<span class="frame-idx">#`)
+	b.WriteString(strconv.Itoa(frame.Index))
+	b.WriteString(`:</span>`)
 
-	if addrPart != "" {
+	if frame.Address != "" {
 		b.WriteString(` <span class="frame-ptr">`)
-		b.WriteString(html.EscapeString(addrPart))
+		b.WriteString(html.EscapeString(frame.Address))
 		b.WriteString(`</span>`)
 	}
 
-	if dllPart != "" {
+	if frame.Module != "" {
 		b.WriteString(` <span class="frame-dll">`)
-		b.WriteString(html.EscapeString(dllPart))
+		b.WriteString(html.EscapeString(frame.Module))
 		b.WriteString(`</span><span class="frame-sep">` + html.EscapeString("`") + `</span>`)
 	}
 
-	if funcPart != "" {
+	if frame.Method != "" {
 		b.WriteString(` <span class="frame-func">`)
-		b.WriteString(html.EscapeString(funcPart))
+		if frame.Namespace != "" {
+			b.WriteString(`<span class="frame-ns">`)
+			b.WriteString(html.EscapeString(frame.Namespace + "."))
+			b.WriteString(`</span>`)
+		}
+		if frame.Type != "" {
+			b.WriteString(`<span class="frame-type">`)
+			b.WriteString(html.EscapeString(frame.TypeName() + "."))
+			b.WriteString(`</span>`)
+		}
+		b.WriteString(`<span class="frame-method">`)
+		b.WriteString(html.EscapeString(frame.MethodName()))
+		b.WriteString(`</span>`)
+		if frame.HasParams {
+			b.WriteString(`<span class="frame-params">`)
+			b.WriteString(html.EscapeString("(" + frame.Params + ")"))
+			b.WriteString(`</span>`)
+		}
+		b.WriteString(`</span>`)
+	} else if frame.Symbol != "" {
+		b.WriteString(` <span class="frame-func">`)
+		b.WriteString(html.EscapeString(frame.Symbol))
 		b.WriteString(`</span>`)
 	}
 
-	if sourcePart != "" {
+	if frame.ILOffset != nil {
+		b.WriteString(` <span class="frame-il">`)
+		b.WriteString(fmt.Sprintf("IL_%04X", *frame.ILOffset))
+		b.WriteString(`</span>`)
+	}
+
+	if frame.File != "" {
+		sourcePath := ""
+		sourceFile := frame.File
+		slash := strings.LastIndex(frame.File, "/")
+		if slash < 0 {
+			slash = strings.LastIndex(frame.File, `\`)
+		}
+		if slash >= 0 {
+			sourcePath = frame.File[:slash+1]
+			sourceFile = frame.File[slash+1:]
+		}
+		if frame.Line > 0 {
+			sourceFile += ":" + strconv.Itoa(frame.Line)
+		}
 		b.WriteString(` <span class="frame-at">at</span> `)
 		if sourcePath != "" {
 			b.WriteString(`<span class="frame-path">`)
@@ -725,19 +954,23 @@ func formatStackFrameHTML(frame string) string {
 	return b.String()
 }
 
-// resolveTargetPID 返回真正的目标进程 pid。
-// 当以 --with.gdb 或 --with.coverage 启动时，h.target.PID() 是 gdb / dotnet-coverage
-// 外壳进程的 pid，必须通过 GlobalOptions.StartupParams 在进程树中定位真正的目标进程，
-// 否则 dotnet-trace / netcoredbg 会挂到外壳进程上，采集不到目标进程的数据。
-func (h *AdminHandler) resolveTargetPID() int {
-	pid := h.target.Load().PID()
-	if !GlobalOptions.WithGDB && !GlobalOptions.WithCoverage {
-		return pid
+// targetRunningSince 返回目标进程是否在运行，以及它的启动时间。
+func (h *AdminHandler) targetRunningSince() (bool, time.Time) {
+	target := h.target.Load()
+	if target == nil {
+		return false, time.Time{}
 	}
-	if resolved, ok := findTargetDescendantPID(pid, GlobalOptions.StartupParams); ok {
-		return resolved
+	return target.Running(), target.startTime
+}
+
+// resolveTargetPID 返回真正的目标进程 pid，详见 TargetProcess.resolvePID。
+// 子进程尚未启动时返回 0。
+func (h *AdminHandler) resolveTargetPID() int {
+	target := h.target.Load()
+	if target == nil {
+		return 0
 	}
-	return pid
+	return target.resolvePID()
 }
 
 func (h *AdminHandler) handleTrace(w http.ResponseWriter, r *http.Request) {
@@ -750,84 +983,66 @@ func (h *AdminHandler) handleTrace(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 		return
 	}
-	seconds, err := parseTraceSeconds(r)
+	req, err := parseTraceRequest(r, false)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	traceID := time.Now().Format(traceIDLayout)
-	outputPath := filepath.Join("/tmp", traceID+".nettrace")
+	traceID := newTraceID()
 	redirectURL := "/speedscope/index.html#profileURL=/profile/" + traceID + ".speedscope.json"
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
 	_, _ = io.WriteString(w, "<!doctype html><html><body><pre>\n")
-	_, _ = fmt.Fprintf(w, "trace id: %s\n", traceID)
+	_, _ = fmt.Fprintf(w, "trace id: %s\nkind: %s\n", traceID, req.Describe())
 	flusher.Flush()
 
-	profiles := TraceProfileCandidates()
-	for i, profile := range profiles {
-		if i > 0 {
-			_, _ = fmt.Fprintf(w, "profile %q unsupported, retrying with %q\n", profiles[i-1], profile)
-			flusher.Flush()
-		}
-		stderrLog := &bytes.Buffer{}
-		cmd := BuildTraceCommand(h.resolveTargetPID(), seconds, outputPath, profile)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = io.MultiWriter(os.Stderr, stderrLog)
-		if err := cmd.Start(); err != nil {
-			_, _ = fmt.Fprintf(w, "trace failed: run dotnet-trace failed: %v\n", err)
-			_, _ = io.WriteString(w, "</pre></body></html>")
-			flusher.Flush()
-			return
-		}
-		if err := streamCountdown(w, r.Context(), flusher, seconds, cmd); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return
-			}
-			detail := strings.TrimSpace(stderrLog.String())
-			if shouldRetryTraceWithAnotherProfile(detail) && i+1 < len(profiles) {
-				continue
-			}
-			_, _ = fmt.Fprintf(w, "trace failed: %v\n", err)
-			if detail != "" {
-				_, _ = io.WriteString(w, "dotnet-trace stderr:\n")
-				_, _ = io.WriteString(w, tailLines(detail, 12))
-				_, _ = io.WriteString(w, "\n")
-			}
-			_, _ = io.WriteString(w, "</pre></body></html>")
-			flusher.Flush()
-			return
-		}
-		h.traces.Add(traceID)
-		_, _ = io.WriteString(w, "trace completed, redirecting...\n")
-		_, _ = io.WriteString(w, "</pre>")
-		_, _ = fmt.Fprintf(w, "<script>window.location.href=%q;</script></body></html>", redirectURL)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	pid := h.resolveTargetPID()
+	var notes []string
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		notes, err = h.collectTrace(ctx, traceID, pid, req, nil)
+		done <- err
+	}()
+	err = streamProgress(w, r.Context(), flusher, int(req.Duration/time.Second), done, cancel)
+	if r.Context().Err() != nil {
+		return
+	}
+	for _, note := range notes {
+		_, _ = io.WriteString(w, note+"\n")
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "trace failed: %v\n</pre></body></html>", err)
 		flusher.Flush()
 		return
 	}
-
-	_, _ = io.WriteString(w, "trace failed: no available trace profile\n</pre></body></html>")
+	h.traces.Add(traceID)
+	_, _ = io.WriteString(w, "trace completed, redirecting...\n")
+	_, _ = io.WriteString(w, "</pre>")
+	_, _ = fmt.Fprintf(w, "<script>window.location.href=%q;</script></body></html>", redirectURL)
 	flusher.Flush()
 }
 
-func streamCountdown(w io.Writer, ctx context.Context, flusher http.Flusher, seconds int, cmd *exec.Cmd) error {
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
-
+// streamProgress 输出剩余时间（一分钟以内每秒一次，更长的 trace 每 10 秒一次），直到 done 返回结果；
+// 请求被取消时调用 stop 并等待 done。
+func streamProgress(w io.Writer, ctx context.Context, flusher http.Flusher, seconds int, done <-chan error, stop func()) error {
 	start := time.Now()
-	ticker := time.NewTicker(time.Second)
+	interval := time.Second
+	if seconds > 60 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		elapsed := int(time.Since(start).Seconds())
-		remaining := seconds - elapsed
+		remaining := time.Duration(seconds)*time.Second - time.Since(start).Round(time.Second)
 		if remaining < 0 {
 			remaining = 0
 		}
-		if _, err := fmt.Fprintf(w, "collecting cpu trace... remaining %d seconds\n", remaining); err != nil {
+		if _, err := fmt.Fprintf(w, "collecting trace... remaining %s\n", remaining); err != nil {
 			return err
 		}
 		flusher.Flush()
@@ -836,9 +1051,7 @@ func streamCountdown(w io.Writer, ctx context.Context, flusher http.Flusher, sec
 		case err := <-done:
 			return err
 		case <-ctx.Done():
-			if cmd.Process != nil {
-				_ = cmd.Process.Kill()
-			}
+			stop()
 			<-done
 			return ctx.Err()
 		case <-ticker.C:
@@ -846,22 +1059,6 @@ func streamCountdown(w io.Writer, ctx context.Context, flusher http.Flusher, sec
 	}
 }
 
-func parseTraceSeconds(r *http.Request) (int, error) {
-	seconds := 10
-	raw := strings.TrimSpace(r.URL.Query().Get("seconds"))
-	if raw != "" {
-		value, err := strconv.Atoi(raw)
-		if err != nil {
-			return 0, fmt.Errorf("invalid seconds: %s", raw)
-		}
-		seconds = value
-	}
-	if seconds < 1 || seconds > 30 {
-		return 0, fmt.Errorf("seconds must be between 1 and 30")
-	}
-	return seconds, nil
-}
-
 func (h *AdminHandler) handleProfileList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -872,6 +1069,7 @@ func (h *AdminHandler) handleProfileList(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
 	_, _ = io.WriteString(w, "<!doctype html><html><head><meta charset=\"utf-8\"><title>Profile List</title></head><body>\n")
+	h.writeTraceJobs(w)
 	_, _ = io.WriteString(w, "<h3>Profile List</h3>\n")
 	if len(traceIDs) == 0 {
 		_, _ = io.WriteString(w, "<div>no profiles</div>\n")
@@ -48,12 +48,16 @@
 	.section-processes{background:#fefce8;}
 	.section-history{background:#fdf2f8;}
 	.section-coverage{background:#ecfeff;}
+	.section-control{background:#f5f3ff;}
+	.section-targets{background:#fff7ed;}
 	a{color:#2563eb;}
 	.links a{
 		display:inline-block;
 		margin:2px 12px 2px 0;
 	}
 	.trace-form{margin-top:10px;}
+	.launch-form{margin-top:10px;}
+	textarea{font-family:Consolas,Monaco,monospace;font-size:12px;}
 	input[type=text]{
 		font-family:Consolas,Monaco,monospace;
 		padding:2px 4px;
@@ -95,28 +99,82 @@
 <body>
 
 <h1>Dotnet Debug Container All-In-One
-<span class="sub">target={{.TargetLabel}} pid={{.PID}} cwd={{if .CWD}}{{.CWD}}{{else}}-{{end}}</span>
+<span class="sub">target={{.TargetLabel}} pid={{.PID}} state={{if .Running}}running{{else}}stopped{{end}} cwd={{if .CWD}}{{.CWD}}{{else}}-{{end}}</span>
 </h1>
+{{if .GDBHoldUntil}}<div style="margin:0 0 12px 0;padding:8px 10px;border-radius:8px;background:#fef3c7;color:#92400e;font-weight:700;">The target is stopped under gdb until {{.GDBHoldUntil}}: <a href="/gdb-console{{.TargetQuery}}" target="_blank">inspect it in the gdb console</a></div>{{end}}
 
+{{if .Targets}}<section class="section-targets">
+<h2>Targets</h2>
+<div class="links">{{range .Targets}}<a href="{{.URL}}"{{if .Selected}} style="font-weight:700;"{{end}}>{{.Name}}</a>{{end}}</div>
+</section>
+{{end}}
 <section class="section-links">
 <h2>Quick Links</h2>
 <div class="links">
-<a href="/log" target="_blank">show log</a>
-<a href="/stack" target="_blank">show stack</a>
-<a href="/profile_list" target="_blank">show cpuprofile list</a>
-{{if .ShowCurrentGDBLog}}<a href="/current-gdb-log" target="_blank">Current Gdb Log</a>{{end}}
+<a href="/log{{.TargetQuery}}" target="_blank">show log</a>
+<a href="/stack{{.TargetQuery}}" target="_blank">show stack</a>
+<a href="/hang{{.TargetQuery}}" target="_blank">hang detection</a>
+<a href="/exceptions{{.TargetQuery}}" target="_blank">exceptions</a>
+<a href="/eventpipe{{.TargetQuery}}" target="_blank">eventpipe sessions</a>
+<a href="/triggers{{.TargetQuery}}" target="_blank">triggers</a>
+<a href="/notifications{{.TargetQuery}}" target="_blank">notifications</a>
+<a href="/coredumps{{.TargetQuery}}" target="_blank">core dumps</a>
+<a href="/profile_list{{.TargetQuery}}" target="_blank">show cpuprofile list</a>
+<a href="/config" target="_blank">show config</a>
+{{if .ShowCurrentGDBLog}}<a href="/current-gdb-log{{.TargetQuery}}" target="_blank">Current Gdb Log</a>
+<a href="/gdb-console{{.TargetQuery}}" target="_blank">gdb console</a>{{end}}
+{{if .ShowPerfMap}}<a href="/perf-map{{.TargetQuery}}">download perf map</a>{{end}}
 </div>
 <div class="trace-form">
 Trace <input type="text" size=4 value=10 id="seconds"/> seconds, then <input type="button" value="Show CPU Profile" onclick="profile()"/>
+<input type="button" value="Native CPU Profile (perf)" onclick="profile('backend=perf')"/>
+<input type="button" value="Allocation Profile" onclick="profile('kind=alloc')"/>
 </div>
+<form class="trace-form" method="post" action="/trace/start{{.TargetQuery}}" target="_blank">
+Background <select name="kind"><option value="cpu">CPU</option><option value="perf">Native CPU (perf)</option><option value="alloc">Allocation</option></select>
+trace for <input type="text" size=6 name="seconds" placeholder="e.g. 5m"/> (empty = until stopped) <input type="submit" value="Start"/>
+</form>
 <script>
-function profile(){
+var targetQuery = "{{.TargetQuery}}";
+function withTarget(url){
+	if(!targetQuery){
+		return url;
+	}
+	return url + (url.indexOf("?") < 0 ? "?" : "&") + targetQuery.substring(1);
+}
+function profile(params){
 	var textbox = document.getElementById("seconds");
-	window.open("/trace?seconds=" + textbox.value, "about:blank");
+	var url = "/trace?seconds=" + textbox.value;
+	if(params){
+		url += "&" + params;
+	}
+	window.open(withTarget(url), "about:blank");
 }
 </script>
 </section>
 
+<section class="section-control">
+<h2>Target Control</h2>
+<div>
+<input type="button" value="Stop" onclick="targetAction('stop')"/>
+<input type="button" value="Start" onclick="targetAction('start')"/>
+<input type="button" value="Restart" onclick="targetAction('restart')"/>
+&nbsp;Signal <input type="text" size=8 value="USR1" id="signal"/> <input type="button" value="Send" onclick="targetAction('signal?sig=' + encodeURIComponent(document.getElementById('signal').value))"/>
+</div>
+<div class="launch-form">
+<div><label>Mode <select id="launch-mode">
+<option value="plain"{{if eq .LaunchMode "plain"}} selected{{end}}>plain</option>
+<option value="gdb"{{if eq .LaunchMode "gdb"}} selected{{end}}>gdb (crash capture)</option>
+<option value="coverage"{{if eq .LaunchMode "coverage"}} selected{{end}}>coverage (dotnet-coverage)</option>
+</select></label></div>
+<div><label>Args (one per line)<br/><textarea id="launch-args" rows="4" cols="60">{{.LaunchArgs}}</textarea></label></div>
+<div><label>Extra env (KEY=VALUE, one per line, e.g. DOTNET_gcServer=1)<br/><textarea id="launch-env" rows="4" cols="60">{{.LaunchEnv}}</textarea></label></div>
+<input type="button" value="Restart With These" onclick="restartWithLaunchSpec()"/>
+<input type="button" value="Revert To Original" onclick="targetAction('revert')"/>
+<span class="empty">original: {{.OriginalLabel}}</span>
+</div>
+</section>
+
 <section class="section-processes">
 <h2>Container Processes</h2>
 <table>
@@ -124,7 +182,7 @@ function profile(){
 {{range .Processes}}<tr{{if .IsTarget}} style="background-color:#fde68a;"{{end}}><td>{{.PID}}</td><td>{{.Uptime}}</td><td>{{.Memory}}</td><td>{{.ThreadCount}}</td><td class="col-cmdline">{{.Cmdline}}</td><td>
 {{if .IsTarget}}
 {{if $.WithCoverage}}
-  <input type="button" value="Show Code Coverage" onclick="window.open('/code_coverage/', '_blank')"/>
+  <input type="button" value="Show Code Coverage" onclick="window.open(withTarget('/code_coverage/'), '_blank')"/>
   <input type="button" value="Reset Coverage Data" onclick="resetCoverageData()"/>
 {{end}}
 {{end}}</td></tr>
@@ -134,8 +192,8 @@ function profile(){
 <section class="section-history">
 <h2>Run History</h2>
 {{if .RunHistory}}<table>
-<tr><th>#</th><th>PID</th><th>Start</th><th>End</th><th>Duration</th><th>Exit</th><th>CoreDump</th><th>GDB Log</th><th>Last Logs</th></tr>
-{{range .RunHistory}}<tr><td>{{.Index}}</td><td>{{.PID}}</td><td>{{.Start}}</td><td>{{.End}}</td><td>{{.Duration}}</td><td>{{if .Abnormal}}<span style="color:#b91c1c;font-weight:700;">code={{.ExitCode}}{{if .Signal}} signal={{.Signal}}{{end}} (abnormal)</span>{{else}}<span style="color:#166534;">code={{.ExitCode}}{{if .Signal}} signal={{.Signal}}{{end}} (normal)</span>{{end}}{{if .ErrMsg}}<br/><span style="color:#b91c1c;">{{.ErrMsg}}</span>{{end}}</td><td>{{if .CoreDumpPath}}{{.CoreDumpPath}}{{else}}-{{end}}</td><td>{{if .GDBLogPath}}<a href="/gdb-log?index={{.GDBLogIndex}}" target="_blank">{{.GDBLogPath}}</a>{{else}}-{{end}}</td><td>{{if .LastLogs}}<pre style="margin:0;white-space:pre-wrap;max-height:160px;overflow:auto;">{{.LastLogs}}</pre>{{else}}-{{end}}</td></tr>
+<tr><th>#</th><th>PID</th><th>Started By</th><th class="col-cmdline">Command</th><th>Start</th><th>End</th><th>Duration</th><th>Exit</th><th>CoreDump</th><th>GDB Log</th><th>Perf Map</th><th>Last Logs</th></tr>
+{{range .RunHistory}}<tr><td>{{.Index}}</td><td>{{.PID}}</td><td>{{if .StartReason}}{{.StartReason}}{{else}}-{{end}}</td><td class="col-cmdline">{{if .Command}}{{.Command}}{{else}}-{{end}}</td><td>{{.Start}}</td><td>{{.End}}</td><td>{{.Duration}}</td><td>{{if .Manual}}<span style="color:#6b7280;">code={{.ExitCode}}{{if .Signal}} signal={{.Signal}}{{end}} (manual)</span>{{else if .Abnormal}}<span style="color:#b91c1c;font-weight:700;">code={{.ExitCode}}{{if .Signal}} signal={{.Signal}}{{end}} (abnormal)</span>{{else}}<span style="color:#166534;">code={{.ExitCode}}{{if .Signal}} signal={{.Signal}}{{end}} (normal)</span>{{end}}{{if .ErrMsg}}<br/><span style="color:#b91c1c;">{{.ErrMsg}}</span>{{end}}</td><td>{{if .CoreDumpID}}<a href="/coredumps/download?id={{.CoreDumpID}}{{$.TargetParam}}">{{.CoreDumpPath}}</a>{{else if .CoreDumpPath}}{{.CoreDumpPath}}{{else}}-{{end}}{{if .ReportID}}<br/><a href="/crash-report?id={{.ReportID}}{{$.TargetParam}}" target="_blank">crash report</a>{{end}}</td><td>{{if .GDBLogPath}}<a href="/gdb-log?index={{.GDBLogIndex}}{{$.TargetParam}}" target="_blank">{{.GDBLogPath}}</a>{{if .GDBScript}} <a href="/gdb-log?index={{.GDBLogIndex}}&kind=script{{$.TargetParam}}" target="_blank">script</a>{{end}}{{else}}-{{end}}</td><td>{{if .PerfMap}}<a href="/perf-map?index={{.GDBLogIndex}}{{$.TargetParam}}">map</a>{{if .PerfInfo}} <a href="/perf-map?index={{.GDBLogIndex}}&kind=info{{$.TargetParam}}">perfinfo</a>{{end}}{{else}}-{{end}}</td><td>{{if .LastLogs}}<pre style="margin:0;white-space:pre-wrap;max-height:160px;overflow:auto;">{{.LastLogs}}</pre>{{else}}-{{end}}</td></tr>
 {{end}}</table>{{else}}<div class="empty">no exit records yet</div>{{end}}
 </section>
 
@@ -150,8 +208,36 @@ function profile(){
 {{end}}
 
 <script>
+function targetAction(action){
+	fetch(withTarget("/api/target/" + action), {method: "POST"}).then(function(resp){
+		if(!resp.ok){
+			return resp.text().then(function(text){
+				throw new Error(text || ("HTTP " + resp.status));
+			});
+		}
+		window.location.reload();
+	}).catch(function(err){
+		alert("Target " + action + " failed: " + err.message);
+	});
+}
+function restartWithLaunchSpec(){
+	var body = new URLSearchParams();
+	body.set("mode", document.getElementById("launch-mode").value);
+	body.set("args", document.getElementById("launch-args").value);
+	body.set("env", document.getElementById("launch-env").value);
+	fetch(withTarget("/api/target/launch"), {method: "POST", body: body}).then(function(resp){
+		if(!resp.ok){
+			return resp.text().then(function(text){
+				throw new Error(text || ("HTTP " + resp.status));
+			});
+		}
+		window.location.reload();
+	}).catch(function(err){
+		alert("Restart failed: " + err.message);
+	});
+}
 function resetCoverageData(){
-	fetch("/reset_coverage_data", {method: "POST"}).then(function(resp){
+	fetch(withTarget("/reset_coverage_data"), {method: "POST"}).then(function(resp){
 		if(!resp.ok){
 			return resp.text().then(function(text){
 				throw new Error(text || ("HTTP " + resp.status));
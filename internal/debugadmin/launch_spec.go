@@ -0,0 +1,150 @@
+package debugadmin
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RunMode 是子进程的运行方式，每次启动都可以不同。
+type RunMode string
+
+const (
+	RunModePlain    RunMode = "plain"    // 直接运行
+	RunModeGDB      RunMode = "gdb"      // 在 gdb 下运行，崩溃时记录现场
+	RunModeCoverage RunMode = "coverage" // 在 dotnet-coverage collect 下运行，采集代码覆盖率
+)
+
+// parseRunMode 解析管理端提交的运行方式，空字符串表示 plain。
+func parseRunMode(raw string) (RunMode, error) {
+	switch mode := RunMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return RunModePlain, nil
+	case RunModePlain, RunModeGDB, RunModeCoverage:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid run mode %q, want plain, gdb or coverage", raw)
+	}
+}
+
+// LaunchSpec 描述一次启动子进程所使用的运行方式、命令行参数和额外环境变量。
+// 默认值来自 GlobalOptions.StartupParams；管理端可以在重启时修改，
+// 每次运行实际使用的 LaunchSpec 会记录到 RunRecord 中。
+type LaunchSpec struct {
+	Mode RunMode  `json:"mode"`
+	Args []string `json:"args"` // 与 -- 之后的参数含义相同，第一个元素是 xx.dll 或可执行文件
+	Env  []string `json:"env"`  // 追加到 DebugAdmin 自身环境变量之后的 KEY=VALUE 列表
+	// Dir 是子进程的工作目录，为空时使用 DebugAdmin 的工作目录；只能通过配置文件的 targets[].dir 指定。
+	Dir string `json:"dir,omitempty"`
+	// CoverageSession 是 coverage 模式下 dotnet-coverage 的 session id，每个目标进程各不相同；
+	// 为空时使用 GlobalOptions.CoverageOpts.CoverageName。
+	CoverageSession string `json:"-"`
+}
+
+// DefaultLaunchSpec 返回命令行 -- 之后指定的原始启动参数，运行方式由 -with.gdb / -with.coverage 决定。
+func DefaultLaunchSpec() LaunchSpec {
+	mode := RunModePlain
+	switch {
+	case GlobalOptions.WithGDB:
+		mode = RunModeGDB
+	case GlobalOptions.WithCoverage:
+		mode = RunModeCoverage
+	}
+	return LaunchSpec{Mode: mode, Args: append([]string(nil), GlobalOptions.StartupParams...)}
+}
+
+// Clone 返回一份深拷贝，避免多个 RunRecord 共享同一个切片。
+func (s LaunchSpec) Clone() LaunchSpec {
+	return LaunchSpec{
+		Mode: s.Mode,
+		Args: append([]string(nil), s.Args...),
+		Env:  append([]string(nil), s.Env...),
+		Dir:  s.Dir,
+
+		CoverageSession: s.CoverageSession,
+	}
+}
+
+// WorkDir 返回子进程实际使用的工作目录（绝对路径），内核按 core_pattern 写相对路径的 core 时相对于它。
+func (s LaunchSpec) WorkDir() string {
+	dir := s.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+		return dir
+	}
+	return absPath(dir)
+}
+
+func (s LaunchSpec) coverageSession() string {
+	if s.CoverageSession != "" {
+		return s.CoverageSession
+	}
+	return GlobalOptions.CoverageOpts.CoverageName
+}
+
+// perfMapEnabledEnv 让 .NET 运行时把 JIT 方法的地址范围写到 /tmp/perf-{pid}.map 和 /tmp/perfinfo-{pid}.map。
+const perfMapEnabledEnv = "DOTNET_PerfMapEnabled=1"
+
+// Environ 返回子进程的完整环境变量；没有额外环境变量时返回 nil，即继承 DebugAdmin 的环境变量。
+// 开启 -perf.map 时加上 DOTNET_PerfMapEnabled=1，开启 -coredump.createdump 时加上 DOTNET_DbgEnableMiniDump 等，
+// 都放在 s.Env 之前，s.Env 中的同名变量优先。
+func (s LaunchSpec) Environ() []string {
+	env := s.Env
+	if GlobalOptions != nil && GlobalOptions.CoreDump.CreateDump {
+		env = append(createDumpEnv(GlobalOptions.CoreDump), env...)
+	}
+	if GlobalOptions != nil && GlobalOptions.PerfMap {
+		env = append([]string{perfMapEnabledEnv}, env...)
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
+// Label 返回用于页面展示的命令行，非 plain 模式时带上运行方式。
+func (s LaunchSpec) Label() string {
+	label := strings.Join(s.Args, " ")
+	if len(s.Env) > 0 {
+		label = strings.Join(s.Env, " ") + " " + label
+	}
+	if s.Mode != "" && s.Mode != RunModePlain {
+		label = "[" + string(s.Mode) + "] " + label
+	}
+	return label
+}
+
+// parseLaunchSpec 解析管理端表单提交的启动参数：args 和 env 都是每行一项，
+// 不做 shell 风格的引号解析，参数中可以直接包含空格。
+func parseLaunchSpec(rawMode, rawArgs, rawEnv string) (LaunchSpec, error) {
+	mode, err := parseRunMode(rawMode)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+	args := splitNonEmptyLines(rawArgs)
+	if len(args) == 0 {
+		return LaunchSpec{}, fmt.Errorf("args must not be empty")
+	}
+	env := splitNonEmptyLines(rawEnv)
+	for _, item := range env {
+		name, _, found := strings.Cut(item, "=")
+		if !found || !envNamePattern.MatchString(name) {
+			return LaunchSpec{}, fmt.Errorf("invalid environment variable %q, want KEY=VALUE", item)
+		}
+	}
+	return LaunchSpec{Mode: mode, Args: args, Env: env}, nil
+}
+
+func splitNonEmptyLines(raw string) []string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	var out []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
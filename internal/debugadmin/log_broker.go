@@ -1,11 +1,16 @@
 package debugadmin
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 type LogBroker struct {
 	mu      sync.RWMutex
 	nextID  int
 	clients map[int]chan string
+	last    atomic.Int64 // 最近一次 Broadcast 的时间，UnixNano
 }
 
 func NewLogBroker() *LogBroker {
@@ -35,6 +40,7 @@ func (b *LogBroker) Subscribe() (<-chan string, func()) {
 }
 
 func (b *LogBroker) Broadcast(message string) {
+	b.last.Store(time.Now().UnixNano())
 	b.mu.RLock()
 	for _, ch := range b.clients {
 		select {
@@ -44,3 +50,12 @@ func (b *LogBroker) Broadcast(message string) {
 	}
 	b.mu.RUnlock()
 }
+
+// LastBroadcast 返回最近一次输出日志的时间，从未输出时返回零值。
+func (b *LogBroker) LastBroadcast() time.Time {
+	last := b.last.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
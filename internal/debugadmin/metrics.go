@@ -0,0 +1,65 @@
+package debugadmin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// serveMetrics 以 Prometheus 文本格式输出所有目标进程的指标，带 target= 参数时只输出这个目标进程。
+func (t *TargetRouter) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	handlers := t.handlers
+	if name := r.URL.Query().Get("target"); name != "" {
+		handler, ok := t.byName[name]
+		if !ok {
+			http.Error(w, "unknown target: "+name, http.StatusNotFound)
+			return
+		}
+		handlers = []*AdminHandler{handler}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeExceptionMetrics(w, handlers)
+}
+
+// writeExceptionMetrics 输出异常统计。计数在 /exceptions 上重置后从 0 开始，Prometheus 会按 counter reset 处理。
+func writeExceptionMetrics(w io.Writer, handlers []*AdminHandler) {
+	stats := make([]ExceptionStats, len(handlers))
+	for i, handler := range handlers {
+		stats[i] = handler.exceptions.Stats()
+	}
+	_, _ = fmt.Fprint(w, "# HELP debugadmin_exceptions_total First-chance exceptions thrown by the target process since the last reset.\n")
+	_, _ = fmt.Fprint(w, "# TYPE debugadmin_exceptions_total counter\n")
+	for i, handler := range handlers {
+		if !stats[i].Enabled {
+			continue
+		}
+		for _, item := range stats[i].Types {
+			_, _ = fmt.Fprintf(w, "debugadmin_exceptions_total{target=\"%s\",type=\"%s\"} %d\n",
+				escapeMetricLabel(handler.name), escapeMetricLabel(item.Type), item.Count)
+		}
+	}
+	_, _ = fmt.Fprint(w, "# HELP debugadmin_exception_tracker_connected Whether the exception tracker has an EventPipe session with the target process.\n")
+	_, _ = fmt.Fprint(w, "# TYPE debugadmin_exception_tracker_connected gauge\n")
+	for i, handler := range handlers {
+		if !stats[i].Enabled {
+			continue
+		}
+		connected := 0
+		if stats[i].Connected {
+			connected = 1
+		}
+		_, _ = fmt.Fprintf(w, "debugadmin_exception_tracker_connected{target=\"%s\"} %d\n", escapeMetricLabel(handler.name), connected)
+	}
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricLabel(value string) string {
+	return metricLabelEscaper.Replace(value)
+}
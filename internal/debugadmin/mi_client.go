@@ -0,0 +1,381 @@
+package debugadmin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// miMaxLineSize 是一行 MI 输出的最大长度，线程很多时 -thread-info 的结果可能很长。
+const miMaxLineSize = 16 * 1024 * 1024
+
+// ErrMIClosed 表示调试器已经退出或输出已关闭，未完成的请求不会再有结果。
+var ErrMIClosed = errors.New("debugger MI connection closed")
+
+// MIError 是 ^error 结果，Msg 为调试器返回的错误信息。
+type MIError struct {
+	Command string
+	Msg     string
+}
+
+func (e *MIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Msg)
+}
+
+// MIClient 通过 GDB/MI 协议驱动 netcoredbg --interpreter=mi。
+// 每个命令带有递增的序号，结果按序号与请求对应，与输出的时间无关。
+type MIClient struct {
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu      sync.Mutex
+	nextTok int
+	pending map[int]chan MIRecord
+	closed  bool
+	readErr error
+	log     strings.Builder
+
+	stopped chan MIRecord // *stopped 异步记录
+	done    chan struct{} // 读取循环结束时关闭
+}
+
+// NewMIClient 从 r 读取调试器输出，向 w 写入命令。r 读到 EOF 后所有未完成的请求返回 ErrMIClosed。
+func NewMIClient(r io.Reader, w io.Writer) *MIClient {
+	c := &MIClient{
+		w:       w,
+		nextTok: 1,
+		pending: make(map[int]chan MIRecord),
+		stopped: make(chan MIRecord, 8),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(r)
+	return c
+}
+
+func (c *MIClient) readLoop(r io.Reader) {
+	defer close(c.done)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), miMaxLineSize)
+	for scanner.Scan() {
+		record, ok, err := ParseMIRecord(scanner.Text())
+		if err != nil {
+			c.appendLog(scanner.Text() + "\n")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		switch record.Kind {
+		case MIResultRecord:
+			c.mu.Lock()
+			ch := c.pending[record.Token]
+			delete(c.pending, record.Token)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- record
+			}
+		case MIExecAsync:
+			if record.Class == "stopped" {
+				select {
+				case c.stopped <- record:
+				default:
+				}
+			}
+		case MIConsoleStream, MITargetStream, MILogStream:
+			c.appendLog(record.Text)
+		}
+	}
+	c.mu.Lock()
+	c.closed = true
+	c.readErr = scanner.Err()
+	c.pending = make(map[int]chan MIRecord)
+	c.mu.Unlock()
+}
+
+func (c *MIClient) appendLog(text string) {
+	c.mu.Lock()
+	c.log.WriteString(text)
+	c.mu.Unlock()
+}
+
+// Log 返回调试器输出的流记录（~ @ &），用于在页面上展示调试器自身的信息。
+func (c *MIClient) Log() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.log.String()
+}
+
+// Done 在调试器输出关闭后关闭。
+func (c *MIClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Exec 发送一条 MI 命令并等待对应序号的结果。^error 以 *MIError 返回。
+func (c *MIClient) Exec(ctx context.Context, command string) (MIRecord, error) {
+	ch := make(chan MIRecord, 1)
+	c.mu.Lock()
+	if c.closed {
+		err := c.closedErr()
+		c.mu.Unlock()
+		return MIRecord{}, fmt.Errorf("%s: %w", command, err)
+	}
+	token := c.nextTok
+	c.nextTok++
+	c.pending[token] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	_, err := io.WriteString(c.w, strconv.Itoa(token)+command+"\n")
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(token)
+		return MIRecord{}, fmt.Errorf("send %s failed: %w", command, err)
+	}
+
+	select {
+	case record := <-ch:
+		if record.Class == "error" {
+			return record, &MIError{Command: command, Msg: record.Results.String("msg")}
+		}
+		return record, nil
+	case <-c.done:
+		// 结果可能与 EOF 同时到达
+		select {
+		case record := <-ch:
+			if record.Class == "error" {
+				return record, &MIError{Command: command, Msg: record.Results.String("msg")}
+			}
+			return record, nil
+		default:
+		}
+		c.mu.Lock()
+		err := c.closedErr()
+		c.mu.Unlock()
+		return MIRecord{}, fmt.Errorf("%s: %w", command, err)
+	case <-ctx.Done():
+		c.forget(token)
+		return MIRecord{}, fmt.Errorf("%s: %w", command, ctx.Err())
+	}
+}
+
+func (c *MIClient) forget(token int) {
+	c.mu.Lock()
+	delete(c.pending, token)
+	c.mu.Unlock()
+}
+
+// closedErr 需要持有 c.mu。
+func (c *MIClient) closedErr() error {
+	if c.readErr != nil {
+		return fmt.Errorf("%w: %v", ErrMIClosed, c.readErr)
+	}
+	return ErrMIClosed
+}
+
+// WaitStopped 等待下一个 *stopped 异步记录。
+func (c *MIClient) WaitStopped(ctx context.Context) (MIRecord, error) {
+	select {
+	case record := <-c.stopped:
+		return record, nil
+	case <-c.done:
+		return MIRecord{}, fmt.Errorf("wait for stop: %w", ErrMIClosed)
+	case <-ctx.Done():
+		return MIRecord{}, fmt.Errorf("wait for stop: %w", ctx.Err())
+	}
+}
+
+// Attach 附加到进程。失败时返回的错误中包含调试器给出的原因。
+func (c *MIClient) Attach(ctx context.Context, pid int) error {
+	if _, err := c.Exec(ctx, "-target-attach "+strconv.Itoa(pid)); err != nil {
+		return fmt.Errorf("attach to pid %d failed: %w", pid, err)
+	}
+	return nil
+}
+
+// Interrupt 暂停目标进程并等待 *stopped。
+func (c *MIClient) Interrupt(ctx context.Context) error {
+	if _, err := c.Exec(ctx, "-exec-interrupt"); err != nil {
+		return err
+	}
+	_, err := c.WaitStopped(ctx)
+	return err
+}
+
+// Detach 与目标进程分离，目标进程继续运行。
+func (c *MIClient) Detach(ctx context.Context) error {
+	_, err := c.Exec(ctx, "-target-detach")
+	return err
+}
+
+// Exit 请求调试器退出。调试器可能在回复 ^exit 之前就关闭输出，这种情况不视为错误。
+func (c *MIClient) Exit(ctx context.Context) error {
+	if _, err := c.Exec(ctx, "-gdb-exit"); err != nil && !errors.Is(err, ErrMIClosed) {
+		return err
+	}
+	return nil
+}
+
+// MIThread 是 -thread-info 返回的一个线程。
+type MIThread struct {
+	ID    int
+	Name  string
+	State string
+}
+
+// Threads 返回目标进程的所有线程。
+func (c *MIClient) Threads(ctx context.Context) ([]MIThread, error) {
+	record, err := c.Exec(ctx, "-thread-info")
+	if err != nil {
+		return nil, err
+	}
+	items := record.Results.Get("threads").Items
+	threads := make([]MIThread, 0, len(items))
+	for _, item := range items {
+		id, err := strconv.Atoi(item.String("id"))
+		if err != nil {
+			return nil, fmt.Errorf("-thread-info: invalid thread id %q", item.String("id"))
+		}
+		threads = append(threads, MIThread{ID: id, Name: item.String("name"), State: item.String("state")})
+	}
+	return threads, nil
+}
+
+// Frames 返回线程的调用栈。
+func (c *MIClient) Frames(ctx context.Context, threadID int) ([]StackFrame, error) {
+	record, err := c.Exec(ctx, fmt.Sprintf("-stack-list-frames --thread %d", threadID))
+	if err != nil {
+		return nil, err
+	}
+	items := record.Results.Get("stack").Items
+	frames := make([]StackFrame, 0, len(items))
+	for _, item := range items {
+		frames = append(frames, miStackFrame(item))
+	}
+	return frames, nil
+}
+
+// MIVariable 是 -stack-list-variables 返回的一个参数或局部变量。
+type MIVariable struct {
+	Name  string `json:"name"`
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value"`
+}
+
+// Variables 返回线程某一帧的参数与局部变量。
+func (c *MIClient) Variables(ctx context.Context, threadID, frameLevel int) ([]MIVariable, error) {
+	record, err := c.Exec(ctx, fmt.Sprintf("-stack-list-variables --thread %d --frame %d --all-values", threadID, frameLevel))
+	if err != nil {
+		return nil, err
+	}
+	items := record.Results.Get("variables").Items
+	variables := make([]MIVariable, 0, len(items))
+	for _, item := range items {
+		variables = append(variables, MIVariable{Name: item.String("name"), Type: item.String("type"), Value: item.String("value")})
+	}
+	return variables, nil
+}
+
+// miStackFrame 把 -stack-list-frames 中的 frame={...} 转换为 StackFrame，
+// Raw 使用与 CLI "bt all" 相同的格式，方便与以前的输出对照。
+func miStackFrame(item MIValue) StackFrame {
+	frame := StackFrame{Index: -1, Address: item.String("addr"), Module: item.String("module")}
+	if level, err := strconv.Atoi(item.String("level")); err == nil {
+		frame.Index = level
+	}
+	frame.File = item.String("fullname")
+	if frame.File == "" {
+		frame.File = item.String("file")
+	}
+	frame.Line, _ = strconv.Atoi(item.String("line"))
+	if raw := item.Get("clr-addr").String("il-offset"); raw != "" {
+		if offset, err := strconv.Atoi(raw); err == nil {
+			frame.ILOffset = &offset
+		}
+	}
+	function := item.String("func")
+	if !parseFrameSymbol(function, &frame) {
+		frame.Symbol = function
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d:", frame.Index)
+	if frame.Address != "" {
+		b.WriteString(" " + frame.Address)
+	}
+	if frame.Module != "" {
+		b.WriteString(" " + frame.Module + "`")
+	}
+	if function != "" {
+		b.WriteString(" " + function)
+	}
+	if frame.File != "" {
+		b.WriteString(" at " + frame.File)
+		if frame.Line > 0 {
+			b.WriteString(":" + strconv.Itoa(frame.Line))
+		}
+	}
+	frame.Raw = b.String()
+	return frame
+}
+
+// miDetachTimeout 是 detach 的超时时间。detach 使用独立的 context，请求超时之后仍然会尝试 detach。
+const miDetachTimeout = 3 * time.Second
+
+// WhileStopped 暂停目标进程后执行 fn，无论 fn 或暂停是否成功，最后都会 detach，保证目标进程继续运行。
+// 调用方负责在此之前完成 Attach。
+func (c *MIClient) WhileStopped(ctx context.Context, fn func() error) (err error) {
+	defer func() {
+		detachCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), miDetachTimeout)
+		defer cancel()
+		if detachErr := c.Detach(detachCtx); detachErr != nil && err == nil {
+			err = fmt.Errorf("detach failed: %w", detachErr)
+		}
+	}()
+	if err := c.Interrupt(ctx); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// CollectStackDump 暂停目标进程，读取所有线程的调用栈，然后 detach。
+func (c *MIClient) CollectStackDump(ctx context.Context) (StackDump, error) {
+	dump := StackDump{Threads: make([]StackThread, 0, 16)}
+	err := c.WhileStopped(ctx, func() error {
+		threads, err := c.Threads(ctx)
+		if err != nil {
+			return err
+		}
+		for _, thread := range threads {
+			frames, err := c.Frames(ctx, thread.ID)
+			if err != nil {
+				var miErr *MIError
+				if !errors.As(err, &miErr) {
+					return err
+				}
+				// 个别线程（例如正在退出的线程）取不到调用栈时，保留错误信息继续处理其他线程
+				dump.Threads = append(dump.Threads, miStackThread(thread, nil, []string{miErr.Msg}))
+				continue
+			}
+			dump.Threads = append(dump.Threads, miStackThread(thread, frames, nil))
+		}
+		return nil
+	})
+	return dump, err
+}
+
+func miStackThread(thread MIThread, frames []StackFrame, extra []string) StackThread {
+	if frames == nil {
+		frames = []StackFrame{}
+	}
+	header := fmt.Sprintf("Thread %d, name=%s", thread.ID, miQuote(thread.Name))
+	if thread.State != "" {
+		header += ", state=" + thread.State
+	}
+	return StackThread{ID: thread.ID, Name: thread.Name, State: thread.State, Header: header, Frames: frames, Extra: extra}
+}
@@ -0,0 +1,288 @@
+package debugadmin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMIPeer 模拟 netcoredbg --interpreter=mi：按命令名查表回复，并在回复之前混入异步记录与流记录。
+type fakeMIPeer struct {
+	t       *testing.T
+	out     *io.PipeWriter
+	replies map[string]string // 完整命令或命令名 -> 结果记录（不含序号），例如 "^done"
+	mu      sync.Mutex
+	seen    []string
+}
+
+func newFakeMIPeer(t *testing.T, replies map[string]string) (*fakeMIPeer, *MIClient) {
+	t.Helper()
+	commandsR, commandsW := io.Pipe()
+	outputR, outputW := io.Pipe()
+	peer := &fakeMIPeer{t: t, out: outputW, replies: replies}
+	go peer.serve(commandsR)
+	client := NewMIClient(outputR, commandsW)
+	t.Cleanup(func() {
+		_ = commandsW.Close()
+		_ = outputW.Close()
+	})
+	return peer, client
+}
+
+func (p *fakeMIPeer) serve(commands io.Reader) {
+	scanner := bufio.NewScanner(commands)
+	_, _ = io.WriteString(p.out, "=message,text=\"netcoredbg started\"\n(gdb)\n")
+	for scanner.Scan() {
+		line := scanner.Text()
+		digits := 0
+		for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+			digits++
+		}
+		token, command := line[:digits], line[digits:]
+		name, _, _ := strings.Cut(command, " ")
+		p.mu.Lock()
+		p.seen = append(p.seen, command)
+		p.mu.Unlock()
+		reply, ok := p.replies[command]
+		if !ok {
+			reply, ok = p.replies[name]
+		}
+		if !ok {
+			reply = fmt.Sprintf(`^error,msg="unknown command %s"`, name)
+		}
+		var b strings.Builder
+		b.WriteString("=library-loaded,id=\"{1}\",target-name=\"/app/My App.dll\"\n")
+		b.WriteString("~\"log from " + name + "\\n\"\n")
+		b.WriteString(token + reply + "\n(gdb)\n")
+		if name == "-exec-interrupt" {
+			b.WriteString("*stopped,reason=\"interrupted\",thread-id=\"1\"\n")
+		}
+		if _, err := io.WriteString(p.out, b.String()); err != nil {
+			return
+		}
+		if name == "-gdb-exit" {
+			_ = p.out.Close()
+			return
+		}
+	}
+}
+
+func (p *fakeMIPeer) commands() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.seen...)
+}
+
+var fakeStackReplies = map[string]string{
+	"-target-attach":  "^done",
+	"-exec-interrupt": "^done",
+	"-target-detach":  "^done",
+	"-gdb-exit":       "^exit",
+	"-thread-info":    `^done,threads=[{id="101",name="Main Thread",state="stopped"},{id="102",name=".NET ThreadPool Worker",state="stopped"}]`,
+	"-stack-list-frames": `^done,stack=[frame={level="0",file="Program.cs",fullname="/src/My App/Program.cs",line="12",col="9",clr-addr={module-id="{1}",method-token="0x06000001",il-offset="26",native-offset="80"},func="MyApp.Program.Main(string[])",addr="0x00007f4ef8c3a000"},` +
+		`frame={level="1",func="[Native Frames]"}]`,
+}
+
+func TestMIClientCollectStackDump(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	peer, client := newFakeMIPeer(t, fakeStackReplies)
+	if err := client.Attach(ctx, 42); err != nil {
+		t.Fatalf("Attach() error = %v", err)
+	}
+	dump, err := client.CollectStackDump(ctx)
+	if err != nil {
+		t.Fatalf("CollectStackDump() error = %v", err)
+	}
+	if len(dump.Threads) != 2 {
+		t.Fatalf("got %d threads, want 2", len(dump.Threads))
+	}
+	thread := dump.Threads[0]
+	if thread.ID != 101 || thread.Name != "Main Thread" || thread.State != "stopped" || len(thread.Frames) != 2 {
+		t.Fatalf("thread = %+v", thread)
+	}
+	frame := thread.Frames[0]
+	if frame.Namespace != "MyApp" || frame.Type != "Program" || frame.Method != "Main" || frame.Params != "string[]" ||
+		frame.File != "/src/My App/Program.cs" || frame.Line != 12 || frame.ILOffset == nil || *frame.ILOffset != 26 {
+		t.Errorf("frame = %+v", frame)
+	}
+	if reparsed := ParseStackFrame(frame.Raw); reparsed.Key() != frame.Key() {
+		t.Errorf("Raw %q parses to key %q, want %q", frame.Raw, reparsed.Key(), frame.Key())
+	}
+	if thread.Frames[1].Symbol != "[Native Frames]" {
+		t.Errorf("native frame = %+v", thread.Frames[1])
+	}
+	if err := client.Exit(ctx); err != nil {
+		t.Errorf("Exit() error = %v", err)
+	}
+	want := []string{"-target-attach 42", "-exec-interrupt", "-thread-info", "-stack-list-frames --thread 101", "-stack-list-frames --thread 102", "-target-detach", "-gdb-exit"}
+	if got := peer.commands(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if log := client.Log(); !strings.Contains(log, "log from -thread-info") {
+		t.Errorf("Log() = %q, want stream records", log)
+	}
+}
+
+func TestMIClientAttachError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, client := newFakeMIPeer(t, map[string]string{
+		"-target-attach": `^error,msg="Error: 0x80131c3c. Unable to attach to pid 42: \"access denied\""`,
+	})
+	err := client.Attach(ctx, 42)
+	var miErr *MIError
+	if !errors.As(err, &miErr) {
+		t.Fatalf("Attach() error = %v, want *MIError", err)
+	}
+	if !strings.Contains(err.Error(), `attach to pid 42 failed`) || !strings.Contains(miErr.Msg, `"access denied"`) {
+		t.Errorf("Attach() error = %q", err)
+	}
+}
+
+func TestMIClientDetachesWhenFramesFail(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	replies := make(map[string]string, len(fakeStackReplies))
+	for name, reply := range fakeStackReplies {
+		replies[name] = reply
+	}
+	replies["-stack-list-frames"] = `^error,msg="thread is not alive"`
+	peer, client := newFakeMIPeer(t, replies)
+	dump, err := client.CollectStackDump(ctx)
+	if err != nil {
+		t.Fatalf("CollectStackDump() error = %v", err)
+	}
+	if len(dump.Threads) != 2 || len(dump.Threads[0].Extra) != 1 {
+		t.Errorf("threads = %+v, want the frame error kept per thread", dump.Threads)
+	}
+	commands := peer.commands()
+	if commands[len(commands)-1] != "-target-detach" {
+		t.Errorf("last command = %q, want -target-detach", commands[len(commands)-1])
+	}
+}
+
+func TestMIClientClosedPeer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	outputR, outputW := io.Pipe()
+	client := NewMIClient(outputR, io.Discard)
+	_ = outputW.Close()
+	<-client.Done()
+	if _, err := client.Exec(ctx, "-thread-info"); !errors.Is(err, ErrMIClosed) {
+		t.Errorf("Exec() after close error = %v, want ErrMIClosed", err)
+	}
+}
+
+func TestMIClientCorrelatesOutOfOrderResults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	commandsR, commandsW := io.Pipe()
+	outputR, outputW := io.Pipe()
+	t.Cleanup(func() { _ = outputW.Close(); _ = commandsR.Close() })
+	client := NewMIClient(outputR, commandsW)
+	go func() {
+		// 读到两条命令之后倒序回复
+		scanner := bufio.NewScanner(commandsR)
+		tokens := make(map[string]string)
+		for len(tokens) < 2 && scanner.Scan() {
+			line := scanner.Text()
+			tokens[strings.TrimLeft(line, "0123456789")] = strings.TrimRight(line, "-abcdefghijklmnopqrstuvwxyz")
+		}
+		_, _ = io.WriteString(outputW, tokens["-second"]+`^done,value="2"`+"\n"+tokens["-first"]+`^done,value="1"`+"\n")
+	}()
+	var wg sync.WaitGroup
+	results := make(map[string]string)
+	var mu sync.Mutex
+	for _, command := range []string{"-first", "-second"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := client.Exec(ctx, command)
+			if err != nil {
+				t.Errorf("Exec(%s) error = %v", command, err)
+				return
+			}
+			mu.Lock()
+			results[command] = record.Results.String("value")
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if results["-first"] != "1" || results["-second"] != "2" {
+		t.Errorf("results = %v, want -first=1 -second=2", results)
+	}
+}
+
+func TestParseMIRecord(t *testing.T) {
+	record, ok, err := ParseMIRecord(`12^done,a="x\"y\\z\n",b={c="1",d=[]},e=["p","q"],f=[g={h="2"},g={h="3"}]`)
+	if err != nil || !ok {
+		t.Fatalf("ParseMIRecord() = %v, %v", ok, err)
+	}
+	if record.Token != 12 || record.Kind != MIResultRecord || record.Class != "done" {
+		t.Errorf("record = %+v", record)
+	}
+	results := record.Results
+	if got := results.String("a"); got != "x\"y\\z\n" {
+		t.Errorf("a = %q", got)
+	}
+	if got := results.Get("b").String("c"); got != "1" {
+		t.Errorf("b.c = %q", got)
+	}
+	if !results.Get("b").Has("d") || len(results.Get("b").Get("d").Items) != 0 {
+		t.Errorf("b.d = %+v, want empty list", results.Get("b").Get("d"))
+	}
+	if items := results.Get("e").Items; len(items) != 2 || items[1].Str != "q" {
+		t.Errorf("e = %+v", items)
+	}
+	if items := results.Get("f").Items; len(items) != 2 || items[1].String("h") != "3" {
+		t.Errorf("f = %+v", items)
+	}
+	if _, ok, _ := ParseMIRecord("(gdb)"); ok {
+		t.Error("ParseMIRecord((gdb)) ok = true, want false")
+	}
+	for _, bad := range []string{`^done,a=`, `^done,a="x`, `^done,a={b="1"`, `^done,=`, `?x`} {
+		if _, _, err := ParseMIRecord(bad); err == nil {
+			t.Errorf("ParseMIRecord(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestMIClientInspectFrame(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, client := newFakeMIPeer(t, map[string]string{
+		"-stack-list-variables":                          `^done,variables=[{name="request",value="{MyApp.Request}"},{name="count",value="3"}]`,
+		`-var-create --thread 7 --frame 1 - * "request"`: `^done,name="var1",numchild="2",value="{MyApp.Request}",type="MyApp.Request"`,
+		`-var-create --thread 7 --frame 1 - * "count"`:   `^done,name="var2",numchild="0",value="3",type="int"`,
+		`-var-list-children --all-values "var1" 0 50`: `^done,numchild="2",children=[child={name="var1.Id",exp="Id",numchild="0",type="string",value="\"<b>42</b>\""},` +
+			`child={name="var1.Headers",exp="Headers",numchild="5",type="Dictionary<string, string>",value="Count = 5"}],has_more="0"`,
+	})
+	nodes, err := client.InspectFrame(ctx, 7, 1, 1)
+	if err != nil {
+		t.Fatalf("InspectFrame() error = %v", err)
+	}
+	if len(nodes) != 2 || nodes[1].Type != "int" || nodes[1].Value != "3" {
+		t.Fatalf("nodes = %+v", nodes)
+	}
+	request := nodes[0]
+	if request.Type != "MyApp.Request" || len(request.Children) != 2 || request.Truncated {
+		t.Fatalf("request = %+v", request)
+	}
+	if headers := request.Children[1]; !headers.Truncated || len(headers.Children) != 0 {
+		t.Errorf("Headers = %+v, want truncated at depth 1", headers)
+	}
+	tree := renderVariableTree(nodes)
+	if strings.Contains(tree, "<b>") || !strings.Contains(tree, "&lt;b&gt;42&lt;/b&gt;") {
+		t.Errorf("renderVariableTree() did not escape values: %s", tree)
+	}
+	if !strings.Contains(tree, "<details>") {
+		t.Errorf("renderVariableTree() = %s, want collapsible nodes", tree)
+	}
+}
@@ -0,0 +1,280 @@
+package debugadmin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MI 输出记录的类型，对应 GDB/MI 输出行的第一个字符。
+const (
+	MIResultRecord  = '^' // 命令的结果：^done / ^running / ^error / ^exit
+	MIExecAsync     = '*' // 执行状态变化，例如 *stopped
+	MIStatusAsync   = '+'
+	MINotifyAsync   = '=' // 通知，例如 =thread-created、=library-loaded
+	MIConsoleStream = '~'
+	MITargetStream  = '@'
+	MILogStream     = '&'
+)
+
+// MIRecord 是一行 MI 输出。流记录（~ @ &）的内容保存在 Text 中。
+type MIRecord struct {
+	Token   int  // 命令前缀的序号，没有序号时为 -1
+	Kind    byte // MIResultRecord、MIExecAsync 等
+	Class   string
+	Results MIValue // 记录中 "," 之后的 name=value 列表，作为一个 tuple 保存
+	Text    string
+}
+
+// MIValue 是 MI 的值：c-string、tuple {a=..,b=..} 或 list [..]。
+// list 中的 name=value 只保留 value，例如 stack=[frame={..},frame={..}]。
+type MIValue struct {
+	Str    string
+	Fields []MIResult
+	Items  []MIValue
+}
+
+// MIResult 是 tuple 中的一个 name=value。
+type MIResult struct {
+	Name  string
+	Value MIValue
+}
+
+// Get 返回 tuple 中第一个名为 name 的值，不存在时返回零值。
+func (v MIValue) Get(name string) MIValue {
+	for _, field := range v.Fields {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return MIValue{}
+}
+
+// String 返回 tuple 中名为 name 的字符串值。
+func (v MIValue) String(name string) string {
+	return v.Get(name).Str
+}
+
+// Has 判断 tuple 中是否存在名为 name 的值。
+func (v MIValue) Has(name string) bool {
+	for _, field := range v.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMIRecord 解析一行 MI 输出。"(gdb)" 提示符返回 ok=false。
+func ParseMIRecord(line string) (MIRecord, bool, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed == "(gdb)" {
+		return MIRecord{}, false, nil
+	}
+	record := MIRecord{Token: -1}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		token, err := strconv.Atoi(line[:digits])
+		if err != nil {
+			return MIRecord{}, false, fmt.Errorf("invalid MI token in %q: %w", line, err)
+		}
+		record.Token = token
+	}
+	rest := line[digits:]
+	if rest == "" {
+		return MIRecord{}, false, fmt.Errorf("invalid MI record %q", line)
+	}
+	record.Kind = rest[0]
+	rest = rest[1:]
+	switch record.Kind {
+	case MIConsoleStream, MITargetStream, MILogStream:
+		p := miParser{input: rest}
+		text, err := p.parseCString()
+		if err != nil {
+			return MIRecord{}, false, fmt.Errorf("invalid MI stream record %q: %w", line, err)
+		}
+		record.Text = text
+		return record, true, nil
+	case MIResultRecord, MIExecAsync, MIStatusAsync, MINotifyAsync:
+	default:
+		return MIRecord{}, false, fmt.Errorf("unknown MI record %q", line)
+	}
+	class, results, _ := strings.Cut(rest, ",")
+	record.Class = class
+	if results != "" {
+		p := miParser{input: results}
+		fields, err := p.parseResults(0)
+		if err != nil {
+			return MIRecord{}, false, fmt.Errorf("invalid MI record %q: %w", line, err)
+		}
+		if p.pos != len(p.input) {
+			return MIRecord{}, false, fmt.Errorf("invalid MI record %q: trailing data at offset %d", line, p.pos)
+		}
+		record.Results = MIValue{Fields: fields}
+	}
+	return record, true, nil
+}
+
+// miMaxDepth 限制嵌套层数，避免异常输入导致递归过深。
+const miMaxDepth = 64
+
+type miParser struct {
+	input string
+	pos   int
+}
+
+var errMIUnexpectedEnd = errors.New("unexpected end of MI record")
+
+func (p *miParser) parseResults(depth int) ([]MIResult, error) {
+	var results []MIResult
+	for {
+		result, err := p.parseResult(depth)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+		if p.pos >= len(p.input) || p.input[p.pos] != ',' {
+			return results, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *miParser) parseResult(depth int) (MIResult, error) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '=' {
+		switch p.input[p.pos] {
+		case ',', '{', '}', '[', ']', '"':
+			return MIResult{}, fmt.Errorf("expected name=value at offset %d", start)
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return MIResult{}, errMIUnexpectedEnd
+	}
+	name := p.input[start:p.pos]
+	p.pos++
+	value, err := p.parseValue(depth)
+	if err != nil {
+		return MIResult{}, err
+	}
+	return MIResult{Name: name, Value: value}, nil
+}
+
+func (p *miParser) parseValue(depth int) (MIValue, error) {
+	if depth > miMaxDepth {
+		return MIValue{}, errors.New("MI value nested too deeply")
+	}
+	if p.pos >= len(p.input) {
+		return MIValue{}, errMIUnexpectedEnd
+	}
+	switch p.input[p.pos] {
+	case '"':
+		text, err := p.parseCString()
+		return MIValue{Str: text}, err
+	case '{':
+		p.pos++
+		if p.pos < len(p.input) && p.input[p.pos] == '}' {
+			p.pos++
+			return MIValue{}, nil
+		}
+		fields, err := p.parseResults(depth + 1)
+		if err != nil {
+			return MIValue{}, err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != '}' {
+			return MIValue{}, fmt.Errorf("expected '}' at offset %d", p.pos)
+		}
+		p.pos++
+		return MIValue{Fields: fields}, nil
+	case '[':
+		p.pos++
+		items := []MIValue{}
+		for p.pos < len(p.input) && p.input[p.pos] != ']' {
+			var item MIValue
+			var err error
+			if c := p.input[p.pos]; c == '"' || c == '{' || c == '[' {
+				item, err = p.parseValue(depth + 1)
+			} else {
+				var result MIResult
+				result, err = p.parseResult(depth + 1)
+				item = result.Value
+			}
+			if err != nil {
+				return MIValue{}, err
+			}
+			items = append(items, item)
+			if p.pos < len(p.input) && p.input[p.pos] == ',' {
+				p.pos++
+			}
+		}
+		if p.pos >= len(p.input) {
+			return MIValue{}, errMIUnexpectedEnd
+		}
+		p.pos++
+		return MIValue{Items: items}, nil
+	default:
+		return MIValue{}, fmt.Errorf("unexpected %q at offset %d", p.input[p.pos], p.pos)
+	}
+}
+
+// parseCString 解析带引号、使用 C 转义的字符串。
+func (p *miParser) parseCString() (string, error) {
+	if p.pos >= len(p.input) || p.input[p.pos] != '"' {
+		return "", fmt.Errorf("expected '\"' at offset %d", p.pos)
+	}
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.input) {
+				return "", errMIUnexpectedEnd
+			}
+			escaped := p.input[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			default:
+				b.WriteByte(escaped)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errMIUnexpectedEnd
+}
+
+// miQuote 把参数转换为 MI 命令中的 c-string。
+func miQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
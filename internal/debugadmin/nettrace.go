@@ -0,0 +1,608 @@
+package debugadmin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// nettrace 是 EventPipe 的输出格式（dotnet-trace 写出的 .nettrace 文件、诊断端口 CollectTracing 返回的流），
+// 格式说明见 https://github.com/microsoft/perfview/blob/main/src/TraceEvent/EventPipe/EventPipeFormat.md
+// 这里只实现 .NET 5 以后的运行时写出的 V4/V5 格式：事件头总是压缩的。
+const (
+	netTraceMagic             = "Nettrace"
+	netTraceSerializationHead = "!FastSerialization.1"
+
+	netTraceTagNullReference      = 1
+	netTraceTagBeginPrivateObject = 5
+	netTraceTagEndObject          = 6
+)
+
+// 压缩事件头中的标志位，表示对应字段是否出现在事件头中（否则沿用上一个事件的值）。
+const (
+	netTraceFlagMetadataID          = 1 << 0
+	netTraceFlagCaptureThreadAndSeq = 1 << 1
+	netTraceFlagThreadID            = 1 << 2
+	netTraceFlagStackID             = 1 << 3
+	netTraceFlagActivityID          = 1 << 4
+	netTraceFlagRelatedActivityID   = 1 << 5
+	netTraceFlagDataLength          = 1 << 7
+)
+
+// NetTraceMetadata 描述一类事件，由 MetadataBlock 中的事件定义，事件头中的 metadata id 引用它。
+type NetTraceMetadata struct {
+	ID       uint32
+	Provider string
+	EventID  uint32
+	Name     string // 运行时内置 provider 的事件名为空，只能按 EventID 识别
+	Keywords uint64
+	Version  uint32
+	Level    uint32
+	Fields   []NetTraceField // EventSource 事件自带的字段定义，用于解码 payload
+}
+
+// NetTraceField 是事件 payload 中的一个字段，TypeCode 与 .NET 的 System.TypeCode 相同。
+type NetTraceField struct {
+	Name     string
+	TypeCode uint32
+	Fields   []NetTraceField // TypeCode 为 Object 时的嵌套字段
+}
+
+// NetTraceValue 是解码后的一个字段。
+type NetTraceValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NetTraceEvent 是一个事件。Payload 与 Stack 只在回调期间有效，需要保留时调用方自行复制。
+type NetTraceEvent struct {
+	Metadata  *NetTraceMetadata
+	ThreadID  uint64
+	Timestamp int64    // QPC 计数，用 NetTraceReader.Time 换算为时间
+	Stack     []uint64 // 托管调用栈，栈顶在前
+	Payload   []byte
+}
+
+// NetTraceReader 顺序读取一个 nettrace 流，可以用于仍在写入的文件或诊断端口返回的流。
+type NetTraceReader struct {
+	r      *bufio.Reader
+	offset int64 // 从流开头起已经读取的字节数，block 内容按它对齐到 4 字节
+
+	SyncTime     time.Time
+	SyncQPC      int64
+	QPCFrequency int64
+	PointerSize  int
+	ProcessID    int
+
+	metadata map[uint32]*NetTraceMetadata
+	stacks   map[uint32][]uint64
+}
+
+// NewNetTraceReader 读取流的开头与 Trace 对象（其中有时钟与指针宽度）。
+func NewNetTraceReader(r io.Reader) (*NetTraceReader, error) {
+	reader := &NetTraceReader{
+		r:        bufio.NewReaderSize(r, 64*1024),
+		metadata: make(map[uint32]*NetTraceMetadata),
+		stacks:   make(map[uint32][]uint64),
+	}
+	magic, err := reader.read(len(netTraceMagic))
+	if err != nil {
+		return nil, fmt.Errorf("read nettrace magic: %w", err)
+	}
+	if string(magic) != netTraceMagic {
+		return nil, errors.New("not a nettrace stream")
+	}
+	head, err := reader.readLengthPrefixed()
+	if err != nil || string(head) != netTraceSerializationHead {
+		return nil, errors.New("unsupported nettrace serialization header")
+	}
+	name, err := reader.readObjectBegin()
+	if err != nil {
+		return nil, err
+	}
+	if name != "Trace" {
+		return nil, fmt.Errorf("unexpected first nettrace object %q", name)
+	}
+	trace, err := reader.read(48)
+	if err != nil {
+		return nil, fmt.Errorf("read nettrace trace object: %w", err)
+	}
+	reader.SyncTime = time.Date(
+		int(binary.LittleEndian.Uint16(trace[0:])), time.Month(binary.LittleEndian.Uint16(trace[2:])),
+		int(binary.LittleEndian.Uint16(trace[6:])), int(binary.LittleEndian.Uint16(trace[8:])),
+		int(binary.LittleEndian.Uint16(trace[10:])), int(binary.LittleEndian.Uint16(trace[12:])),
+		int(binary.LittleEndian.Uint16(trace[14:]))*int(time.Millisecond), time.UTC)
+	reader.SyncQPC = int64(binary.LittleEndian.Uint64(trace[16:]))
+	reader.QPCFrequency = int64(binary.LittleEndian.Uint64(trace[24:]))
+	reader.PointerSize = int(binary.LittleEndian.Uint32(trace[32:]))
+	reader.ProcessID = int(binary.LittleEndian.Uint32(trace[36:]))
+	if reader.PointerSize != 4 && reader.PointerSize != 8 {
+		return nil, fmt.Errorf("unsupported nettrace pointer size %d", reader.PointerSize)
+	}
+	if err := reader.expectTag(netTraceTagEndObject); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// Time 把事件的 QPC 时间戳换算为墙上时间。
+func (r *NetTraceReader) Time(timestamp int64) time.Time {
+	if r.QPCFrequency <= 0 {
+		return r.SyncTime
+	}
+	delta := float64(timestamp-r.SyncQPC) / float64(r.QPCFrequency)
+	return r.SyncTime.Add(time.Duration(delta * float64(time.Second)))
+}
+
+// ReadEvents 读取后续所有 block，对每个事件调用 handle，直到流结束或 handle 返回错误。
+// 正常结束时返回 nil；流在 block 中间被截断时返回 io.ErrUnexpectedEOF。
+func (r *NetTraceReader) ReadEvents(handle func(*NetTraceEvent) error) error {
+	for {
+		tag, err := r.readByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if tag == netTraceTagNullReference {
+			return nil
+		}
+		if tag != netTraceTagBeginPrivateObject {
+			return fmt.Errorf("unexpected nettrace tag %d at offset %d", tag, r.offset-1)
+		}
+		name, err := r.readTypeDescriptor()
+		if err != nil {
+			return err
+		}
+		content, err := r.readBlock()
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "MetadataBlock":
+			err = r.parseEventBlock(content, r.defineMetadata)
+		case "EventBlock":
+			err = r.parseEventBlock(content, handle)
+		case "StackBlock":
+			err = r.parseStackBlock(content)
+		}
+		// SPBlock 之类的其他 block 不需要
+		if err != nil {
+			return err
+		}
+		if err := r.expectTag(netTraceTagEndObject); err != nil {
+			return err
+		}
+	}
+}
+
+// parseEventBlock 解析 EventBlock 与 MetadataBlock。压缩事件头中省略的字段沿用同一个 block 中上一个事件的值。
+func (r *NetTraceReader) parseEventBlock(content []byte, handle func(*NetTraceEvent) error) error {
+	if len(content) < 20 {
+		return errors.New("nettrace event block is too short")
+	}
+	headerSize := int(binary.LittleEndian.Uint16(content[0:]))
+	flags := binary.LittleEndian.Uint16(content[2:])
+	if flags&1 == 0 {
+		return errors.New("uncompressed nettrace event headers are not supported")
+	}
+	if headerSize < 20 || headerSize > len(content) {
+		return fmt.Errorf("invalid nettrace event block header size %d", headerSize)
+	}
+	data := netTraceCursor{data: content[headerSize:]}
+	var metadataID, stackID uint32
+	var threadID uint64
+	var timestamp int64
+	var payloadSize uint32
+	for data.remaining() > 0 {
+		eventFlags := data.byte()
+		if eventFlags&netTraceFlagMetadataID != 0 {
+			metadataID = data.varUint32()
+		}
+		if eventFlags&netTraceFlagCaptureThreadAndSeq != 0 {
+			data.varUint32() // sequence number delta
+			data.varUint64() // capture thread id
+			data.varUint32() // processor number
+		}
+		if eventFlags&netTraceFlagThreadID != 0 {
+			threadID = data.varUint64()
+		}
+		if eventFlags&netTraceFlagStackID != 0 {
+			stackID = data.varUint32()
+		}
+		timestamp += int64(data.varUint64())
+		if eventFlags&netTraceFlagActivityID != 0 {
+			data.bytes(16)
+		}
+		if eventFlags&netTraceFlagRelatedActivityID != 0 {
+			data.bytes(16)
+		}
+		if eventFlags&netTraceFlagDataLength != 0 {
+			payloadSize = data.varUint32()
+		}
+		payload := data.bytes(int(payloadSize))
+		if data.err != nil {
+			return fmt.Errorf("parse nettrace event: %w", data.err)
+		}
+		event := &NetTraceEvent{
+			Metadata:  r.metadata[metadataID],
+			ThreadID:  threadID,
+			Timestamp: timestamp,
+			Stack:     r.stacks[stackID],
+			Payload:   payload,
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defineMetadata 处理 MetadataBlock 中的一个事件，其 payload 定义一个 metadata id。
+func (r *NetTraceReader) defineMetadata(event *NetTraceEvent) error {
+	data := netTraceCursor{data: event.Payload}
+	metadata := &NetTraceMetadata{ID: data.uint32()}
+	metadata.Provider = data.utf16String()
+	metadata.EventID = data.uint32()
+	metadata.Name = data.utf16String()
+	metadata.Keywords = data.uint64()
+	metadata.Version = data.uint32()
+	metadata.Level = data.uint32()
+	if data.err != nil {
+		return fmt.Errorf("parse nettrace metadata: %w", data.err)
+	}
+	// 之后是 V1 格式的字段定义。运行时内置事件没有字段定义；
+	// 含数组字段的事件只在 V2 扩展中描述，V1 中字段数为 0，这里不解析 V2 扩展，这些事件的 payload 不解码。
+	if data.remaining() >= 4 {
+		fields := parseNetTraceFields(&data, data.uint32(), 0)
+		if data.err == nil {
+			metadata.Fields = fields
+		}
+	}
+	r.metadata[metadata.ID] = metadata
+	return nil
+}
+
+func parseNetTraceFields(data *netTraceCursor, count uint32, depth int) []NetTraceField {
+	if count > 1024 || depth > 8 {
+		data.err = errors.New("nettrace field metadata is too large")
+		return nil
+	}
+	fields := make([]NetTraceField, 0, count)
+	for i := uint32(0); i < count && data.err == nil; i++ {
+		field := NetTraceField{TypeCode: data.uint32()}
+		if field.TypeCode == netTraceTypeObject {
+			field.Fields = parseNetTraceFields(data, data.uint32(), depth+1)
+		}
+		field.Name = data.utf16String()
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// parseStackBlock 解析 StackBlock：从 firstID 开始编号的一组调用栈，每个是 size 字节的地址数组。
+func (r *NetTraceReader) parseStackBlock(content []byte) error {
+	data := netTraceCursor{data: content}
+	firstID := data.uint32()
+	count := data.uint32()
+	for i := uint32(0); i < count && data.err == nil; i++ {
+		raw := data.bytes(int(data.uint32()))
+		stack := make([]uint64, 0, len(raw)/r.PointerSize)
+		for offset := 0; offset+r.PointerSize <= len(raw); offset += r.PointerSize {
+			if r.PointerSize == 8 {
+				stack = append(stack, binary.LittleEndian.Uint64(raw[offset:]))
+			} else {
+				stack = append(stack, uint64(binary.LittleEndian.Uint32(raw[offset:])))
+			}
+		}
+		// 运行时在 sequence point 之后可能重新编号，后定义的覆盖先定义的
+		r.stacks[firstID+i] = stack
+	}
+	if data.err != nil {
+		return fmt.Errorf("parse nettrace stack block: %w", data.err)
+	}
+	return nil
+}
+
+// readObjectBegin 读取对象开头的 BeginPrivateObject 标记与类型描述，返回类型名。
+func (r *NetTraceReader) readObjectBegin() (string, error) {
+	if err := r.expectTag(netTraceTagBeginPrivateObject); err != nil {
+		return "", err
+	}
+	return r.readTypeDescriptor()
+}
+
+// readTypeDescriptor 读取类型描述：BeginPrivateObject, NullReference, version, minReaderVersion, name, EndObject。
+func (r *NetTraceReader) readTypeDescriptor() (string, error) {
+	if err := r.expectTag(netTraceTagBeginPrivateObject); err != nil {
+		return "", err
+	}
+	if err := r.expectTag(netTraceTagNullReference); err != nil {
+		return "", err
+	}
+	if _, err := r.read(8); err != nil {
+		return "", err
+	}
+	name, err := r.readLengthPrefixed()
+	if err != nil {
+		return "", err
+	}
+	if err := r.expectTag(netTraceTagEndObject); err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// readBlock 读取 block 的长度、对齐用的填充与内容。
+func (r *NetTraceReader) readBlock() ([]byte, error) {
+	sizeBytes, err := r.read(4)
+	if err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(sizeBytes)
+	if padding := (4 - r.offset%4) % 4; padding > 0 {
+		if _, err := r.read(int(padding)); err != nil {
+			return nil, err
+		}
+	}
+	if size > 64*1024*1024 {
+		return nil, fmt.Errorf("nettrace block of %d bytes is too large", size)
+	}
+	content := make([]byte, size)
+	if err := r.readFull(content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (r *NetTraceReader) readLengthPrefixed() ([]byte, error) {
+	lengthBytes, err := r.read(4)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(lengthBytes)
+	if length > 1024 {
+		return nil, fmt.Errorf("nettrace string of %d bytes is too long", length)
+	}
+	return r.read(int(length))
+}
+
+func (r *NetTraceReader) expectTag(want byte) error {
+	tag, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	if tag != want {
+		return fmt.Errorf("expected nettrace tag %d at offset %d, got %d", want, r.offset-1, tag)
+	}
+	return nil
+}
+
+func (r *NetTraceReader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+	return b, err
+}
+
+func (r *NetTraceReader) read(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if err := r.readFull(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (r *NetTraceReader) readFull(buf []byte) error {
+	n, err := io.ReadFull(r.r, buf)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// System.TypeCode 中事件字段可能用到的类型。
+const (
+	netTraceTypeObject   = 1
+	netTraceTypeBoolean  = 3
+	netTraceTypeChar     = 4
+	netTraceTypeSByte    = 5
+	netTraceTypeByte     = 6
+	netTraceTypeInt16    = 7
+	netTraceTypeUInt16   = 8
+	netTraceTypeInt32    = 9
+	netTraceTypeUInt32   = 10
+	netTraceTypeInt64    = 11
+	netTraceTypeUInt64   = 12
+	netTraceTypeSingle   = 13
+	netTraceTypeDouble   = 14
+	netTraceTypeDecimal  = 15
+	netTraceTypeDateTime = 16
+	netTraceTypeGUID     = 17
+	netTraceTypeString   = 18
+)
+
+// DecodePayload 按字段定义解码 payload。没有字段定义或 payload 与定义不符时返回 false。
+func (m *NetTraceMetadata) DecodePayload(payload []byte) ([]NetTraceValue, bool) {
+	if m == nil || len(m.Fields) == 0 {
+		return nil, false
+	}
+	data := netTraceCursor{data: payload}
+	values := decodeNetTraceFields(&data, m.Fields)
+	if data.err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+func decodeNetTraceFields(data *netTraceCursor, fields []NetTraceField) []NetTraceValue {
+	values := make([]NetTraceValue, 0, len(fields))
+	for _, field := range fields {
+		var value string
+		switch field.TypeCode {
+		case netTraceTypeObject:
+			nested := decodeNetTraceFields(data, field.Fields)
+			parts := make([]string, 0, len(nested))
+			for _, item := range nested {
+				parts = append(parts, item.Name+"="+item.Value)
+			}
+			value = "{" + strings.Join(parts, ", ") + "}"
+		case netTraceTypeBoolean:
+			value = strconv.FormatBool(data.uint32() != 0)
+		case netTraceTypeChar:
+			value = string(rune(data.uint16()))
+		case netTraceTypeSByte:
+			value = strconv.Itoa(int(int8(data.byte())))
+		case netTraceTypeByte:
+			value = strconv.Itoa(int(data.byte()))
+		case netTraceTypeInt16:
+			value = strconv.Itoa(int(int16(data.uint16())))
+		case netTraceTypeUInt16:
+			value = strconv.Itoa(int(data.uint16()))
+		case netTraceTypeInt32:
+			value = strconv.Itoa(int(int32(data.uint32())))
+		case netTraceTypeUInt32:
+			value = strconv.FormatUint(uint64(data.uint32()), 10)
+		case netTraceTypeInt64:
+			value = strconv.FormatInt(int64(data.uint64()), 10)
+		case netTraceTypeUInt64:
+			value = strconv.FormatUint(data.uint64(), 10)
+		case netTraceTypeSingle:
+			value = strconv.FormatFloat(float64(math.Float32frombits(data.uint32())), 'g', -1, 32)
+		case netTraceTypeDouble:
+			value = strconv.FormatFloat(math.Float64frombits(data.uint64()), 'g', -1, 64)
+		case netTraceTypeDecimal:
+			value = fmt.Sprintf("%x", data.bytes(16))
+		case netTraceTypeDateTime:
+			// EventSource 把 DateTime 写为 FILETIME：从 1601-01-01 开始的 100ns 数
+			const fileTimeToUnix = 116444736000000000
+			ticks := int64(data.uint64()) - fileTimeToUnix
+			value = time.Unix(0, ticks*100).UTC().Format(time.RFC3339Nano)
+		case netTraceTypeGUID:
+			value = formatNetTraceGUID(data.bytes(16))
+		case netTraceTypeString:
+			value = data.utf16String()
+		default:
+			data.err = fmt.Errorf("unsupported field type %d", field.TypeCode)
+		}
+		if data.err != nil {
+			return nil
+		}
+		values = append(values, NetTraceValue{Name: field.Name, Value: value})
+	}
+	return values
+}
+
+// formatNetTraceGUID 按 .NET Guid 的内存布局（前三段小端）格式化。
+func formatNetTraceGUID(b []byte) string {
+	if len(b) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x", binary.LittleEndian.Uint32(b[0:]), binary.LittleEndian.Uint16(b[4:]),
+		binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// netTraceCursor 顺序读取 block 内容或事件 payload，越界时记录错误并返回零值。
+type netTraceCursor struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (c *netTraceCursor) remaining() int {
+	if c.err != nil {
+		return 0
+	}
+	return len(c.data) - c.pos
+}
+
+func (c *netTraceCursor) bytes(n int) []byte {
+	if c.err != nil {
+		return nil
+	}
+	if n < 0 || c.pos+n > len(c.data) {
+		c.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	result := c.data[c.pos : c.pos+n]
+	c.pos += n
+	return result
+}
+
+func (c *netTraceCursor) byte() byte {
+	if b := c.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (c *netTraceCursor) uint16() uint16 {
+	if b := c.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (c *netTraceCursor) uint32() uint32 {
+	if b := c.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (c *netTraceCursor) uint64() uint64 {
+	if b := c.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (c *netTraceCursor) varUint64() uint64 {
+	var result uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b := c.byte()
+		if c.err != nil {
+			return 0
+		}
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result
+		}
+	}
+	c.err = errors.New("varint overflows 64 bits")
+	return 0
+}
+
+func (c *netTraceCursor) varUint32() uint32 {
+	return uint32(c.varUint64())
+}
+
+// utf16String 读取以 0 结尾的 UTF-16LE 字符串，这是事件 payload 中字符串的编码。
+func (c *netTraceCursor) utf16String() string {
+	if c.err != nil {
+		return ""
+	}
+	var units []uint16
+	for {
+		if c.pos+2 > len(c.data) {
+			c.err = io.ErrUnexpectedEOF
+			return ""
+		}
+		unit := binary.LittleEndian.Uint16(c.data[c.pos:])
+		c.pos += 2
+		if unit == 0 {
+			return string(utf16.Decode(units))
+		}
+		units = append(units, unit)
+	}
+}
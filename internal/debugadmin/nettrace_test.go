@@ -0,0 +1,197 @@
+package debugadmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// netTraceWriter 按 V5 格式写出测试用的 nettrace 流。
+type netTraceWriter struct {
+	buf bytes.Buffer
+}
+
+type netTraceTestEvent struct {
+	MetadataID uint32
+	ThreadID   uint64
+	StackID    uint32
+	Timestamp  int64
+	Payload    []byte
+}
+
+func newNetTraceWriter(pid int, syncTime time.Time) *netTraceWriter {
+	w := &netTraceWriter{}
+	w.buf.WriteString(netTraceMagic)
+	w.lengthPrefixed(netTraceSerializationHead)
+	w.buf.WriteByte(netTraceTagBeginPrivateObject)
+	w.typeDescriptor("Trace")
+	for _, value := range []int{syncTime.Year(), int(syncTime.Month()), int(syncTime.Weekday()), syncTime.Day(),
+		syncTime.Hour(), syncTime.Minute(), syncTime.Second(), syncTime.Nanosecond() / int(time.Millisecond)} {
+		w.put(uint16(value))
+	}
+	w.put(int64(1000))    // sync QPC
+	w.put(int64(1000000)) // QPC frequency
+	w.put(int32(8))
+	w.put(int32(pid))
+	w.put(int32(4))
+	w.put(int32(1000))
+	w.buf.WriteByte(netTraceTagEndObject)
+	return w
+}
+
+func (w *netTraceWriter) put(value any) {
+	_ = binary.Write(&w.buf, binary.LittleEndian, value)
+}
+
+func (w *netTraceWriter) lengthPrefixed(s string) {
+	w.put(int32(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *netTraceWriter) typeDescriptor(name string) {
+	w.buf.WriteByte(netTraceTagBeginPrivateObject)
+	w.buf.WriteByte(netTraceTagNullReference)
+	w.put(int32(2))
+	w.put(int32(2))
+	w.lengthPrefixed(name)
+	w.buf.WriteByte(netTraceTagEndObject)
+}
+
+func (w *netTraceWriter) block(name string, content []byte) {
+	w.buf.WriteByte(netTraceTagBeginPrivateObject)
+	w.typeDescriptor(name)
+	w.put(int32(len(content)))
+	for w.buf.Len()%4 != 0 {
+		w.buf.WriteByte(0)
+	}
+	w.buf.Write(content)
+	w.buf.WriteByte(netTraceTagEndObject)
+}
+
+func (w *netTraceWriter) events(name string, events []netTraceTestEvent) {
+	var content bytes.Buffer
+	_ = binary.Write(&content, binary.LittleEndian, uint16(20))
+	_ = binary.Write(&content, binary.LittleEndian, uint16(1))
+	content.Write(make([]byte, 16))
+	var timestamp int64
+	for _, event := range events {
+		content.WriteByte(netTraceFlagMetadataID | netTraceFlagThreadID | netTraceFlagStackID | netTraceFlagDataLength)
+		content.Write(binary.AppendUvarint(nil, uint64(event.MetadataID)))
+		content.Write(binary.AppendUvarint(nil, event.ThreadID))
+		content.Write(binary.AppendUvarint(nil, uint64(event.StackID)))
+		content.Write(binary.AppendUvarint(nil, uint64(event.Timestamp-timestamp)))
+		timestamp = event.Timestamp
+		content.Write(binary.AppendUvarint(nil, uint64(len(event.Payload))))
+		content.Write(event.Payload)
+	}
+	w.block(name, content.Bytes())
+}
+
+func (w *netTraceWriter) metadata(id uint32, provider string, eventID uint32, name string, version uint32, fields ...NetTraceField) {
+	payload := &netTracePayload{}
+	payload.put(id)
+	payload.str(provider)
+	payload.put(eventID)
+	payload.str(name)
+	payload.put(uint64(0))
+	payload.put(version)
+	payload.put(uint32(5))
+	payload.put(uint32(len(fields)))
+	for _, field := range fields {
+		payload.put(field.TypeCode).str(field.Name)
+	}
+	w.events("MetadataBlock", []netTraceTestEvent{{Payload: payload.Bytes()}})
+}
+
+func (w *netTraceWriter) stacks(firstID uint32, stacks ...[]uint64) {
+	var content bytes.Buffer
+	_ = binary.Write(&content, binary.LittleEndian, firstID)
+	_ = binary.Write(&content, binary.LittleEndian, uint32(len(stacks)))
+	for _, stack := range stacks {
+		_ = binary.Write(&content, binary.LittleEndian, uint32(len(stack)*8))
+		_ = binary.Write(&content, binary.LittleEndian, stack)
+	}
+	w.block("StackBlock", content.Bytes())
+}
+
+func (w *netTraceWriter) Bytes() []byte {
+	return append(w.buf.Bytes(), netTraceTagNullReference)
+}
+
+// netTracePayload 拼接事件 payload，字符串为以 0 结尾的 UTF-16LE。
+type netTracePayload struct {
+	bytes.Buffer
+}
+
+func (p *netTracePayload) put(value any) *netTracePayload {
+	_ = binary.Write(&p.Buffer, binary.LittleEndian, value)
+	return p
+}
+
+func (p *netTracePayload) str(s string) *netTracePayload {
+	return p.put(append(utf16.Encode([]rune(s)), 0))
+}
+
+func TestNetTraceReader(t *testing.T) {
+	syncTime := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	w := newNetTraceWriter(4321, syncTime)
+	w.metadata(1, "MyCompany-MyApp", 7, "RequestStart", 0)
+	w.stacks(1, []uint64{0x1000, 0x2000})
+	w.events("EventBlock", []netTraceTestEvent{
+		{MetadataID: 1, ThreadID: 11, StackID: 1, Timestamp: 3000, Payload: []byte("first")},
+		{MetadataID: 1, ThreadID: 12, Timestamp: 1001000, Payload: []byte("second")},
+	})
+	w.block("SPBlock", make([]byte, 12))
+
+	reader, err := NewNetTraceReader(bytes.NewReader(w.Bytes()))
+	if err != nil {
+		t.Fatalf("NewNetTraceReader() error = %v", err)
+	}
+	if reader.ProcessID != 4321 || reader.PointerSize != 8 || !reader.SyncTime.Equal(syncTime) {
+		t.Errorf("trace object = pid %d, pointer %d, time %v", reader.ProcessID, reader.PointerSize, reader.SyncTime)
+	}
+	var events []NetTraceEvent
+	err = reader.ReadEvents(func(event *NetTraceEvent) error {
+		events = append(events, *event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	first, second := events[0], events[1]
+	if first.Metadata == nil || first.Metadata.Provider != "MyCompany-MyApp" || first.Metadata.Name != "RequestStart" || first.Metadata.EventID != 7 {
+		t.Errorf("metadata = %+v", first.Metadata)
+	}
+	if first.ThreadID != 11 || string(first.Payload) != "first" || len(first.Stack) != 2 || first.Stack[1] != 0x2000 {
+		t.Errorf("first event = %+v", first)
+	}
+	if second.ThreadID != 12 || second.Stack != nil || string(second.Payload) != "second" {
+		t.Errorf("second event = %+v", second)
+	}
+	if got := reader.Time(second.Timestamp); !got.Equal(syncTime.Add(time.Second)) {
+		t.Errorf("Time() = %v, want one second after the sync time", got)
+	}
+}
+
+func TestNetTraceReaderTruncated(t *testing.T) {
+	w := newNetTraceWriter(1, time.Now())
+	w.metadata(1, "MyCompany-MyApp", 7, "RequestStart", 0)
+	data := w.Bytes()
+	reader, err := NewNetTraceReader(bytes.NewReader(data[:len(data)-10]))
+	if err != nil {
+		t.Fatalf("NewNetTraceReader() error = %v", err)
+	}
+	if err := reader.ReadEvents(func(*NetTraceEvent) error { return nil }); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadEvents() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := NewNetTraceReader(bytes.NewReader([]byte("not a trace"))); err == nil {
+		t.Error("NewNetTraceReader() accepted a stream without the nettrace magic")
+	}
+}
@@ -0,0 +1,49 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Notifications</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+.pending{color:#b45309;font-weight:700;}
+.sent{color:#15803d;}
+.failed{color:#991b1b;font-weight:700;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:4px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.num{text-align:right;}
+.error{white-space:pre-wrap;color:#991b1b;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Notifications</h1>
+<div class="meta">Crashes, crash loops and trigger firings of all targets are POSTed to the webhooks below; failed deliveries are retried with backoff. Links in notifications start with <code>{{.BaseURL}}</code> (<code>-notify.base.url</code>). Crash loop: {{.CrashLoop}}, {{.Suppressed}} crash(es) suppressed inside a loop window. Full data at <a href="/api/notifications{{.TargetQuery}}" target="_blank">/api/notifications</a>. <a href="/notifications{{.TargetQuery}}">refresh</a></div>
+{{if not .Endpoints}}
+<div class="meta" style="margin-top:12px;">No webhook is configured. Use <code>-notify.webhook</code> or <code>notifiers:</code> in the file given by <code>-config</code>.</div>
+{{else}}
+<h2>Webhooks</h2>
+<table>
+<thead><tr><th>name</th><th>url</th><th>format</th><th>events</th></tr></thead>
+<tbody>
+{{range .Endpoints}}<tr><td>{{.Name}}</td><td>{{.URL}}</td><td>{{.Format}}</td><td>{{.Events}}</td></tr>
+{{end}}</tbody>
+</table>
+<form method="post" action="{{.TestURL}}" style="margin-top:8px;"><button type="submit">Send a test notification</button></form>
+<h2>Deliveries</h2>
+{{if not .Deliveries}}<div class="meta">Nothing has been sent yet.</div>{{else}}
+<table>
+<thead><tr><th>time</th><th>webhook</th><th>event</th><th>target</th><th>title</th><th>attempts</th><th>status</th></tr></thead>
+<tbody>
+{{range .Deliveries}}<tr><td>{{.Created}}</td><td>{{.Endpoint}}</td><td>{{.Event}}</td><td>{{.Target}}</td><td>{{.Title}}</td><td class="num">{{.Attempts}}</td><td><span class="{{.Status}}">{{.Status}}</span>{{if .Error}}<div class="error">{{.Error}}</div>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{end}}
+</div>
+</body>
+</html>
@@ -0,0 +1,616 @@
+package debugadmin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通知的事件。
+const (
+	NotifyEventCrash     = "crash"      // 目标进程异常退出
+	NotifyEventCrashLoop = "crash_loop" // 一个时间窗口内反复崩溃，窗口内之后的崩溃不再逐次通知
+	NotifyEventTrigger   = "trigger"    // 触发规则执行完了它的动作
+	NotifyEventTest      = "test"       // /notifications 页面上的测试按钮，发给所有 endpoint
+)
+
+// 通知的格式，没有指定时根据 webhook 的域名判断，其他域名使用 generic。
+const (
+	NotifyFormatGeneric  = "generic"  // POST Notification 的 JSON
+	NotifyFormatSlack    = "slack"    // Slack incoming webhook，以及兼容它的 Mattermost、Rocket.Chat
+	NotifyFormatWeCom    = "wecom"    // 企业微信群机器人
+	NotifyFormatDingTalk = "dingtalk" // 钉钉群机器人
+	NotifyFormatFeishu   = "feishu"   // 飞书 / Lark 群机器人
+)
+
+const (
+	defaultCrashLoopCount     = 3
+	defaultCrashLoopWindow    = 10 * time.Minute
+	maxNotificationDeliveries = 100 // 保留的发送记录数
+	maxNotificationAttempts   = 5
+	notificationQueueSize     = 256
+	notificationTimeout       = 10 * time.Second
+	maxNotificationLogLines   = 20   // generic 格式中携带的日志行数
+	maxChatLogLines           = 10   // 聊天机器人消息中携带的日志行数
+	maxChatTextBytes          = 4000 // 企业微信 markdown 消息最长 4096 字节
+)
+
+// NotifyOptions 是通知的参数，来自 -notify.* 与配置文件的 notifiers:。
+type NotifyOptions struct {
+	Endpoints       []NotificationEndpoint
+	BaseURL         string // 通知中链接的前缀，为空时使用 http://<hostname>:<admin.port>
+	CrashLoopCount  int    // CrashLoopWindow 内崩溃这么多次时发送 crash_loop，0 表示不检测
+	CrashLoopWindow time.Duration
+}
+
+// NotificationEndpoint 是一个接收通知的 webhook。
+type NotificationEndpoint struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"-"` // 可能带有 token，展示时使用 redactNotifyURL
+	Format string   `json:"format"`
+	Events []string `json:"events,omitempty"` // 为空时接收所有事件
+}
+
+// Accepts 判断 endpoint 是否订阅了 event，测试通知总是发送。
+func (e NotificationEndpoint) Accepts(event string) bool {
+	return event == NotifyEventTest || len(e.Events) == 0 || slices.Contains(e.Events, event)
+}
+
+// NotificationLink 是通知中的一个链接，URL 在入队时转换为绝对地址。
+type NotificationLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Notification 是一条通知，generic 格式直接 POST 它的 JSON。
+type Notification struct {
+	Event      string             `json:"event"`
+	Title      string             `json:"title"`
+	Host       string             `json:"host"`
+	Target     string             `json:"target,omitempty"`
+	Time       time.Time          `json:"time"`
+	PID        int                `json:"pid,omitempty"`
+	Signature  string             `json:"signature,omitempty"` // 崩溃特征：信号或退出码、未处理的异常类型、gdb 中的栈顶
+	ExitCode   int                `json:"exit_code,omitempty"`
+	Signal     string             `json:"signal,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	CoreDump   string             `json:"core_dump,omitempty"`
+	Restarting bool               `json:"restarting,omitempty"`
+	Crashes    int                `json:"crashes,omitempty"` // crash_loop：窗口内的崩溃次数
+	Window     string             `json:"window,omitempty"`
+	Rule       string             `json:"rule,omitempty"`
+	Reason     string             `json:"reason,omitempty"`
+	Details    []string           `json:"details,omitempty"` // trigger：每个动作的结果
+	LastLogs   []string           `json:"last_logs,omitempty"`
+	Links      []NotificationLink `json:"links,omitempty"`
+}
+
+// 发送记录的状态。
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// NotificationDelivery 是一条通知发往一个 endpoint 的记录。
+type NotificationDelivery struct {
+	ID       int64     `json:"id"`
+	Endpoint string    `json:"endpoint"`
+	Event    string    `json:"event"`
+	Target   string    `json:"target,omitempty"`
+	Title    string    `json:"title"`
+	Created  time.Time `json:"created"`
+	Attempts int       `json:"attempts"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Sent     time.Time `json:"sent,omitzero"`
+}
+
+type notificationJob struct {
+	delivery *NotificationDelivery
+	endpoint NotificationEndpoint
+	body     []byte
+}
+
+// Notifier 把崩溃、反复崩溃与触发规则的结果发到 webhook，所有目标进程共用一个实例。
+// 通知先进入队列，由 Run 中的 goroutine 逐个发送；发送失败时按指数退避重试，最多 maxNotificationAttempts 次。
+type Notifier struct {
+	opts       NotifyOptions
+	host       string
+	client     *http.Client
+	retryDelay time.Duration // 第一次重试前的等待时间，之后每次翻倍，测试中可以替换
+	now        func() time.Time
+	queue      chan notificationJob
+
+	mu         sync.Mutex
+	nextID     int64
+	deliveries []*NotificationDelivery // 按创建时间排序
+	crashes    map[string][]time.Time  // 每个目标进程最近的崩溃时间
+	loopUntil  map[string]time.Time    // 发送 crash_loop 之后，这个时间之前的崩溃不再通知
+	suppressed int
+}
+
+func NewNotifier(opts NotifyOptions) *Notifier {
+	host, _ := os.Hostname()
+	return &Notifier{
+		opts:       opts,
+		host:       host,
+		client:     &http.Client{Timeout: notificationTimeout},
+		retryDelay: 2 * time.Second,
+		now:        time.Now,
+		queue:      make(chan notificationJob, notificationQueueSize),
+		crashes:    make(map[string][]time.Time),
+		loopUntil:  make(map[string]time.Time),
+	}
+}
+
+// Enabled 判断是否配置了 webhook。
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.opts.Endpoints) > 0
+}
+
+func (n *Notifier) Options() NotifyOptions {
+	return n.opts
+}
+
+// Run 发送队列中的通知，直到 ctx 结束。
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-n.queue:
+			n.deliver(ctx, job)
+		}
+	}
+}
+
+// Crashed 通知一次崩溃。CrashLoopWindow 内的第 CrashLoopCount 次崩溃改为发送一条 crash_loop，
+// 此后的一个窗口内不再逐次通知，避免自动重启反复崩溃时刷屏。
+func (n *Notifier) Crashed(notification Notification) {
+	if !n.Enabled() {
+		return
+	}
+	now := n.now()
+	notification.Event = NotifyEventCrash
+	n.mu.Lock()
+	if now.Before(n.loopUntil[notification.Target]) {
+		n.suppressed++
+		n.mu.Unlock()
+		return
+	}
+	crashes := append(n.crashes[notification.Target], now)
+	for len(crashes) > 0 && now.Sub(crashes[0]) > n.opts.CrashLoopWindow {
+		crashes = crashes[1:]
+	}
+	n.crashes[notification.Target] = crashes
+	if n.opts.CrashLoopCount > 0 && len(crashes) >= n.opts.CrashLoopCount {
+		notification.Event = NotifyEventCrashLoop
+		notification.Crashes = len(crashes)
+		notification.Window = n.opts.CrashLoopWindow.String()
+		n.loopUntil[notification.Target] = now.Add(n.opts.CrashLoopWindow)
+		delete(n.crashes, notification.Target)
+	}
+	n.mu.Unlock()
+	n.Notify(notification)
+}
+
+// TriggerFired 通知一次执行完的触发，links 中包含触发页面与动作生成的文件。
+func (n *Notifier) TriggerFired(status TriggerFiringStatus, links []NotificationLink) {
+	if !n.Enabled() {
+		return
+	}
+	notification := Notification{
+		Event:  NotifyEventTrigger,
+		Target: status.Target,
+		Time:   status.Time,
+		PID:    status.PID,
+		Rule:   status.Rule,
+		Reason: status.Reason,
+		Links:  links,
+	}
+	for _, result := range status.Results {
+		line := result.Action.Describe() + ": done"
+		if result.Error != "" {
+			line = result.Action.Describe() + ": failed: " + firstLine(result.Error)
+		}
+		notification.Details = append(notification.Details, line)
+		for _, artifact := range result.Artifacts {
+			notification.Links = append(notification.Links, NotificationLink{Name: artifact.Name, URL: artifact.URL})
+		}
+	}
+	n.Notify(notification)
+}
+
+// Test 向所有 endpoint 发送一条测试通知。
+func (n *Notifier) Test(target string) {
+	n.Notify(Notification{Event: NotifyEventTest, Target: target, Time: n.now()})
+}
+
+// Notify 补全标题与链接后，为每个订阅了该事件的 endpoint 创建一条发送记录并入队。
+func (n *Notifier) Notify(notification Notification) {
+	if !n.Enabled() {
+		return
+	}
+	notification.Host = n.host
+	if notification.Time.IsZero() {
+		notification.Time = n.now()
+	}
+	if notification.Title == "" {
+		notification.Title = notificationTitle(notification)
+	}
+	links := make([]NotificationLink, 0, len(notification.Links))
+	for _, link := range notification.Links {
+		links = append(links, NotificationLink{Name: link.Name, URL: n.absoluteURL(link.URL)})
+	}
+	notification.Links = links
+	for _, endpoint := range n.opts.Endpoints {
+		if !endpoint.Accepts(notification.Event) {
+			continue
+		}
+		body, err := renderNotification(endpoint.Format, notification)
+		n.mu.Lock()
+		n.nextID++
+		delivery := &NotificationDelivery{
+			ID:       n.nextID,
+			Endpoint: endpoint.Name,
+			Event:    notification.Event,
+			Target:   notification.Target,
+			Title:    notification.Title,
+			Created:  n.now(),
+			Status:   NotificationPending,
+		}
+		n.deliveries = append(n.deliveries, delivery)
+		for len(n.deliveries) > maxNotificationDeliveries {
+			n.deliveries = n.deliveries[1:]
+		}
+		if err != nil {
+			delivery.Status, delivery.Error = NotificationFailed, err.Error()
+		}
+		n.mu.Unlock()
+		if err == nil {
+			n.enqueue(notificationJob{delivery: delivery, endpoint: endpoint, body: body})
+		}
+	}
+}
+
+func (n *Notifier) enqueue(job notificationJob) {
+	select {
+	case n.queue <- job:
+	default:
+		n.mu.Lock()
+		job.delivery.Status, job.delivery.Error = NotificationFailed, "notification queue is full"
+		n.mu.Unlock()
+		_, _ = fmt.Fprintf(os.Stderr, "[notify] queue is full, dropped %q for %s\n", job.delivery.Title, job.endpoint.Name)
+	}
+}
+
+// deliver 发送一次，失败且还有重试次数时在退避之后重新入队，不阻塞队列中的其他通知。
+func (n *Notifier) deliver(ctx context.Context, job notificationJob) {
+	err := n.send(ctx, job.endpoint, job.body)
+	n.mu.Lock()
+	job.delivery.Attempts++
+	attempts := job.delivery.Attempts
+	switch {
+	case err == nil:
+		job.delivery.Status, job.delivery.Error, job.delivery.Sent = NotificationSent, "", n.now()
+	case attempts >= maxNotificationAttempts:
+		job.delivery.Status, job.delivery.Error = NotificationFailed, err.Error()
+	default:
+		job.delivery.Error = err.Error()
+	}
+	n.mu.Unlock()
+	if err == nil {
+		return
+	}
+	if attempts >= maxNotificationAttempts {
+		_, _ = fmt.Fprintf(os.Stderr, "[notify] send %q to %s failed after %d attempts: %v\n", job.delivery.Title, job.endpoint.Name, attempts, err)
+		return
+	}
+	time.AfterFunc(n.retryDelay<<(attempts-1), func() {
+		if ctx.Err() == nil {
+			n.enqueue(job)
+		}
+	})
+}
+
+// send POST 一次通知。聊天机器人在出错时也可能返回 200，需要检查返回的 errcode / code。
+func (n *Notifier) send(ctx context.Context, endpoint NotificationEndpoint, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// url.Error 中带有完整的 webhook 地址
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(response)))
+	}
+	switch endpoint.Format {
+	case NotifyFormatWeCom, NotifyFormatDingTalk, NotifyFormatFeishu:
+		var result struct {
+			ErrCode *int   `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+			Code    *int   `json:"code"`
+			Msg     string `json:"msg"`
+		}
+		if json.Unmarshal(response, &result) != nil {
+			return nil
+		}
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return fmt.Errorf("robot returned errcode %d: %s", *result.ErrCode, result.ErrMsg)
+		}
+		if result.Code != nil && *result.Code != 0 {
+			return fmt.Errorf("robot returned code %d: %s", *result.Code, result.Msg)
+		}
+	}
+	return nil
+}
+
+// Deliveries 返回发送记录，最近的在前。
+func (n *Notifier) Deliveries() []NotificationDelivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	deliveries := make([]NotificationDelivery, 0, len(n.deliveries))
+	for i := len(n.deliveries) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *n.deliveries[i])
+	}
+	return deliveries
+}
+
+// Suppressed 返回因为处于 crash_loop 窗口内而没有通知的崩溃次数。
+func (n *Notifier) Suppressed() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.suppressed
+}
+
+func (n *Notifier) absoluteURL(link string) string {
+	if !strings.HasPrefix(link, "/") {
+		return link
+	}
+	return strings.TrimRight(n.opts.BaseURL, "/") + link
+}
+
+func notificationTitle(notification Notification) string {
+	target := notification.Target
+	if target == "" {
+		target = "target"
+	}
+	switch notification.Event {
+	case NotifyEventCrash:
+		title := fmt.Sprintf("[DebugAdmin] %s crashed on %s", target, notification.Host)
+		if notification.Signature != "" {
+			title += ": " + notification.Signature
+		}
+		return title
+	case NotifyEventCrashLoop:
+		return fmt.Sprintf("[DebugAdmin] %s is crash looping on %s: %d crashes within %s", target, notification.Host, notification.Crashes, notification.Window)
+	case NotifyEventTrigger:
+		return fmt.Sprintf("[DebugAdmin] trigger %s fired for %s on %s", notification.Rule, target, notification.Host)
+	}
+	return fmt.Sprintf("[DebugAdmin] test notification from %s", notification.Host)
+}
+
+// renderNotification 按 endpoint 的格式生成 POST 的内容。
+func renderNotification(format string, notification Notification) ([]byte, error) {
+	switch format {
+	case NotifyFormatGeneric:
+		if len(notification.LastLogs) > maxNotificationLogLines {
+			notification.LastLogs = notification.LastLogs[len(notification.LastLogs)-maxNotificationLogLines:]
+		}
+		return json.Marshal(notification)
+	case NotifyFormatSlack:
+		return json.Marshal(map[string]any{"text": notificationText(notification, true)})
+	case NotifyFormatWeCom:
+		return json.Marshal(map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]any{"content": notificationText(notification, false)},
+		})
+	case NotifyFormatDingTalk:
+		return json.Marshal(map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]any{"title": notification.Title, "text": notificationText(notification, true)},
+		})
+	case NotifyFormatFeishu:
+		return json.Marshal(map[string]any{
+			"msg_type": "text",
+			"content":  map[string]any{"text": notificationText(notification, false)},
+		})
+	}
+	return nil, fmt.Errorf("unknown notification format %q", format)
+}
+
+// notificationText 生成聊天机器人的消息正文；fence 为 true 时日志放在 ``` 代码块中。
+func notificationText(notification Notification, fence bool) string {
+	var b strings.Builder
+	b.WriteString(notification.Title + "\n")
+	line := func(name, value string) {
+		if value != "" {
+			b.WriteString(name + ": " + value + "\n")
+		}
+	}
+	if notification.PID > 0 {
+		line("pid", fmt.Sprintf("%d", notification.PID))
+	}
+	line("time", notification.Time.Format("2006-01-02 15:04:05"))
+	if notification.Event == NotifyEventCrash || notification.Event == NotifyEventCrashLoop {
+		exit := fmt.Sprintf("code=%d", notification.ExitCode)
+		if notification.Signal != "" {
+			exit += " signal=" + notification.Signal
+		}
+		line("exit", exit)
+		line("signature", notification.Signature)
+		line("error", notification.Error)
+		line("core dump", notification.CoreDump)
+		if notification.Restarting {
+			line("restarting", "yes")
+		}
+	}
+	line("rule", notification.Rule)
+	line("reason", notification.Reason)
+	for _, detail := range notification.Details {
+		b.WriteString("- " + detail + "\n")
+	}
+	for _, link := range notification.Links {
+		line(link.Name, link.URL)
+	}
+	if logs := notification.LastLogs; len(logs) > 0 {
+		if len(logs) > maxChatLogLines {
+			logs = logs[len(logs)-maxChatLogLines:]
+		}
+		b.WriteString("last logs:\n")
+		if fence {
+			b.WriteString("```\n")
+		}
+		for _, log := range logs {
+			b.WriteString(strings.TrimRight(log, "\n") + "\n")
+		}
+		if fence {
+			b.WriteString("```\n")
+		}
+	}
+	text := strings.TrimRight(b.String(), "\n")
+	if len(text) > maxChatTextBytes {
+		text = strings.ToValidUTF8(text[:maxChatTextBytes-3], "") + "..."
+	}
+	return text
+}
+
+// unhandledExceptionPattern 匹配 .NET 运行时在未处理异常时输出的 "Unhandled exception. System.Xxx: message"。
+var unhandledExceptionPattern = regexp.MustCompile(`Unhandled exception\.\s+([A-Za-z_][\w.+` + "`" + `]*)`)
+
+// gdbFramePattern 匹配 gdb bt 的栈顶 "#0  0x00007f... in func (args) at file:line"。
+var gdbFramePattern = regexp.MustCompile(`^#0\s+(?:0x[0-9a-fA-F]+\s+in\s+)?(\S+)`)
+
+// crashSignature 给出崩溃的特征，用于在通知中区分不同的崩溃：
+// 信号（或退出码）、日志中未处理的异常类型，以及 gdb 日志中最后一次 bt 的栈顶函数。
+func crashSignature(record RunRecord) string {
+	var parts []string
+	if record.Signal != "" {
+		parts = append(parts, record.Signal)
+	} else {
+		parts = append(parts, fmt.Sprintf("exit code %d", record.ExitCode))
+	}
+	for i := len(record.LastLogs) - 1; i >= 0; i-- {
+		if match := unhandledExceptionPattern.FindStringSubmatch(record.LastLogs[i]); match != nil {
+			parts = append(parts, strings.TrimSuffix(match[1], ":"))
+			break
+		}
+	}
+	if frame := gdbTopFrame(record.GDBLogPath); frame != "" {
+		parts = append(parts, "at "+frame)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// gdbTopFrame 返回 gdb 日志中最后一个 "#0" 栈帧的函数名，只读取日志的最后 64KB。
+func gdbTopFrame(path string) string {
+	if path == "" {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.Size() > 64<<10 {
+		_, _ = file.Seek(-64<<10, io.SeekEnd)
+	}
+	frame := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		if match := gdbFramePattern.FindStringSubmatch(scanner.Text()); match != nil {
+			frame = match[1]
+		}
+	}
+	return frame
+}
+
+// firstLine 返回多行文本的第一行。
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// newNotificationEndpoint 校验一个 webhook，format 为空时根据域名判断。
+func newNotificationEndpoint(name, rawURL, format string, events []string) (NotificationEndpoint, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return NotificationEndpoint{}, fmt.Errorf("webhook needs an http(s) url, got %q", redactURL(rawURL))
+	}
+	endpoint := NotificationEndpoint{Name: strings.TrimSpace(name), URL: parsed.String(), Format: strings.ToLower(strings.TrimSpace(format))}
+	if endpoint.Name == "" {
+		endpoint.Name = parsed.Host
+	}
+	switch endpoint.Format {
+	case "":
+		endpoint.Format = detectNotifyFormat(parsed.Hostname())
+	case NotifyFormatGeneric, NotifyFormatSlack, NotifyFormatWeCom, NotifyFormatDingTalk, NotifyFormatFeishu:
+	default:
+		return NotificationEndpoint{}, fmt.Errorf("unknown format %q, expected generic, slack, wecom, dingtalk or feishu", format)
+	}
+	for _, event := range events {
+		switch event = strings.TrimSpace(event); event {
+		case NotifyEventCrash, NotifyEventCrashLoop, NotifyEventTrigger:
+			endpoint.Events = append(endpoint.Events, event)
+		default:
+			return NotificationEndpoint{}, fmt.Errorf("unknown event %q, expected crash, crash_loop or trigger", event)
+		}
+	}
+	return endpoint, nil
+}
+
+func detectNotifyFormat(host string) string {
+	switch host {
+	case "hooks.slack.com":
+		return NotifyFormatSlack
+	case "qyapi.weixin.qq.com":
+		return NotifyFormatWeCom
+	case "oapi.dingtalk.com":
+		return NotifyFormatDingTalk
+	case "open.feishu.cn", "open.larksuite.com":
+		return NotifyFormatFeishu
+	}
+	return NotifyFormatGeneric
+}
+
+// redactNotifyURL 隐藏 webhook 中的 token：query 中的 key / access_token，
+// 以及 Slack、飞书这样把 token 放在路径最后一段的地址。
+func redactNotifyURL(endpoint NotificationEndpoint) string {
+	value := redactURL(endpoint.URL)
+	if endpoint.Format != NotifyFormatSlack && endpoint.Format != NotifyFormatFeishu {
+		return value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return redactedValue
+	}
+	if index := strings.LastIndex(parsed.Path, "/"); index >= 0 && index < len(parsed.Path)-1 {
+		parsed.Path = parsed.Path[:index+1] + redactedValue
+		parsed.RawPath = ""
+	}
+	return parsed.String()
+}
@@ -0,0 +1,187 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+)
+
+//go:embed notifications.html.tpl
+var notificationsHTMLContent string
+
+var notificationsHTMLTemplate = template.Must(template.New("notifications.html").Parse(notificationsHTMLContent))
+
+type notificationsPageData struct {
+	TargetQuery string
+	BaseURL     string
+	CrashLoop   string
+	Suppressed  int
+	Endpoints   []notificationEndpointView
+	Deliveries  []notificationDeliveryView
+	TestURL     string
+}
+
+type notificationEndpointView struct {
+	Name   string
+	URL    string
+	Format string
+	Events string
+}
+
+type notificationDeliveryView struct {
+	Created  string
+	Endpoint string
+	Event    string
+	Target   string
+	Title    string
+	Attempts int
+	Status   string
+	Error    string
+}
+
+// notificationEndpointStatus 是 /api/notifications 中的 endpoint，URL 已经隐藏了 token。
+type notificationEndpointStatus struct {
+	NotificationEndpoint
+	URL string `json:"url"`
+}
+
+// notificationsAPIResponse 是 /api/notifications 的返回值。
+type notificationsAPIResponse struct {
+	BaseURL         string                       `json:"base_url"`
+	CrashLoopCount  int                          `json:"crash_loop_count"`
+	CrashLoopWindow string                       `json:"crash_loop_window"`
+	Suppressed      int                          `json:"suppressed"`
+	Endpoints       []notificationEndpointStatus `json:"endpoints"`
+	Deliveries      []NotificationDelivery       `json:"deliveries"`
+}
+
+// notifyExit 在目标进程崩溃时发送通知，由 TargetSupervisor.OnExit 调用。
+func (h *AdminHandler) notifyExit(exit TargetExit) {
+	if !h.notifier.Enabled() || exit.Target.reason != RunReasonCrash {
+		return
+	}
+	records := h.history.Snapshot()
+	if len(records) == 0 {
+		return
+	}
+	index := len(records) - 1
+	record := records[index]
+	notification := Notification{
+		Target:     h.name,
+		Time:       record.EndTime,
+		PID:        record.PID,
+		Signature:  crashSignature(record),
+		ExitCode:   record.ExitCode,
+		Signal:     record.Signal,
+		Error:      record.Err,
+		CoreDump:   record.CoreDumpPath,
+		Restarting: h.supervisor != nil && h.supervisor.AutoRestart(),
+		LastLogs:   record.LastLogs,
+		Links:      []NotificationLink{{Name: "run history", URL: "/" + h.targetQuery("?")}},
+	}
+	if record.GDBLogPath != "" {
+		notification.Links = append(notification.Links, NotificationLink{Name: "gdb log", URL: fmt.Sprintf("/gdb-log?index=%d%s", index, h.targetQuery("&"))})
+	}
+	if record.CrashReportID != "" {
+		notification.Links = append(notification.Links, NotificationLink{Name: "crash report", URL: h.crashReportURL(record.CrashReportID)})
+	}
+	if record.CoreDumpID != "" {
+		notification.Links = append(notification.Links, NotificationLink{Name: "core dump", URL: h.coreDumpURL(record.CoreDumpID)})
+	}
+	h.notifier.Crashed(notification)
+}
+
+// notifyTriggerFired 在触发规则的动作执行完之后发送通知，由 TriggerEngine.OnFired 调用。
+func (h *AdminHandler) notifyTriggerFired(status TriggerFiringStatus) {
+	h.notifier.TriggerFired(status, []NotificationLink{{Name: "triggers", URL: "/triggers" + h.targetQuery("?")}})
+}
+
+// handleNotifications 展示通知的 webhook 与最近的发送记录。
+func (h *AdminHandler) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	opts := h.notifier.Options()
+	data := notificationsPageData{
+		TargetQuery: h.targetQuery("?"),
+		BaseURL:     html.EscapeString(opts.BaseURL),
+		CrashLoop:   "disabled",
+		Suppressed:  h.notifier.Suppressed(),
+		TestURL:     "/notifications/test" + h.targetQuery("?"),
+	}
+	if opts.CrashLoopCount > 0 {
+		data.CrashLoop = fmt.Sprintf("%d crashes within %s", opts.CrashLoopCount, opts.CrashLoopWindow)
+	}
+	for _, endpoint := range opts.Endpoints {
+		events := "all"
+		if len(endpoint.Events) > 0 {
+			events = strings.Join(endpoint.Events, ", ")
+		}
+		data.Endpoints = append(data.Endpoints, notificationEndpointView{
+			Name:   html.EscapeString(endpoint.Name),
+			URL:    html.EscapeString(redactNotifyURL(endpoint)),
+			Format: endpoint.Format,
+			Events: events,
+		})
+	}
+	for _, delivery := range h.notifier.Deliveries() {
+		data.Deliveries = append(data.Deliveries, notificationDeliveryView{
+			Created:  delivery.Created.Format("2006-01-02 15:04:05"),
+			Endpoint: html.EscapeString(delivery.Endpoint),
+			Event:    delivery.Event,
+			Target:   html.EscapeString(delivery.Target),
+			Title:    html.EscapeString(delivery.Title),
+			Attempts: delivery.Attempts,
+			Status:   delivery.Status,
+			Error:    html.EscapeString(delivery.Error),
+		})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := notificationsHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render notifications page failed: %v\n", err)
+	}
+}
+
+// handleNotificationsTest 向所有 webhook 发送一条测试通知，用于检查地址与格式是否正确。
+func (h *AdminHandler) handleNotificationsTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.notifier.Enabled() {
+		http.Error(w, "no webhook is configured; use -notify.webhook or notifiers: in the config file", http.StatusConflict)
+		return
+	}
+	h.notifier.Test(h.name)
+	http.Redirect(w, r, "/notifications"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleNotificationsAPI 以 JSON 返回通知的配置与发送记录。
+func (h *AdminHandler) handleNotificationsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	opts := h.notifier.Options()
+	response := notificationsAPIResponse{
+		BaseURL:         opts.BaseURL,
+		CrashLoopCount:  opts.CrashLoopCount,
+		CrashLoopWindow: opts.CrashLoopWindow.String(),
+		Suppressed:      h.notifier.Suppressed(),
+		Endpoints:       []notificationEndpointStatus{},
+		Deliveries:      h.notifier.Deliveries(),
+	}
+	for _, endpoint := range opts.Endpoints {
+		response.Endpoints = append(response.Endpoints, notificationEndpointStatus{NotificationEndpoint: endpoint, URL: redactNotifyURL(endpoint)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,369 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadOptionsNotifiers(t *testing.T) {
+	path := writeConfigFile(t, `
+targets: [{name: api, args: [/app/Api.dll]}]
+notify.crashloop.count: 5
+notifiers:
+  - name: oncall
+    url: https://open.feishu.cn/open-apis/bot/v2/hook/abc-token
+    events: [crash_loop, trigger]
+  - name: alerts
+    url: https://alerts.example.com/hook?token=secret
+    format: slack
+`)
+	opts, err := loadOptions([]string{"-config", path, "-notify.webhook", "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=k1"})
+	if err != nil {
+		t.Fatalf("loadOptions() error = %v", err)
+	}
+	notify := opts.Notify
+	if notify.CrashLoopCount != 5 || notify.CrashLoopWindow != defaultCrashLoopWindow || !strings.HasSuffix(notify.BaseURL, ":8089") {
+		t.Errorf("notify options = %+v", notify)
+	}
+	if len(notify.Endpoints) != 3 {
+		t.Fatalf("endpoints = %+v", notify.Endpoints)
+	}
+	wecom, feishu, slack := notify.Endpoints[0], notify.Endpoints[1], notify.Endpoints[2]
+	if wecom.Name != "qyapi.weixin.qq.com" || wecom.Format != NotifyFormatWeCom || !wecom.Accepts(NotifyEventCrash) {
+		t.Errorf("wecom endpoint = %+v", wecom)
+	}
+	if feishu.Format != NotifyFormatFeishu || feishu.Accepts(NotifyEventCrash) || !feishu.Accepts(NotifyEventCrashLoop) || !feishu.Accepts(NotifyEventTest) {
+		t.Errorf("feishu endpoint = %+v", feishu)
+	}
+	if slack.Format != NotifyFormatSlack {
+		t.Errorf("slack endpoint = %+v", slack)
+	}
+	for i, want := range []string{
+		"https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=REDACTED",
+		"https://open.feishu.cn/open-apis/bot/v2/hook/REDACTED",
+		"https://alerts.example.com/REDACTED?token=REDACTED",
+	} {
+		if got := redactNotifyURL(notify.Endpoints[i]); got != want {
+			t.Errorf("redactNotifyURL(%s) = %s, want %s", notify.Endpoints[i].Name, got, want)
+		}
+	}
+
+	for name, notifier := range map[string]string{
+		"bad url":        `{url: "ftp://example.com"}`,
+		"unknown format": `{url: "https://example.com", format: teams}`,
+		"unknown event":  `{url: "https://example.com", events: [exit]}`,
+		"unknown field":  `{url: "https://example.com", retries: 3}`,
+	} {
+		path := writeConfigFile(t, "targets: [{name: api, args: [/app/Api.dll]}]\nnotifiers:\n  - "+notifier+"\n")
+		if _, err := loadOptions([]string{"-config", path}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	path = writeConfigFile(t, "targets: [{name: api, args: [/app/Api.dll]}]\nnotifiers: [{name: a, url: 'https://x'}, {name: a, url: 'https://y'}]\n")
+	if _, err := loadOptions([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate notifier error = %v", err)
+	}
+}
+
+func TestRenderNotification(t *testing.T) {
+	notification := Notification{
+		Event:     NotifyEventCrash,
+		Title:     "[DebugAdmin] api crashed on host1: SIGSEGV",
+		Target:    "api",
+		Time:      time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+		PID:       42,
+		Signature: "SIGSEGV",
+		ExitCode:  -1,
+		Signal:    "segmentation fault",
+		LastLogs:  []string{"line 1", "line 2"},
+		Links:     []NotificationLink{{Name: "gdb log", URL: "http://host1:8089/gdb-log?index=0"}},
+	}
+	for format, check := range map[string]func(map[string]any) string{
+		NotifyFormatGeneric: func(body map[string]any) string {
+			if body["signature"] != "SIGSEGV" || body["event"] != "crash" {
+				return "missing signature"
+			}
+			return ""
+		},
+		NotifyFormatSlack: func(body map[string]any) string {
+			return body["text"].(string)
+		},
+		NotifyFormatWeCom: func(body map[string]any) string {
+			return body["markdown"].(map[string]any)["content"].(string)
+		},
+		NotifyFormatDingTalk: func(body map[string]any) string {
+			return body["markdown"].(map[string]any)["text"].(string)
+		},
+		NotifyFormatFeishu: func(body map[string]any) string {
+			return body["content"].(map[string]any)["text"].(string)
+		},
+	} {
+		data, err := renderNotification(format, notification)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		text := check(body)
+		if format == NotifyFormatGeneric {
+			if text != "" {
+				t.Errorf("generic body = %s", data)
+			}
+			continue
+		}
+		for _, want := range []string{notification.Title, "exit: code=-1 signal=segmentation fault", "gdb log: http://host1:8089/gdb-log?index=0", "line 2"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s text misses %q:\n%s", format, want, text)
+			}
+		}
+	}
+	if _, err := renderNotification("teams", notification); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestCrashSignature(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "20260501-100000.log")
+	gdbLog := "Thread 1 \"dotnet\" received signal SIGSEGV\n" +
+		"#0  0x00007f0000001000 in raise () from /lib/libc.so.6\n" +
+		"Thread 1 \"dotnet\" received signal SIGABRT\n" +
+		"#0  __pthread_kill_implementation (threadid=1) at pthread_kill.c:44\n" +
+		"#1  0x00007f0000002000 in abort ()\n"
+	if err := os.WriteFile(logPath, []byte(gdbLog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	record := RunRecord{
+		Signal:     "aborted",
+		LastLogs:   []string{"Unhandled exception. System.InvalidOperationException: boom", "   at Program.Main()"},
+		GDBLogPath: logPath,
+	}
+	if got, want := crashSignature(record), "aborted | System.InvalidOperationException | at __pthread_kill_implementation"; got != want {
+		t.Errorf("crashSignature() = %q, want %q", got, want)
+	}
+	if got := crashSignature(RunRecord{ExitCode: 134}); got != "exit code 134" {
+		t.Errorf("crashSignature() = %q", got)
+	}
+}
+
+// notificationReceiver 记录收到的通知，fail 决定每次请求的响应，为 nil 时总是返回成功。
+type notificationReceiver struct {
+	mu     sync.Mutex
+	bodies map[string][]string // path -> body
+	fail   func(path string, count int) (int, string)
+}
+
+func newNotificationReceiver(t *testing.T, fail func(path string, count int) (int, string)) (*notificationReceiver, *httptest.Server) {
+	receiver := &notificationReceiver{bodies: make(map[string][]string), fail: fail}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.bodies[r.URL.Path] = append(receiver.bodies[r.URL.Path], string(body))
+		count := len(receiver.bodies[r.URL.Path])
+		receiver.mu.Unlock()
+		status, response := http.StatusOK, `{"errcode":0,"errmsg":"ok"}`
+		if receiver.fail != nil {
+			status, response = receiver.fail(r.URL.Path, count)
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func (r *notificationReceiver) received(path string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.bodies[path]...)
+}
+
+func waitNotificationDeliveries(t *testing.T, notifier *Notifier, count int) []NotificationDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries := notifier.Deliveries()
+		done := len(deliveries) >= count
+		for _, delivery := range deliveries {
+			done = done && delivery.Status != NotificationPending
+		}
+		if done {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d finished deliveries, got %+v", count, notifier.Deliveries())
+	return nil
+}
+
+func TestNotifierRetriesAndRobotErrors(t *testing.T) {
+	receiver, server := newNotificationReceiver(t, func(path string, count int) (int, string) {
+		switch {
+		case path == "/generic" && count == 1:
+			return http.StatusInternalServerError, "busy"
+		case path == "/robot":
+			return http.StatusOK, `{"errcode":93000,"errmsg":"invalid webhook url"}`
+		}
+		return http.StatusOK, "ok"
+	})
+	notifier := NewNotifier(NotifyOptions{
+		Endpoints: []NotificationEndpoint{
+			{Name: "generic", URL: server.URL + "/generic", Format: NotifyFormatGeneric},
+			{Name: "robot", URL: server.URL + "/robot", Format: NotifyFormatWeCom},
+		},
+		BaseURL: "http://debug.example.com:8089",
+	})
+	notifier.retryDelay = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	notifier.Crashed(Notification{Target: "api", PID: 42, Signature: "SIGSEGV", Links: []NotificationLink{{Name: "gdb log", URL: "/gdb-log?index=0"}}})
+	deliveries := waitNotificationDeliveries(t, notifier, 2)
+	byName := map[string]NotificationDelivery{}
+	for _, delivery := range deliveries {
+		byName[delivery.Endpoint] = delivery
+	}
+	if got := byName["generic"]; got.Status != NotificationSent || got.Attempts != 2 || got.Event != NotifyEventCrash {
+		t.Errorf("generic delivery = %+v", got)
+	}
+	if got := byName["robot"]; got.Status != NotificationFailed || got.Attempts != maxNotificationAttempts || !strings.Contains(got.Error, "errcode 93000") {
+		t.Errorf("robot delivery = %+v", got)
+	}
+	bodies := receiver.received("/generic")
+	var sent Notification
+	if len(bodies) != 2 || json.Unmarshal([]byte(bodies[1]), &sent) != nil {
+		t.Fatalf("generic bodies = %q", bodies)
+	}
+	if sent.Signature != "SIGSEGV" || sent.Links[0].URL != "http://debug.example.com:8089/gdb-log?index=0" || !strings.Contains(sent.Title, "api crashed") {
+		t.Errorf("generic notification = %+v", sent)
+	}
+}
+
+func TestNotifierCrashLoop(t *testing.T) {
+	notifier := NewNotifier(NotifyOptions{
+		Endpoints: []NotificationEndpoint{
+			{Name: "all", URL: "http://127.0.0.1:1/", Format: NotifyFormatGeneric},
+			{Name: "loops", URL: "http://127.0.0.1:1/", Format: NotifyFormatGeneric, Events: []string{NotifyEventCrashLoop}},
+		},
+		CrashLoopCount:  3,
+		CrashLoopWindow: 10 * time.Minute,
+	})
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	notifier.now = func() time.Time { return now }
+	for _, minute := range []int{0, 1, 2, 3, 5, 13, 30} {
+		now = start.Add(time.Duration(minute) * time.Minute)
+		notifier.Crashed(Notification{Target: "api"})
+	}
+	notifier.Crashed(Notification{Target: "worker"})
+
+	var events []string
+	deliveries := notifier.Deliveries()
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		events = append(events, deliveries[i].Endpoint+":"+deliveries[i].Event+":"+deliveries[i].Target)
+	}
+	want := []string{
+		"all:crash:api", "all:crash:api", // 10:00, 10:01
+		"all:crash_loop:api", "loops:crash_loop:api", // 10:02，之后 10 分钟内的崩溃不再通知
+		"all:crash:api",    // 10:13
+		"all:crash:api",    // 10:30，10:13 已经超出了窗口
+		"all:crash:worker", // 每个目标进程分别统计
+	}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if notifier.Suppressed() != 2 {
+		t.Errorf("suppressed = %d", notifier.Suppressed())
+	}
+	if title := deliveries[len(deliveries)-3].Title; !strings.Contains(title, "3 crashes within 10m0s") {
+		t.Errorf("crash loop title = %q", title)
+	}
+}
+
+func TestNotificationsForCrashAndTrigger(t *testing.T) {
+	useTempTriggerArtifactDir(t)
+	receiver, server := newNotificationReceiver(t, nil)
+	notifier := NewNotifier(NotifyOptions{
+		Endpoints:      []NotificationEndpoint{{Name: "ops", URL: server.URL + "/hook?token=secret", Format: NotifyFormatGeneric}},
+		BaseURL:        "http://debug.example.com:8089",
+		CrashLoopCount: 3, CrashLoopWindow: time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	history := NewRunHistory()
+	history.Add(RunRecord{PID: 41, Reason: RunReasonExit})
+	history.Add(RunRecord{PID: 42, ExitCode: -1, Signal: "segmentation fault", Abnormal: true, Reason: RunReasonCrash,
+		GDBLogPath: "/tmp/gdb/20260501-100000.log", CoreDumpPath: "/tmp/core.42", LastLogs: []string{"starting", "Unhandled exception. System.NullReferenceException: x"}})
+	h := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, history: history, notifier: notifier, mux: http.NewServeMux()}
+	h.Register(h.mux)
+
+	h.notifyExit(TargetExit{Target: &TargetProcess{reason: RunReasonExit}})
+	h.notifyExit(TargetExit{Target: &TargetProcess{reason: RunReasonCrash}})
+	waitNotificationDeliveries(t, notifier, 1)
+	var crash Notification
+	if bodies := receiver.received("/hook"); len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &crash) != nil {
+		t.Fatalf("bodies = %q", bodies)
+	}
+	if crash.Event != NotifyEventCrash || crash.PID != 42 || crash.CoreDump != "/tmp/core.42" ||
+		crash.Signature != "segmentation fault | System.NullReferenceException" || len(crash.LastLogs) != 2 {
+		t.Errorf("crash notification = %+v", crash)
+	}
+	if len(crash.Links) != 2 || crash.Links[1].URL != "http://debug.example.com:8089/gdb-log?index=1&target=api" {
+		t.Errorf("crash links = %+v", crash.Links)
+	}
+
+	rules := []TriggerRule{{Name: "snapshot", Condition: TriggerConditionCPU, Threshold: 90, Cooldown: time.Hour,
+		Actions: []TriggerAction{{Type: TriggerActionStack}, {Type: TriggerActionDump, DumpType: "Full"}}}}
+	actions := &recordingActions{}
+	h.triggers = NewTriggerEngine(h.name, rules, func() int { return 42 }, nil, nil, actions.run)
+	h.triggers.OnFired(h.notifyTriggerFired)
+	if _, err := h.triggers.Fire("snapshot"); err != nil {
+		t.Fatal(err)
+	}
+	waitNotificationDeliveries(t, notifier, 2)
+	var trigger Notification
+	if bodies := receiver.received("/hook"); len(bodies) != 2 || json.Unmarshal([]byte(bodies[1]), &trigger) != nil {
+		t.Fatalf("bodies = %q", bodies)
+	}
+	if trigger.Event != NotifyEventTrigger || trigger.Rule != "snapshot" || trigger.Reason != "manual" ||
+		strings.Join(trigger.Details, ";") != "stack: done;dump(Full): failed: no dotnet-dump" ||
+		trigger.Links[0].URL != "http://debug.example.com:8089/triggers?target=api" {
+		t.Errorf("trigger notification = %+v", trigger)
+	}
+
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/notifications/test", nil))
+	if response.Code != http.StatusSeeOther {
+		t.Fatalf("test status = %d, body = %s", response.Code, response.Body.String())
+	}
+	waitNotificationDeliveries(t, notifier, 3)
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/notifications", nil))
+	page := response.Body.String()
+	if strings.Contains(page, "secret") || !strings.Contains(page, "token=REDACTED") || !strings.Contains(page, "test notification") {
+		t.Errorf("notifications page = %s", page)
+	}
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/api/notifications", nil))
+	var api notificationsAPIResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &api); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.Endpoints) != 1 || strings.Contains(api.Endpoints[0].URL, "secret") || len(api.Deliveries) != 3 || api.Deliveries[0].Event != NotifyEventTest {
+		t.Errorf("api = %+v", api)
+	}
+}
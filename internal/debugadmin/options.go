@@ -2,6 +2,7 @@ package debugadmin
 
 import (
 	"regexp"
+	"time"
 )
 
 type CoverageOptions struct {
@@ -13,16 +14,53 @@ type CoverageOptions struct {
 	SourceFromPDB             bool   // 是否允许从 gdb 文件得到源码。对应选项 -coverage.source.from.pdb
 }
 
+// HangOptions 是卡死检测的参数。
+type HangOptions struct {
+	LogSilence time.Duration // 目标进程超过这个时间没有输出日志时自动检测，0 表示不自动检测
+	Samples    int           // 采样次数
+	Interval   time.Duration // 采样间隔
+}
+
+// TargetOptions 描述一个被调试的目标进程。
+// 命令行中每个 "-- name=xxx" 分组对应一个目标进程。
+type TargetOptions struct {
+	Name          string
+	StartupParams []string
+	Env           []string // 额外的环境变量，只能通过配置文件指定
+	Mode          RunMode  // 为空时由 -with.gdb / -with.coverage 决定
+	AutoRestart   bool     // 崩溃后是否自动重启，默认取 -auto.restart
+	Dir           string   // 工作目录，为空时使用 DebugAdmin 的工作目录，只能通过配置文件指定
+}
+
+// ConfigEntry 是 /config 页面展示的一个生效配置项。
+type ConfigEntry struct {
+	Name   string
+	Value  string
+	Source string // flag / env / file / default
+}
+
 type Options struct {
 	AdminPort         int
-	StartupParams     []string
+	StartupParams     []string // 第一个目标进程的启动参数
+	Targets           []TargetOptions
 	LogPushURL        string
 	LogStdoutOutput   bool
 	CoreDumpUnlimited bool
+	CoreDump          CoreDumpOptions
+	PerfMap           bool // 以 DOTNET_PerfMapEnabled=1 启动目标进程，运行时写出 /tmp/perf-{pid}.map
 	AutoRestart       bool
+	StopTimeout       time.Duration // 手动停止子进程时，SIGTERM 之后等待的时间，超时则 SIGKILL
 	WithGDB           bool
+	GDBScript         GDBScriptOptions // -with.gdb 时的崩溃采集脚本
 	WithCoverage      bool
 	CoverageOpts      CoverageOptions
+	Hang              HangOptions
+	TrackExceptions   bool          // 在后台通过 EventPipe 统计目标进程的 first-chance 异常
+	Triggers          []TriggerRule // 配置文件中的 triggers:，以及 -trace.cpu.* 对应的内置规则
+	Notify            NotifyOptions
+	ConfigPath        string        // -config 指定的配置文件
+	Effective         []ConfigEntry // 合并命令行、环境变量与配置文件之后的全部配置项，按名称排序
+	TargetsSource     string        // Targets 的来源：flag(--)、env(DEBUGADMIN_TARGET) 或 file
 }
 
 // GlobalOptions 保存命令行解析得到的配置信息。
@@ -0,0 +1,241 @@
+package debugadmin
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PerfMapEntry 是 perf map 中的一行：一段 JIT 代码的起始地址、长度与方法名。
+type PerfMapEntry struct {
+	Start uint64 `json:"start"`
+	Size  uint64 `json:"size"`
+	Name  string `json:"name"`
+}
+
+// End 返回这段代码之后的第一个地址。
+func (e PerfMapEntry) End() uint64 {
+	return e.Start + e.Size
+}
+
+// PerfMap 是 .NET 运行时在 DOTNET_PerfMapEnabled 时写出的 /tmp/perf-{pid}.map，
+// 按起始地址排序，用于把 gdb / perf 中没有 ELF 符号的 JIT 地址还原为托管方法名。
+type PerfMap struct {
+	entries []PerfMapEntry
+}
+
+// perfMapPath 返回运行时为 pid 写出的 perf map 路径。
+func perfMapPath(pid int) string {
+	return filepath.Join("/tmp", fmt.Sprintf("perf-%d.map", pid))
+}
+
+// perfInfoPath 返回运行时为 pid 写出的 perfinfo 路径，其中记录了加载的程序集映像，perf report 用它定位 R2R 代码。
+func perfInfoPath(pid int) string {
+	return filepath.Join("/tmp", fmt.Sprintf("perfinfo-%d.map", pid))
+}
+
+// ParsePerfMap 解析 perf map，每行格式为 "START SIZE name"，START 与 SIZE 是不带 0x 的十六进制数。
+// 无法解析的行会被跳过：运行时在进程运行中持续追加，最后一行可能只写了一半。
+func ParsePerfMap(r io.Reader) (*PerfMap, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var entries []PerfMapEntry
+	for scanner.Scan() {
+		if entry, ok := parsePerfMapLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return newPerfMap(entries), nil
+}
+
+func parsePerfMapLine(line string) (PerfMapEntry, bool) {
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+	if len(fields) != 3 {
+		return PerfMapEntry{}, false
+	}
+	start, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 64)
+	if err != nil {
+		return PerfMapEntry{}, false
+	}
+	size, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "0x"), 16, 64)
+	if err != nil || size == 0 {
+		return PerfMapEntry{}, false
+	}
+	return PerfMapEntry{Start: start, Size: size, Name: fields[2]}, true
+}
+
+// newPerfMap 按起始地址排序 entries，返回的 PerfMap 之后不再修改，可以被多个 goroutine 同时读取。
+func newPerfMap(entries []PerfMapEntry) *PerfMap {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start < entries[j].Start
+	})
+	return &PerfMap{entries: entries}
+}
+
+// Len 返回 perf map 中的条目数。
+func (m *PerfMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+// Lookup 查找包含 addr 的代码段。同一地址被多段覆盖时（例如 stub），返回起始地址最近的一段。
+func (m *PerfMap) Lookup(addr uint64) (PerfMapEntry, bool) {
+	if m == nil {
+		return PerfMapEntry{}, false
+	}
+	index := sort.Search(len(m.entries), func(i int) bool {
+		return m.entries[i].Start > addr
+	})
+	for i := index - 1; i >= 0 && i >= index-8; i-- {
+		if addr < m.entries[i].End() {
+			return m.entries[i], true
+		}
+	}
+	return PerfMapEntry{}, false
+}
+
+// gdbUnknownFramePattern 匹配 gdb backtrace 中没有符号的帧，例如 "#3  0x00007f1c2a3b4c5d in ?? ()"。
+var gdbUnknownFramePattern = regexp.MustCompile(`^(\s*#\d+\s+0x([0-9a-fA-F]+) in )\?\?( \(\).*)$`)
+
+// SymbolizeGDBBacktrace 用 perf map 把 gdb 输出中 "??" 帧替换为托管方法名，
+// 例如 "#3  0x00007f1c2a3b4c5d in [managed] void [MyApp] MyApp.Program::Main(string[])[Optimized] ()"。
+// 返回替换后的文本与替换的帧数；m 为 nil 时原样返回。
+func SymbolizeGDBBacktrace(output string, m *PerfMap) (string, int) {
+	if m.Len() == 0 {
+		return output, 0
+	}
+	lines := strings.Split(output, "\n")
+	resolved := 0
+	for i, line := range lines {
+		match := gdbUnknownFramePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		addr, err := strconv.ParseUint(match[2], 16, 64)
+		if err != nil {
+			continue
+		}
+		entry, ok := m.Lookup(addr)
+		if !ok {
+			continue
+		}
+		lines[i] = match[1] + "[managed] " + entry.Name + match[3]
+		resolved++
+	}
+	return strings.Join(lines, "\n"), resolved
+}
+
+// gdbInferiorPIDPattern 匹配 gdb 日志中被调试进程的 pid，
+// 例如 "Using the running image of child process 12345." 或 "[Inferior 1 (process 12345) killed]"。
+var gdbInferiorPIDPattern = regexp.MustCompile(`\bprocess (\d+)\b`)
+
+// PerfMapIndex 缓存各进程的 perf map，供 gdb backtrace、profiler 等把 JIT 地址还原为托管方法名。
+// 运行时只在文件末尾追加，所以文件变大时只解析新增的完整行；文件被替换或变小时重新读取。
+type PerfMapIndex struct {
+	mu   sync.Mutex
+	maps map[int]*cachedPerfMap
+}
+
+type cachedPerfMap struct {
+	info   os.FileInfo
+	offset int64 // 已经解析到的位置，总是在一行的末尾
+	m      *PerfMap
+}
+
+func NewPerfMapIndex() *PerfMapIndex {
+	return &PerfMapIndex{maps: make(map[int]*cachedPerfMap)}
+}
+
+// Get 返回 pid 当前的 perf map。文件不存在时返回的 error 满足 errors.Is(err, os.ErrNotExist)。
+func (x *PerfMapIndex) Get(pid int) (*PerfMap, error) {
+	file, err := os.Open(perfMapPath(pid))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	cached := x.maps[pid]
+	if cached == nil || !os.SameFile(cached.info, info) || info.Size() < cached.offset {
+		cached = &cachedPerfMap{m: &PerfMap{}}
+	}
+	cached.info = info
+	if info.Size() == cached.offset {
+		x.maps[pid] = cached
+		return cached.m, nil
+	}
+	data := make([]byte, info.Size()-cached.offset)
+	n, err := file.ReadAt(data, cached.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	data = data[:n]
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete == 0 {
+		x.maps[pid] = cached
+		return cached.m, nil
+	}
+	entries := append([]PerfMapEntry(nil), cached.m.entries...)
+	for _, line := range strings.Split(string(data[:complete]), "\n") {
+		if entry, ok := parsePerfMapLine(line); ok {
+			entries = append(entries, entry)
+		}
+	}
+	cached.offset += int64(complete)
+	cached.m = newPerfMap(entries)
+	x.maps[pid] = cached
+	return cached.m, nil
+}
+
+// Resolve 查找 pid 中地址 addr 所在的托管方法。
+func (x *PerfMapIndex) Resolve(pid int, addr uint64) (PerfMapEntry, bool) {
+	if x == nil {
+		return PerfMapEntry{}, false
+	}
+	m, err := x.Get(pid)
+	if err != nil {
+		return PerfMapEntry{}, false
+	}
+	return m.Lookup(addr)
+}
+
+// SymbolizeGDBLog 在 gdb 崩溃日志中找到被调试进程的 pid，用它的 perf map 还原 "??" 帧。
+// 进程退出后 perf map 仍然留在 /tmp 中，所以崩溃之后查看日志也能还原。
+func (x *PerfMapIndex) SymbolizeGDBLog(data []byte) []byte {
+	match := gdbInferiorPIDPattern.FindSubmatch(data)
+	if x == nil || match == nil {
+		return data
+	}
+	pid, err := strconv.Atoi(string(match[1]))
+	if err != nil {
+		return data
+	}
+	m, err := x.Get(pid)
+	if err != nil {
+		return data
+	}
+	symbolized, resolved := SymbolizeGDBBacktrace(string(data), m)
+	if resolved == 0 {
+		return data
+	}
+	return []byte(symbolized)
+}
@@ -0,0 +1,143 @@
+package debugadmin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const samplePerfMap = `7F1C2A3B4000 1A0 stub<1> AllocateTemporaryEntryPoints<PRECODE_STUB>
+7F1C2A3B4C00 80 void [MyApp] MyApp.Program::Main(string[])[Optimized]
+7F1C2A3B4D00 40 instance int32 [MyApp] MyApp.Worker::Step()[QuickJitted]
+7F1C2A3B5000 garbage line
+7F1C2A3B6000 20 instance void [MyApp] MyApp.Worker::Run(`
+
+func TestParsePerfMapLookup(t *testing.T) {
+	m, err := ParsePerfMap(strings.NewReader(samplePerfMap))
+	if err != nil {
+		t.Fatalf("ParsePerfMap() error = %v", err)
+	}
+	if m.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4 (the garbage line is skipped)", m.Len())
+	}
+	for addr, want := range map[uint64]string{
+		0x7F1C2A3B4C00: "void [MyApp] MyApp.Program::Main(string[])[Optimized]",
+		0x7F1C2A3B4D3F: "instance int32 [MyApp] MyApp.Worker::Step()[QuickJitted]",
+		0x7F1C2A3B4010: "stub<1> AllocateTemporaryEntryPoints<PRECODE_STUB>",
+	} {
+		if entry, ok := m.Lookup(addr); !ok || entry.Name != want {
+			t.Errorf("Lookup(%#x) = %+v %v, want %q", addr, entry, ok, want)
+		}
+	}
+	for _, addr := range []uint64{0x7F1C2A3B4D40, 0x1000, 0x7F1C2A3B4C80} {
+		if entry, ok := m.Lookup(addr); ok {
+			t.Errorf("Lookup(%#x) = %+v, want no match", addr, entry)
+		}
+	}
+	var empty *PerfMap
+	if _, ok := empty.Lookup(1); ok || empty.Len() != 0 {
+		t.Error("nil PerfMap should not match anything")
+	}
+}
+
+func TestSymbolizeGDBBacktrace(t *testing.T) {
+	m, err := ParsePerfMap(strings.NewReader(samplePerfMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := "Thread 1 (Thread 0x7f1c (LWP 42) \"dotnet\"):\n" +
+		"#0  0x00007f1c2b000000 in __futex_abstimed_wait_common64 () from /lib/libc.so.6\n" +
+		"#1  0x00007f1c2a3b4d10 in ?? ()\n" +
+		"#2  0x00007f1c2a3b4c20 in ?? ()\n" +
+		"#3  0x0000000000001000 in ?? ()"
+	symbolized, resolved := SymbolizeGDBBacktrace(output, m)
+	if resolved != 2 {
+		t.Errorf("resolved = %d, want 2", resolved)
+	}
+	for _, want := range []string{
+		"#1  0x00007f1c2a3b4d10 in [managed] instance int32 [MyApp] MyApp.Worker::Step()[QuickJitted] ()",
+		"#2  0x00007f1c2a3b4c20 in [managed] void [MyApp] MyApp.Program::Main(string[])[Optimized] ()",
+		"#3  0x0000000000001000 in ?? ()",
+		"in __futex_abstimed_wait_common64 () from /lib/libc.so.6",
+	} {
+		if !strings.Contains(symbolized, want) {
+			t.Errorf("symbolized output missing %q:\n%s", want, symbolized)
+		}
+	}
+	if got, n := SymbolizeGDBBacktrace(output, nil); got != output || n != 0 {
+		t.Error("SymbolizeGDBBacktrace() with a nil map should return the input unchanged")
+	}
+}
+
+func TestSymbolizeGDBLog(t *testing.T) {
+	// 用一个不会与真实进程冲突的大 pid 写一份 perf map
+	pid := 4000000 + os.Getpid()%100000
+	path := perfMapPath(pid)
+	if filepath.Dir(path) != "/tmp" {
+		t.Fatalf("perfMapPath(%d) = %s, want a file under /tmp", pid, path)
+	}
+	if err := os.WriteFile(path, []byte(samplePerfMap), 0o600); err != nil {
+		t.Skipf("write %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = os.Remove(path) })
+
+	log := "\tUsing the running image of child process " + strconv.Itoa(pid) + ".\n" +
+		"===== Crash backtrace =====\n#0  0x00007f1c2a3b4c20 in ?? ()\n"
+	index := NewPerfMapIndex()
+	if got := string(index.SymbolizeGDBLog([]byte(log))); !strings.Contains(got, "MyApp.Program::Main") {
+		t.Errorf("SymbolizeGDBLog() = %q, want the managed method name", got)
+	}
+	if got := string(index.SymbolizeGDBLog([]byte("#0  0x00007f1c2a3b4c20 in ?? ()"))); strings.Contains(got, "[managed]") {
+		t.Errorf("SymbolizeGDBLog() without a pid = %q, want it unchanged", got)
+	}
+}
+
+func TestPerfMapIndexIncremental(t *testing.T) {
+	pid := 4100000 + os.Getpid()%100000
+	path := perfMapPath(pid)
+	// 最后一行只写了一半，要等运行时写完换行之后才解析
+	if err := os.WriteFile(path, []byte("7F0000001000 100 void [MyApp] A::First()\n7F0000002000 10"), 0o600); err != nil {
+		t.Skipf("write %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = os.Remove(path) })
+
+	index := NewPerfMapIndex()
+	first, err := index.Get(pid)
+	if err != nil || first.Len() != 1 {
+		t.Fatalf("Get() = %d entries, %v; want 1 complete entry", first.Len(), err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString("0 void [MyApp] A::Second()\n7F0000000100 20 void [MyApp] A::Early()\n")
+	_ = file.Close()
+
+	second, err := index.Get(pid)
+	if err != nil || second.Len() != 3 {
+		t.Fatalf("Get() after append = %d entries, %v; want 3", second.Len(), err)
+	}
+	if first.Len() != 1 {
+		t.Error("a PerfMap returned earlier should not change after the file grows")
+	}
+	if entry, ok := index.Resolve(pid, 0x7F0000002005); !ok || entry.Name != "void [MyApp] A::Second()" {
+		t.Errorf("Resolve() = %+v %v, want A::Second", entry, ok)
+	}
+	if entry, ok := index.Resolve(pid, 0x7F0000000110); !ok || entry.Name != "void [MyApp] A::Early()" {
+		t.Errorf("Resolve() = %+v %v, want A::Early", entry, ok)
+	}
+
+	// 文件被重新创建（pid 被复用）时重新读取
+	if err := os.WriteFile(path, []byte("7F0000009000 10 void [Other] B::Only()\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if third, err := index.Get(pid); err != nil || third.Len() != 1 {
+		t.Errorf("Get() after rewrite = %d entries, %v; want 1", third.Len(), err)
+	}
+	if _, err := index.Get(pid + 1); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Get() for a pid without perf map error = %v, want os.ErrNotExist", err)
+	}
+}
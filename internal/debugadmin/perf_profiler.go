@@ -0,0 +1,294 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultPerfFrequency = 99 // 与 perf record 的默认值一样避开 100Hz，减少与定时任务同步造成的偏差
+	maxPerfFrequency     = 1000
+	// perfRingDataPages 是每个线程的 ring buffer 数据页数，必须是 2 的幂。
+	perfRingDataPages      = 16
+	perfDrainInterval      = 100 * time.Millisecond
+	perfTaskRescanInterval = time.Second
+)
+
+// perf_event_mmap_page 中 data_head / data_tail 的偏移，见 linux/perf_event.h。
+const (
+	perfMmapDataHeadOffset = 1024
+	perfMmapDataTailOffset = 1032
+)
+
+// callchain 中用来标记后续地址属于内核态还是用户态的特殊值，见 linux/perf_event.h。
+const (
+	perfContextKernel = ^uint64(128) + 1 // (u64)-128
+	perfContextUser   = ^uint64(512) + 1 // (u64)-512
+	perfContextMax    = ^uint64(4095) + 1
+)
+
+// PerfFrame 是一次采样中的一帧。
+type PerfFrame struct {
+	Addr   uint64
+	Kernel bool
+}
+
+// PerfSample 是一次采样得到的调用栈，Frames[0] 是栈顶。
+type PerfSample struct {
+	TID    int
+	Frames []PerfFrame
+}
+
+// PerfProfile 是一次 perf_event 采样的结果。
+type PerfProfile struct {
+	PID            int
+	Frequency      int
+	Start          time.Time
+	End            time.Time
+	Samples        []PerfSample
+	Lost           uint64         // ring buffer 写满时内核丢弃的采样数
+	Threads        map[int]string // tid -> 线程名
+	KernelExcluded bool           // 没有权限采集内核调用栈时只采集用户态
+}
+
+// perfThreadEvent 是一个线程上的采样事件及其 ring buffer。
+type perfThreadEvent struct {
+	fd   int
+	ring []byte
+}
+
+// perfSampler 对一个进程的所有线程采样。每个线程各自打开一个事件：
+// 按线程打开、cpu=-1 的事件不能通过 PERF_EVENT_IOC_SET_OUTPUT 共用 ring buffer。
+type perfSampler struct {
+	pid           int
+	frequency     int
+	excludeKernel bool
+	events        map[int]*perfThreadEvent
+	profile       *PerfProfile
+	pageSize      int
+	record        []byte
+}
+
+// CollectPerfProfile 用 perf_event_open 以 frequency Hz 对 pid 的所有线程采样 duration 时间，
+// 调用栈由内核按帧指针回溯（.NET JIT 代码与 Go 代码都保留帧指针）。采样期间新建的线程每秒补充一次。
+// stop 被关闭时提前结束并返回已经采到的数据；duration 为 0 时一直采样到 stop 被关闭。ctx 结束时放弃采样。
+func CollectPerfProfile(ctx context.Context, pid int, duration time.Duration, frequency int, stop <-chan struct{}) (*PerfProfile, error) {
+	if pid <= 0 {
+		return nil, errors.New("target process is not running")
+	}
+	if frequency <= 0 || frequency > maxPerfFrequency {
+		return nil, fmt.Errorf("perf frequency should be between 1 and %d, got %d", maxPerfFrequency, frequency)
+	}
+	sampler := &perfSampler{
+		pid:       pid,
+		frequency: frequency,
+		events:    make(map[int]*perfThreadEvent),
+		profile:   &PerfProfile{PID: pid, Frequency: frequency, Threads: make(map[int]string)},
+		pageSize:  os.Getpagesize(),
+	}
+	defer sampler.close()
+	if err := sampler.openThreads(); err != nil {
+		return nil, err
+	}
+	if len(sampler.events) == 0 {
+		return nil, fmt.Errorf("process %d has no threads to sample", pid)
+	}
+
+	sampler.profile.Start = time.Now()
+	var deadline <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	drain := time.NewTicker(perfDrainInterval)
+	defer drain.Stop()
+	rescan := time.NewTicker(perfTaskRescanInterval)
+	defer rescan.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return sampler.finish(), nil
+		case <-stop:
+			return sampler.finish(), nil
+		case <-drain.C:
+			sampler.drainAll()
+		case <-rescan.C:
+			// 进程退出时 openThreads 会失败，已经采到的数据仍然有效，在 deadline 时返回
+			_ = sampler.openThreads()
+		}
+	}
+}
+
+// finish 读出剩余的采样，结束 profile。
+func (s *perfSampler) finish() *PerfProfile {
+	s.drainAll()
+	s.profile.End = time.Now()
+	s.profile.KernelExcluded = s.excludeKernel
+	return s.profile
+}
+
+// openThreads 为还没有采样事件的线程打开事件。
+func (s *perfSampler) openThreads() error {
+	entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(s.pid), "task"))
+	if err != nil {
+		return fmt.Errorf("list threads of process %d: %w", s.pid, err)
+	}
+	var firstErr error
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil || s.events[tid] != nil {
+			continue
+		}
+		event, err := s.openThread(tid)
+		if err != nil {
+			if errors.Is(err, unix.ESRCH) {
+				continue // 线程已经退出
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		s.events[tid] = event
+		comm, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(s.pid), "task", entry.Name(), "comm"))
+		s.profile.Threads[tid] = strings.TrimSpace(string(comm))
+	}
+	if len(s.events) == 0 && firstErr != nil {
+		return firstErr
+	}
+	return nil
+}
+
+func (s *perfSampler) openThread(tid int) (*perfThreadEvent, error) {
+	attr := unix.PerfEventAttr{
+		Type:        unix.PERF_TYPE_SOFTWARE,
+		Config:      unix.PERF_COUNT_SW_CPU_CLOCK,
+		Sample:      uint64(s.frequency),
+		Sample_type: unix.PERF_SAMPLE_IP | unix.PERF_SAMPLE_TID | unix.PERF_SAMPLE_CALLCHAIN,
+		Bits:        unix.PerfBitFreq | unix.PerfBitExcludeHv,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	if s.excludeKernel {
+		attr.Bits |= unix.PerfBitExcludeKernel
+	}
+	fd, err := unix.PerfEventOpen(&attr, tid, -1, -1, unix.PERF_FLAG_FD_CLOEXEC)
+	if (errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM)) && !s.excludeKernel {
+		// perf_event_paranoid 不允许采集内核态时，退回到只采集用户态
+		s.excludeKernel = true
+		return s.openThread(tid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("perf_event_open for thread %d: %w", tid, err)
+	}
+	ring, err := unix.Mmap(fd, 0, (1+perfRingDataPages)*s.pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("mmap perf ring buffer for thread %d: %w", tid, err)
+	}
+	return &perfThreadEvent{fd: fd, ring: ring}, nil
+}
+
+func (s *perfSampler) drainAll() {
+	for _, event := range s.events {
+		s.drain(event)
+	}
+}
+
+// drain 读出 ring buffer 中 data_tail 到 data_head 之间的记录，再把 data_tail 推进到 data_head。
+func (s *perfSampler) drain(event *perfThreadEvent) {
+	head := atomic.LoadUint64((*uint64)(unsafe.Pointer(&event.ring[perfMmapDataHeadOffset])))
+	tailPointer := (*uint64)(unsafe.Pointer(&event.ring[perfMmapDataTailOffset]))
+	tail := atomic.LoadUint64(tailPointer)
+	data := event.ring[s.pageSize:]
+	for tail+8 <= head {
+		header := s.readRing(data, tail, 8)
+		recordSize := uint64(binary.NativeEndian.Uint16(header[6:8]))
+		if recordSize < 8 || tail+recordSize > head {
+			break
+		}
+		record := s.readRing(data, tail, recordSize)
+		switch binary.NativeEndian.Uint32(record[0:4]) {
+		case unix.PERF_RECORD_SAMPLE:
+			if sample, ok := parsePerfSampleRecord(record[8:]); ok {
+				s.profile.Samples = append(s.profile.Samples, sample)
+			}
+		case unix.PERF_RECORD_LOST:
+			if len(record) >= 24 {
+				s.profile.Lost += binary.NativeEndian.Uint64(record[16:24])
+			}
+		}
+		tail += recordSize
+	}
+	atomic.StoreUint64(tailPointer, tail)
+}
+
+// readRing 从环形的 data 区读出 [offset, offset+n) 的字节，处理跨越末尾的情况。
+func (s *perfSampler) readRing(data []byte, offset, n uint64) []byte {
+	size := uint64(len(data))
+	start := offset % size
+	if start+n <= size {
+		return data[start : start+n]
+	}
+	if uint64(cap(s.record)) < n {
+		s.record = make([]byte, n)
+	}
+	buf := s.record[:n]
+	copied := copy(buf, data[start:])
+	copy(buf[copied:], data[:n-uint64(copied)])
+	return buf
+}
+
+func (s *perfSampler) close() {
+	for _, event := range s.events {
+		_ = unix.Munmap(event.ring)
+		_ = unix.Close(event.fd)
+	}
+	s.events = nil
+}
+
+// parsePerfSampleRecord 解析 sample_type 为 IP|TID|CALLCHAIN 的 PERF_RECORD_SAMPLE 记录体（不含 8 字节的头）：
+// u64 ip; u32 pid, tid; u64 nr; u64 ips[nr]。callchain 中的 PERF_CONTEXT_* 标记后续地址属于内核态还是用户态。
+func parsePerfSampleRecord(body []byte) (PerfSample, bool) {
+	if len(body) < 24 {
+		return PerfSample{}, false
+	}
+	sample := PerfSample{TID: int(binary.NativeEndian.Uint32(body[12:16]))}
+	count := binary.NativeEndian.Uint64(body[16:24])
+	if count > uint64(len(body)-24)/8 {
+		return PerfSample{}, false
+	}
+	kernel := false
+	sample.Frames = make([]PerfFrame, 0, count)
+	for i := uint64(0); i < count; i++ {
+		addr := binary.NativeEndian.Uint64(body[24+i*8:])
+		if addr >= perfContextMax {
+			switch addr {
+			case perfContextKernel:
+				kernel = true
+			case perfContextUser:
+				kernel = false
+			}
+			continue
+		}
+		sample.Frames = append(sample.Frames, PerfFrame{Addr: addr, Kernel: kernel})
+	}
+	if len(sample.Frames) == 0 {
+		// 没有 callchain 时至少保留采样点的 ip
+		sample.Frames = append(sample.Frames, PerfFrame{Addr: binary.NativeEndian.Uint64(body[0:8])})
+	}
+	return sample, true
+}
@@ -0,0 +1,127 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const perfWorkloadEnv = "DEBUGADMIN_PERF_WORKLOAD"
+
+// TestPerfWorkloadHelper 不是真正的测试：TestCollectPerfProfile 以子进程的方式运行它，作为一个持续占用 CPU 的合成负载。
+func TestPerfWorkloadHelper(t *testing.T) {
+	if os.Getenv(perfWorkloadEnv) != "1" {
+		t.Skip("only runs as the workload process of TestCollectPerfProfile")
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		perfWorkloadSink += perfWorkloadSpin(1 << 20)
+	}
+	os.Exit(0)
+}
+
+var perfWorkloadSink uint64
+
+//go:noinline
+func perfWorkloadSpin(n int) uint64 {
+	var sum uint64
+	for i := 0; i < n; i++ {
+		sum = sum*31 + uint64(i)
+	}
+	return sum
+}
+
+func TestCollectPerfProfile(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPerfWorkloadHelper$")
+	cmd.Env = append(os.Environ(), perfWorkloadEnv+"=1")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start workload: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	// 等待子进程进入负载循环
+	time.Sleep(200 * time.Millisecond)
+
+	pid := cmd.Process.Pid
+	profile, err := CollectPerfProfile(context.Background(), pid, time.Second, 199, nil)
+	if err != nil {
+		t.Skipf("perf_event_open is not available here: %v", err)
+	}
+	if len(profile.Samples) == 0 {
+		t.Fatalf("no samples collected from the workload: %+v", profile)
+	}
+	mappings, err := readProcMaps(pid)
+	if err != nil {
+		t.Fatalf("readProcMaps() error = %v", err)
+	}
+
+	file := buildPerfSpeedscope(profile, newPerfSymbolizer(pid, mappings, nil))
+	if len(file.Profiles) < 2 || len(file.Profiles[0].Samples) != len(profile.Samples) {
+		t.Fatalf("speedscope profiles = %d, want all threads plus per-thread profiles", len(file.Profiles))
+	}
+	found := false
+	for _, frame := range file.Shared.Frames {
+		if strings.HasSuffix(frame.Name, ".perfWorkloadSpin") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("no frame symbolized as perfWorkloadSpin in %+v", file.Shared.Frames)
+	}
+}
+
+func TestParsePerfSampleRecord(t *testing.T) {
+	values := []uint64{0x401000, 7 | 9<<32, 5, perfContextKernel, 0xffffffff81000000, perfContextUser, 0x401000, 0x402000}
+	body := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.NativeEndian.PutUint64(body[i*8:], value)
+	}
+	sample, ok := parsePerfSampleRecord(body)
+	if !ok {
+		t.Fatal("parsePerfSampleRecord() failed")
+	}
+	want := []PerfFrame{{Addr: 0xffffffff81000000, Kernel: true}, {Addr: 0x401000}, {Addr: 0x402000}}
+	if sample.TID != 9 || len(sample.Frames) != len(want) {
+		t.Fatalf("sample = %+v, want tid 9 with %d frames", sample, len(want))
+	}
+	for i := range want {
+		if sample.Frames[i] != want[i] {
+			t.Errorf("frame %d = %+v, want %+v", i, sample.Frames[i], want[i])
+		}
+	}
+	if _, ok := parsePerfSampleRecord(body[:40]); ok {
+		t.Error("parsePerfSampleRecord() accepted a truncated callchain")
+	}
+}
+
+func TestPerfSymbolizerUsesPerfMapForAnonymousCode(t *testing.T) {
+	const pid = 999001
+	path := perfMapPath(pid)
+	if err := os.WriteFile(path, []byte("7f0000001000 100 [MyApp] MyApp.Program::Main()\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	mappings := parseProcMaps("7f0000000000-7f0000010000 rwxp 00000000 00:00 0 \n" +
+		"00400000-00452000 r-xp 00000000 08:02 173521 /usr/bin/app\n")
+	if len(mappings) != 2 || mappings[0].Path != "/usr/bin/app" || mappings[1].Path != "" {
+		t.Fatalf("parseProcMaps() = %+v", mappings)
+	}
+	symbolizer := newPerfSymbolizer(pid, mappings, NewPerfMapIndex())
+	if got := symbolizer.Symbolize(PerfFrame{Addr: 0x7f0000001010}); got.Name != "[MyApp] MyApp.Program::Main()" {
+		t.Errorf("JIT frame = %+v", got)
+	}
+	if got := symbolizer.Symbolize(PerfFrame{Addr: 0x7f0000009000}); got.Name != "0x7f0000009000" {
+		t.Errorf("unknown anonymous frame = %+v", got)
+	}
+	if got := symbolizer.Symbolize(PerfFrame{Addr: 0x401234}); got.Name != "app+0x1234" || got.Module != "app" {
+		t.Errorf("frame in a missing ELF file = %+v", got)
+	}
+}
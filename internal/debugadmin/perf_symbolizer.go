@@ -0,0 +1,292 @@
+package debugadmin
+
+import (
+	"bufio"
+	"debug/elf"
+	"debug/gosym"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procMapping 是 /proc/{pid}/maps 中的一段映射。
+type procMapping struct {
+	Start  uint64
+	End    uint64
+	Offset uint64
+	Path   string // 匿名映射为空，或者是 [heap]、[stack]、[anon:xxx] 之类的伪路径
+}
+
+// parseProcMaps 解析 /proc/{pid}/maps，结果按起始地址排序。
+// 每行格式为 "start-end perms offset dev inode path"。
+func parseProcMaps(content string) []procMapping {
+	var mappings []procMapping
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		startText, endText, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		start, err1 := strconv.ParseUint(startText, 16, 64)
+		end, err2 := strconv.ParseUint(endText, 16, 64)
+		offset, err3 := strconv.ParseUint(fields[2], 16, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		mapping := procMapping{Start: start, End: end, Offset: offset}
+		if len(fields) >= 6 {
+			mapping.Path = strings.Join(fields[5:], " ")
+		}
+		mappings = append(mappings, mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Start < mappings[j].Start })
+	return mappings
+}
+
+func findProcMapping(mappings []procMapping, addr uint64) (procMapping, bool) {
+	index := sort.Search(len(mappings), func(i int) bool { return mappings[i].Start > addr })
+	if index == 0 || addr >= mappings[index-1].End {
+		return procMapping{}, false
+	}
+	return mappings[index-1], true
+}
+
+// symbolTable 是按地址排序的符号，用于 ELF 文件和 /proc/kallsyms。
+type symbolTable struct {
+	addrs []uint64
+	sizes []uint64 // 0 表示未知，延伸到下一个符号
+	names []string
+}
+
+func (t *symbolTable) add(addr, size uint64, name string) {
+	t.addrs = append(t.addrs, addr)
+	t.sizes = append(t.sizes, size)
+	t.names = append(t.names, name)
+}
+
+func (t *symbolTable) sort() {
+	index := make([]int, len(t.addrs))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return t.addrs[index[i]] < t.addrs[index[j]] })
+	addrs := make([]uint64, len(index))
+	sizes := make([]uint64, len(index))
+	names := make([]string, len(index))
+	for i, from := range index {
+		addrs[i], sizes[i], names[i] = t.addrs[from], t.sizes[from], t.names[from]
+	}
+	t.addrs, t.sizes, t.names = addrs, sizes, names
+}
+
+func (t *symbolTable) lookup(addr uint64) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	index := sort.Search(len(t.addrs), func(i int) bool { return t.addrs[i] > addr }) - 1
+	if index < 0 {
+		return "", false
+	}
+	if t.sizes[index] > 0 && addr >= t.addrs[index]+t.sizes[index] {
+		return "", false
+	}
+	return t.names[index], true
+}
+
+// elfSymbols 是一个 ELF 文件的函数符号和 PT_LOAD 段，用于把映射中的文件偏移换算为符号地址。
+type elfSymbols struct {
+	symbols symbolTable
+	loads   []elf.ProgHeader
+}
+
+func loadELFSymbols(path string) (*elfSymbols, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	result := &elfSymbols{}
+	for _, prog := range file.Progs {
+		if prog.Type == elf.PT_LOAD {
+			result.loads = append(result.loads, prog.ProgHeader)
+		}
+	}
+	seen := make(map[uint64]bool)
+	for _, load := range []func() ([]elf.Symbol, error){file.Symbols, file.DynamicSymbols} {
+		symbols, err := load()
+		if err != nil {
+			continue // 被 strip 的文件没有 .symtab
+		}
+		for _, symbol := range symbols {
+			if elf.ST_TYPE(symbol.Info) != elf.STT_FUNC || symbol.Value == 0 || seen[symbol.Value] {
+				continue
+			}
+			seen[symbol.Value] = true
+			result.symbols.add(symbol.Value, symbol.Size, symbol.Name)
+		}
+	}
+	if file.Section(".symtab") == nil {
+		// 被 strip 的 Go 程序（go test、go run 默认如此）仍然保留 .gopclntab
+		addGoSymbols(file, &result.symbols)
+	}
+	result.symbols.sort()
+	return result, nil
+}
+
+func addGoSymbols(file *elf.File, symbols *symbolTable) {
+	pclntab, text := file.Section(".gopclntab"), file.Section(".text")
+	if pclntab == nil || text == nil {
+		return
+	}
+	data, err := pclntab.Data()
+	if err != nil {
+		return
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return
+	}
+	for _, fn := range table.Funcs {
+		symbols.add(fn.Entry, fn.End-fn.Entry, fn.Name)
+	}
+}
+
+// lookup 把文件偏移换算为 PT_LOAD 段中的虚拟地址后查找符号。
+func (e *elfSymbols) lookup(fileOffset uint64) (string, bool) {
+	for _, load := range e.loads {
+		if fileOffset >= load.Off && fileOffset < load.Off+load.Filesz {
+			return e.symbols.lookup(fileOffset - load.Off + load.Vaddr)
+		}
+	}
+	return "", false
+}
+
+// perfSymbolizer 把 perf 采样得到的地址还原为函数名：
+// 用户态地址先找所在映射的 ELF 符号，匿名映射（JIT 代码）用 .NET perf map，内核地址用 /proc/kallsyms。
+type perfSymbolizer struct {
+	pid      int
+	mappings []procMapping
+	perfMaps *PerfMapIndex
+	elfs     map[string]*elfSymbols // 路径 -> 符号，读取失败时为 nil
+	kernel   *symbolTable
+	kernelOK bool
+	cache    map[PerfFrame]perfSymbol
+}
+
+// perfSymbol 是还原后的一帧：函数名与所在模块。
+type perfSymbol struct {
+	Name   string
+	Module string
+}
+
+// readProcMaps 读取 pid 当前的内存映射，必须在进程退出之前调用。
+func readProcMaps(pid int) ([]procMapping, error) {
+	maps, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "maps"))
+	if err != nil {
+		return nil, fmt.Errorf("read memory maps of process %d: %w", pid, err)
+	}
+	return parseProcMaps(string(maps)), nil
+}
+
+// newPerfSymbolizer 创建符号还原器。mappings 为空时（进程已经退出）只能用 perf map 还原 JIT 代码。
+func newPerfSymbolizer(pid int, mappings []procMapping, perfMaps *PerfMapIndex) *perfSymbolizer {
+	return &perfSymbolizer{
+		pid:      pid,
+		mappings: mappings,
+		perfMaps: perfMaps,
+		elfs:     make(map[string]*elfSymbols),
+		cache:    make(map[PerfFrame]perfSymbol),
+	}
+}
+
+func (s *perfSymbolizer) Symbolize(frame PerfFrame) perfSymbol {
+	if symbol, ok := s.cache[frame]; ok {
+		return symbol
+	}
+	var symbol perfSymbol
+	if frame.Kernel {
+		symbol = s.symbolizeKernel(frame.Addr)
+	} else {
+		symbol = s.symbolizeUser(frame.Addr)
+	}
+	s.cache[frame] = symbol
+	return symbol
+}
+
+func (s *perfSymbolizer) symbolizeUser(addr uint64) perfSymbol {
+	mapping, mapped := findProcMapping(s.mappings, addr)
+	if mapped && strings.HasPrefix(mapping.Path, "/") {
+		module := filepath.Base(mapping.Path)
+		if symbols := s.elf(mapping.Path); symbols != nil {
+			if name, ok := symbols.lookup(addr - mapping.Start + mapping.Offset); ok {
+				return perfSymbol{Name: name, Module: module}
+			}
+		}
+		// .NET 程序集（R2R 代码）不是 ELF 文件，也可能出现在 perf map 中
+		if entry, ok := s.perfMaps.Resolve(s.pid, addr); ok {
+			return perfSymbol{Name: entry.Name, Module: "[perf-map]"}
+		}
+		return perfSymbol{Name: fmt.Sprintf("%s+0x%x", module, addr-mapping.Start+mapping.Offset), Module: module}
+	}
+	if entry, ok := s.perfMaps.Resolve(s.pid, addr); ok {
+		return perfSymbol{Name: entry.Name, Module: "[perf-map]"}
+	}
+	return perfSymbol{Name: fmt.Sprintf("0x%x", addr), Module: "[unknown]"}
+}
+
+func (s *perfSymbolizer) elf(path string) *elfSymbols {
+	if symbols, ok := s.elfs[path]; ok {
+		return symbols
+	}
+	// 优先通过 /proc/{pid}/root 读取，目标进程在不同的 mount namespace 中时也能找到文件
+	symbols, err := loadELFSymbols(filepath.Join("/proc", strconv.Itoa(s.pid), "root", path))
+	if err != nil {
+		symbols, _ = loadELFSymbols(path)
+	}
+	s.elfs[path] = symbols
+	return symbols
+}
+
+func (s *perfSymbolizer) symbolizeKernel(addr uint64) perfSymbol {
+	if !s.kernelOK {
+		s.kernel = loadKallsyms()
+		s.kernelOK = true
+	}
+	if name, ok := s.kernel.lookup(addr); ok {
+		return perfSymbol{Name: name, Module: "[kernel]"}
+	}
+	return perfSymbol{Name: "[kernel]", Module: "[kernel]"}
+}
+
+// loadKallsyms 读取内核符号。kptr_restrict 生效时地址全部为 0，此时返回 nil。
+func loadKallsyms() *symbolTable {
+	file, err := os.Open("/proc/kallsyms")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	table := &symbolTable{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || (fields[1] != "t" && fields[1] != "T") {
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil || addr == 0 {
+			continue
+		}
+		table.add(addr, 0, fields[2])
+	}
+	if len(table.addrs) == 0 {
+		return nil
+	}
+	table.sort()
+	return table
+}
@@ -0,0 +1,84 @@
+package debugadmin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildPerfSpeedscope 把 perf 采样转换为 speedscope 文件：第一个 profile 包含所有线程，之后每个线程一个，采样多的在前。
+// 每个采样的权重是采样间隔（毫秒），speedscope 中显示的就是 CPU 时间。
+func buildPerfSpeedscope(profile *PerfProfile, symbolizer *perfSymbolizer) *speedscopeFile {
+	builder := newSpeedscopeBuilder(fmt.Sprintf("perf cpu profile of process %d", profile.PID))
+	weight := 1000 / float64(profile.Frequency)
+	duration := float64(profile.End.Sub(profile.Start)) / float64(time.Millisecond)
+
+	all := speedscopeProfile{
+		Type:     "sampled",
+		Name:     fmt.Sprintf("all threads (%d Hz)", profile.Frequency),
+		Unit:     "milliseconds",
+		EndValue: duration,
+	}
+	perThread := make(map[int]*speedscopeProfile)
+	for _, sample := range profile.Samples {
+		stack := make([]int, 0, len(sample.Frames))
+		for i := len(sample.Frames) - 1; i >= 0; i-- {
+			symbol := symbolizer.Symbolize(sample.Frames[i])
+			stack = append(stack, builder.frame(symbol.Name, symbol.Module))
+		}
+		all.Samples = append(all.Samples, stack)
+		all.Weights = append(all.Weights, weight)
+
+		thread := perThread[sample.TID]
+		if thread == nil {
+			thread = &speedscopeProfile{
+				Type:     "sampled",
+				Name:     perfThreadName(sample.TID, profile.Threads[sample.TID]),
+				Unit:     "milliseconds",
+				EndValue: duration,
+			}
+			perThread[sample.TID] = thread
+		}
+		thread.Samples = append(thread.Samples, stack)
+		thread.Weights = append(thread.Weights, weight)
+	}
+
+	builder.addProfile(all)
+	tids := make([]int, 0, len(perThread))
+	for tid := range perThread {
+		tids = append(tids, tid)
+	}
+	sort.Slice(tids, func(i, j int) bool {
+		left, right := len(perThread[tids[i]].Samples), len(perThread[tids[j]].Samples)
+		if left != right {
+			return left > right
+		}
+		return tids[i] < tids[j]
+	})
+	for _, tid := range tids {
+		builder.addProfile(*perThread[tid])
+	}
+	return &builder.file
+}
+
+func perfThreadName(tid int, comm string) string {
+	if comm == "" {
+		return strconv.Itoa(tid)
+	}
+	return fmt.Sprintf("%d (%s)", tid, comm)
+}
+
+// parsePerfFrequency 读取 perf 的 frequency 参数，单位 Hz，省略时为 defaultPerfFrequency。
+func parsePerfFrequency(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultPerfFrequency, nil
+	}
+	frequency, err := strconv.Atoi(raw)
+	if err != nil || frequency < 1 || frequency > maxPerfFrequency {
+		return 0, fmt.Errorf("frequency must be between 1 and %d", maxPerfFrequency)
+	}
+	return frequency, nil
+}
@@ -206,6 +206,50 @@ func readProcessRSSBytes(pid int) uint64 {
 	return 0
 }
 
+// readProcessTracerPID 返回 /proc/[pid]/status 中的 TracerPid：正在 ptrace 这个进程的调试器 pid，没有时为 0。
+func readProcessTracerPID(pid int) int {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "TracerPid:" {
+			tracer, _ := strconv.Atoi(fields[1])
+			return tracer
+		}
+	}
+	return 0
+}
+
+// isProcessStoppedByTracer 返回 pid 是否被 tracer ptrace 并且处于 tracing stop（/proc/[pid]/status 中 State 为 t）。
+// TracerPid 是调用 ptrace 的线程 id，不一定是 tracer 的主线程，因此在 tracer 的 task 目录中查找。
+func isProcessStoppedByTracer(pid, tracer int) bool {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	stopped, traced := false, false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "State:":
+			stopped = fields[1] == "t"
+		case "TracerPid:":
+			tid, _ := strconv.Atoi(fields[1])
+			traced = tid == tracer || (tid > 0 && fileExists(fmt.Sprintf("/proc/%d/task/%d", tracer, tid)))
+		}
+	}
+	return stopped && traced
+}
+
 // readProcessThreadCount 统计 /proc/[pid]/task 目录下的条目数，
 // 每个条目对应一个物理线程。
 func readProcessThreadCount(pid int) int {
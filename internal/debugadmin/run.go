@@ -23,6 +23,15 @@ import (
 
 const (
 	defaultPort = 8089
+	// defaultStopTimeout 是手动停止子进程时，SIGTERM 之后等待进程退出的时间
+	defaultStopTimeout  = 10 * time.Second
+	defaultHangSamples  = 5
+	defaultHangInterval = 2 * time.Second
+
+	defaultCPUTraceFor      = 30 * time.Second
+	defaultCPUTraceDuration = 30 * time.Second
+	defaultCPUTraceCooldown = 10 * time.Minute
+
 	//configPath  = "init.config.yaml"
 	vectorCfg = "/tmp/vector.toml"
 )
@@ -67,17 +76,13 @@ func Run(staticFS fs.FS, vectorTOMLTemplate *template.Template) int {
 		}()
 	}
 
-	broker := NewLogBroker()
-	history := NewRunHistory()
-	// 创建子进程
-	target, err := StartTarget(broker, vectorStdin, options.LogStdoutOutput, history)
+	supervisors, err := startSupervisors(options, vectorStdin)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "start target process failed: %v\n", err)
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
 		return 1
 	}
-	_, _ = fmt.Fprintf(os.Stdout, "target process started, pid=%d\n", target.PID())
 
-	server, handler, err := NewHTTPServer(staticFS, vectorTOMLTemplate, broker, target, history)
+	server, err := NewHTTPServer(staticFS, vectorTOMLTemplate, supervisors)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "create http server failed: %v\n", err)
 		return 1
@@ -95,25 +100,33 @@ func Run(staticFS fs.FS, vectorTOMLTemplate *template.Template) int {
 		<-serverErrCh
 	}
 
+	exitCh := mergeTargetExits(supervisors)
 	for {
 		select {
-		case targetErr := <-target.Done():
-			_, _, abnormal := classifyExit(targetErr)
+		case exit := <-exitCh:
+			// 手动停止的退出不会出现在这里，DebugAdmin 会继续运行，等待管理端重新启动子进程。
+			supervisor, targetErr := exit.supervisor, exit.Err
 			// 开启 auto.restart 且进程异常退出时，立即重建子进程；正常退出则忽略。
-			if options.AutoRestart && abnormal {
-				_, _ = fmt.Fprintf(os.Stdout, "target process crashed (err=%v), restarting...\n", targetErr)
-				newTarget, restartErr := StartTarget(broker, vectorStdin, options.LogStdoutOutput, history)
-				if restartErr != nil {
-					_, _ = fmt.Fprintf(os.Stderr, "restart target process failed: %v\n", restartErr)
-					shutdown()
-					return 1
+			if supervisor.AutoRestart() && exit.Target.reason == RunReasonCrash {
+				_, _ = fmt.Fprintf(os.Stdout, "target process %q crashed (err=%v), restarting...\n", supervisor.Name(), targetErr)
+				newTarget, restartErr := supervisor.RestartAfterCrash(exit.Target)
+				switch {
+				case restartErr == nil && newTarget == nil:
+					_, _ = fmt.Fprintf(os.Stdout, "target process %q was already started again from the admin page\n", supervisor.Name())
+					continue
+				case restartErr == nil:
+					_, _ = fmt.Fprintf(os.Stdout, "target process %q restarted, pid=%d\n", supervisor.Name(), newTarget.PID())
+					continue
 				}
-				target = newTarget
-				handler.SetTarget(newTarget)
-				_, _ = fmt.Fprintf(os.Stdout, "target process restarted, pid=%d\n", target.PID())
+				// 重启失败时这个目标进程按已结束处理，其他目标进程继续运行；全部结束时才退出 DebugAdmin。
+				_, _ = fmt.Fprintf(os.Stderr, "restart target process %q failed: %v\n", supervisor.Name(), restartErr)
+				targetErr = restartErr
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "target process %q finished, err=%v\n", supervisor.Name(), targetErr)
+			if !allTargetsFinished(supervisors) {
 				continue
 			}
-			_, _ = fmt.Fprintf(os.Stdout, "target process finished, DebugAdmin will exit, err=%v\n", targetErr)
+			_, _ = fmt.Fprintln(os.Stdout, "all target processes finished, DebugAdmin will exit")
 			shutdown()
 			if targetErr != nil {
 				return 1
@@ -129,6 +142,55 @@ func Run(staticFS fs.FS, vectorTOMLTemplate *template.Template) int {
 	}
 }
 
+// startSupervisors 为每个目标进程创建 TargetSupervisor 并启动子进程。
+// 任意一个启动失败时，停止已经启动的子进程并返回 error。
+func startSupervisors(options *Options, vectorStdin io.Writer) ([]*TargetSupervisor, error) {
+	supervisors := make([]*TargetSupervisor, 0, len(options.Targets))
+	for _, targetOpts := range options.Targets {
+		supervisor := NewTargetSupervisor(targetOpts, vectorStdin, options.LogStdoutOutput, options.StopTimeout)
+		// 创建子进程
+		target, err := supervisor.Start(RunReasonInitial)
+		if err != nil {
+			for _, started := range supervisors {
+				_ = started.Stop()
+			}
+			return nil, fmt.Errorf("start target process %q failed: %w", targetOpts.Name, err)
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "target process %q started, pid=%d\n", targetOpts.Name, target.PID())
+		supervisors = append(supervisors, supervisor)
+	}
+	return supervisors, nil
+}
+
+// supervisedExit 是带上所属 TargetSupervisor 的 TargetExit。
+type supervisedExit struct {
+	TargetExit
+	supervisor *TargetSupervisor
+}
+
+// mergeTargetExits 把所有目标进程的退出事件汇总到一个 channel。
+func mergeTargetExits(supervisors []*TargetSupervisor) <-chan supervisedExit {
+	merged := make(chan supervisedExit)
+	for _, supervisor := range supervisors {
+		go func(supervisor *TargetSupervisor) {
+			for exit := range supervisor.Exits() {
+				merged <- supervisedExit{TargetExit: exit, supervisor: supervisor}
+			}
+		}(supervisor)
+	}
+	return merged
+}
+
+// allTargetsFinished 返回是否所有目标进程都已经自行退出，此时 DebugAdmin 也随之退出。
+func allTargetsFinished(supervisors []*TargetSupervisor) bool {
+	for _, supervisor := range supervisors {
+		if !supervisor.Finished() {
+			return false
+		}
+	}
+	return true
+}
+
 func loadOptions(args []string) (*Options, error) {
 	var startupParams []string
 	for idx, item := range args {
@@ -138,14 +200,44 @@ func loadOptions(args []string) (*Options, error) {
 			break
 		}
 	}
+	targetGroups, err := splitTargetGroups(startupParams)
+	if err != nil {
+		return nil, err
+	}
 	port := defaultPort
 	//startup := cfg.Startup
 	logPushURL := ""
 	logStdoutOutput := true
 	coreDumpUnlimited := false
+	coreDumpDir := defaultCoreDumpDir
+	coreDumpMaxSize := defaultCoreDumpMaxSize
+	createDump := false
+	createDumpType := defaultCreateDumpType
+	coreDumpAnalyze := true
+	coreDumpAnalyzeTimeout := defaultCrashAnalyzeTimeout
+	perfMap := false
 	autoRestart := false
 	withGDB := false
+	var gdbStopSignals stringSliceFlag
+	var gdbCommands stringSliceFlag
+	gdbScriptFile := ""
+	gdbHold := time.Duration(0)
 	withCoverage := false
+	stopTimeout := defaultStopTimeout
+	hangLogSilence := time.Duration(0)
+	hangSamples := defaultHangSamples
+	hangInterval := defaultHangInterval
+	trackExceptions := false
+	cpuTraceThreshold := 0.0
+	cpuTraceFor := defaultCPUTraceFor
+	cpuTraceKind := TraceKindCPU
+	cpuTraceDuration := defaultCPUTraceDuration
+	cpuTraceCooldown := defaultCPUTraceCooldown
+	notifyBaseURL := ""
+	notifyCrashLoopCount := defaultCrashLoopCount
+	notifyCrashLoopWindow := defaultCrashLoopWindow
+	var notifyWebhooks stringSliceFlag
+	configPath := ""
 	coverageXMLSettingsFile := ""
 	coverageSourceDirs := ""
 	coverageSourceFromPDB := false
@@ -153,13 +245,39 @@ func loadOptions(args []string) (*Options, error) {
 
 	flagSet := flag.NewFlagSet("DebugAdmin", flag.ContinueOnError)
 	flagSet.SetOutput(io.Discard)
+	flagSet.StringVar(&configPath, "config", configPath, "path to a yaml config file (e.g. init.config.yaml); command-line flags take precedence over it")
 	flagSet.IntVar(&port, "admin.port", port, "http service listen port")
 	//flagSet.StringVar(&startup, "startup", startup, "startup dll or executable")
 	flagSet.StringVar(&logPushURL, "log.push.url", logPushURL, "push logs to remote endpoint URL via vector")
 	flagSet.BoolVar(&logStdoutOutput, "log.stdout.output", logStdoutOutput, "output target process stdout/stderr to DebugAdmin stdout/stderr")
 	flagSet.BoolVar(&coreDumpUnlimited, "coredump.unlimited", coreDumpUnlimited, "set the core dump size limit to unlimited")
+	flagSet.StringVar(&coreDumpDir, "coredump.dir", coreDumpDir, "directory where crash and on-demand core dumps are stored gzip-compressed; see /coredumps")
+	flagSet.StringVar(&coreDumpMaxSize, "coredump.max.size", coreDumpMaxSize, "total size of the compressed core dumps to keep, e.g. 10GiB; the oldest are deleted beyond it")
+	flagSet.BoolVar(&createDump, "coredump.createdump", createDump, "start the target process with DOTNET_DbgEnableMiniDump=1 so the runtime writes a dump with createdump when it crashes")
+	flagSet.StringVar(&createDumpType, "coredump.createdump.type", createDumpType, "type of the dump written by createdump on crash: Mini, Heap, Triage or Full")
+	flagSet.BoolVar(&coreDumpAnalyze, "coredump.analyze", coreDumpAnalyze, "analyze the core of a crash with gdb and dotnet-dump before compressing it and show a crash report")
+	flagSet.DurationVar(&coreDumpAnalyzeTimeout, "coredump.analyze.timeout", coreDumpAnalyzeTimeout, "time limit of the analysis of a crash core")
+	flagSet.BoolVar(&perfMap, "perf.map", perfMap, "start the target process with DOTNET_PerfMapEnabled=1 so JIT frames can be symbolized from /tmp/perf-{pid}.map")
 	flagSet.BoolVar(&autoRestart, "auto.restart", autoRestart, "automatically restart the target process when it crashes")
+	flagSet.DurationVar(&stopTimeout, "target.stop.timeout", stopTimeout, "how long a manual stop waits after SIGTERM before sending SIGKILL")
+	flagSet.DurationVar(&hangLogSilence, "hang.log.silence", hangLogSilence, "run hang detection automatically when the target process writes no log for this long; 0 disables it")
+	flagSet.IntVar(&hangSamples, "hang.samples", hangSamples, "number of stack samples taken by hang detection")
+	flagSet.DurationVar(&hangInterval, "hang.interval", hangInterval, "interval between stack samples taken by hang detection")
+	flagSet.BoolVar(&trackExceptions, "exceptions.track", trackExceptions, "count first-chance exceptions of the target process in the background through EventPipe; see /exceptions and /metrics")
+	flagSet.Float64Var(&cpuTraceThreshold, "trace.cpu.threshold", cpuTraceThreshold, "start a background trace automatically when the target's CPU usage (percent, 100 = one core) stays at or above this for trace.cpu.for; 0 disables it")
+	flagSet.DurationVar(&cpuTraceFor, "trace.cpu.for", cpuTraceFor, "how long the CPU usage must stay above trace.cpu.threshold before a trace starts")
+	flagSet.StringVar(&cpuTraceKind, "trace.cpu.kind", cpuTraceKind, "kind of the automatic trace: cpu, perf or alloc")
+	flagSet.DurationVar(&cpuTraceDuration, "trace.cpu.duration", cpuTraceDuration, "duration of the automatic trace")
+	flagSet.DurationVar(&cpuTraceCooldown, "trace.cpu.cooldown", cpuTraceCooldown, "minimum interval between two automatic traces")
+	flagSet.Var(&notifyWebhooks, "notify.webhook", "POST crash, crash loop and trigger notifications to this webhook; the format (slack, wecom, dingtalk, feishu or generic JSON) follows the host; can be specified multiple times")
+	flagSet.StringVar(&notifyBaseURL, "notify.base.url", notifyBaseURL, "prefix of the links in notifications, e.g. http://10.0.0.1:8080; defaults to http://<hostname>:<admin.port>")
+	flagSet.IntVar(&notifyCrashLoopCount, "notify.crashloop.count", notifyCrashLoopCount, "send one crash_loop notification instead of per-crash ones when a target crashes this many times within notify.crashloop.window; 0 disables it")
+	flagSet.DurationVar(&notifyCrashLoopWindow, "notify.crashloop.window", notifyCrashLoopWindow, "time window of crash loop detection")
 	flagSet.BoolVar(&withGDB, "with.gdb", withGDB, "start the target process with gdb")
+	flagSet.Var(&gdbStopSignals, "gdb.stop.signals", "signals that stop the target under gdb and capture the crash, comma-separated or specified multiple times; defaults to SIGSEGV,SIGABRT,SIGBUS,SIGILL,SIGFPE")
+	flagSet.Var(&gdbCommands, "gdb.commands", "extra gdb command run after the crash backtrace and registers are captured, e.g. \"thread apply all bt full\"; can be specified multiple times")
+	flagSet.StringVar(&gdbScriptFile, "gdb.script", gdbScriptFile, "gdb script file run after gdb.commands when the target crashes; it is read on every start and copied into the saved crash script")
+	flagSet.DurationVar(&gdbHold, "gdb.hold", gdbHold, "keep the target stopped under gdb for this long after a stop signal, so it can be inspected from /gdb-console before it is killed; 0 kills it right after the capture")
 	flagSet.BoolVar(&withCoverage, "with.coverage", withCoverage, "start the target process with dotnet-coverage to collect code coverage")
 	flagSet.Var(&excludeRegexpPatternsForCoverage, "coverage.exclude.re", "regexp pattern of files to exclude from code coverage; can be specified multiple times")
 	flagSet.StringVar(&coverageXMLSettingsFile, "coverage.xml.settings", coverageXMLSettingsFile, "path to a dotnet-coverage settings xml file, passed via --settings when collecting coverage")
@@ -168,9 +286,36 @@ func loadOptions(args []string) (*Options, error) {
 	if err := flagSet.Parse(args); err != nil {
 		return nil, err
 	}
+	sources := make(map[string]string)
+	flagSet.Visit(func(f *flag.Flag) { sources[f.Name] = ConfigSourceFlag })
+	if err := applyEnvOverrides(flagSet, sources); err != nil {
+		return nil, err
+	}
+	var cfg *configFile
+	if configPath = strings.TrimSpace(configPath); configPath != "" {
+		if cfg, err = loadConfigFile(configPath, flagSet); err != nil {
+			return nil, err
+		}
+		if err := cfg.apply(flagSet, sources); err != nil {
+			return nil, err
+		}
+	}
 	if withGDB && withCoverage {
 		return nil, errors.New("-with.gdb and -with.coverage cannot be used together")
 	}
+	stopSignals, err := parseGDBSignals(gdbStopSignals)
+	if err != nil {
+		return nil, fmt.Errorf("gdb.stop.signals: %w", err)
+	}
+	gdbScriptFile = strings.TrimSpace(gdbScriptFile)
+	if gdbScriptFile != "" {
+		if _, err := os.Stat(gdbScriptFile); err != nil {
+			return nil, fmt.Errorf("gdb.script: %w", err)
+		}
+	}
+	if gdbHold < 0 {
+		return nil, errors.New("gdb.hold must not be negative")
+	}
 	excludeRegexpsForCoverage, err := compileExcludeRegexpsForCoverage(excludeRegexpPatternsForCoverage)
 	if err != nil {
 		return nil, err
@@ -185,23 +330,140 @@ func loadOptions(args []string) (*Options, error) {
 	if err != nil {
 		return nil, err
 	}
+	if stopTimeout <= 0 {
+		return nil, fmt.Errorf("target.stop.timeout should be positive, got %s", stopTimeout)
+	}
+	if hangLogSilence < 0 {
+		return nil, fmt.Errorf("hang.log.silence should not be negative, got %s", hangLogSilence)
+	}
+	if hangSamples < 2 || hangInterval <= 0 || time.Duration(hangSamples-1)*hangInterval > maxHangDuration {
+		return nil, fmt.Errorf("hang.samples should be at least 2 and hang.interval positive, with (samples-1) × interval at most %s", maxHangDuration)
+	}
+	if cpuTraceThreshold < 0 {
+		return nil, fmt.Errorf("trace.cpu.threshold should not be negative, got %g", cpuTraceThreshold)
+	}
+	if cpuTraceKind, err = ParseTraceKind(strings.TrimSpace(cpuTraceKind)); err != nil {
+		return nil, fmt.Errorf("trace.cpu.kind: %w", err)
+	}
+	if cpuTraceFor <= 0 || cpuTraceDuration < time.Second || cpuTraceDuration > maxTraceDuration || cpuTraceCooldown < 0 {
+		return nil, fmt.Errorf("trace.cpu.for should be positive, trace.cpu.duration between 1s and %s, trace.cpu.cooldown not negative", maxTraceDuration)
+	}
 	if port < 1 || port > 65535 {
 		return nil, fmt.Errorf("admin.port should be between 1 and 65535, got %d", port)
 	}
+	coreDumpDir = strings.TrimSpace(coreDumpDir)
+	if coreDumpDir == "" {
+		return nil, errors.New("coredump.dir should not be empty")
+	}
+	coreDumpMaxBytes, err := parseByteSize(coreDumpMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("coredump.max.size: %w", err)
+	}
+	if _, ok := createDumpTypes[createDumpType]; !ok {
+		return nil, fmt.Errorf("coredump.createdump.type should be Mini, Heap, Triage or Full, got %q", createDumpType)
+	}
+	if coreDumpAnalyzeTimeout <= 0 {
+		return nil, errors.New("coredump.analyze.timeout should be positive")
+	}
+	if notifyCrashLoopCount < 0 || notifyCrashLoopWindow <= 0 {
+		return nil, errors.New("notify.crashloop.count should not be negative and notify.crashloop.window should be positive")
+	}
+	notifyBaseURL = strings.TrimSpace(notifyBaseURL)
+	if notifyBaseURL == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "localhost"
+		}
+		notifyBaseURL = fmt.Sprintf("http://%s:%d", host, port)
+	}
+	var notifyEndpoints []NotificationEndpoint
+	for _, raw := range notifyWebhooks {
+		endpoint, err := newNotificationEndpoint("", raw, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("notify.webhook: %w", err)
+		}
+		notifyEndpoints = append(notifyEndpoints, endpoint)
+	}
+	if cfg != nil {
+		endpoints, err := cfg.notificationEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		notifyEndpoints = append(notifyEndpoints, endpoints...)
+	}
 	//startup = strings.TrimSpace(startup)
-	if len(startupParams) == 0 {
-		return nil, errors.New("startup is required; use -- <startup command>")
+	// 目标进程的命令行优先级：-- 之后的参数 > DEBUGADMIN_TARGET > 配置文件中的 targets
+	targetsSource := ConfigSourceFlag
+	if len(targetGroups) == 0 {
+		if targetGroups, err = targetGroupsFromEnv(); err != nil {
+			return nil, err
+		}
+		targetsSource = ConfigSourceEnv
+	}
+	for i := range targetGroups {
+		targetGroups[i].AutoRestart = autoRestart
+	}
+	if len(targetGroups) == 0 && cfg != nil {
+		if targetGroups, err = cfg.targetOptions(autoRestart); err != nil {
+			return nil, err
+		}
+		targetsSource = ConfigSourceFile
+	}
+	if len(targetGroups) == 0 {
+		return nil, fmt.Errorf("startup is required; use -- <startup command>, %s or targets in the config file", targetCommandEnv)
+	}
+	var triggers []TriggerRule
+	if cfg != nil {
+		names := make([]string, 0, len(targetGroups))
+		for _, target := range targetGroups {
+			names = append(names, target.Name)
+		}
+		if triggers, err = cfg.triggerRules(names); err != nil {
+			return nil, err
+		}
+	}
+	cpuTrace := CPUTraceOptions{
+		Threshold: cpuTraceThreshold,
+		For:       cpuTraceFor,
+		Kind:      cpuTraceKind,
+		Duration:  cpuTraceDuration.Round(time.Second),
+		Cooldown:  cpuTraceCooldown,
+	}
+	if rule, ok := cpuTrace.TriggerRule(); ok {
+		for _, existing := range triggers {
+			if existing.Name == rule.Name {
+				return nil, fmt.Errorf("config file: trigger name %q is reserved for -trace.cpu.threshold", rule.Name)
+			}
+		}
+		triggers = append(triggers, rule)
 	}
 	logPushURL = strings.TrimSpace(logPushURL)
 	return &Options{
 		AdminPort:         port,
-		StartupParams:     startupParams,
+		StartupParams:     targetGroups[0].StartupParams,
+		Targets:           targetGroups,
 		LogPushURL:        logPushURL,
 		LogStdoutOutput:   logStdoutOutput,
 		CoreDumpUnlimited: coreDumpUnlimited,
-		AutoRestart:       autoRestart,
-		WithGDB:           withGDB,
-		WithCoverage:      withCoverage,
+		CoreDump: CoreDumpOptions{
+			Dir:            coreDumpDir,
+			MaxSize:        int64(coreDumpMaxBytes),
+			CreateDump:     createDump,
+			CreateDumpType: createDumpType,
+			Analyze:        coreDumpAnalyze,
+			AnalyzeTimeout: coreDumpAnalyzeTimeout,
+		},
+		PerfMap:     perfMap,
+		AutoRestart: autoRestart,
+		StopTimeout: stopTimeout,
+		WithGDB:     withGDB,
+		GDBScript: GDBScriptOptions{
+			StopSignals: stopSignals,
+			Commands:    splitNonEmptyLines(strings.Join(gdbCommands, "\n")),
+			ScriptFile:  gdbScriptFile,
+			Hold:        gdbHold,
+		},
+		WithCoverage: withCoverage,
 		CoverageOpts: CoverageOptions{
 			CoverageName:              uuid.NewString(),
 			CoverageXMLSettingsFile:   coverageXMLSettingsFile,
@@ -209,9 +471,86 @@ func loadOptions(args []string) (*Options, error) {
 			SourceDirs:                coverageSourceDirs,
 			SourceFromPDB:             coverageSourceFromPDB,
 		},
+		Hang: HangOptions{
+			LogSilence: hangLogSilence,
+			Samples:    hangSamples,
+			Interval:   hangInterval,
+		},
+		TrackExceptions: trackExceptions,
+		Triggers:        triggers,
+		Notify: NotifyOptions{
+			Endpoints:       notifyEndpoints,
+			BaseURL:         notifyBaseURL,
+			CrashLoopCount:  notifyCrashLoopCount,
+			CrashLoopWindow: notifyCrashLoopWindow,
+		},
+		ConfigPath:    configPath,
+		Effective:     collectEffectiveConfig(flagSet, sources),
+		TargetsSource: targetsSource,
 	}, nil
 }
 
+// collectEffectiveConfig 列出所有参数的生效值及其来源，供 /config 页面展示。
+func collectEffectiveConfig(flagSet *flag.FlagSet, sources map[string]string) []ConfigEntry {
+	entries := make([]ConfigEntry, 0, 16)
+	flagSet.VisitAll(func(f *flag.Flag) {
+		source := sources[f.Name]
+		if source == "" {
+			source = ConfigSourceDefault
+		}
+		entries = append(entries, ConfigEntry{Name: f.Name, Value: f.Value.String(), Source: source})
+	})
+	return entries
+}
+
+// defaultTargetName 是第一个分组没有指定 name= 时使用的目标进程名。
+const defaultTargetName = "main"
+
+var targetNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// splitTargetGroups 把 -- 之后的参数切分为多个目标进程：
+//
+//	-- /app/Api.dll --port=1 -- name=worker /app/Worker.dll
+//
+// 分组的第一个参数形如 name=xxx 时作为目标进程名，第一个分组可以省略，默认为 "main"。
+// 只有紧跟 name=xxx 的 "--" 才会开始一个新的分组，其他的 "--" 原样传给目标进程，
+// 保证单个目标进程时与以前的行为一致。
+func splitTargetGroups(params []string) ([]TargetOptions, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	groups := []TargetOptions{{Name: defaultTargetName}}
+	for idx := 0; idx < len(params); idx++ {
+		item := params[idx]
+		if item == "--" && idx+1 < len(params) && isTargetNameParam(params[idx+1]) {
+			groups = append(groups, TargetOptions{})
+			continue
+		}
+		current := &groups[len(groups)-1]
+		if len(current.StartupParams) == 0 && isTargetNameParam(item) {
+			current.Name = strings.TrimPrefix(item, "name=")
+			continue
+		}
+		current.StartupParams = append(current.StartupParams, item)
+	}
+	seen := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		if len(group.StartupParams) == 0 {
+			return nil, fmt.Errorf("target %q has no startup command", group.Name)
+		}
+		if _, ok := seen[group.Name]; ok {
+			return nil, fmt.Errorf("duplicate target name %q", group.Name)
+		}
+		seen[group.Name] = struct{}{}
+	}
+	return groups, nil
+}
+
+func isTargetNameParam(item string) bool {
+	name, ok := strings.CutPrefix(item, "name=")
+	return ok && targetNamePattern.MatchString(name)
+}
+
 // stringSliceFlag 支持在命令行中重复指定同一个参数，多次出现的值会依次追加到切片中。
 type stringSliceFlag []string
 
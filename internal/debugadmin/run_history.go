@@ -2,27 +2,45 @@ package debugadmin
 
 import (
 	"errors"
-	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 )
 
+// RunRecord 中 StartReason / Reason 的取值。
+const (
+	RunReasonInitial     = "initial"      // DebugAdmin 启动时创建的第一个子进程
+	RunReasonAutoRestart = "auto-restart" // 崩溃后由 -auto.restart 拉起
+	RunReasonManual      = "manual"       // 管理端手动启动/停止/重启/发信号
+	RunReasonCrash       = "crash"        // 异常退出
+	RunReasonExit        = "exit"         // 正常退出
+)
+
 // RunRecord 记录目标子进程的一次启动到退出的完整信息。
 type RunRecord struct {
-	PID          int
-	StartTime    time.Time
-	EndTime      time.Time
-	ExitCode     int
-	Signal       string
-	Abnormal     bool
-	Err          string
-	LastLogs     []string
-	CoreDumpPath string
-	GDBLogPath   string
+	PID            int
+	StartTime      time.Time
+	EndTime        time.Time
+	ExitCode       int
+	Signal         string
+	Abnormal       bool
+	Err            string
+	LastLogs       []string
+	CoreDumpPath   string // 崩溃时找到的 core 文件，压缩保存之后原文件会被删除
+	CoreDumpSource string // CoreDumpSource*
+	CoreDumpID     string // CoreDumpStore 中压缩保存的 core，可以从 /coredumps 下载
+	CrashReportID  string // CrashReportStore 中对 core 的分析报告，见 /crash-report
+	GDBLogPath     string
+	GDBScriptPath  string   // 生成 GDBLogPath 的崩溃采集脚本，与日志放在一起
+	PerfMapPath    string   // 运行时写出的 /tmp/perf-{pid}.map，没有开启 perf map 时为空
+	PerfInfoPath   string   // 运行时写出的 /tmp/perfinfo-{pid}.map
+	StartReason    string   // 这次运行是怎么启动的：initial / auto-restart / manual
+	Reason         string   // 这次运行是怎么结束的：manual / crash / exit
+	Mode           RunMode  // 这次运行的运行方式：plain / gdb / coverage
+	Args           []string // 这次运行实际使用的启动参数
+	Env            []string // 这次运行额外设置的环境变量
+	Dir            string   // 这次运行的工作目录，相对路径的 core_pattern 相对于它
 }
 
 // RunHistory 是并发安全的启动记录列表，供 AdminHandler 展示。
@@ -41,6 +59,24 @@ func (h *RunHistory) Add(record RunRecord) {
 	h.mu.Unlock()
 }
 
+// SetCoreDump 把压缩保存的 core dump 关联到第 index 条记录。
+func (h *RunHistory) SetCoreDump(index int, id string) {
+	h.mu.Lock()
+	if index >= 0 && index < len(h.records) {
+		h.records[index].CoreDumpID = id
+	}
+	h.mu.Unlock()
+}
+
+// SetCrashReport 把崩溃报告关联到第 index 条记录。
+func (h *RunHistory) SetCrashReport(index int, id string) {
+	h.mu.Lock()
+	if index >= 0 && index < len(h.records) {
+		h.records[index].CrashReportID = id
+	}
+	h.mu.Unlock()
+}
+
 func (h *RunHistory) Snapshot() []RunRecord {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -65,25 +101,3 @@ func classifyExit(err error) (exitCode int, signal string, abnormal bool) {
 	}
 	return -1, "", true
 }
-
-// detectCoreDump 尽力查找目标进程可能产生的 core dump 文件。
-// 由于 core_pattern 各系统不同，这里只检查常见路径，找到即返回绝对路径。
-func detectCoreDump(pid int) string {
-	candidates := []string{
-		fmt.Sprintf("core.%d", pid),
-		"core",
-		fmt.Sprintf("/tmp/core.%d", pid),
-		"/tmp/core",
-	}
-	for _, candidate := range candidates {
-		info, err := os.Stat(candidate)
-		if err != nil || info.IsDir() {
-			continue
-		}
-		if abs, err := filepath.Abs(candidate); err == nil {
-			return abs
-		}
-		return candidate
-	}
-	return ""
-}
@@ -0,0 +1,91 @@
+package debugadmin
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// speedscopeFile 是 speedscope 的文件格式，只实现了本项目需要的 sampled profile。
+// 格式说明见 https://github.com/jlfwong/speedscope/wiki/Importing-from-custom-sources
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name,omitempty"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter,omitempty"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+}
+
+type speedscopeProfile struct {
+	Type       string    `json:"type"`
+	Name       string    `json:"name"`
+	Unit       string    `json:"unit"`
+	StartValue float64   `json:"startValue"`
+	EndValue   float64   `json:"endValue"`
+	Samples    [][]int   `json:"samples"` // 每个采样是 shared.frames 的下标，从栈底到栈顶
+	Weights    []float64 `json:"weights"`
+}
+
+// speedscopeBuilder 在构造 speedscope 文件时合并相同的帧。
+type speedscopeBuilder struct {
+	file   speedscopeFile
+	frames map[speedscopeFrame]int
+}
+
+func newSpeedscopeBuilder(name string) *speedscopeBuilder {
+	return &speedscopeBuilder{
+		file: speedscopeFile{
+			Schema:   speedscopeSchema,
+			Name:     name,
+			Exporter: "CSharpDbgContainer",
+		},
+		frames: make(map[speedscopeFrame]int),
+	}
+}
+
+func (b *speedscopeBuilder) frame(name, file string) int {
+	key := speedscopeFrame{Name: name, File: file}
+	if index, ok := b.frames[key]; ok {
+		return index
+	}
+	index := len(b.file.Shared.Frames)
+	b.file.Shared.Frames = append(b.file.Shared.Frames, key)
+	b.frames[key] = index
+	return index
+}
+
+func (b *speedscopeBuilder) addProfile(profile speedscopeProfile) {
+	if profile.Samples == nil {
+		profile.Samples = [][]int{}
+		profile.Weights = []float64{}
+	}
+	b.file.Profiles = append(b.file.Profiles, profile)
+}
+
+// writeSpeedscopeFile 先写临时文件再改名，避免浏览器读到写了一半的 profile。
+func writeSpeedscopeFile(path string, file *speedscopeFile) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package debugadmin
+
+import (
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ThreadRef 标识一个线程，用于在分组中列出属于同一调用栈的线程。
+type ThreadRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// StackGroup 是调用栈完全相同（StackThread.Signature 相同）的一组线程，类似 Go goroutine dump 的合并方式。
+type StackGroup struct {
+	Threads []ThreadRef  `json:"threads"`
+	Frames  []StackFrame `json:"frames"`
+}
+
+// GroupStacks 按调用栈合并线程，线程数多的组在前，数量相同时保持第一次出现的顺序。
+func GroupStacks(threads []StackThread) []StackGroup {
+	groups := make(map[string]*StackGroup)
+	order := make([]string, 0, len(threads))
+	for _, thread := range threads {
+		signature := thread.Signature()
+		group := groups[signature]
+		if group == nil {
+			group = &StackGroup{Frames: thread.Frames}
+			groups[signature] = group
+			order = append(order, signature)
+		}
+		group.Threads = append(group.Threads, ThreadRef{ID: thread.ID, Name: thread.Name})
+	}
+	result := make([]StackGroup, 0, len(order))
+	for _, signature := range order {
+		result = append(result, *groups[signature])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].Threads) > len(result[j].Threads)
+	})
+	return result
+}
+
+// CallTreeNode 是合并调用树中的一个函数，从线程入口（栈底）向栈顶展开。
+// Count 是经过这个函数的线程数，Self 是栈顶正好停在这个函数的线程数。
+type CallTreeNode struct {
+	Name     string          `json:"name"`
+	Count    int             `json:"count"`
+	Self     int             `json:"self,omitempty"`
+	Children []*CallTreeNode `json:"children,omitempty"`
+}
+
+// BuildCallTree 把所有线程的调用栈合并成一棵调用树，根节点代表全部线程。
+// 同一个函数的不同行号合并为一个节点；子节点按线程数从多到少排列。
+func BuildCallTree(threads []StackThread) *CallTreeNode {
+	root := &CallTreeNode{Name: "all threads"}
+	for _, thread := range threads {
+		root.Count++
+		node := root
+		for i := len(thread.Frames) - 1; i >= 0; i-- {
+			frame := thread.Frames[i]
+			if frame.Index < 0 {
+				continue
+			}
+			node = node.child(callTreeName(frame))
+			node.Count++
+		}
+		node.Self++
+	}
+	root.sortChildren()
+	return root
+}
+
+func (n *CallTreeNode) child(name string) *CallTreeNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &CallTreeNode{Name: name}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+func (n *CallTreeNode) sortChildren() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Count > n.Children[j].Count
+	})
+	for _, child := range n.Children {
+		child.sortChildren()
+	}
+}
+
+// callTreeName 是帧在调用树中的名字：模块、函数与参数，不含行号。
+func callTreeName(frame StackFrame) string {
+	name := frame.Function()
+	if name == "" {
+		return strings.TrimSpace(frame.Raw)
+	}
+	if frame.HasParams {
+		name += "(" + frame.Params + ")"
+	}
+	if frame.Module != "" {
+		name = frame.Module + "!" + name
+	}
+	return name
+}
+
+// callTreeOpenRatio 以下的分支默认折叠，避免只有一两个线程的分支把页面撑得太长。
+const callTreeOpenRatio = 0.1
+
+// renderCallTreeHTML 把调用树渲染为嵌套的 <details>，每个节点带一条按线程占比缩放的横条，
+// 可以当作自上而下的火焰图阅读。
+func renderCallTreeHTML(root *CallTreeNode) string {
+	if root == nil || root.Count == 0 {
+		return ""
+	}
+	var b strings.Builder
+	writeCallTreeNode(&b, root, root.Count)
+	return b.String()
+}
+
+func writeCallTreeNode(b *strings.Builder, node *CallTreeNode, total int) {
+	percent := float64(node.Count) * 100 / float64(total)
+	label := `<span class="tree-bar"><span style="width:` + strconv.FormatFloat(percent, 'f', 1, 64) + `%"></span></span>` +
+		`<span class="tree-count">` + strconv.Itoa(node.Count) + `</span> ` +
+		`<span class="tree-name">` + html.EscapeString(node.Name) + `</span>`
+	if node.Self > 0 && len(node.Children) > 0 {
+		label += ` <span class="tree-self">(` + strconv.Itoa(node.Self) + ` stop here)</span>`
+	}
+	if len(node.Children) == 0 {
+		b.WriteString(`<div class="tree-leaf">`)
+		b.WriteString(label)
+		b.WriteString("</div>\n")
+		return
+	}
+	if float64(node.Count) >= float64(total)*callTreeOpenRatio {
+		b.WriteString(`<details open>`)
+	} else {
+		b.WriteString(`<details>`)
+	}
+	b.WriteString("<summary>")
+	b.WriteString(label)
+	b.WriteString("</summary>\n")
+	for _, child := range node.Children {
+		writeCallTreeNode(b, child, total)
+	}
+	b.WriteString("</details>\n")
+}
@@ -0,0 +1,83 @@
+package debugadmin
+
+import (
+	"strings"
+	"testing"
+)
+
+const aggregateDump = "Thread 1, name=\"main\"\n" +
+	"#0: 0x1 MyApp.dll` MyApp.Server.Accept() at /src/Server.cs:10\n" +
+	"#1: 0x2 MyApp.dll` MyApp.Program.Main(string[]) at /src/Program.cs:3\n" +
+	"Thread 2, name=\".NET ThreadPool Worker\"\n" +
+	"#0: 0x3 System.Private.CoreLib.dll` System.Threading.LowLevelLifoSemaphore.WaitForSignal(int)\n" +
+	"#1: 0x4 System.Private.CoreLib.dll` System.Threading.PortableThreadPool.WorkerThread.WorkerThreadStart()\n" +
+	"Thread 3, name=\".NET ThreadPool Worker\"\n" +
+	"#0: 0x5 System.Private.CoreLib.dll` System.Threading.LowLevelLifoSemaphore.WaitForSignal(int)\n" +
+	"#1: 0x6 System.Private.CoreLib.dll` System.Threading.PortableThreadPool.WorkerThread.WorkerThreadStart()\n" +
+	"Thread 4\n" +
+	"#0: 0x7 MyApp.dll` MyApp.Server.Handle() at /src/Server.cs:20\n" +
+	"#1: 0x8 MyApp.dll` MyApp.Program.Main(string[]) at /src/Program.cs:3"
+
+func TestGroupStacks(t *testing.T) {
+	groups := GroupStacks(ParseStackDump(aggregateDump).Threads)
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3: %+v", len(groups), groups)
+	}
+	if len(groups[0].Threads) != 2 || groups[0].Threads[0].ID != 2 || groups[0].Threads[1].ID != 3 {
+		t.Errorf("group 0 threads = %+v, want threads 2 and 3", groups[0].Threads)
+	}
+	if groups[1].Threads[0] != (ThreadRef{ID: 1, Name: "main"}) || groups[2].Threads[0].ID != 4 {
+		t.Errorf("single-thread groups = %+v %+v, want threads 1 then 4", groups[1].Threads, groups[2].Threads)
+	}
+	if got := formatThreadRefs(groups[0].Threads); got != "2 (.NET ThreadPool Worker), 3 (.NET ThreadPool Worker)" {
+		t.Errorf("formatThreadRefs() = %q", got)
+	}
+}
+
+func TestBuildCallTree(t *testing.T) {
+	root := BuildCallTree(ParseStackDump(aggregateDump).Threads)
+	if root.Count != 4 || len(root.Children) != 2 {
+		t.Fatalf("root = %+v, want 4 threads and 2 entry points", root)
+	}
+	// 两个入口各有两个线程，数量相同时保持第一次出现的顺序
+	main := root.Children[0]
+	if main.Name != "MyApp.dll!MyApp.Program.Main(string[])" || main.Count != 2 || main.Self != 0 {
+		t.Errorf("first entry = %+v, want Main with 2 threads", main)
+	}
+	if len(main.Children) != 2 || main.Children[0].Name != "MyApp.dll!MyApp.Server.Accept()" || main.Children[0].Self != 1 {
+		t.Errorf("Main children = %+v, want Accept and Handle leaves", main.Children)
+	}
+	worker := root.Children[1]
+	if worker.Count != 2 || len(worker.Children) != 1 || worker.Children[0].Self != 2 {
+		t.Errorf("worker entry = %+v, want both workers merged into one leaf", worker)
+	}
+
+	rendered := renderCallTreeHTML(root)
+	if !strings.Contains(rendered, "all threads") || !strings.Contains(rendered, `style="width:50.0%"`) ||
+		!strings.Contains(rendered, "MyApp.Program.Main(string[])") {
+		t.Errorf("renderCallTreeHTML() = %s", rendered)
+	}
+	if renderCallTreeHTML(BuildCallTree(nil)) != "" {
+		t.Error("renderCallTreeHTML() of an empty dump should be empty")
+	}
+}
+
+func TestBuildStackPageDataViews(t *testing.T) {
+	dump := ParseStackDump(aggregateDump)
+	grouped := buildStackPageData(stackViewGrouped, "", dump, "", nil)
+	if len(grouped.Groups) != 3 || grouped.Threads != nil || grouped.Tree != "" || grouped.ThreadCount != 4 {
+		t.Errorf("grouped view = %+v, want only groups", grouped)
+	}
+	tree := buildStackPageData(stackViewTree, "", dump, "", nil)
+	if tree.Tree == "" || tree.Groups != nil || tree.Threads != nil {
+		t.Errorf("tree view = %+v, want only the call tree", tree)
+	}
+	threads := buildStackPageData("bogus", "", dump, "", nil)
+	if threads.View != stackViewThreads || len(threads.Threads) != 4 {
+		t.Errorf("unknown view = %+v, want the threads view", threads)
+	}
+	links := buildStackViewLinks(stackViewTree, "&target=api")
+	if len(links) != 4 || !links[2].Selected || links[2].URL != "/stack?view=tree&target=api" {
+		t.Errorf("view links = %+v", links)
+	}
+}
@@ -1,17 +1,126 @@
 package debugadmin
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os/exec"
-	"strconv"
+	"sync"
+	"time"
 )
 
-func BuildStackCommand(ctx context.Context, pid int) *exec.Cmd {
-	return exec.CommandContext(
-		ctx,
+// debuggerExitTimeout 是关闭调试会话时等待 netcoredbg 自行退出的时间，超时后强制结束。
+const debuggerExitTimeout = 3 * time.Second
+
+// BuildStackCommand 以 MI 模式启动 netcoredbg，attach 通过 -target-attach 命令完成，
+// 这样 attach 失败时能拿到明确的错误信息。
+// 进程的生命周期不跟随请求的 context：请求超时或者客户端断开时，netcoredbg 必须先 detach，
+// 否则目标进程会一直停在那里；进程只会在 debuggerSession.Close 中被结束。
+func BuildStackCommand() *exec.Cmd {
+	return exec.Command(
 		"netcoredbg",
-		"--interpreter=cli",
-		"--attach",
-		strconv.Itoa(pid),
+		"--interpreter=mi",
 	)
 }
+
+// debuggerSession 是一个 netcoredbg MI 进程及其客户端。
+type debuggerSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	client *MIClient
+	stderr lockedBuffer
+}
+
+// startDebuggerSession 启动 netcoredbg 并 attach 到 pid。attach 失败时会关闭会话。
+func startDebuggerSession(ctx context.Context, pid int) (*debuggerSession, error) {
+	if pid <= 0 {
+		return nil, errors.New("target process is not running")
+	}
+	session := &debuggerSession{cmd: BuildStackCommand()}
+	session.cmd.Stderr = &session.stderr
+	stdout, err := session.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("read debugger stdout failed: %w", err)
+	}
+	if session.stdin, err = session.cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("open debugger stdin failed: %w", err)
+	}
+	if err := session.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start debugger failed: %w", err)
+	}
+	session.client = NewMIClient(stdout, session.stdin)
+	if err := session.client.Attach(ctx, pid); err != nil {
+		// ctx 在 attach 过程中被取消时，netcoredbg 可能已经 attach 上了，先尝试 detach 再退出
+		detachCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), miDetachTimeout)
+		_ = session.client.Detach(detachCtx)
+		cancel()
+		session.Close()
+		return session, err
+	}
+	return session, nil
+}
+
+// Close 请求调试器退出并等待进程结束，超时后强制结束。可以重复调用。
+func (s *debuggerSession) Close() {
+	if s.cmd.ProcessState != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), debuggerExitTimeout)
+	defer cancel()
+	_ = s.client.Exit(ctx)
+	_ = s.stdin.Close()
+	select {
+	case <-s.client.Done():
+	case <-ctx.Done():
+		_ = s.cmd.Process.Kill()
+	}
+	_ = s.cmd.Wait()
+}
+
+// Log 返回调试器输出的日志。
+func (s *debuggerSession) Log() string {
+	if s == nil || s.client == nil {
+		return ""
+	}
+	return s.client.Log()
+}
+
+// Stderr 返回调试器的 stderr。
+func (s *debuggerSession) Stderr() string {
+	if s == nil {
+		return ""
+	}
+	return s.stderr.String()
+}
+
+// collectStack attach 到 pid，暂停进程读取所有线程的调用栈后 detach。
+// 返回解析后的调用栈、调试器输出的日志和 stderr。
+func collectStack(ctx context.Context, pid int) (StackDump, string, string, error) {
+	session, err := startDebuggerSession(ctx, pid)
+	if err != nil {
+		return StackDump{}, session.Log(), session.Stderr(), err
+	}
+	dump, err := session.client.CollectStackDump(ctx)
+	session.Close()
+	return dump, session.Log(), session.Stderr(), err
+}
+
+// lockedBuffer 是可以被 exec.Cmd 写入、同时被其他 goroutine 读取的 bytes.Buffer。
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
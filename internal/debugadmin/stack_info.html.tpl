@@ -18,10 +18,29 @@ h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
 .frame-dll{color:#6b7280;}
 .frame-sep{color:#6b7280;}
 .frame-func{color:#111827;}
+.frame-ns{color:#6b7280;}
+.frame-type{color:#374151;}
+.frame-method{color:#111827;font-weight:700;}
+.frame-params{color:#4b5563;}
+.frame-il{color:#9ca3af;}
 .frame-at{color:#6b7280;}
 .frame-path{color:#0f766e;}
 .frame-file{color:#1d4ed8;font-weight:700;}
 .thread-extra{color:#374151;}
+.inspect{font-size:11px;color:#7c3aed;text-decoration:none;margin-left:6px;}
+.inspect:hover{text-decoration:underline;}
+.views{margin:0 0 10px 0;font-size:13px;}
+.views a{margin-right:12px;color:#1d4ed8;}
+.views a.selected{font-weight:700;color:#111827;text-decoration:none;}
+.group-threads{color:#374151;font-size:12px;margin-top:2px;}
+.tree details{margin-left:14px;}
+.tree>details{margin-left:0;}
+.tree summary,.tree-leaf{white-space:nowrap;line-height:1.5;cursor:default;}
+.tree-leaf{margin-left:28px;}
+.tree-bar{display:inline-block;width:80px;height:8px;background:#f3f4f6;border:1px solid #e5e7eb;margin-right:6px;vertical-align:middle;}
+.tree-bar span{display:block;height:100%;background:#f97316;}
+.tree-count{display:inline-block;min-width:32px;text-align:right;color:#b91c1c;font-weight:700;}
+.tree-self{color:#6b7280;font-size:12px;}
 .misc,.stderr,.error{margin-top:16px;white-space:pre-wrap;padding:10px;border-radius:8px;}
 .misc{background:#eef2ff;border:1px solid #c7d2fe;color:#1f2937;}
 .stderr{background:#fff7ed;border:1px solid #fed7aa;color:#7c2d12;}
@@ -31,9 +50,25 @@ h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
 <body>
 <div class="wrap">
 <h1>Stack Dump</h1>
-{{if .StartupOutput}}<h2>netcoredbg stdout (before "bt all")</h2><pre class="startup">{{.StartupOutput}}</pre>{{end}}
+{{if .DebuggerLog}}<h2>netcoredbg output</h2><pre class="startup">{{.DebuggerLog}}</pre>{{end}}
+<div class="views">{{range .Views}}<a href="{{.URL}}"{{if .Selected}} class="selected"{{end}}>{{.Name}}</a>{{end}}<span class="group-threads">{{.ThreadCount}} thread(s)</span></div>
 {{if .NoData}}<div class="misc">No stack data returned.</div>{{end}}
-{{range .Threads}}<div class="thread"><div class="thread-title">{{.Header}}</div>{{if or .Frames .Extra}}<div class="thread-stack">{{range .Frames}}<div class="frame">{{.}}</div>
+{{with .Async}}<h2>Async Stacks ({{.Count}} chain(s), innermost first)</h2>
+{{if .Error}}<div class="error">async stack error: {{.Error}}</div>{{else if not .Groups}}<div class="misc">No pending async state machines found on the heap.</div>{{end}}
+{{range .Groups}}<div class="thread"><div class="thread-title">{{.Count}} async chain(s){{if .Awaiting}} awaiting {{.Awaiting}}{{end}}</div>
+<div class="thread-stack">{{range .Frames}}<div class="frame">{{.}}</div>
+{{end}}</div></div>
+{{end}}
+{{if .Output}}<h2>dotnet-dump output</h2><pre class="startup">{{.Output}}</pre>{{end}}
+<h2>Thread Stacks</h2>
+{{end}}
+{{range .Groups}}<div class="thread"><div class="thread-title">{{.Count}} thread(s) with this stack</div>
+<div class="group-threads">threads: {{.Threads}}</div>
+<div class="thread-stack">{{range .Frames}}<div class="frame">{{.}}</div>
+{{end}}</div></div>
+{{end}}
+{{if .Tree}}<div class="tree">{{.Tree}}</div>{{end}}
+{{range $thread := .Threads}}<div class="thread"><div class="thread-title">{{.Header}}</div>{{if or .Frames .Extra}}<div class="thread-stack">{{range .Frames}}<div class="frame">{{.HTML}}{{if .Inspectable}} <a class="inspect" href="/stack/inspect?thread={{$thread.ID}}&frame={{.Level}}{{$.TargetParam}}" target="_blank">inspect</a>{{end}}</div>
 {{end}}{{range .Extra}}<div class="thread-extra">{{.}}</div>
 {{end}}</div>{{end}}</div>
 {{end}}
@@ -0,0 +1,427 @@
+package debugadmin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StackDump 是一次 "bt all" 的解析结果。线程保持调试器输出的顺序。
+type StackDump struct {
+	Threads []StackThread `json:"threads"`
+	Misc    []string      `json:"misc,omitempty"` // 不属于任何线程的输出行
+}
+
+// StackThread 是一个线程的调用栈。
+type StackThread struct {
+	ID     int          `json:"id"`
+	Name   string       `json:"name,omitempty"`
+	State  string       `json:"state,omitempty"`
+	Header string       `json:"header"` // 原始的线程标题行
+	Frames []StackFrame `json:"frames"`
+	Extra  []string     `json:"extra,omitempty"` // 线程下非 "#N" 开头的行
+}
+
+// StackFrame 是调用栈中的一帧。托管帧会尽量拆分出模块、命名空间、类型和方法，
+// 无法识别的部分保持为空，Raw 中始终保留原始文本。
+type StackFrame struct {
+	Index           int      `json:"index"`
+	Address         string   `json:"address,omitempty"`
+	Module          string   `json:"module,omitempty"`
+	Namespace       string   `json:"namespace,omitempty"`
+	Type            string   `json:"type,omitempty"` // 不含泛型参数，嵌套类型保持 Outer+Inner 的形式
+	TypeGenericArgs []string `json:"type_generic_args,omitempty"`
+	Method          string   `json:"method,omitempty"` // 不含泛型参数，例如 .ctor、<Main>b__0_0
+	GenericArgs     []string `json:"generic_args,omitempty"`
+	Params          string   `json:"params,omitempty"` // 括号内的参数列表，原样保留
+	HasParams       bool     `json:"has_params,omitempty"`
+	ILOffset        *int     `json:"il_offset,omitempty"`
+	File            string   `json:"file,omitempty"`
+	Line            int      `json:"line,omitempty"`
+	Symbol          string   `json:"symbol,omitempty"` // 无法拆分时的符号文本，例如 [Native Frames]
+	Raw             string   `json:"raw"`
+}
+
+var (
+	stackThreadIDPattern    = regexp.MustCompile(`^Thread\s+(\d+)`)
+	stackThreadNamePattern  = regexp.MustCompile(`name="((?:[^"\\]|\\.)*)"`)
+	stackThreadStatePattern = regexp.MustCompile(`state=([A-Za-z_-]+)`)
+	stackFrameIndexPattern  = regexp.MustCompile(`^#(\d+):?`)
+	stackILOffsetPattern    = regexp.MustCompile(`(?:\+\s*0x([0-9A-Fa-f]+)|\[?IL_([0-9A-Fa-f]+)\]?)$`)
+	stackSourcePattern      = regexp.MustCompile(`^(.+):(\d+)$`)
+)
+
+// ParseStackDump 解析 netcoredbg "bt all" 的输出。解析器只做尽力而为的拆分，
+// 对任意输入都不会 panic，也不会丢弃任何行。
+func ParseStackDump(raw string) StackDump {
+	normalized := strings.ReplaceAll(raw, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+
+	dump := StackDump{Threads: make([]StackThread, 0, 16)}
+	var current *StackThread
+	flush := func() {
+		if current != nil {
+			dump.Threads = append(dump.Threads, *current)
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(normalized, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Thread ") {
+			flush()
+			thread := parseStackThreadHeader(trimmed)
+			current = &thread
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		if current == nil {
+			dump.Misc = append(dump.Misc, trimmed)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			current.Frames = append(current.Frames, ParseStackFrame(trimmed))
+		} else {
+			current.Extra = append(current.Extra, trimmed)
+		}
+	}
+	flush()
+	return dump
+}
+
+func parseStackThreadHeader(header string) StackThread {
+	thread := StackThread{Header: header, Frames: []StackFrame{}}
+	if match := stackThreadIDPattern.FindStringSubmatch(header); match != nil {
+		thread.ID, _ = strconv.Atoi(match[1])
+	}
+	if match := stackThreadNamePattern.FindStringSubmatch(header); match != nil {
+		thread.Name = strings.ReplaceAll(match[1], `\"`, `"`)
+	}
+	if match := stackThreadStatePattern.FindStringSubmatch(header); match != nil {
+		thread.State = match[1]
+	} else {
+		// 部分版本把状态写在标题末尾，例如 "Thread 1, name="Main", stopped"
+		fields := strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(fields) > 0 {
+			switch last := fields[len(fields)-1]; last {
+			case "running", "stopped", "suspended":
+				thread.State = last
+			}
+		}
+	}
+	return thread
+}
+
+// ParseStackFrame 解析一行形如
+//
+//	#3: 0x00007f1c2d3e4f50 MyApp.dll` MyApp.Services.Worker<T>.Run<int>(System.Threading.CancellationToken) + 0x1a at /src/My App/Worker.cs:42
+//
+// 的栈帧。参数列表按括号配对定位，路径中的空格或 " at " 不会影响解析。
+func ParseStackFrame(line string) StackFrame {
+	frame := StackFrame{Raw: line, Index: -1}
+	rest := strings.TrimSpace(line)
+	if match := stackFrameIndexPattern.FindStringSubmatch(rest); match != nil {
+		if index, err := strconv.Atoi(match[1]); err == nil {
+			frame.Index = index
+		}
+		rest = strings.TrimSpace(rest[len(match[0]):])
+	}
+	if fields := strings.Fields(rest); len(fields) > 0 && strings.HasPrefix(fields[0], "0x") {
+		frame.Address = fields[0]
+		rest = strings.TrimSpace(rest[len(fields[0]):])
+	}
+	if sep := strings.Index(rest, "`"); sep >= 0 {
+		frame.Module = strings.TrimSpace(rest[:sep])
+		rest = strings.TrimSpace(rest[sep+1:])
+	}
+
+	symbol, location := splitFrameLocation(rest)
+	if location != "" {
+		if match := stackSourcePattern.FindStringSubmatch(location); match != nil {
+			frame.File = match[1]
+			frame.Line, _ = strconv.Atoi(match[2])
+		} else {
+			frame.File = location
+		}
+	}
+	if match := stackILOffsetPattern.FindStringSubmatchIndex(symbol); match != nil {
+		var digits string
+		if match[2] >= 0 {
+			digits = symbol[match[2]:match[3]]
+		} else {
+			digits = symbol[match[4]:match[5]]
+		}
+		if offset, err := strconv.ParseInt(digits, 16, 64); err == nil && offset <= 1<<31-1 {
+			value := int(offset)
+			frame.ILOffset = &value
+			symbol = strings.TrimSpace(symbol[:match[0]])
+		}
+	}
+	if !parseFrameSymbol(symbol, &frame) {
+		frame.Symbol = symbol
+	}
+	return frame
+}
+
+// splitFrameLocation 把 "符号 at 路径:行号" 拆开。存在参数列表时只在其右括号之后查找 " at "。
+func splitFrameLocation(rest string) (string, string) {
+	searchFrom := 0
+	if open := topLevelIndex(rest, '('); open >= 0 {
+		if closeIdx := matchingParen(rest, open); closeIdx >= 0 {
+			searchFrom = closeIdx + 1
+		}
+	}
+	at := strings.Index(rest[searchFrom:], " at ")
+	if at < 0 {
+		return strings.TrimSpace(rest), ""
+	}
+	at += searchFrom
+	return strings.TrimSpace(rest[:at]), strings.TrimSpace(rest[at+len(" at "):])
+}
+
+// parseFrameSymbol 拆分 Namespace.Type<TArgs>.Method<MArgs>(params)，无法识别时返回 false。
+func parseFrameSymbol(symbol string, frame *StackFrame) bool {
+	if symbol == "" || strings.HasPrefix(symbol, "[") {
+		return false
+	}
+	name := symbol
+	if open := topLevelIndex(symbol, '('); open >= 0 {
+		closeIdx := matchingParen(symbol, open)
+		if closeIdx != len(symbol)-1 {
+			return false
+		}
+		frame.Params = strings.TrimSpace(symbol[open+1 : closeIdx])
+		frame.HasParams = true
+		name = strings.TrimSpace(symbol[:open])
+	}
+	if name == "" || (strings.ContainsAny(name, " \t") && !strings.Contains(name, "<")) {
+		frame.Params, frame.HasParams = "", false
+		return false
+	}
+	segments := splitQualifiedName(name)
+	method := segments[len(segments)-1]
+	frame.Method, frame.GenericArgs = splitGenericArgs(method)
+	if len(segments) >= 2 {
+		frame.Type, frame.TypeGenericArgs = splitGenericArgs(segments[len(segments)-2])
+		frame.Namespace = strings.Join(segments[:len(segments)-2], ".")
+	}
+	return true
+}
+
+// splitQualifiedName 按不在 <> 或 [] 之内的 "." 切分，.ctor/.cctor 这类以 "." 开头的方法名保持完整。
+func splitQualifiedName(name string) []string {
+	var segments []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '<', '[':
+			depth++
+		case '>', ']':
+			if depth > 0 {
+				depth--
+			}
+		case '.':
+			if depth != 0 || i == start {
+				continue
+			}
+			segments = append(segments, name[start:i])
+			start = i + 1
+		}
+	}
+	return append(segments, name[start:])
+}
+
+// splitGenericArgs 把 Name<A, B<C>> 拆成 Name 与 [A, B<C>]。
+// 以 "<" 开头的编译器生成名（例如 <Main>b__0_0、<>c）不视为泛型。
+func splitGenericArgs(name string) (string, []string) {
+	if !strings.HasSuffix(name, ">") {
+		return name, nil
+	}
+	depth := 0
+	for i := len(name) - 1; i >= 0; i-- {
+		switch name[i] {
+		case '>':
+			depth++
+		case '<':
+			depth--
+			if depth != 0 {
+				continue
+			}
+			if i == 0 {
+				return name, nil
+			}
+			return name[:i], splitTopLevel(name[i+1 : len(name)-1])
+		}
+	}
+	return name, nil
+}
+
+// splitTopLevel 按不在 <> [] () 之内的逗号切分。
+func splitTopLevel(list string) []string {
+	var items []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '<', '[', '(':
+			depth++
+		case '>', ']', ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
+
+// topLevelIndex 返回第一个不在 <> 之内的 c 的位置。
+func topLevelIndex(s string, c byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case c:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// matchingParen 返回与 s[open] 处 "(" 配对的 ")" 的位置，不存在时返回 -1。
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// TypeName 返回带泛型参数的类型名，例如 Dictionary<string, int>。
+func (f StackFrame) TypeName() string {
+	return withGenericArgs(f.Type, f.TypeGenericArgs)
+}
+
+// MethodName 返回带泛型参数的方法名。
+func (f StackFrame) MethodName() string {
+	return withGenericArgs(f.Method, f.GenericArgs)
+}
+
+// Function 返回完整的函数名（不含参数与模块），无法拆分时返回原始符号。
+func (f StackFrame) Function() string {
+	if f.Method == "" {
+		return f.Symbol
+	}
+	parts := make([]string, 0, 3)
+	if f.Namespace != "" {
+		parts = append(parts, f.Namespace)
+	}
+	if f.Type != "" {
+		parts = append(parts, f.TypeName())
+	}
+	parts = append(parts, f.MethodName())
+	return strings.Join(parts, ".")
+}
+
+// Key 是与地址和帧序号无关的帧标识（模块、函数、参数与行号），用于比较两次采样中的调用栈是否相同。
+func (f StackFrame) Key() string {
+	function := f.Function()
+	if f.HasParams {
+		function += "(" + f.Params + ")"
+	}
+	if f.Line > 0 {
+		function += ":" + strconv.Itoa(f.Line)
+	}
+	if f.Module != "" {
+		return f.Module + "!" + function
+	}
+	return function
+}
+
+func withGenericArgs(name string, args []string) string {
+	if len(args) == 0 {
+		return name
+	}
+	return name + "<" + strings.Join(args, ", ") + ">"
+}
+
+// Signature 是线程调用栈的标识：所有帧的 Key 按顺序拼接，相同的调用栈具有相同的 Signature。
+func (t StackThread) Signature() string {
+	keys := make([]string, 0, len(t.Frames))
+	for _, frame := range t.Frames {
+		keys = append(keys, frame.Key())
+	}
+	return strings.Join(keys, "\n")
+}
+
+// 线程在两次采样之间的变化。
+const (
+	StackDiffAdded     = "added"
+	StackDiffRemoved   = "removed"
+	StackDiffChanged   = "changed"
+	StackDiffUnchanged = "unchanged"
+)
+
+// StackThreadDiff 描述同一个线程在两次采样之间的变化。
+type StackThreadDiff struct {
+	ID     int          `json:"id"`
+	Name   string       `json:"name,omitempty"`
+	Status string       `json:"status"`
+	Before *StackThread `json:"before,omitempty"`
+	After  *StackThread `json:"after,omitempty"`
+}
+
+// DiffStackDumps 按线程 id 比较两次采样，返回的结果按 after 中的线程顺序排列，消失的线程排在最后。
+func DiffStackDumps(before, after StackDump) []StackThreadDiff {
+	previous := make(map[int]*StackThread, len(before.Threads))
+	for i := range before.Threads {
+		previous[before.Threads[i].ID] = &before.Threads[i]
+	}
+	diffs := make([]StackThreadDiff, 0, len(after.Threads))
+	seen := make(map[int]struct{}, len(after.Threads))
+	for i := range after.Threads {
+		thread := &after.Threads[i]
+		seen[thread.ID] = struct{}{}
+		diff := StackThreadDiff{ID: thread.ID, Name: thread.Name, After: thread, Status: StackDiffAdded}
+		if old, ok := previous[thread.ID]; ok {
+			diff.Before = old
+			diff.Status = StackDiffChanged
+			if old.Signature() == thread.Signature() {
+				diff.Status = StackDiffUnchanged
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	for i := range before.Threads {
+		thread := &before.Threads[i]
+		if _, ok := seen[thread.ID]; ok {
+			continue
+		}
+		diffs = append(diffs, StackThreadDiff{ID: thread.ID, Name: thread.Name, Status: StackDiffRemoved, Before: thread})
+	}
+	return diffs
+}
@@ -0,0 +1,133 @@
+package debugadmin
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleBtAll = `Thread 101, name="Main Thread", state=stopped
+#0: 0x00007f4ef8c3b7b2 System.Private.CoreLib.dll` + "`" + ` System.Threading.Monitor.Wait(System.Object, int) at /_/src/System/Threading/Monitor.CoreCLR.cs:156
+#1: 0x00007f4ef8c3a000 MyApp.dll` + "`" + ` MyApp.Program.Main(string[]) at /src/My App/Program.cs:12
+Thread 102, name=".NET ThreadPool Worker", state=stopped
+#0: [Native Frames]
+#1: 0x00007f4ef8c3c000 System.Private.CoreLib.dll` + "`" + ` System.Threading.PortableThreadPool.WorkerThread.WorkerThreadStart()
+`
+
+func intPtr(v int) *int { return &v }
+
+func TestParseStackFrame(t *testing.T) {
+	tests := []struct {
+		line string
+		want StackFrame
+	}{
+		{
+			line: "#0: 0x00007f4ef8c3b7b2 System.Private.CoreLib.dll` System.Threading.Monitor.Wait(System.Object, int) at /_/src/Monitor.cs:156",
+			want: StackFrame{Index: 0, Address: "0x00007f4ef8c3b7b2", Module: "System.Private.CoreLib.dll", Namespace: "System.Threading", Type: "Monitor", Method: "Wait", Params: "System.Object, int", HasParams: true, File: "/_/src/Monitor.cs", Line: 156},
+		},
+		{
+			line: "#3: 0x1 MyApp.dll` MyApp.Services.Worker<T>.Run<int, System.Collections.Generic.List<string>>(System.Threading.CancellationToken) + 0x1a at /src/My App/at home/Worker.cs:42",
+			want: StackFrame{Index: 3, Address: "0x1", Module: "MyApp.dll", Namespace: "MyApp.Services", Type: "Worker", TypeGenericArgs: []string{"T"}, Method: "Run", GenericArgs: []string{"int", "System.Collections.Generic.List<string>"}, Params: "System.Threading.CancellationToken", HasParams: true, ILOffset: intPtr(0x1a), File: "/src/My App/at home/Worker.cs", Line: 42},
+		},
+		{
+			line: "#4: 0x2 MyApp.dll` MyApp.Program.<>c__DisplayClass0_0.<Main>b__0(System.Func<int, string>) [IL_0010]",
+			want: StackFrame{Index: 4, Address: "0x2", Module: "MyApp.dll", Namespace: "MyApp.Program", Type: "<>c__DisplayClass0_0", Method: "<Main>b__0", Params: "System.Func<int, string>", HasParams: true, ILOffset: intPtr(0x10)},
+		},
+		{
+			line: "#5: 0x3 System.Private.CoreLib.dll` System.Object..ctor()",
+			want: StackFrame{Index: 5, Address: "0x3", Module: "System.Private.CoreLib.dll", Namespace: "System", Type: "Object", Method: ".ctor", HasParams: true},
+		},
+		{
+			line: "#6: [Native Frames]",
+			want: StackFrame{Index: 6, Symbol: "[Native Frames]"},
+		},
+	}
+	for _, tt := range tests {
+		got := ParseStackFrame(tt.line)
+		tt.want.Raw = tt.line
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseStackFrame(%q)\n got  %+v\n want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseStackDump(t *testing.T) {
+	dump := ParseStackDump("Attaching...\n" + sampleBtAll)
+	if want := []string{"Attaching..."}; !reflect.DeepEqual(dump.Misc, want) {
+		t.Errorf("Misc = %q, want %q", dump.Misc, want)
+	}
+	if len(dump.Threads) != 2 {
+		t.Fatalf("got %d threads, want 2", len(dump.Threads))
+	}
+	main := dump.Threads[0]
+	if main.ID != 101 || main.Name != "Main Thread" || main.State != "stopped" || len(main.Frames) != 2 {
+		t.Errorf("thread 0 = %+v", main)
+	}
+	if got := main.Frames[1].Function(); got != "MyApp.Program.Main" {
+		t.Errorf("Function() = %q, want MyApp.Program.Main", got)
+	}
+	if got := sortThreadPoolWorkersFirst(dump.Threads)[0].ID; got != 102 {
+		t.Errorf("first sorted thread = %d, want the thread pool worker 102", got)
+	}
+
+	data, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded StackDump
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, dump) {
+		t.Errorf("JSON round trip changed the dump:\n got  %+v\n want %+v", decoded, dump)
+	}
+}
+
+func TestDiffStackDumps(t *testing.T) {
+	before := ParseStackDump(sampleBtAll)
+	after := ParseStackDump(strings.Replace(sampleBtAll, "Program.cs:12", "Program.cs:13", 1) +
+		"Thread 103, name=\"Timer\"\n#0: 0x9 System.Private.CoreLib.dll` System.Threading.TimerQueue.TimerThread()\n")
+	after.Threads = after.Threads[1:] // 线程 101 消失，102 不变，103 新增
+	got := make(map[int]string)
+	for _, diff := range DiffStackDumps(before, after) {
+		got[diff.ID] = diff.Status
+	}
+	want := map[int]string{101: StackDiffRemoved, 102: StackDiffUnchanged, 103: StackDiffAdded}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffStackDumps() = %v, want %v", got, want)
+	}
+
+	// 地址变化不影响比较，行号变化视为调用栈已改变
+	moved := ParseStackDump(strings.Replace(sampleBtAll, "0x00007f4ef8c3a000", "0x00007f4ef8c3a999", 1))
+	changed := ParseStackDump(strings.Replace(sampleBtAll, "Program.cs:12", "Program.cs:13", 1))
+	if diffs := DiffStackDumps(before, moved); diffs[0].Status != StackDiffUnchanged {
+		t.Errorf("address change status = %s, want unchanged", diffs[0].Status)
+	}
+	if diffs := DiffStackDumps(before, changed); diffs[0].Status != StackDiffChanged || diffs[1].Status != StackDiffUnchanged {
+		t.Errorf("line change statuses = %s/%s, want changed/unchanged", diffs[0].Status, diffs[1].Status)
+	}
+}
+
+func FuzzParseStackDump(f *testing.F) {
+	f.Add(sampleBtAll)
+	f.Add("Thread 1\n#0: 0x1 a.dll` A.B<C<D>>.E<F>(G<H, I>) + 0xffffffffffff at x:1\n")
+	f.Add("Thread\n#:\n#0: ((((\n#1: >>><<<.(.).\n#2: ` at :")
+	f.Fuzz(func(t *testing.T, raw string) {
+		dump := ParseStackDump(raw)
+		frames := 0
+		for _, thread := range dump.Threads {
+			frames += len(thread.Frames)
+			for _, frame := range thread.Frames {
+				_ = formatStackFrameHTML(frame)
+				_ = frame.Key()
+			}
+		}
+		if frames > strings.Count(raw, "#") {
+			t.Errorf("parsed %d frames from %d '#' characters", frames, strings.Count(raw, "#"))
+		}
+		if _, err := json.Marshal(dump); err != nil {
+			t.Errorf("json.Marshal() error = %v", err)
+		}
+	})
+}
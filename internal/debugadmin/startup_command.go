@@ -8,23 +8,32 @@ import (
 )
 
 func BuildStartupCommand() (*exec.Cmd, error) {
-	program, args, err := resolveStartupProgram()
+	return BuildLaunchCommand(DefaultLaunchSpec())
+}
+
+// BuildLaunchCommand 按 spec 中的参数和环境变量构造子进程命令。
+func BuildLaunchCommand(spec LaunchSpec) (*exec.Cmd, error) {
+	program, args, err := resolveStartupProgram(spec.Args)
 	if err != nil {
 		return nil, err
 	}
-	if GlobalOptions.WithGDB {
-		return exec.Command("gdb", append([]string{"--args", program}, args...)...), nil
-	}
-	if GlobalOptions.WithCoverage {
-		return exec.Command("dotnet-coverage", buildCoverageArgs(program, args)...), nil
+	var cmd *exec.Cmd
+	switch spec.Mode {
+	case RunModeGDB:
+		cmd = exec.Command("gdb", append([]string{"--args", program}, args...)...)
+	case RunModeCoverage:
+		cmd = exec.Command("dotnet-coverage", buildCoverageArgs(spec.coverageSession(), program, args)...)
+	default:
+		cmd = exec.Command(program, args...)
 	}
-	return exec.Command(program, args...), nil
+	cmd.Env = spec.Environ()
+	cmd.Dir = spec.Dir
+	return cmd, nil
 }
 
 // buildCoverageArgs 构造 dotnet-coverage 的命令行参数：
 // collect --session-id ${name} --output /tmp/${name}.coverage ${program} ${args...}
-func buildCoverageArgs(program string, args []string) []string {
-	name := GlobalOptions.CoverageOpts.CoverageName
+func buildCoverageArgs(name string, program string, args []string) []string {
 	coverageArgs := []string{
 		"collect",
 		"--session-id", name,
@@ -37,10 +46,9 @@ func buildCoverageArgs(program string, args []string) []string {
 	return append(coverageArgs, args...)
 }
 
-// resolveStartupProgram 根据 GlobalOptions.StartupParams 解析出实际要执行的程序名和参数，
-// 不涉及 gdb 包装，供 BuildStartupCommand 和 BuildGDBStartupCommand 共用。
-func resolveStartupProgram() (string, []string, error) {
-	parts := GlobalOptions.StartupParams
+// resolveStartupProgram 根据启动参数（默认是 GlobalOptions.StartupParams）解析出实际要执行的程序名和参数，
+// 不涉及 gdb 包装，供 BuildLaunchCommand 和 BuildGDBLaunchCommand 共用。
+func resolveStartupProgram(parts []string) (string, []string, error) {
 	if len(parts) == 0 {
 		return "", nil, errors.New("invalid startup command")
 	}
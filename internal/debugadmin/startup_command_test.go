@@ -2,9 +2,11 @@ package debugadmin
 
 import (
 	"compress/gzip"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -73,9 +75,169 @@ func TestLoadOptionsWithGDBAndCoverageMutuallyExclusive(t *testing.T) {
 	}
 }
 
+func TestLoadOptionsGDBScript(t *testing.T) {
+	configPath := writeConfigFile(t, "with.gdb: true\ngdb.stop.signals: SEGV,ABRT\ngdb.commands:\n  - thread apply all bt full\n  - info sharedlibrary\n")
+	opts, err := loadOptions([]string{"-config=" + configPath, "-gdb.commands=generate-core-file", "--", "app.dll"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GDBScriptOptions{StopSignals: []string{"SIGSEGV", "SIGABRT"}, Commands: []string{"generate-core-file"}}
+	if !reflect.DeepEqual(opts.GDBScript, want) {
+		t.Errorf("GDBScript = %+v, want %+v (flags replace the file list)", opts.GDBScript, want)
+	}
+	opts, err = loadOptions([]string{"-config=" + configPath, "--", "app.dll"})
+	if err != nil || strings.Join(opts.GDBScript.Commands, ";") != "thread apply all bt full;info sharedlibrary" {
+		t.Errorf("GDBScript = %+v, err %v", opts.GDBScript, err)
+	}
+	if _, err := loadOptions([]string{"-gdb.script=/nonexistent/capture.gdb", "--", "app.dll"}); err == nil {
+		t.Error("loadOptions() should reject a missing gdb.script")
+	}
+	if _, err := loadOptions([]string{"-gdb.stop.signals=SIG TERM", "--", "app.dll"}); err == nil {
+		t.Error("loadOptions() should reject an invalid signal")
+	}
+}
+
+func TestLoadOptionsWithEnv(t *testing.T) {
+	configPath := writeConfigFile(t, "admin.port: 9000\nwith.gdb: true\n")
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantErr    bool
+		check      func(t *testing.T, opts *Options)
+		wantSource map[string]string
+	}{
+		{
+			name: "env sets flags",
+			args: []string{"--", "app.dll"},
+			env: map[string]string{
+				"DEBUGADMIN_ADMIN_PORT":          "8070",
+				"DEBUGADMIN_AUTO_RESTART":        "true",
+				"DEBUGADMIN_WITH_GDB":            "1",
+				"DEBUGADMIN_LOG_PUSH_URL":        "http://vlogs:9428/insert/jsonline",
+				"DEBUGADMIN_COVERAGE_EXCLUDE_RE": "^System\\.\nTests$\n",
+			},
+			check: func(t *testing.T, opts *Options) {
+				if opts.AdminPort != 8070 || !opts.AutoRestart || !opts.WithGDB || opts.LogPushURL != "http://vlogs:9428/insert/jsonline" {
+					t.Errorf("opts = %+v, want values from env", opts)
+				}
+				if got := len(opts.CoverageOpts.ExcludeRegexpsForCoverage); got != 2 {
+					t.Errorf("ExcludeRegexpsForCoverage has %d items, want 2", got)
+				}
+			},
+			wantSource: map[string]string{"admin.port": ConfigSourceEnv, "with.coverage": ConfigSourceDefault},
+		},
+		{
+			name: "flag overrides env",
+			args: []string{"-admin.port=8071", "--", "app.dll"},
+			env:  map[string]string{"DEBUGADMIN_ADMIN_PORT": "8070"},
+			check: func(t *testing.T, opts *Options) {
+				if opts.AdminPort != 8071 {
+					t.Errorf("AdminPort = %d, want 8071", opts.AdminPort)
+				}
+			},
+			wantSource: map[string]string{"admin.port": ConfigSourceFlag},
+		},
+		{
+			name: "env overrides file",
+			args: []string{"--", "app.dll"},
+			env:  map[string]string{"DEBUGADMIN_CONFIG": configPath, "DEBUGADMIN_ADMIN_PORT": "8070"},
+			check: func(t *testing.T, opts *Options) {
+				if opts.AdminPort != 8070 || !opts.WithGDB {
+					t.Errorf("AdminPort = %d WithGDB = %t, want 8070 and true", opts.AdminPort, opts.WithGDB)
+				}
+			},
+			wantSource: map[string]string{"config": ConfigSourceEnv, "admin.port": ConfigSourceEnv, "with.gdb": ConfigSourceFile},
+		},
+		{
+			name: "empty env is ignored",
+			args: []string{"--", "app.dll"},
+			env:  map[string]string{"DEBUGADMIN_ADMIN_PORT": ""},
+			check: func(t *testing.T, opts *Options) {
+				if opts.AdminPort != defaultPort {
+					t.Errorf("AdminPort = %d, want %d", opts.AdminPort, defaultPort)
+				}
+			},
+			wantSource: map[string]string{"admin.port": ConfigSourceDefault},
+		},
+		{
+			name:    "invalid env value",
+			args:    []string{"--", "app.dll"},
+			env:     map[string]string{"DEBUGADMIN_WITH_GDB": "maybe"},
+			wantErr: true,
+		},
+		{
+			name: "target command from env",
+			env:  map[string]string{"DEBUGADMIN_TARGET": `/app/Api.dll --name "a b" -- name=worker /app/Worker.dll`},
+			check: func(t *testing.T, opts *Options) {
+				want := []TargetOptions{
+					{Name: "main", StartupParams: []string{"/app/Api.dll", "--name", "a b"}},
+					{Name: "worker", StartupParams: []string{"/app/Worker.dll"}},
+				}
+				if !reflect.DeepEqual(opts.Targets, want) || opts.TargetsSource != ConfigSourceEnv {
+					t.Errorf("Targets = %+v source = %q, want %+v from env", opts.Targets, opts.TargetsSource, want)
+				}
+			},
+		},
+		{
+			name: "-- overrides target env",
+			args: []string{"--", "app.dll"},
+			env:  map[string]string{"DEBUGADMIN_TARGET": "other.dll"},
+			check: func(t *testing.T, opts *Options) {
+				if want := []string{"app.dll"}; !reflect.DeepEqual(opts.StartupParams, want) || opts.TargetsSource != ConfigSourceFlag {
+					t.Errorf("StartupParams = %q source = %q, want %q from flag", opts.StartupParams, opts.TargetsSource, want)
+				}
+			},
+		},
+		{
+			name:    "unterminated quote in target env",
+			env:     map[string]string{"DEBUGADMIN_TARGET": `app.dll "oops`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			opts, err := loadOptions(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadOptions() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.check != nil {
+				tt.check(t, opts)
+			}
+			sources := make(map[string]string)
+			for _, entry := range opts.Effective {
+				sources[entry.Name] = entry.Source
+			}
+			for name, source := range tt.wantSource {
+				if sources[name] != source {
+					t.Errorf("source of %s = %q, want %q", name, sources[name], source)
+				}
+			}
+		})
+	}
+}
+
+func TestFlagEnvName(t *testing.T) {
+	for name, want := range map[string]string{
+		"admin.port":               "DEBUGADMIN_ADMIN_PORT",
+		"coverage.source.from.pdb": "DEBUGADMIN_COVERAGE_SOURCE_FROM_PDB",
+		"target.stop.timeout":      "DEBUGADMIN_TARGET_STOP_TIMEOUT",
+	} {
+		if got := flagEnvName(name); got != want {
+			t.Errorf("flagEnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
 func TestWriteGDBCommandScript(t *testing.T) {
 	now := time.Date(2026, time.July, 21, 12, 34, 56, 0, time.UTC)
-	scriptPath, logPath, err := WriteGDBCommandScript(now)
+	scriptPath, logPath, err := WriteGDBCommandScript(now, GDBScriptOptions{})
 	if err != nil {
 		t.Fatalf("WriteGDBCommandScript() error = %v", err)
 	}
@@ -92,21 +254,120 @@ func TestWriteGDBCommandScript(t *testing.T) {
 	script := string(data)
 	for _, command := range []string{
 		"set logging file " + logPath,
-		"set logging on",
+		"set logging enabled on",
 		"handle SIGSEGV stop print pass",
 		"run",
-		"bt 10",
+		"\nbt\n",
 		"quit 128",
 	} {
 		if !strings.Contains(script, command) {
 			t.Errorf("command script does not contain %q", command)
 		}
 	}
-	if strings.Index(script, "set logging on") > strings.Index(script, "run") {
+	if strings.Index(script, "set logging enabled on") > strings.Index(script, "run") {
 		t.Error("gdb logging should be configured before run")
 	}
 }
 
+func TestWriteGDBCommandScriptSameSecond(t *testing.T) {
+	// 两个目标进程在同一秒启动时，各自的脚本和日志不能互相覆盖
+	now := time.Date(2026, time.July, 21, 12, 34, 54, 0, time.UTC)
+	var scripts, logs []string
+	for _, command := range []string{"info sharedlibrary", "info threads"} {
+		scriptPath, logPath, err := WriteGDBCommandScript(now, GDBScriptOptions{Commands: []string{command}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = os.Remove(scriptPath)
+		})
+		if !isGDBLogPath(logPath) || gdbScriptPathForLog(logPath) != scriptPath {
+			t.Errorf("WriteGDBCommandScript() = %q, %q", scriptPath, logPath)
+		}
+		data, err := os.ReadFile(scriptPath)
+		if err != nil || !strings.Contains(string(data), "set logging file "+logPath+"\n") || !strings.Contains(string(data), command) {
+			t.Errorf("script %s = %q, %v", scriptPath, data, err)
+		}
+		scripts, logs = append(scripts, scriptPath), append(logs, logPath)
+	}
+	if scripts[0] == scripts[1] || logs[0] == logs[1] {
+		t.Errorf("scripts = %q, logs = %q, want different files", scripts, logs)
+	}
+	if want := "/tmp/20260721-123454-2.log"; logs[1] != want {
+		t.Errorf("second log path = %q, want %q", logs[1], want)
+	}
+}
+
+func TestWriteGDBCommandScriptDefaults(t *testing.T) {
+	scriptPath, logPath, err := WriteGDBCommandScript(time.Date(2026, time.July, 21, 12, 34, 55, 0, time.UTC), GDBScriptOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/tmp/20260721-123455.gdb"; scriptPath != want || gdbScriptPathForLog(logPath) != want {
+		t.Errorf("WriteGDBCommandScript() script path = %q, want %q", scriptPath, want)
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(scriptPath)
+	})
+	data, err := os.ReadFile(scriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(data)
+	for _, command := range []string{
+		"handle SIGFPE stop print pass",
+		"handle SIGPIPE nostop noprint pass",
+		"info registers\nset logging enabled off\nkill\nquit 128\n",
+	} {
+		if !strings.Contains(script, command) {
+			t.Errorf("command script does not contain %q", command)
+		}
+	}
+	if strings.Contains(script, "nostop print pass") || strings.Contains(script, "Extra commands") {
+		t.Errorf("default script = %s", script)
+	}
+}
+
+func TestRenderGDBCommandScriptOptions(t *testing.T) {
+	userScript := filepath.Join(t.TempDir(), "capture.gdb")
+	if err := os.WriteFile(userScript, []byte("info proc mappings\nx/32i $pc\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script, err := renderGDBCommandScript("/tmp/20260721-123456.log", GDBScriptOptions{
+		StopSignals: []string{"SIGSEGV", "SIGTERM", "SIGPIPE"},
+		Commands:    []string{"thread apply all bt full", "generate-core-file"},
+		ScriptFile:  userScript,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{
+		"handle SIGSEGV stop print pass\nhandle SIGTERM stop print pass\nhandle SIGPIPE stop print pass\n",
+		"handle SIGABRT nostop print pass\n",
+		"handle SIG34 nostop noprint pass\n",
+		"info registers\necho \\n===== Extra commands =====\\n\nthread apply all bt full\ngenerate-core-file\n",
+		"echo \\n===== " + userScript + " =====\\n\ninfo proc mappings\nx/32i $pc\nset logging enabled off\n",
+	} {
+		if !strings.Contains(script, command) {
+			t.Errorf("command script does not contain %q:\n%s", command, script)
+		}
+	}
+	if strings.Contains(script, "handle SIGPIPE nostop") {
+		t.Error("SIGPIPE is a stop signal and must not be ignored")
+	}
+	if _, err := renderGDBCommandScript("/tmp/x.log", GDBScriptOptions{ScriptFile: filepath.Join(t.TempDir(), "missing.gdb")}); err == nil {
+		t.Error("a missing user script must fail the start")
+	}
+
+	signals, err := parseGDBSignals([]string{"segv, SIGABRT", "sigsegv", "usr1"})
+	if err != nil || strings.Join(signals, ",") != "SIGSEGV,SIGABRT,SIGUSR1" {
+		t.Errorf("parseGDBSignals() = %q, %v", signals, err)
+	}
+	if _, err := parseGDBSignals([]string{"SIG-TERM"}); err == nil {
+		t.Error("parseGDBSignals() should reject invalid names")
+	}
+}
+
 func TestBuildGDBStartupCommand(t *testing.T) {
 	GlobalOptions = &Options{WithGDB: true, StartupParams: []string{"app.dll", "-param1=value1"}}
 	cmd, err := BuildGDBStartupCommand("/tmp/debugadmin-gdb.gdb")
@@ -139,6 +400,37 @@ func TestHandleGDBLog(t *testing.T) {
 	}
 }
 
+func TestHandleGDBLogScript(t *testing.T) {
+	scriptPath, logPath, err := WriteGDBCommandScript(time.Date(2026, time.July, 21, 12, 34, 50, 0, time.UTC), GDBScriptOptions{Commands: []string{"info sharedlibrary"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(scriptPath)
+	})
+	handler := &AdminHandler{history: &RunHistory{records: []RunRecord{
+		{GDBLogPath: logPath, GDBScriptPath: scriptPath},
+		{GDBLogPath: logPath, GDBScriptPath: "/etc/passwd"},
+		{GDBLogPath: "/etc/passwd.log", GDBScriptPath: "/etc/passwd.gdb"},
+	}}}
+	response := httptest.NewRecorder()
+	handler.handleGDBLog(response, httptest.NewRequest("GET", "/gdb-log?index=0&kind=script", nil))
+	if response.Code != 200 || !strings.Contains(response.Body.String(), "set logging file "+logPath+"\n") ||
+		!strings.Contains(response.Body.String(), "info sharedlibrary") {
+		t.Errorf("handleGDBLog() = %d %q", response.Code, response.Body.String())
+	}
+	response = httptest.NewRecorder()
+	handler.handleGDBLog(response, httptest.NewRequest("GET", "/gdb-log?index=1&kind=script", nil))
+	if response.Code != 404 {
+		t.Errorf("handleGDBLog() status = %d, only the script next to the log can be read", response.Code)
+	}
+	response = httptest.NewRecorder()
+	handler.handleGDBLog(response, httptest.NewRequest("GET", "/gdb-log?index=2&kind=script", nil))
+	if response.Code != 404 {
+		t.Errorf("handleGDBLog() status = %d, the log must be a gdb log under os.TempDir()", response.Code)
+	}
+}
+
 func TestHandleGDBLogGzip(t *testing.T) {
 	logPath := "/tmp/20260721-123457.log"
 	if err := os.WriteFile(logPath, []byte("crash backtrace"), 0o600); err != nil {
@@ -211,3 +503,158 @@ func TestHandleRootShowsCurrentGDBLogOnlyForGDBTarget(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildLaunchCommandWithEnv(t *testing.T) {
+	GlobalOptions = &Options{StartupParams: []string{"app.dll"}}
+	spec, err := parseLaunchSpec("", "app.dll\n--urls=http://*:8080\n", "DOTNET_gcServer=1\r\n\nDOTNET_TieredPGO=0")
+	if err != nil {
+		t.Fatalf("parseLaunchSpec() error = %v", err)
+	}
+	cmd, err := BuildLaunchCommand(spec)
+	if err != nil {
+		t.Fatalf("BuildLaunchCommand() error = %v", err)
+	}
+	if want := []string{"dotnet", "app.dll", "--urls=http://*:8080"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("BuildLaunchCommand() args = %q, want %q", cmd.Args, want)
+	}
+	if got := cmd.Env[len(cmd.Env)-2:]; !reflect.DeepEqual(got, []string{"DOTNET_gcServer=1", "DOTNET_TieredPGO=0"}) {
+		t.Errorf("BuildLaunchCommand() env tail = %q", got)
+	}
+	if _, err := parseLaunchSpec("", "app.dll", "not an env"); err == nil {
+		t.Error("parseLaunchSpec() error = nil, want error for invalid env")
+	}
+}
+
+func TestBuildLaunchCommandWithPerfMap(t *testing.T) {
+	GlobalOptions = &Options{PerfMap: true, StartupParams: []string{"app.dll"}}
+	cmd, err := BuildLaunchCommand(LaunchSpec{Args: []string{"app.dll"}})
+	if err != nil {
+		t.Fatalf("BuildLaunchCommand() error = %v", err)
+	}
+	if got := cmd.Env[len(cmd.Env)-1]; got != "DOTNET_PerfMapEnabled=1" {
+		t.Errorf("BuildLaunchCommand() env tail = %q, want DOTNET_PerfMapEnabled=1", got)
+	}
+	// 启动参数中指定的值排在后面，优先于 -perf.map
+	cmd, err = BuildLaunchCommand(LaunchSpec{Args: []string{"app.dll"}, Env: []string{"DOTNET_PerfMapEnabled=3"}})
+	if err != nil {
+		t.Fatalf("BuildLaunchCommand() error = %v", err)
+	}
+	if got := cmd.Env[len(cmd.Env)-2:]; !reflect.DeepEqual(got, []string{"DOTNET_PerfMapEnabled=1", "DOTNET_PerfMapEnabled=3"}) {
+		t.Errorf("BuildLaunchCommand() env tail = %q", got)
+	}
+	GlobalOptions = &Options{StartupParams: []string{"app.dll"}}
+	if env := (LaunchSpec{Args: []string{"app.dll"}}).Environ(); env != nil {
+		t.Errorf("Environ() without -perf.map = %d entries, want nil", len(env))
+	}
+}
+
+func TestHandlePerfMap(t *testing.T) {
+	pid := 4200000 + os.Getpid()%100000
+	if err := os.WriteFile(perfMapPath(pid), []byte("7F0000001000 100 void [MyApp] A::First()\n"), 0o600); err != nil {
+		t.Fatalf("write perf map: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Remove(perfMapPath(pid)) })
+	history := NewRunHistory()
+	history.Add(RunRecord{PID: pid, PerfMapPath: perfMapPath(pid)})
+	history.Add(RunRecord{PID: pid + 1, PerfMapPath: "/etc/passwd"})
+	handler := &AdminHandler{history: history}
+
+	tests := []struct {
+		url        string
+		wantStatus int
+	}{
+		{url: "/perf-map?index=0", wantStatus: 200},
+		{url: "/perf-map?index=0&kind=info", wantStatus: 404},
+		{url: "/perf-map?index=1", wantStatus: 404},
+		{url: "/perf-map?index=2", wantStatus: 400},
+		{url: "/perf-map", wantStatus: 404},
+	}
+	for _, tt := range tests {
+		response := httptest.NewRecorder()
+		handler.handlePerfMap(response, httptest.NewRequest("GET", tt.url, nil))
+		if response.Code != tt.wantStatus {
+			t.Errorf("GET %s status = %d, want %d", tt.url, response.Code, tt.wantStatus)
+		}
+	}
+	response := httptest.NewRecorder()
+	handler.handlePerfMap(response, httptest.NewRequest("GET", "/perf-map?index=0", nil))
+	if !strings.Contains(response.Body.String(), "A::First") ||
+		response.Header().Get("Content-Disposition") != fmt.Sprintf(`attachment; filename="perf-%d.map"`, pid) {
+		t.Errorf("download = %q %q", response.Header().Get("Content-Disposition"), response.Body.String())
+	}
+}
+
+func TestBuildLaunchCommandUsesSpecMode(t *testing.T) {
+	// -with.gdb 只决定第一次启动的运行方式，之后每次启动以 LaunchSpec.Mode 为准。
+	GlobalOptions = &Options{WithGDB: true, StartupParams: []string{"app.dll"}, CoverageOpts: CoverageOptions{CoverageName: "cov-1"}}
+	tests := []struct {
+		mode RunMode
+		want []string
+	}{
+		{mode: RunModePlain, want: []string{"dotnet", "app.dll"}},
+		{mode: RunModeGDB, want: []string{"gdb", "--args", "dotnet", "app.dll"}},
+		{mode: RunModeCoverage, want: []string{"dotnet-coverage", "collect", "--session-id", "cov-1", "--output", "/tmp/cov-1.coverage", "dotnet", "app.dll"}},
+	}
+	for _, tt := range tests {
+		cmd, err := BuildLaunchCommand(LaunchSpec{Mode: tt.mode, Args: []string{"app.dll"}})
+		if err != nil {
+			t.Fatalf("BuildLaunchCommand(%s) error = %v", tt.mode, err)
+		}
+		if !reflect.DeepEqual(cmd.Args, tt.want) {
+			t.Errorf("BuildLaunchCommand(%s) args = %q, want %q", tt.mode, cmd.Args, tt.want)
+		}
+	}
+	if _, err := parseRunMode("lldb"); err == nil {
+		t.Error("parseRunMode() error = nil, want error for unknown mode")
+	}
+}
+
+func TestLoadOptionsMultipleTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []TargetOptions
+		wantErr bool
+	}{
+		{
+			name: "single target keeps inner --",
+			args: []string{"--", "app.dll", "--", "-x"},
+			want: []TargetOptions{{Name: "main", StartupParams: []string{"app.dll", "--", "-x"}}},
+		},
+		{
+			name: "named groups",
+			args: []string{"-auto.restart", "--", "name=api", "api.dll", "--port=1", "--", "name=worker", "worker.dll"},
+			want: []TargetOptions{
+				{Name: "api", StartupParams: []string{"api.dll", "--port=1"}, AutoRestart: true},
+				{Name: "worker", StartupParams: []string{"worker.dll"}, AutoRestart: true},
+			},
+		},
+		{
+			name: "unnamed first group",
+			args: []string{"--", "api.dll", "--", "name=worker", "worker.dll"},
+			want: []TargetOptions{
+				{Name: "main", StartupParams: []string{"api.dll"}},
+				{Name: "worker", StartupParams: []string{"worker.dll"}},
+			},
+		},
+		{name: "duplicate name", args: []string{"--", "name=a", "x.dll", "--", "name=a", "y.dll"}, wantErr: true},
+		{name: "empty group", args: []string{"--", "app.dll", "--", "name=worker"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := loadOptions(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadOptions() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(opts.Targets, tt.want) {
+				t.Errorf("loadOptions() Targets = %+v, want %+v", opts.Targets, tt.want)
+			}
+			if !reflect.DeepEqual(opts.StartupParams, tt.want[0].StartupParams) {
+				t.Errorf("loadOptions() StartupParams = %q, want first target's params", opts.StartupParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package debugadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// targetStatus 是 /api/target/* 接口返回的 json。
+type targetStatus struct {
+	PID     int  `json:"pid"`
+	Running bool `json:"running"`
+}
+
+// handleTargetStop 优雅停止目标进程：SIGTERM，等待 -target.stop.timeout 后 SIGKILL。
+// 手动停止后 DebugAdmin 继续运行，可以通过 /api/target/start 重新拉起。
+func (h *AdminHandler) handleTargetStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.supervisor.Stop(); err != nil {
+		http.Error(w, fmt.Sprintf("stop target failed: %v", err), http.StatusConflict)
+		return
+	}
+	h.writeTargetStatus(w)
+}
+
+func (h *AdminHandler) handleTargetStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.supervisor.Start(RunReasonManual); err != nil {
+		http.Error(w, fmt.Sprintf("start target failed: %v", err), http.StatusConflict)
+		return
+	}
+	h.writeTargetStatus(w)
+}
+
+func (h *AdminHandler) handleTargetRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.supervisor.Restart(); err != nil {
+		http.Error(w, fmt.Sprintf("restart target failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.writeTargetStatus(w)
+}
+
+// handleTargetSignal 向目标进程发送 sig 参数指定的信号，例如 sig=TERM、sig=SIGUSR1、sig=9。
+func (h *AdminHandler) handleTargetSignal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sig, err := parseSignal(r.URL.Query().Get("sig"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.supervisor.Signal(sig); err != nil {
+		http.Error(w, fmt.Sprintf("send %s failed: %v", unix.SignalName(sig), err), http.StatusConflict)
+		return
+	}
+	h.writeTargetStatus(w)
+}
+
+// handleTargetLaunch GET 返回下一次启动使用的参数和原始参数；
+// POST 使用表单中的 mode（plain / gdb / coverage）和 args / env（每行一项）重启目标进程。
+func (h *AdminHandler) handleTargetLaunch(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		current, original := h.supervisor.LaunchSpecs()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			Current  LaunchSpec `json:"current"`
+			Original LaunchSpec `json:"original"`
+		}{current, original})
+	case http.MethodPost:
+		spec, err := parseLaunchSpec(r.FormValue("mode"), r.FormValue("args"), r.FormValue("env"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := h.supervisor.RestartWith(spec); err != nil {
+			http.Error(w, fmt.Sprintf("restart target failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		h.writeTargetStatus(w)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTargetRevert 恢复命令行指定的原始启动参数，并重启目标进程。
+func (h *AdminHandler) handleTargetRevert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.supervisor.RevertLaunchSpec(); err != nil {
+		http.Error(w, fmt.Sprintf("restart target failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.writeTargetStatus(w)
+}
+
+func (h *AdminHandler) writeTargetStatus(w http.ResponseWriter) {
+	status := targetStatus{}
+	if target := h.supervisor.Current(); target != nil {
+		status.PID = target.PID()
+		status.Running = target.Running()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// parseSignal 解析信号名或者信号编号，"TERM" 和 "SIGTERM" 都可以。
+func parseSignal(raw string) (syscall.Signal, error) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	if raw == "" {
+		return 0, fmt.Errorf("missing sig parameter")
+	}
+	if number, err := strconv.Atoi(raw); err == nil {
+		if number <= 0 || number >= 65 {
+			return 0, fmt.Errorf("invalid signal number: %d", number)
+		}
+		return syscall.Signal(number), nil
+	}
+	if !strings.HasPrefix(raw, "SIG") {
+		raw = "SIG" + raw
+	}
+	sig := unix.SignalNum(raw)
+	if sig == 0 {
+		return 0, fmt.Errorf("unknown signal: %s", raw)
+	}
+	return sig, nil
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -20,9 +22,13 @@ type TargetProcess struct {
 	broker        *LogBroker
 	history       *RunHistory
 	startTime     time.Time
+	startReason   string
+	spec          LaunchSpec
+	reason        string // 退出原因，waitForExit 在关闭 done 之前写入
 	gdbLogPath    string
 	gdbScriptPath string
 	done          chan error
+	exitedCh      chan struct{}
 	stdoutWriter  io.Writer
 	stderrWriter  io.Writer
 	lineWriter    io.Writer
@@ -30,11 +36,32 @@ type TargetProcess struct {
 	lineWriteDead bool
 	recentMu      sync.Mutex
 	recentLines   []string
+	exited        atomic.Bool
+	// targetPID 是 resolvePID 最近一次在进程树中找到的真正目标进程 pid，进程退出后用来定位 perf map。
+	targetPID atomic.Int64
+	// manualStop 为 true 表示进程是被管理端手动停止的，退出记录的原因为 "manual"。
+	manualStop atomic.Bool
+	// manualSignal 记录管理端最近一次手动发送的信号名，进程因该信号退出时同样按 "manual" 记录。
+	manualSignal atomic.Pointer[string]
+	// gdbStdin 只在 -with.gdb 且 -gdb.hold 大于 0 时存在，用于向保留现场的 gdb 发送命令，见 gdb_console.go。
+	gdbStdin     io.WriteCloser
+	gdbHold      time.Duration
+	holdMarker   string // 这次运行的 gdb 停下后打印的一行，见 checkGDBHoldMarker
+	holdMu       sync.Mutex
+	holdUntil    time.Time
+	holdTimer    *time.Timer
+	holdReleased bool
 }
 
 // StartTarget 创建被调试的子进程
-func StartTarget(broker *LogBroker, lineWriter io.Writer, logStdoutOutput bool, history *RunHistory) (*TargetProcess, error) {
-	cmd, gdbLogPath, gdbScriptPath, err := buildTargetCommand()
+// @param startReason 记录到 RunRecord.StartReason，取值见 RunReasonInitial 等常量
+// @param spec 本次启动使用的命令行参数和额外环境变量
+func StartTarget(broker *LogBroker, lineWriter io.Writer, logStdoutOutput bool, history *RunHistory, startReason string, spec LaunchSpec) (*TargetProcess, error) {
+	gdbScript := GlobalOptions.GDBScript
+	if spec.Mode == RunModeGDB && gdbScript.Hold > 0 {
+		gdbScript.holdMarker = newGDBHoldMarker()
+	}
+	cmd, gdbLogPath, gdbScriptPath, err := buildTargetCommand(spec, gdbScript)
 	if err != nil {
 		return nil, err
 	}
@@ -47,6 +74,15 @@ func StartTarget(broker *LogBroker, lineWriter io.Writer, logStdoutOutput bool,
 	if err != nil {
 		return nil, err
 	}
+	// 保留现场时 gdb 在脚本执行完之后从 stdin 读取命令；否则 stdin 为 /dev/null，gdb 读到 EOF 即退出
+	var gdbStdin io.WriteCloser
+	gdbHold := time.Duration(0)
+	if spec.Mode == RunModeGDB && gdbScript.Hold > 0 {
+		gdbHold = gdbScript.Hold
+		if gdbStdin, err = cmd.StdinPipe(); err != nil {
+			return nil, err
+		}
+	}
 	if err := cmd.Start(); err != nil {
 		removeGDBCommandScript(gdbScriptPath)
 		return nil, err
@@ -58,10 +94,16 @@ func StartTarget(broker *LogBroker, lineWriter io.Writer, logStdoutOutput bool,
 		broker:        broker,
 		history:       history,
 		startTime:     time.Now(),
+		startReason:   startReason,
+		spec:          spec.Clone(),
 		gdbLogPath:    gdbLogPath,
 		gdbScriptPath: gdbScriptPath,
 		done:          make(chan error, 1),
+		exitedCh:      make(chan struct{}),
 		lineWriter:    lineWriter,
+		gdbStdin:      gdbStdin,
+		gdbHold:       gdbHold,
+		holdMarker:    gdbScript.holdMarker,
 	}
 	if logStdoutOutput {
 		target.stdoutWriter = os.Stdout
@@ -70,13 +112,49 @@ func StartTarget(broker *LogBroker, lineWriter io.Writer, logStdoutOutput bool,
 	go target.consumeOutput(stdoutPipe, target.stdoutWriter)
 	go target.consumeOutput(stderrPipe, target.stderrWriter)
 	go target.waitForExit()
+	if spec.Mode == RunModeGDB || spec.Mode == RunModeCoverage {
+		go target.watchTargetPID()
+	}
 	return target, nil
 }
 
+// targetPIDWatchTimeout 是启动后在进程树中查找真正目标进程的最长时间。
+const targetPIDWatchTimeout = 30 * time.Second
+
+// watchTargetPID 在 gdb / coverage 模式下定时查找真正的目标进程，记下它的 pid，
+// 这样即使运行期间没有任何请求调用 resolvePID，退出时也能找到它写出的 perf map。
+func (p *TargetProcess) watchTargetPID() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(targetPIDWatchTimeout)
+	for {
+		select {
+		case <-p.exitedCh:
+			return
+		case <-deadline:
+			return
+		case <-ticker.C:
+		}
+		if p.resolvePID() != p.pid {
+			return
+		}
+	}
+}
+
 func (p *TargetProcess) PID() int {
 	return p.pid
 }
 
+// LaunchSpec 返回启动这个子进程时使用的运行方式、参数和环境变量。
+func (p *TargetProcess) LaunchSpec() LaunchSpec {
+	return p.spec.Clone()
+}
+
+// Mode 返回这个子进程的运行方式。
+func (p *TargetProcess) Mode() RunMode {
+	return p.spec.Mode
+}
+
 // GDBLogPath returns the log file created for this target when it was started with gdb.
 func (p *TargetProcess) GDBLogPath() string {
 	return p.gdbLogPath
@@ -86,6 +164,94 @@ func (p *TargetProcess) Done() <-chan error {
 	return p.done
 }
 
+// Running 返回子进程是否仍在运行。
+func (p *TargetProcess) Running() bool {
+	return !p.exited.Load()
+}
+
+// resolvePID 返回真正的目标进程 pid。
+// 当以 gdb 或 coverage 模式启动时，p.PID() 是 gdb / dotnet-coverage
+// 外壳进程的 pid，必须通过启动参数在进程树中定位真正的目标进程，
+// 否则 dotnet-trace / netcoredbg 会挂到外壳进程上，采集不到目标进程的数据。
+func (p *TargetProcess) resolvePID() int {
+	if p.spec.Mode != RunModeGDB && p.spec.Mode != RunModeCoverage {
+		return p.pid
+	}
+	if resolved, ok := findTargetDescendantPID(p.pid, p.spec.Args); ok {
+		p.targetPID.Store(int64(resolved))
+		return resolved
+	}
+	return p.pid
+}
+
+// corePID 返回崩溃时写 core 的进程：gdb / coverage 模式下是外壳进程启动的真正目标进程。
+func (p *TargetProcess) corePID() int {
+	if pid := int(p.targetPID.Load()); pid > 0 {
+		return pid
+	}
+	return p.pid
+}
+
+// perfMapFiles 返回运行时为真正的目标进程写出的 perf map 与 perfinfo 文件，不存在的为空字符串。
+func (p *TargetProcess) perfMapFiles() (perfMap, perfInfo string) {
+	pid := p.corePID()
+	if fileExists(perfMapPath(pid)) {
+		perfMap = perfMapPath(pid)
+	}
+	if fileExists(perfInfoPath(pid)) {
+		perfInfo = perfInfoPath(pid)
+	}
+	return perfMap, perfInfo
+}
+
+// Signal 向真正的目标进程发送信号，并记录下来：如果进程因为这个信号退出，
+// 退出记录的原因会是 "manual" 而不是崩溃。
+func (p *TargetProcess) Signal(sig syscall.Signal) error {
+	if !p.Running() {
+		return fmt.Errorf("target process %d is not running", p.pid)
+	}
+	name := sig.String()
+	p.manualSignal.Store(&name)
+	return syscall.Kill(p.resolvePID(), sig)
+}
+
+// Stop 优雅地停止子进程：先向目标进程发送 SIGTERM，在 timeout 内没有退出则对
+// 外壳进程和目标进程发送 SIGKILL。返回时进程已经退出，退出记录的原因为 "manual"。
+func (p *TargetProcess) Stop(timeout time.Duration) error {
+	if !p.Running() {
+		return fmt.Errorf("target process %d is not running", p.pid)
+	}
+	// 先标记手动停止，结束现场后 gdb 马上退出，TargetSupervisor 需要知道这次退出不应该触发 auto.restart
+	p.manualStop.Store(true)
+	if p.ReleaseGDBHold() == nil {
+		// 保留现场的进程已经停在崩溃信号上，结束现场即可，退出记录仍然是崩溃
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-p.exitedCh:
+			return nil
+		case <-timer.C:
+		}
+	}
+	targetPID := p.resolvePID()
+	if err := syscall.Kill(targetPID, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("send SIGTERM to pid %d failed: %w", targetPID, err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.exitedCh:
+		return nil
+	case <-timer.C:
+	}
+	_ = syscall.Kill(targetPID, syscall.SIGKILL)
+	if targetPID != p.pid {
+		_ = p.cmd.Process.Kill()
+	}
+	<-p.exitedCh
+	return nil
+}
+
 func (p *TargetProcess) consumeOutput(reader io.ReadCloser, localWriter io.Writer) {
 	defer reader.Close()
 
@@ -94,6 +260,7 @@ func (p *TargetProcess) consumeOutput(reader io.ReadCloser, localWriter io.Write
 	scanner.Buffer(buf, 1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text() + "\n"
+		p.checkGDBHoldMarker(scanner.Text())
 		if localWriter != nil {
 			_, _ = io.WriteString(localWriter, line)
 		}
@@ -110,56 +277,95 @@ func (p *TargetProcess) consumeOutput(reader io.ReadCloser, localWriter io.Write
 
 func (p *TargetProcess) waitForExit() {
 	err := p.cmd.Wait()
-	removeGDBCommandScript(p.gdbScriptPath)
 	endTime := time.Now()
+	p.holdMu.Lock()
+	p.holdReleased = true
+	if p.holdTimer != nil {
+		p.holdTimer.Stop()
+	}
+	held := !p.holdUntil.IsZero()
+	p.holdMu.Unlock()
 	message := fmt.Sprintf("[target exited] pid=%d err=%v\n", p.pid, err)
 	_, _ = os.Stdout.WriteString(message)
 	p.broker.Broadcast(message)
+	exitCode, signal, abnormal := classifyExit(err)
+	if p.spec.Mode == RunModeCoverage && !abnormal {
+		// dotnet-coverage collect 是被调试进程的外壳进程：即使它包裹的目标进程被信号杀死
+		// 或者非零退出，dotnet-coverage 自身也总是返回 0（已知限制，见
+		// https://github.com/microsoft/vstest/issues/4094）。因此这种模式下无法用外壳
+		// 进程的退出码判断目标进程是否正常退出，这里统一按异常处理，
+		// 保证现场日志被记录、auto.restart 能够正常触发重启。
+		abnormal = true
+	}
+	p.reason = p.exitReason(signal, abnormal, held)
 	if p.history != nil {
-		exitCode, signal, abnormal := classifyExit(err)
-		if GlobalOptions.WithCoverage && !abnormal {
-			// dotnet-coverage collect 是被调试进程的外壳进程：即使它包裹的目标进程被信号杀死
-			// 或者非零退出，dotnet-coverage 自身也总是返回 0（已知限制，见
-			// https://github.com/microsoft/vstest/issues/4094）。因此这种模式下无法用外壳
-			// 进程的退出码判断目标进程是否正常退出，这里统一按异常处理，
-			// 保证现场日志被记录、auto.restart 能够正常触发重启。
-			abnormal = true
-		}
 		record := RunRecord{
-			PID:        p.pid,
-			StartTime:  p.startTime,
-			EndTime:    endTime,
-			ExitCode:   exitCode,
-			Signal:     signal,
-			Abnormal:   abnormal,
-			LastLogs:   p.RecentLines(),
-			GDBLogPath: p.gdbLogPath,
+			PID:           p.pid,
+			StartTime:     p.startTime,
+			EndTime:       endTime,
+			ExitCode:      exitCode,
+			Signal:        signal,
+			Abnormal:      abnormal,
+			LastLogs:      p.RecentLines(),
+			GDBLogPath:    p.gdbLogPath,
+			GDBScriptPath: p.gdbScriptPath,
+			StartReason:   p.startReason,
+			Mode:          p.spec.Mode,
+			Args:          p.spec.Args,
+			Env:           p.spec.Env,
+			Dir:           p.spec.WorkDir(),
 		}
 		if err != nil {
 			record.Err = err.Error()
 		}
-		if abnormal {
-			record.CoreDumpPath = detectCoreDump(p.pid)
+		record.Reason = p.reason
+		if record.Reason == RunReasonManual {
+			// 手动停止的记录与崩溃分开统计，不触发 auto.restart。
+			record.Abnormal = false
+		}
+		if record.Abnormal {
+			record.CoreDumpPath, record.CoreDumpSource = detectCoreDump(p.corePID(), p.startTime.Truncate(time.Second), record.Dir)
 		}
+		record.PerfMapPath, record.PerfInfoPath = p.perfMapFiles()
 		p.history.Add(record)
 	}
+	p.exited.Store(true)
+	close(p.exitedCh)
 	p.done <- err
 	close(p.done)
 }
 
-func buildTargetCommand() (*exec.Cmd, string, string, error) {
-	if !GlobalOptions.WithGDB {
+// exitReason 根据是否由管理端手动停止/发信号，以及退出是否异常，给出退出原因。
+// gdb 保留过现场（held）的进程已经停在崩溃信号上，之后即使由管理端结束也按崩溃记录。
+func (p *TargetProcess) exitReason(signal string, abnormal, held bool) string {
+	if held {
+		return RunReasonCrash
+	}
+	if p.manualStop.Load() {
+		return RunReasonManual
+	}
+	if name := p.manualSignal.Load(); name != nil && signal != "" && *name == signal {
+		return RunReasonManual
+	}
+	if abnormal {
+		return RunReasonCrash
+	}
+	return RunReasonExit
+}
+
+func buildTargetCommand(spec LaunchSpec, gdbScript GDBScriptOptions) (*exec.Cmd, string, string, error) {
+	if spec.Mode != RunModeGDB {
 		// 没有 gdb 的情况，走原来的逻辑
-		cmd, err := BuildStartupCommand()
+		cmd, err := BuildLaunchCommand(spec)
 		return cmd, "", "", err
 	}
 	// 构造 *.gdb 命令文件
-	scriptPath, logPath, err := WriteGDBCommandScript(time.Now())
+	scriptPath, logPath, err := WriteGDBCommandScript(time.Now(), gdbScript)
 	if err != nil {
 		return nil, "", "", err
 	}
 	// 构造 gdb 命令行:  gdb -q -x xx.gdb --args ${params}
-	cmd, err := BuildGDBStartupCommand(scriptPath)
+	cmd, err := BuildGDBLaunchCommand(spec, scriptPath)
 	if err != nil {
 		removeGDBCommandScript(scriptPath)
 		return nil, "", "", err
@@ -0,0 +1,85 @@
+package debugadmin
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+)
+
+// TargetRouter 根据请求中的 target= 参数，把请求分发给对应目标进程的 AdminHandler。
+// 没有 target= 参数时使用第一个目标进程，因此只有一个目标进程时所有接口与以前一致。
+type TargetRouter struct {
+	handlers []*AdminHandler
+	byName   map[string]*AdminHandler
+}
+
+func NewTargetRouter(handlers []*AdminHandler) *TargetRouter {
+	byName := make(map[string]*AdminHandler, len(handlers))
+	for _, handler := range handlers {
+		byName[handler.name] = handler
+	}
+	return &TargetRouter{handlers: handlers, byName: byName}
+}
+
+func (t *TargetRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/metrics" {
+		t.serveMetrics(w, r)
+		return
+	}
+	name := r.URL.Query().Get("target")
+	if name == "" {
+		t.defaultHandler(r).mux.ServeHTTP(w, r)
+		return
+	}
+	handler, ok := t.byName[name]
+	if !ok {
+		http.Error(w, "unknown target: "+name, http.StatusNotFound)
+		return
+	}
+	handler.mux.ServeHTTP(w, r)
+}
+
+// defaultHandler 返回没有 target= 参数时使用的 AdminHandler。
+// speedscope 根据 #profileURL=/profile/xxx.speedscope.json 加载火焰图，无法携带 target= 参数，
+// 因此 /profile/ 按 trace id 查找产生这份 profile 的目标进程。
+func (t *TargetRouter) defaultHandler(r *http.Request) *AdminHandler {
+	if traceID, ok := parseProfilePath(r.URL.Path); ok {
+		for _, handler := range t.handlers {
+			if handler.traces.Exists(traceID) {
+				return handler
+			}
+		}
+	}
+	return t.handlers[0]
+}
+
+// targetLink 是页面顶部目标进程选择器中的一项。
+type targetLink struct {
+	Name     string
+	URL      string
+	Selected bool
+}
+
+// targetQuery 返回附加到链接上的 target= 参数，sep 为 "?" 或 "&"。
+// 只有一个目标进程时返回空字符串，页面上的链接与以前保持一致。
+func (h *AdminHandler) targetQuery(sep string) string {
+	if h.name == "" || len(h.targetNames) <= 1 {
+		return ""
+	}
+	return sep + "target=" + url.QueryEscape(h.name)
+}
+
+func (h *AdminHandler) buildTargetLinks() []targetLink {
+	if len(h.targetNames) <= 1 {
+		return nil
+	}
+	links := make([]targetLink, 0, len(h.targetNames))
+	for _, name := range h.targetNames {
+		links = append(links, targetLink{
+			Name:     html.EscapeString(name),
+			URL:      "/?target=" + url.QueryEscape(name),
+			Selected: name == h.name,
+		})
+	}
+	return links
+}
@@ -0,0 +1,35 @@
+package debugadmin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTargetRouterDispatchesByTargetParam(t *testing.T) {
+	api := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, traces: NewTraceStore(), mux: http.NewServeMux()}
+	worker := &AdminHandler{name: "worker", targetNames: []string{"api", "worker"}, traces: NewTraceStore(), mux: http.NewServeMux()}
+	for _, h := range []*AdminHandler{api, worker} {
+		name := h.name
+		h.mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) { _, _ = io.WriteString(w, name) })
+	}
+	worker.traces.Add("20260721123456.789")
+	router := NewTargetRouter([]*AdminHandler{api, worker})
+	for path, want := range map[string]string{
+		"/":               "api",
+		"/?target=worker": "worker",
+		"/profile/20260721123456.789.speedscope.json": "worker",
+	} {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest("GET", path, nil))
+		if got := response.Body.String(); got != want {
+			t.Errorf("GET %s served by %q, want %q", path, got, want)
+		}
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/?target=nope", nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("unknown target status = %d, want 404", response.Code)
+	}
+}
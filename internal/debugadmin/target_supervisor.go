@@ -0,0 +1,252 @@
+package debugadmin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// TargetExit 是 TargetSupervisor 上报给 Run() 的一次非手动退出。
+type TargetExit struct {
+	Target *TargetProcess
+	Err    error
+}
+
+// TargetSupervisor 管理一个目标子进程的生命周期：启动、停止、重启、发信号。
+// 所有生命周期操作串行执行；手动停止的进程退出后不会上报到 Exits()，
+// 因此 Run() 只需要处理崩溃和正常退出两种情况。
+// 每个目标进程有自己的 LogBroker 和 RunHistory。
+type TargetSupervisor struct {
+	name            string
+	autoRestart     bool
+	opMu            sync.Mutex
+	current         atomic.Pointer[TargetProcess]
+	broker          *LogBroker
+	history         *RunHistory
+	lineWriter      io.Writer
+	logStdoutOutput bool
+	stopTimeout     time.Duration
+	specMu          sync.Mutex
+	spec            LaunchSpec // 下一次启动使用的参数，受 specMu 保护
+	original        LaunchSpec // 命令行 -- 之后指定的原始参数
+	exits           chan TargetExit
+	onStartMu       sync.Mutex
+	onStart         []func(*TargetProcess)
+	onExit          []func(TargetExit) // 受 onStartMu 保护
+}
+
+func NewTargetSupervisor(opts TargetOptions, lineWriter io.Writer, logStdoutOutput bool, stopTimeout time.Duration) *TargetSupervisor {
+	spec := DefaultLaunchSpec()
+	spec.Args = append([]string(nil), opts.StartupParams...)
+	spec.Env = append([]string(nil), opts.Env...)
+	spec.Dir = opts.Dir
+	if opts.Mode != "" {
+		spec.Mode = opts.Mode
+	}
+	if opts.Name != "" {
+		spec.CoverageSession = GlobalOptions.CoverageOpts.CoverageName + "-" + opts.Name
+	}
+	return &TargetSupervisor{
+		name:            opts.Name,
+		autoRestart:     opts.AutoRestart,
+		broker:          NewLogBroker(),
+		history:         NewRunHistory(),
+		lineWriter:      lineWriter,
+		logStdoutOutput: logStdoutOutput,
+		stopTimeout:     stopTimeout,
+		spec:            spec,
+		original:        spec.Clone(),
+		exits:           make(chan TargetExit, 1),
+	}
+}
+
+// Name 返回目标进程名，对应 http 接口的 target= 参数。
+func (s *TargetSupervisor) Name() string {
+	return s.name
+}
+
+// AutoRestart 返回目标进程崩溃后是否自动重启。
+func (s *TargetSupervisor) AutoRestart() bool {
+	return s.autoRestart
+}
+
+func (s *TargetSupervisor) Broker() *LogBroker {
+	return s.broker
+}
+
+func (s *TargetSupervisor) History() *RunHistory {
+	return s.history
+}
+
+// Finished 返回目标进程是否已经自行退出（崩溃或者正常结束，且没有被重启）。
+// 手动停止的目标进程不算 finished，DebugAdmin 会继续等待管理端重新启动它。
+func (s *TargetSupervisor) Finished() bool {
+	target := s.current.Load()
+	return target != nil && !target.Running() && target.reason != RunReasonManual && !target.manualStop.Load()
+}
+
+// OnStart 注册一个回调，每次创建新的子进程之后调用（例如切换 AdminHandler 指向的目标进程）。
+func (s *TargetSupervisor) OnStart(fn func(*TargetProcess)) {
+	s.onStartMu.Lock()
+	s.onStart = append(s.onStart, fn)
+	s.onStartMu.Unlock()
+}
+
+// OnExit 注册一个回调，子进程非手动停止的退出在上报给 Run() 之前调用（例如发送崩溃通知）。
+// 回调时这次运行的 RunRecord 已经记录到 History() 中。
+func (s *TargetSupervisor) OnExit(fn func(TargetExit)) {
+	s.onStartMu.Lock()
+	s.onExit = append(s.onExit, fn)
+	s.onStartMu.Unlock()
+}
+
+// Current 返回最近一次启动的子进程，可能已经退出；从未启动过时返回 nil。
+func (s *TargetSupervisor) Current() *TargetProcess {
+	return s.current.Load()
+}
+
+// Exits 上报非手动停止的退出事件，由 Run() 决定是否自动重启或者退出 DebugAdmin。
+func (s *TargetSupervisor) Exits() <-chan TargetExit {
+	return s.exits
+}
+
+// Start 启动一个新的子进程；已有子进程在运行时返回 error。
+func (s *TargetSupervisor) Start(reason string) (*TargetProcess, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	return s.startLocked(reason)
+}
+
+// RestartAfterCrash 是 auto.restart 的重启：只有 exited 仍然是当前子进程时才启动新的子进程。
+// 崩溃上报到 Run() 之前，管理端可能已经通过 start / restart / launch 启动了新的子进程，
+// 这时这次退出已经过时，返回 nil 且不报错。
+func (s *TargetSupervisor) RestartAfterCrash(exited *TargetProcess) (*TargetProcess, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if s.current.Load() != exited {
+		return nil, nil
+	}
+	return s.startLocked(RunReasonAutoRestart)
+}
+
+// Stop 优雅停止当前子进程：SIGTERM，超时后 SIGKILL。
+func (s *TargetSupervisor) Stop() error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	return s.stopLocked()
+}
+
+// Restart 停止当前子进程（如果还在运行），然后启动一个新的子进程。
+func (s *TargetSupervisor) Restart() (*TargetProcess, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if target := s.current.Load(); target != nil && target.Running() {
+		if err := s.stopLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return s.startLocked(RunReasonManual)
+}
+
+// RestartWith 用新的启动参数和环境变量重启子进程，之后的重启（包括 auto.restart）都使用新参数。
+// spec 没有指定工作目录时沿用配置文件中的工作目录。
+func (s *TargetSupervisor) RestartWith(spec LaunchSpec) (*TargetProcess, error) {
+	if spec.Dir == "" {
+		spec.Dir = s.original.Dir
+	}
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	if target := s.current.Load(); target != nil && target.Running() {
+		if err := s.stopLocked(); err != nil {
+			return nil, err
+		}
+	}
+	s.specMu.Lock()
+	s.spec = spec.Clone()
+	s.specMu.Unlock()
+	return s.startLocked(RunReasonManual)
+}
+
+// RevertLaunchSpec 恢复命令行指定的原始启动参数并重启子进程。
+func (s *TargetSupervisor) RevertLaunchSpec() (*TargetProcess, error) {
+	return s.RestartWith(s.original)
+}
+
+// LaunchSpecs 返回下一次启动使用的参数，以及命令行指定的原始参数。
+func (s *TargetSupervisor) LaunchSpecs() (current, original LaunchSpec) {
+	s.specMu.Lock()
+	defer s.specMu.Unlock()
+	return s.spec.Clone(), s.original.Clone()
+}
+
+// Signal 向当前运行中的目标进程发送信号。
+func (s *TargetSupervisor) Signal(sig syscall.Signal) error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	target := s.current.Load()
+	if target == nil {
+		return errors.New("target process is not started")
+	}
+	return target.Signal(sig)
+}
+
+func (s *TargetSupervisor) startLocked(reason string) (*TargetProcess, error) {
+	if target := s.current.Load(); target != nil && target.Running() {
+		return nil, fmt.Errorf("target process is already running, pid=%d", target.PID())
+	}
+	s.specMu.Lock()
+	spec := s.spec.Clone()
+	s.specMu.Unlock()
+	target, err := StartTarget(s.broker, s.lineWriter, s.logStdoutOutput, s.history, reason, spec)
+	if err != nil {
+		return nil, err
+	}
+	s.current.Store(target)
+	s.onStartMu.Lock()
+	callbacks := append([]func(*TargetProcess){}, s.onStart...)
+	s.onStartMu.Unlock()
+	for _, fn := range callbacks {
+		fn(target)
+	}
+	go s.watch(target)
+	return target, nil
+}
+
+func (s *TargetSupervisor) stopLocked() error {
+	target := s.current.Load()
+	if target == nil {
+		return errors.New("target process is not started")
+	}
+	if err := target.Stop(s.stopTimeout); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("[target stopped manually] pid=%d\n", target.PID())
+	_, _ = os.Stdout.WriteString(message)
+	s.broker.Broadcast(message)
+	return nil
+}
+
+// watch 等待子进程退出，非手动停止的退出上报给 Run()。
+// 手动结束 gdb 保留的现场时，退出记录仍然是崩溃，OnExit 回调照常执行，但不上报给 Run()，不会触发 auto.restart。
+func (s *TargetSupervisor) watch(target *TargetProcess) {
+	err := <-target.Done()
+	if target.reason == RunReasonManual {
+		return
+	}
+	exit := TargetExit{Target: target, Err: err}
+	s.onStartMu.Lock()
+	callbacks := append([]func(TargetExit){}, s.onExit...)
+	s.onStartMu.Unlock()
+	for _, fn := range callbacks {
+		fn(exit)
+	}
+	if target.manualStop.Load() {
+		return
+	}
+	s.exits <- exit
+}
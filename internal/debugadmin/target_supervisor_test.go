@@ -0,0 +1,100 @@
+package debugadmin
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTargetSupervisorManualStopAndStart(t *testing.T) {
+	GlobalOptions = &Options{StartupParams: []string{"sleep", "30"}}
+	supervisor := NewTargetSupervisor(TargetOptions{Name: "main", StartupParams: []string{"sleep", "30"}}, nil, false, 2*time.Second)
+	history := supervisor.History()
+	first, err := supervisor.Start(RunReasonInitial)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := supervisor.Start(RunReasonManual); err == nil {
+		t.Error("Start() while running error = nil, want error")
+	}
+	if err := supervisor.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if first.Running() {
+		t.Error("target still running after Stop()")
+	}
+	second, err := supervisor.Start(RunReasonManual)
+	if err != nil {
+		t.Fatalf("Start() after stop error = %v", err)
+	}
+	t.Cleanup(func() { _ = supervisor.Stop() })
+	if second.PID() == first.PID() {
+		t.Error("Start() after stop reused the old pid")
+	}
+
+	records := history.Snapshot()
+	if len(records) != 1 {
+		t.Fatalf("history has %d records, want 1", len(records))
+	}
+	if records[0].Reason != RunReasonManual || records[0].Abnormal {
+		t.Errorf("record reason = %q abnormal = %t, want manual and not abnormal", records[0].Reason, records[0].Abnormal)
+	}
+	if records[0].StartReason != RunReasonInitial {
+		t.Errorf("record start reason = %q, want %q", records[0].StartReason, RunReasonInitial)
+	}
+	select {
+	case exit := <-supervisor.Exits():
+		t.Errorf("manual stop reported as exit: %v", exit.Err)
+	default:
+	}
+}
+
+func TestTargetSupervisorRestartAfterCrashSkipsStaleExit(t *testing.T) {
+	GlobalOptions = &Options{StartupParams: []string{"sleep", "30"}}
+	supervisor := NewTargetSupervisor(TargetOptions{Name: "main", StartupParams: []string{"sleep", "30"}, AutoRestart: true}, nil, false, 2*time.Second)
+	first, err := supervisor.Start(RunReasonInitial)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := supervisor.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	second, err := supervisor.Start(RunReasonManual)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = supervisor.Stop() })
+
+	// 管理端已经启动了新的子进程，旧进程的崩溃不应再触发重启，也不应报错
+	if target, err := supervisor.RestartAfterCrash(first); target != nil || err != nil {
+		t.Errorf("RestartAfterCrash(stale) = %v, %v, want nil, nil", target, err)
+	}
+	if supervisor.Current() != second || !second.Running() {
+		t.Error("RestartAfterCrash(stale) replaced the running target")
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{raw: "TERM", want: syscall.SIGTERM},
+		{raw: "sigusr1", want: syscall.SIGUSR1},
+		{raw: "9", want: syscall.SIGKILL},
+		{raw: "", wantErr: true},
+		{raw: "NOPE", wantErr: true},
+		{raw: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSignal(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSignal(%q) error = %v, wantErr %t", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSignal(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
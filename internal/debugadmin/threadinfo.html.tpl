@@ -78,7 +78,8 @@ body{
 
 <div class="stack-view">
 <h1>Stack Trace</h1>
-{{if .Threads}}{{range .Threads}}<pre class="stack-pre" id="stack-{{.ID}}" style="display:none;">{{.Stack}}</pre>
+{{if .PerfMap}}<div class="placeholder">{{.PerfMap}}</div>
+{{end}}{{if .Threads}}{{range .Threads}}<pre class="stack-pre" id="stack-{{.ID}}" style="display:none;">{{.Stack}}</pre>
 {{end}}{{else}}<div class="placeholder">No thread data available.</div>{{end}}
 {{if .Misc}}<div class="misc">{{.Misc}}</div>{{end}}
 {{if .Stderr}}<div class="stderr">{{.Stderr}}</div>{{end}}
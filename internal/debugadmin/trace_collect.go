@@ -0,0 +1,249 @@
+package debugadmin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trace 的种类。
+const (
+	TraceKindCPU   = "cpu"   // dotnet-trace 的 CPU 采样
+	TraceKindPerf  = "perf"  // perf_event_open 采样，包含 native 代码与内核
+	TraceKindAlloc = "alloc" // GC AllocationTick 分配采样
+)
+
+const (
+	defaultTraceSeconds = 10
+	maxTraceDuration    = 24 * time.Hour
+	// traceStopTimeout 是手动停止时发送 SIGINT 之后，等待 dotnet-trace 结束会话并转换格式的时间。
+	traceStopTimeout = 2 * time.Minute
+)
+
+// TraceRequest 描述一次 trace 采集。
+type TraceRequest struct {
+	Kind      string        `json:"kind"`
+	Duration  time.Duration `json:"duration"`            // 0 表示一直采集到手动停止
+	Frequency int           `json:"frequency,omitempty"` // 只用于 perf
+}
+
+// Describe 返回用于页面展示的说明，例如 "perf 99Hz, 30s"。
+func (r TraceRequest) Describe() string {
+	text := r.Kind
+	if r.Kind == TraceKindPerf {
+		text = fmt.Sprintf("%s %dHz", r.Kind, r.Frequency)
+	}
+	if r.Duration <= 0 {
+		return text + ", until stopped"
+	}
+	return text + ", " + r.Duration.String()
+}
+
+// ParseTraceKind 校验 trace 种类，空字符串表示 cpu。
+func ParseTraceKind(kind string) (string, error) {
+	switch kind {
+	case "", TraceKindCPU:
+		return TraceKindCPU, nil
+	case TraceKindPerf, TraceKindAlloc:
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown trace kind %q, expected cpu, perf or alloc", kind)
+}
+
+// parseTraceRequest 解析 trace 参数：kind=cpu|perf|alloc（也兼容 backend=perf），
+// seconds 为秒数或 Go duration（例如 90、5m），frequency 为 perf 的采样频率。
+// unbounded 为 true 时 seconds 可以省略或为 0，表示一直采集到手动停止；否则省略时为 10 秒。
+func parseTraceRequest(r *http.Request, unbounded bool) (TraceRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return TraceRequest{}, err
+	}
+	query := r.Form
+	kind := query.Get("kind")
+	if query.Get("backend") == TraceKindPerf {
+		kind = TraceKindPerf
+	}
+	var req TraceRequest
+	var err error
+	if req.Kind, err = ParseTraceKind(kind); err != nil {
+		return TraceRequest{}, err
+	}
+	raw := strings.TrimSpace(query.Get("seconds"))
+	switch {
+	case raw == "" && !unbounded:
+		req.Duration = defaultTraceSeconds * time.Second
+	case raw == "":
+	default:
+		if req.Duration, err = parseTraceDuration(raw); err != nil {
+			return TraceRequest{}, err
+		}
+	}
+	if req.Duration == 0 && !unbounded {
+		return TraceRequest{}, errors.New("seconds must be positive")
+	}
+	if req.Kind == TraceKindPerf {
+		if req.Frequency, err = parsePerfFrequency(query.Get("frequency")); err != nil {
+			return TraceRequest{}, err
+		}
+	}
+	return req, nil
+}
+
+// parseTraceDuration 解析秒数或 Go duration，向上取整到秒，最长 maxTraceDuration。
+func parseTraceDuration(raw string) (time.Duration, error) {
+	var duration time.Duration
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		duration = time.Duration(seconds) * time.Second
+	} else if duration, err = time.ParseDuration(raw); err != nil {
+		return 0, fmt.Errorf("invalid seconds: %s", raw)
+	}
+	if duration < 0 || duration > maxTraceDuration {
+		return 0, fmt.Errorf("trace duration must be between 0 and %s, got %s", maxTraceDuration, raw)
+	}
+	if rest := duration % time.Second; rest != 0 {
+		duration += time.Second - rest
+	}
+	return duration, nil
+}
+
+// collectTrace 对 pid 采集一次 trace，结果写到 /tmp/{traceID}.speedscope.json。
+// stop 被关闭时提前结束并保留已经采集的数据；ctx 结束时放弃采集。返回采集过程中需要告诉用户的说明。
+func (h *AdminHandler) collectTrace(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+	if pid <= 0 {
+		return nil, errors.New("target process is not running")
+	}
+	switch req.Kind {
+	case TraceKindPerf:
+		return h.collectPerfTrace(ctx, traceID, pid, req, stop)
+	case TraceKindAlloc:
+		return h.collectAllocTrace(ctx, traceID, pid, req, stop)
+	default:
+		return collectDotnetTrace(ctx, traceID, pid, req, stop)
+	}
+}
+
+// collectDotnetTrace 用 dotnet-trace 采集 CPU profile，当前版本不支持的 profile 名依次换下一个重试。
+func collectDotnetTrace(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+	outputPath := filepath.Join("/tmp", traceID+".nettrace")
+	var notes []string
+	profiles := TraceProfileCandidates()
+	for i, profile := range profiles {
+		if i > 0 {
+			notes = append(notes, fmt.Sprintf("profile %q unsupported, retrying with %q", profiles[i-1], profile))
+		}
+		cmd := BuildTraceCommand(pid, int(req.Duration/time.Second), outputPath, profile)
+		stderr, err := runTraceCommand(ctx, cmd, stop)
+		if err == nil {
+			return notes, nil
+		}
+		if ctx.Err() != nil {
+			return notes, ctx.Err()
+		}
+		if shouldRetryTraceWithAnotherProfile(stderr) && i+1 < len(profiles) {
+			continue
+		}
+		return notes, withTraceStderr(err, stderr)
+	}
+	return notes, errors.New("no available trace profile")
+}
+
+// collectAllocTrace 用 dotnet-trace 采集 GC AllocationTick 事件，
+// 在 DebugAdmin 中把 nettrace 转换为按分配字节数计量的 speedscope profile。
+func (h *AdminHandler) collectAllocTrace(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+	nettracePath := filepath.Join("/tmp", traceID+".nettrace")
+	outputPath := filepath.Join("/tmp", traceID+".speedscope.json")
+	cmd := BuildAllocTraceCommand(pid, int(req.Duration/time.Second), nettracePath)
+	if stderr, err := runTraceCommand(ctx, cmd, stop); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, withTraceStderr(err, stderr)
+	}
+	samples, err := h.convertAllocTrace(nettracePath, outputPath)
+	if err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("collected %d allocation samples", samples)}, nil
+}
+
+// collectPerfTrace 用 perf_event_open 采样。与 EventPipe 不同，perf 能看到 native 代码、GC 与内核中的时间。
+func (h *AdminHandler) collectPerfTrace(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+	profile, err := CollectPerfProfile(ctx, pid, req.Duration, req.Frequency, stop)
+	if err != nil {
+		return nil, err
+	}
+	// 采样结束后马上读取内存映射，之后目标进程可能已经退出
+	mappings, mapsErr := readProcMaps(pid)
+	note := fmt.Sprintf("collected %d samples from %d threads", len(profile.Samples), len(profile.Threads))
+	if profile.Lost > 0 {
+		note += fmt.Sprintf(", %d samples lost", profile.Lost)
+	}
+	notes := []string{note}
+	if profile.KernelExcluded {
+		notes = append(notes, "kernel stacks excluded: perf_event_paranoid does not allow kernel sampling")
+	}
+	if mapsErr != nil {
+		notes = append(notes, fmt.Sprintf("native frames are not symbolized: %v", mapsErr))
+	}
+	file := buildPerfSpeedscope(profile, newPerfSymbolizer(pid, mappings, h.perfMaps))
+	if err := writeSpeedscopeFile(filepath.Join("/tmp", traceID+".speedscope.json"), file); err != nil {
+		return notes, fmt.Errorf("write profile failed: %w", err)
+	}
+	return notes, nil
+}
+
+// runTraceCommand 运行 dotnet-trace 直到它退出，返回 stderr 的内容。
+// stop 被关闭时发送 SIGINT，dotnet-trace 会结束会话并写完输出文件；ctx 结束时直接杀掉进程。
+func runTraceCommand(ctx context.Context, cmd *exec.Cmd, stop <-chan struct{}) (string, error) {
+	stderrLog := &bytes.Buffer{}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderrLog)
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("run dotnet-trace failed: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	kill := func() error {
+		_ = cmd.Process.Kill()
+		<-done
+		return ctx.Err()
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = kill()
+	case <-stop:
+		_ = cmd.Process.Signal(os.Interrupt)
+		timer := time.NewTimer(traceStopTimeout)
+		defer timer.Stop()
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = kill()
+		case <-timer.C:
+			_ = cmd.Process.Kill()
+			<-done
+			err = fmt.Errorf("dotnet-trace did not exit within %s after SIGINT", traceStopTimeout)
+		}
+	}
+	return strings.TrimSpace(stderrLog.String()), err
+}
+
+// withTraceStderr 把 dotnet-trace 的最后几行 stderr 附加到 error 中。
+func withTraceStderr(err error, stderr string) error {
+	if stderr == "" {
+		return err
+	}
+	return fmt.Errorf("%w\ndotnet-trace stderr:\n%s", err, tailLines(stderr, 12))
+}
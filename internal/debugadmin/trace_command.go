@@ -13,21 +13,46 @@ func TraceProfileCandidates() []string {
 	}
 }
 
+// formatTraceDuration 把秒数格式化为 dotnet-trace --duration 的 dd:hh:mm:ss 格式。
+func formatTraceDuration(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", seconds/86400, seconds/3600%24, seconds/60%60, seconds%60)
+}
+
+// traceDurationArgs 返回 --duration 参数；seconds 为 0 时不限时长，由 SIGINT 结束采集。
+func traceDurationArgs(seconds int) []string {
+	if seconds <= 0 {
+		return nil
+	}
+	return []string{"--duration", formatTraceDuration(seconds)}
+}
+
+// BuildTraceCommand 采集 CPU profile，直接输出 speedscope 格式。seconds 为 0 时一直采集到收到 SIGINT。
 func BuildTraceCommand(pid int, seconds int, outputBase string, profile string) *exec.Cmd {
-	duration := fmt.Sprintf("00:00:00:%02d", seconds)
-	args := []string{
-		"collect",
-		"--duration",
-		duration,
+	args := []string{"collect"}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	args = append(args, traceDurationArgs(seconds)...)
+	args = append(args,
 		"--format",
 		"Speedscope",
 		"-p",
 		strconv.Itoa(pid),
 		"-o",
 		outputBase,
-	}
-	if profile != "" {
-		args = append([]string{"collect", "--profile", profile}, args[1:]...)
-	}
+	)
+	return exec.Command("dotnet-trace", args...)
+}
+
+// BuildAllocTraceCommand 采集运行时 GC keyword 的 Verbose 事件，其中的 GCAllocationTick 每分配约 100KB 记录一次类型与调用栈。
+// 输出为 nettrace，由 DebugAdmin 转换为 speedscope。seconds 为 0 时一直采集到收到 SIGINT。
+func BuildAllocTraceCommand(pid int, seconds int, outputPath string) *exec.Cmd {
+	providers := fmt.Sprintf("%s:0x%x:%d", dotnetRuntimeProvider, allocTraceGCKeyword, allocTraceVerboseEventLevel)
+	args := append([]string{"collect"}, traceDurationArgs(seconds)...)
+	args = append(args,
+		"--providers", providers,
+		"-p", strconv.Itoa(pid),
+		"-o", outputPath,
+	)
 	return exec.Command("dotnet-trace", args...)
 }
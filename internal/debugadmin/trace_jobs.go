@@ -0,0 +1,177 @@
+package debugadmin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	maxTraceJobs        = 50 // 保留的后台 trace 记录数
+	maxRunningTraceJobs = 3
+)
+
+// TraceTriggerManual 表示从管理页面手动开始的后台 trace。
+const TraceTriggerManual = "manual"
+
+// traceCollector 采集一次 trace，即 AdminHandler.collectTrace，测试中可以替换。
+type traceCollector func(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error)
+
+// TraceJob 是一次在后台运行的 trace，不依赖于某个 http 请求：可以手动停止，也可以由条件自动触发。
+type TraceJob struct {
+	ID      string // 即 trace id，成功后 profile 为 /profile/{ID}.speedscope.json
+	Request TraceRequest
+	Trigger string
+	PID     int
+	Started time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu      sync.Mutex
+	running bool
+	ended   time.Time
+	notes   []string
+	err     error
+}
+
+// TraceJobStatus 是后台 trace 状态的快照。
+type TraceJobStatus struct {
+	ID         string       `json:"id"`
+	Request    TraceRequest `json:"request"`
+	Trigger    string       `json:"trigger"`
+	PID        int          `json:"pid"`
+	Started    time.Time    `json:"started"`
+	Ended      time.Time    `json:"ended,omitzero"`
+	Running    bool         `json:"running"`
+	Stopping   bool         `json:"stopping"`
+	Notes      []string     `json:"notes,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	ProfileURL string       `json:"profile_url,omitempty"` // 成功后的 speedscope 链接
+}
+
+// Stop 提前结束采集，已经采集的数据仍然会生成 profile。
+func (j *TraceJob) Stop() {
+	j.stopOnce.Do(func() { close(j.stop) })
+}
+
+// Done 在采集结束后被关闭。
+func (j *TraceJob) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *TraceJob) Status() TraceJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := TraceJobStatus{
+		ID:      j.ID,
+		Request: j.Request,
+		Trigger: j.Trigger,
+		PID:     j.PID,
+		Started: j.Started,
+		Ended:   j.ended,
+		Running: j.running,
+		Notes:   j.notes,
+	}
+	select {
+	case <-j.stop:
+		status.Stopping = j.running
+	default:
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	} else if !j.running {
+		status.ProfileURL = "/speedscope/index.html#profileURL=/profile/" + j.ID + ".speedscope.json"
+	}
+	return status
+}
+
+// TraceJobManager 管理一个目标进程的后台 trace，完成的 profile 加入 TraceStore。
+type TraceJobManager struct {
+	pid     func() int
+	traces  *TraceStore
+	collect traceCollector
+
+	mu   sync.Mutex
+	jobs []*TraceJob // 按开始时间排序
+}
+
+func NewTraceJobManager(pid func() int, traces *TraceStore, collect traceCollector) *TraceJobManager {
+	return &TraceJobManager{pid: pid, traces: traces, collect: collect}
+}
+
+// Start 对当前目标进程开始一次后台 trace。req.Duration 为 0 时一直采集到 Stop。
+func (m *TraceJobManager) Start(req TraceRequest, trigger string) (*TraceJob, error) {
+	pid := m.pid()
+	if pid <= 0 {
+		return nil, fmt.Errorf("target process is not running")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, job := range m.jobs {
+		if job.Status().Running {
+			running++
+		}
+	}
+	if running >= maxRunningTraceJobs {
+		return nil, fmt.Errorf("at most %d background traces can run at the same time", maxRunningTraceJobs)
+	}
+	job := &TraceJob{
+		ID:      newTraceID(),
+		Request: req,
+		Trigger: trigger,
+		PID:     pid,
+		Started: time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		running: true,
+	}
+	m.jobs = append(m.jobs, job)
+	for len(m.jobs) > maxTraceJobs && !m.jobs[0].Status().Running {
+		m.jobs = m.jobs[1:]
+	}
+	go m.run(job)
+	return job, nil
+}
+
+func (m *TraceJobManager) run(job *TraceJob) {
+	defer close(job.done)
+	notes, err := m.collect(context.Background(), job.ID, job.PID, job.Request, job.stop)
+	if err == nil {
+		m.traces.Add(job.ID)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "[trace] background trace %s (%s, %s) failed: %v\n", job.ID, job.Request.Describe(), job.Trigger, err)
+	}
+	job.mu.Lock()
+	job.running = false
+	job.ended = time.Now()
+	job.notes = notes
+	job.err = err
+	job.mu.Unlock()
+}
+
+func (m *TraceJobManager) Get(id string) *TraceJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// List 返回所有后台 trace，最近开始的在前。
+func (m *TraceJobManager) List() []*TraceJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*TraceJob, 0, len(m.jobs))
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		result = append(result, m.jobs[i])
+	}
+	return result
+}
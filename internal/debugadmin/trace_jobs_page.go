@@ -0,0 +1,93 @@
+package debugadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// handleTraceStart 开始一次后台 trace，参数与 /trace 相同，但 seconds 省略或为 0 时一直采集到手动停止。
+// 成功后跳转到 profile 列表，在那里可以停止它。
+func (h *AdminHandler) handleTraceStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := parseTraceRequest(r, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.traceJobs.Start(req, TraceTriggerManual); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/profile_list"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleTraceStop 停止一次后台 trace，已经采集的数据仍然生成 profile。
+func (h *AdminHandler) handleTraceStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	job := h.traceJobs.Get(r.URL.Query().Get("id"))
+	if job == nil {
+		http.NotFound(w, r)
+		return
+	}
+	job.Stop()
+	http.Redirect(w, r, "/profile_list"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleTraceJobsAPI 以 JSON 返回所有后台 trace 的状态。
+func (h *AdminHandler) handleTraceJobsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobs := h.traceJobs.List()
+	statuses := make([]TraceJobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(statuses)
+}
+
+// writeTraceJobs 在 profile 列表页面上输出后台 trace：运行中的带停止按钮，结束的带 profile 链接或错误。
+func (h *AdminHandler) writeTraceJobs(w io.Writer) {
+	jobs := h.traceJobs.List()
+	if len(jobs) == 0 {
+		return
+	}
+	_, _ = io.WriteString(w, "<h3>Background Traces</h3>\n")
+	for _, job := range jobs {
+		status := job.Status()
+		line := fmt.Sprintf("%s [%s] trigger=%s pid=%d", status.ID, status.Request.Describe(), status.Trigger, status.PID)
+		_, _ = fmt.Fprintf(w, "<div>%s ", html.EscapeString(line))
+		switch {
+		case status.Stopping:
+			_, _ = io.WriteString(w, "<b>stopping...</b>")
+		case status.Running:
+			elapsed := time.Since(status.Started).Round(time.Second)
+			_, _ = fmt.Fprintf(w, "<b>running</b> for %s ", elapsed)
+			_, _ = fmt.Fprintf(w, "<form method=\"post\" action=%q style=\"display:inline;\"><button type=\"submit\">Stop</button></form>",
+				"/trace/stop?id="+url.QueryEscape(status.ID)+h.targetQuery("&"))
+		case status.Error != "":
+			_, _ = fmt.Fprintf(w, "<b>failed</b> <pre style=\"margin:2px 0 6px 16px;\">%s</pre>", html.EscapeString(status.Error))
+		default:
+			_, _ = fmt.Fprintf(w, "<a href=%q target=\"_blank\" rel=\"noopener\">%s.speedscope.json</a>", status.ProfileURL, html.EscapeString(status.ID))
+		}
+		if len(status.Notes) > 0 {
+			_, _ = fmt.Fprintf(w, " <span style=\"color:#6b7280;\">%s</span>", html.EscapeString(strings.Join(status.Notes, "; ")))
+		}
+		_, _ = io.WriteString(w, "</div>\n")
+	}
+}
@@ -0,0 +1,213 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTraceCommandDuration(t *testing.T) {
+	cmd := BuildTraceCommand(123, 90, "/tmp/x.nettrace", "cpu-sampling")
+	if !slices.Contains(cmd.Args, "00:00:01:30") {
+		t.Errorf("args = %v, want duration 00:00:01:30", cmd.Args)
+	}
+	if got := formatTraceDuration(26 * 3600); got != "01:02:00:00" {
+		t.Errorf("formatTraceDuration = %s", got)
+	}
+	for _, args := range [][]string{
+		BuildTraceCommand(123, 0, "/tmp/x.nettrace", "cpu-sampling").Args,
+		BuildAllocTraceCommand(123, 0, "/tmp/x.nettrace").Args,
+	} {
+		if slices.Contains(args, "--duration") {
+			t.Errorf("unbounded trace args = %v", args)
+		}
+	}
+}
+
+func TestParseTraceRequest(t *testing.T) {
+	tests := []struct {
+		query     string
+		unbounded bool
+		want      TraceRequest
+		wantErr   bool
+	}{
+		{query: "", want: TraceRequest{Kind: TraceKindCPU, Duration: 10 * time.Second}},
+		{query: "", unbounded: true, want: TraceRequest{Kind: TraceKindCPU}},
+		{query: "seconds=0", wantErr: true},
+		{query: "kind=alloc&seconds=90", want: TraceRequest{Kind: TraceKindAlloc, Duration: 90 * time.Second}},
+		{query: "backend=perf&seconds=5m", want: TraceRequest{Kind: TraceKindPerf, Duration: 5 * time.Minute, Frequency: defaultPerfFrequency}},
+		{query: "seconds=1500ms", want: TraceRequest{Kind: TraceKindCPU, Duration: 2 * time.Second}},
+		{query: "seconds=25h", wantErr: true},
+		{query: "seconds=-1", wantErr: true},
+		{query: "kind=gc", wantErr: true},
+	}
+	for _, test := range tests {
+		req, err := parseTraceRequest(httptest.NewRequest("GET", "/trace?"+test.query, nil), test.unbounded)
+		if (err != nil) != test.wantErr || (err == nil && req != test.want) {
+			t.Errorf("parseTraceRequest(%q, %v) = %+v, %v", test.query, test.unbounded, req, err)
+		}
+	}
+}
+
+func TestTraceJobManager(t *testing.T) {
+	traces := NewTraceStore()
+	collect := func(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+		if req.Kind == TraceKindAlloc {
+			return nil, errors.New("boom")
+		}
+		<-stop
+		return []string{"stopped"}, nil
+	}
+	manager := NewTraceJobManager(func() int { return 42 }, traces, collect)
+
+	job, err := manager.Start(TraceRequest{Kind: TraceKindCPU}, TraceTriggerManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := job.Status(); !status.Running || status.PID != 42 || status.ProfileURL != "" {
+		t.Errorf("running status = %+v", status)
+	}
+	job.Stop()
+	<-job.Done()
+	status := job.Status()
+	if status.Running || status.Error != "" || !strings.Contains(status.ProfileURL, job.ID+".speedscope.json") || !traces.Exists(job.ID) {
+		t.Errorf("stopped status = %+v", status)
+	}
+
+	time.Sleep(time.Millisecond) // trace id 精确到毫秒
+	failed, err := manager.Start(TraceRequest{Kind: TraceKindAlloc, Duration: time.Second}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-failed.Done()
+	if status := failed.Status(); status.Error != "boom" || status.ProfileURL != "" || traces.Exists(failed.ID) {
+		t.Errorf("failed status = %+v", status)
+	}
+	if jobs := manager.List(); len(jobs) != 2 || jobs[0] != failed || manager.Get(job.ID) != job {
+		t.Errorf("jobs = %v", jobs)
+	}
+
+	var running []*TraceJob
+	for range maxRunningTraceJobs {
+		time.Sleep(time.Millisecond)
+		job, err := manager.Start(TraceRequest{Kind: TraceKindCPU}, TraceTriggerManual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		running = append(running, job)
+	}
+	if _, err := manager.Start(TraceRequest{Kind: TraceKindCPU}, TraceTriggerManual); err == nil {
+		t.Error("expected an error when too many traces are running")
+	}
+	for _, job := range running {
+		job.Stop()
+		<-job.Done()
+	}
+
+	stopped := NewTraceJobManager(func() int { return 0 }, traces, collect)
+	if _, err := stopped.Start(TraceRequest{Kind: TraceKindCPU}, TraceTriggerManual); err == nil {
+		t.Error("expected an error without a target process")
+	}
+}
+
+func TestTraceJobHandlers(t *testing.T) {
+	h := &AdminHandler{name: "api", traces: NewTraceStore(), mux: http.NewServeMux()}
+	h.traceJobs = NewTraceJobManager(func() int { return 42 }, h.traces,
+		func(ctx context.Context, traceID string, pid int, req TraceRequest, stop <-chan struct{}) ([]string, error) {
+			<-stop
+			return nil, nil
+		})
+	h.Register(h.mux)
+
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/trace/start?kind=cpu&seconds=", nil))
+	if response.Code != http.StatusSeeOther {
+		t.Fatalf("start status = %d, body = %s", response.Code, response.Body.String())
+	}
+	job := h.traceJobs.List()[0]
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/profile_list", nil))
+	if body := response.Body.String(); !strings.Contains(body, "/trace/stop?id="+job.ID) || !strings.Contains(body, "until stopped") {
+		t.Errorf("profile list = %s", body)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/trace/stop?id="+job.ID, nil))
+	if response.Code != http.StatusSeeOther {
+		t.Fatalf("stop status = %d", response.Code)
+	}
+	<-job.Done()
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/api/trace/jobs", nil))
+	var statuses []TraceJobStatus
+	if err := json.Unmarshal(response.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Running || statuses[0].ProfileURL == "" {
+		t.Errorf("statuses = %+v", statuses)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/trace/stop?id=missing", nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("stop missing status = %d", response.Code)
+	}
+}
+
+func TestCPUUsageSampler(t *testing.T) {
+	var cpu time.Duration
+	sampler := &cpuUsageSampler{read: func(pid int) (time.Duration, error) { return cpu, nil }}
+	base := time.Now()
+	if _, ok := sampler.Sample(1, base); ok {
+		t.Error("first sample should not report usage")
+	}
+	cpu = 1500 * time.Millisecond
+	if usage, ok := sampler.Sample(1, base.Add(time.Second)); !ok || usage != 150 {
+		t.Errorf("usage = %v, %v, want 150", usage, ok)
+	}
+	if _, ok := sampler.Sample(2, base.Add(2*time.Second)); ok {
+		t.Error("a new pid should restart sampling")
+	}
+}
+
+func TestCPUTraceTriggerRule(t *testing.T) {
+	opts, err := loadOptions([]string{"-trace.cpu.threshold=150", "-trace.cpu.for=40s", "-trace.cpu.kind=alloc", "-trace.cpu.duration=1m", "--", "app.dll"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Triggers) != 1 {
+		t.Fatalf("Triggers = %+v, want the built-in cpu rule", opts.Triggers)
+	}
+	rule := opts.Triggers[0]
+	if rule.Name != cpuTraceTriggerName || rule.Condition != TriggerConditionCPU || rule.Threshold != 150 || rule.For != 40*time.Second ||
+		rule.Cooldown != defaultCPUTraceCooldown || len(rule.Actions) != 1 || rule.Actions[0].Trace != (TraceRequest{Kind: TraceKindAlloc, Duration: time.Minute}) {
+		t.Errorf("rule = %+v", rule)
+	}
+	if opts, err := loadOptions([]string{"--", "app.dll"}); err != nil || len(opts.Triggers) != 0 {
+		t.Errorf("Triggers = %+v, err %v, want none without -trace.cpu.threshold", opts.Triggers, err)
+	}
+
+	configPath := writeConfigFile(t, "triggers:\n  - name: trace.cpu\n    when: {cpu: 90}\n    actions: [{type: stack}]\n")
+	if _, err := loadOptions([]string{"-config=" + configPath, "-trace.cpu.threshold=150", "--", "app.dll"}); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("loadOptions() error = %v, want the reserved name rejected", err)
+	}
+}
+
+func TestNewTraceIDUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 50 {
+		id := newTraceID()
+		if seen[id] || !traceIDPattern.MatchString(id) {
+			t.Fatalf("newTraceID() = %q, duplicated or invalid", id)
+		}
+		seen[id] = true
+	}
+}
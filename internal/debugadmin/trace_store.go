@@ -1,6 +1,27 @@
 package debugadmin
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
+
+var (
+	traceIDMu   sync.Mutex
+	lastTraceAt time.Time
+)
+
+// newTraceID 返回一个新的 trace id。id 同时是 /tmp 下输出文件的名字，所有目标进程的
+// 手动 trace 和后台 trace 共用，同一毫秒内开始的 trace 顺延到下一毫秒，保证 id 不会重复。
+func newTraceID() string {
+	traceIDMu.Lock()
+	defer traceIDMu.Unlock()
+	now := time.Now().Truncate(time.Millisecond)
+	if !now.After(lastTraceAt) {
+		now = lastTraceAt.Add(time.Millisecond)
+	}
+	lastTraceAt = now
+	return now.Format(traceIDLayout)
+}
 
 type TraceStore struct {
 	mu    sync.RWMutex
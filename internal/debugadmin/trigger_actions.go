@@ -0,0 +1,151 @@
+package debugadmin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	triggerStackTimeout   = 20 * time.Second
+	triggerDumpTimeout    = 10 * time.Minute
+	triggerWebhookTimeout = 10 * time.Second
+)
+
+var triggerWebhookClient = &http.Client{Timeout: triggerWebhookTimeout}
+
+// runTriggerAction 对触发时的目标进程执行一个动作。
+func (h *AdminHandler) runTriggerAction(ctx context.Context, firing *TriggerFiring, action TriggerAction) ([]TriggerArtifact, []string, error) {
+	switch action.Type {
+	case TriggerActionTrace:
+		return h.runTriggerTrace(ctx, firing, action.Trace)
+	case TriggerActionStack:
+		return h.runTriggerStack(ctx, firing)
+	case TriggerActionGCDump:
+		return h.runTriggerCommand(ctx, firing, "heap.gcdump", func(ctx context.Context, path string) *exec.Cmd {
+			return BuildGCDumpCommand(ctx, firing.PID, path)
+		})
+	case TriggerActionDump:
+		return h.runTriggerCommand(ctx, firing, "core."+strings.ToLower(action.DumpType)+".dmp", func(ctx context.Context, path string) *exec.Cmd {
+			return BuildDumpCollectCommand(ctx, firing.PID, action.DumpType, path)
+		})
+	case TriggerActionWebhook:
+		return nil, nil, postTriggerWebhook(ctx, action.URL, firing.Status())
+	}
+	return nil, nil, fmt.Errorf("unknown action %q", action.Type)
+}
+
+// runTriggerTrace 开始一次后台 trace 并等待它结束，profile 与手动采集的一样出现在 profile 列表中。
+func (h *AdminHandler) runTriggerTrace(ctx context.Context, firing *TriggerFiring, req TraceRequest) ([]TriggerArtifact, []string, error) {
+	job, err := h.traceJobs.Start(req, "trigger "+firing.Rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	select {
+	case <-job.Done():
+	case <-ctx.Done():
+		job.Stop()
+		<-job.Done()
+	}
+	status := job.Status()
+	if status.Error != "" {
+		return nil, status.Notes, errors.New(status.Error)
+	}
+	return []TriggerArtifact{{Name: status.ID + ".speedscope.json", URL: status.ProfileURL}}, status.Notes, nil
+}
+
+// runTriggerStack 用 netcoredbg 抓一次所有线程的调用栈，保存原始输出与解析后的 JSON。
+func (h *AdminHandler) runTriggerStack(ctx context.Context, firing *TriggerFiring) ([]TriggerArtifact, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, triggerStackTimeout)
+	defer cancel()
+	dump, debuggerLog, stderrOutput, err := collectStack(ctx, firing.PID)
+	if err != nil {
+		if stderrOutput != "" {
+			err = fmt.Errorf("%w\n%s", err, tailLines(stderrOutput, 12))
+		}
+		return nil, nil, err
+	}
+	var artifacts []TriggerArtifact
+	if artifact, err := h.writeTriggerArtifact(firing, "stack.txt", []byte(debuggerLog)); err == nil {
+		artifacts = append(artifacts, artifact)
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return artifacts, nil, err
+	}
+	artifact, err := h.writeTriggerArtifact(firing, "stack.json", data)
+	if err != nil {
+		return artifacts, nil, err
+	}
+	return append(artifacts, artifact), []string{fmt.Sprintf("%d threads", len(dump.Threads))}, nil
+}
+
+// runTriggerCommand 运行 dotnet-gcdump / dotnet-dump 这样把结果写到文件的命令。
+func (h *AdminHandler) runTriggerCommand(ctx context.Context, firing *TriggerFiring, name string, build func(ctx context.Context, path string) *exec.Cmd) ([]TriggerArtifact, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, triggerDumpTimeout)
+	defer cancel()
+	path := filepath.Join(firing.Dir, name)
+	cmd := build(ctx, path)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s failed: %w\n%s", filepath.Base(cmd.Path), err, tailLines(strings.TrimSpace(string(output)), 12))
+	}
+	artifact, err := h.triggerArtifact(firing, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []TriggerArtifact{artifact}, nil, nil
+}
+
+func (h *AdminHandler) writeTriggerArtifact(firing *TriggerFiring, name string, data []byte) (TriggerArtifact, error) {
+	if err := os.WriteFile(filepath.Join(firing.Dir, name), data, 0o644); err != nil {
+		return TriggerArtifact{}, err
+	}
+	return h.triggerArtifact(firing, name)
+}
+
+// triggerArtifact 描述 firing.Dir 下的一个文件，通过 /triggers/artifact 下载。
+func (h *AdminHandler) triggerArtifact(firing *TriggerFiring, name string) (TriggerArtifact, error) {
+	path := filepath.Join(firing.Dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return TriggerArtifact{}, err
+	}
+	return TriggerArtifact{
+		Name: name,
+		URL:  "/triggers/artifact?id=" + url.QueryEscape(firing.ID) + "&name=" + url.QueryEscape(name) + h.targetQuery("&"),
+		Size: info.Size(),
+		Path: path,
+	}, nil
+}
+
+// postTriggerWebhook 把触发记录（包括之前的动作生成的文件）以 JSON POST 到 endpoint。
+func postTriggerWebhook(ctx context.Context, endpoint string, status TriggerFiringStatus) error {
+	body, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := triggerWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+package debugadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	maxTriggerFirings   = 100 // 保留的触发记录数，更早的记录连同文件一起删除
+	triggerPollInterval = time.Second
+	maxTriggerLogReason = 200
+)
+
+// triggerArtifactDir 是触发动作生成的文件所在的目录，每次触发一个子目录，测试中可以替换。
+var triggerArtifactDir = "/tmp/debugadmin-triggers"
+
+// 手动或自动触发被拒绝的原因。
+var (
+	ErrTriggerRunning  = errors.New("the previous firing of this rule is still running")
+	ErrTriggerCooldown = errors.New("the rule is cooling down")
+	ErrTriggerLimit    = errors.New("the rule has reached its limit")
+)
+
+// triggerActionRunner 对 firing.PID 执行一个动作，即 AdminHandler.runTriggerAction，测试中可以替换。
+// 生成的文件写到 firing.Dir 下。
+type triggerActionRunner func(ctx context.Context, firing *TriggerFiring, action TriggerAction) ([]TriggerArtifact, []string, error)
+
+// TriggerArtifact 是一个动作的结果：本地文件通过 /triggers/artifact 下载，trace 链接到 speedscope。
+type TriggerArtifact struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size,omitempty"`
+	Path string `json:"-"`
+}
+
+// TriggerActionResult 是一个动作的执行结果。
+type TriggerActionResult struct {
+	Action    TriggerAction     `json:"action"`
+	Started   time.Time         `json:"started"`
+	Ended     time.Time         `json:"ended,omitzero"`
+	Notes     []string          `json:"notes,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Artifacts []TriggerArtifact `json:"artifacts,omitempty"`
+}
+
+// TriggerFiring 是规则的一次触发，动作按顺序依次执行。
+type TriggerFiring struct {
+	ID     string
+	Rule   string
+	Target string
+	Reason string
+	PID    int
+	Time   time.Time
+	Dir    string // 动作生成的文件所在的目录
+
+	mu      sync.Mutex
+	running bool
+	ended   time.Time
+	results []TriggerActionResult
+}
+
+// TriggerFiringStatus 是一次触发的快照。
+type TriggerFiringStatus struct {
+	ID      string                `json:"id"`
+	Rule    string                `json:"rule"`
+	Target  string                `json:"target"`
+	Reason  string                `json:"reason"`
+	PID     int                   `json:"pid"`
+	Time    time.Time             `json:"time"`
+	Ended   time.Time             `json:"ended,omitzero"`
+	Running bool                  `json:"running"`
+	Results []TriggerActionResult `json:"results"`
+}
+
+func (f *TriggerFiring) Status() TriggerFiringStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return TriggerFiringStatus{
+		ID:      f.ID,
+		Rule:    f.Rule,
+		Target:  f.Target,
+		Reason:  f.Reason,
+		PID:     f.PID,
+		Time:    f.Time,
+		Ended:   f.ended,
+		Running: f.running,
+		Results: slices.Clone(f.results),
+	}
+}
+
+// Artifact 按名称查找本次触发生成的本地文件。
+func (f *TriggerFiring) Artifact(name string) (TriggerArtifact, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, result := range f.results {
+		for _, artifact := range result.Artifacts {
+			if artifact.Name == name && artifact.Path != "" {
+				return artifact, true
+			}
+		}
+	}
+	return TriggerArtifact{}, false
+}
+
+// TriggerRuleStatus 是一条规则的状态。
+type TriggerRuleStatus struct {
+	Rule        TriggerRule `json:"rule"`
+	Description string      `json:"description"`
+	Fired       int         `json:"fired"`
+	Suppressed  int         `json:"suppressed"` // 因为 cooldown、limit 或上一次还没有结束而没有执行的次数
+	LastFired   time.Time   `json:"last_fired,omitzero"`
+	Running     bool        `json:"running"`
+}
+
+type threadCountSample struct {
+	at    time.Time
+	count int
+}
+
+type triggerRuleState struct {
+	rule       TriggerRule
+	aboveSince time.Time
+	threads    []threadCountSample
+	fired      int
+	suppressed int
+	lastFired  time.Time
+	running    bool
+}
+
+// TriggerEngine 对一个目标进程求值触发规则：cpu、rss、异常数与线程数每秒采样一次，日志规则订阅 LogBroker。
+// 条件满足时按 cooldown 与 limit 决定是否执行规则的动作，并记录每次触发的结果。
+type TriggerEngine struct {
+	target      string
+	pid         func() int
+	broker      *LogBroker
+	exceptions  func() int64 // 最近一分钟的异常数
+	cpu         *cpuUsageSampler
+	readRSS     func(pid int) uint64
+	readThreads func(pid int) int
+	act         triggerActionRunner
+	interval    time.Duration
+
+	mu      sync.Mutex
+	lastPID int
+	rules   []*triggerRuleState
+	firings []*TriggerFiring // 按触发时间排序
+	onFired []func(TriggerFiringStatus)
+}
+
+func NewTriggerEngine(target string, rules []TriggerRule, pid func() int, broker *LogBroker, exceptions func() int64, act triggerActionRunner) *TriggerEngine {
+	engine := &TriggerEngine{
+		target:      target,
+		pid:         pid,
+		broker:      broker,
+		exceptions:  exceptions,
+		cpu:         newCPUUsageSampler(),
+		readRSS:     readProcessRSSBytes,
+		readThreads: readProcessThreadCount,
+		act:         act,
+		interval:    triggerPollInterval,
+	}
+	for _, rule := range rules {
+		if rule.Target == "" || rule.Target == target {
+			engine.rules = append(engine.rules, &triggerRuleState{rule: rule})
+		}
+	}
+	return engine
+}
+
+// NeedsExceptions 判断是否有规则依赖异常统计，此时即使没有 -exceptions.track 也要在后台订阅异常。
+func (e *TriggerEngine) NeedsExceptions() bool {
+	return e.uses(TriggerConditionExceptions)
+}
+
+func (e *TriggerEngine) uses(condition string) bool {
+	for _, state := range e.rules {
+		if state.rule.Condition == condition {
+			return true
+		}
+	}
+	return false
+}
+
+// OnFired 注册一个回调，每次触发的所有动作执行完之后调用（例如发送通知）。
+func (e *TriggerEngine) OnFired(fn func(TriggerFiringStatus)) {
+	e.mu.Lock()
+	e.onFired = append(e.onFired, fn)
+	e.mu.Unlock()
+}
+
+// Run 求值规则直到 ctx 结束，没有规则时直接返回。
+func (e *TriggerEngine) Run(ctx context.Context) {
+	if len(e.rules) == 0 {
+		return
+	}
+	if e.uses(TriggerConditionLog) && e.broker != nil {
+		go e.watchLogs(ctx)
+	}
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.poll(now)
+		}
+	}
+}
+
+// poll 采样一次目标进程，对满足条件的规则触发动作。
+func (e *TriggerEngine) poll(now time.Time) {
+	pid := e.pid()
+	values := make(map[string]float64, 4)
+	if pid > 0 {
+		if e.uses(TriggerConditionCPU) {
+			if usage, ok := e.cpu.Sample(pid, now); ok {
+				values[TriggerConditionCPU] = usage
+			}
+		}
+		if e.uses(TriggerConditionRSS) {
+			if rss := e.readRSS(pid); rss > 0 {
+				values[TriggerConditionRSS] = float64(rss)
+			}
+		}
+		if e.uses(TriggerConditionExceptions) && e.exceptions != nil {
+			values[TriggerConditionExceptions] = float64(e.exceptions())
+		}
+		if e.uses(TriggerConditionThreadGrowth) {
+			if threads := e.readThreads(pid); threads > 0 {
+				values[TriggerConditionThreadGrowth] = float64(threads)
+			}
+		}
+	}
+
+	type pending struct {
+		state  *triggerRuleState
+		reason string
+	}
+	var fire []pending
+	e.mu.Lock()
+	if pid != e.lastPID {
+		// 目标进程重启之后重新开始计时
+		e.lastPID = pid
+		for _, state := range e.rules {
+			state.aboveSince, state.threads = time.Time{}, nil
+		}
+	}
+	for _, state := range e.rules {
+		rule := state.rule
+		value, ok := values[rule.Condition]
+		switch rule.Condition {
+		case TriggerConditionCPU, TriggerConditionRSS, TriggerConditionExceptions:
+			if !ok || value < rule.Threshold {
+				state.aboveSince = time.Time{}
+				continue
+			}
+			if state.aboveSince.IsZero() {
+				state.aboveSince = now
+			}
+			if now.Sub(state.aboveSince) < rule.For {
+				continue
+			}
+			state.aboveSince = time.Time{}
+			fire = append(fire, pending{state, triggerValueReason(rule, value)})
+		case TriggerConditionThreadGrowth:
+			if !ok {
+				state.threads = nil
+				continue
+			}
+			count := int(value)
+			state.threads = append(state.threads, threadCountSample{at: now, count: count})
+			for len(state.threads) > 0 && now.Sub(state.threads[0].at) > rule.Window {
+				state.threads = state.threads[1:]
+			}
+			lowest := state.threads[0]
+			for _, sample := range state.threads {
+				if sample.count < lowest.count {
+					lowest = sample
+				}
+			}
+			if float64(count-lowest.count) < rule.Threshold {
+				continue
+			}
+			state.threads = []threadCountSample{{at: now, count: count}}
+			fire = append(fire, pending{state, fmt.Sprintf("threads %d -> %d within %s", lowest.count, count, now.Sub(lowest.at).Round(time.Second))})
+		}
+	}
+	e.mu.Unlock()
+	for _, item := range fire {
+		e.fire(item.state, item.reason, false)
+	}
+}
+
+func triggerValueReason(rule TriggerRule, value float64) string {
+	var text string
+	switch rule.Condition {
+	case TriggerConditionCPU:
+		text = fmt.Sprintf("cpu %.0f%% >= %g%%", value, rule.Threshold)
+	case TriggerConditionRSS:
+		text = fmt.Sprintf("rss %s >= %s", formatBytes(uint64(value)), formatBytes(uint64(rule.Threshold)))
+	case TriggerConditionExceptions:
+		text = fmt.Sprintf("%.0f exceptions in the last minute >= %g", value, rule.Threshold)
+	}
+	if rule.For > 0 {
+		text += " for " + rule.For.String()
+	}
+	return text
+}
+
+// watchLogs 对目标进程的每一行日志求值日志规则。
+func (e *TriggerEngine) watchLogs(ctx context.Context) {
+	lines, cancel := e.broker.Subscribe()
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			for _, state := range e.rules {
+				if state.rule.Condition != TriggerConditionLog || !state.rule.Log.MatchString(line) {
+					continue
+				}
+				if len(line) > maxTriggerLogReason {
+					line = line[:maxTriggerLogReason] + "..."
+				}
+				e.fire(state, "log: "+line, false)
+			}
+		}
+	}
+}
+
+// Fire 手动触发一条规则，不受 cooldown 与 limit 限制。
+func (e *TriggerEngine) Fire(name string) (*TriggerFiring, error) {
+	for _, state := range e.rules {
+		if state.rule.Name == name {
+			return e.fire(state, "manual", true)
+		}
+	}
+	return nil, fmt.Errorf("unknown trigger %q", name)
+}
+
+func (e *TriggerEngine) fire(state *triggerRuleState, reason string, manual bool) (*TriggerFiring, error) {
+	now := time.Now()
+	pid := e.pid()
+	e.mu.Lock()
+	var err error
+	switch {
+	case pid <= 0:
+		err = errors.New("target process is not running")
+	case state.running:
+		err = ErrTriggerRunning
+	case manual:
+	case !state.lastFired.IsZero() && now.Sub(state.lastFired) < state.rule.Cooldown:
+		err = ErrTriggerCooldown
+	case state.rule.Limit > 0 && state.fired >= state.rule.Limit:
+		err = ErrTriggerLimit
+	}
+	if err != nil {
+		if !manual {
+			state.suppressed++
+		}
+		e.mu.Unlock()
+		return nil, err
+	}
+	id := now.Format("20060102-150405.000") + "-" + state.rule.Name
+	firing := &TriggerFiring{
+		ID:      id,
+		Rule:    state.rule.Name,
+		Target:  e.target,
+		Reason:  reason,
+		PID:     pid,
+		Time:    now,
+		Dir:     filepath.Join(triggerArtifactDir, e.target, id),
+		running: true,
+	}
+	state.running = true
+	state.fired++
+	state.lastFired = now
+	e.firings = append(e.firings, firing)
+	for len(e.firings) > maxTriggerFirings && !e.firings[0].Status().Running {
+		_ = os.RemoveAll(e.firings[0].Dir)
+		e.firings = e.firings[1:]
+	}
+	e.mu.Unlock()
+	_, _ = fmt.Fprintf(os.Stderr, "[trigger] %s fired for pid %d: %s\n", state.rule.Name, pid, reason)
+	go e.execute(state, firing)
+	return firing, nil
+}
+
+// execute 依次执行规则的动作，一个动作失败不影响之后的动作。
+func (e *TriggerEngine) execute(state *triggerRuleState, firing *TriggerFiring) {
+	if err := os.MkdirAll(firing.Dir, 0o755); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[trigger] create %s failed: %v\n", firing.Dir, err)
+	}
+	for _, action := range state.rule.Actions {
+		firing.mu.Lock()
+		firing.results = append(firing.results, TriggerActionResult{Action: action, Started: time.Now()})
+		index := len(firing.results) - 1
+		firing.mu.Unlock()
+
+		artifacts, notes, err := e.act(context.Background(), firing, action)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "[trigger] %s: %s failed: %v\n", firing.Rule, action.Type, err)
+		}
+		firing.mu.Lock()
+		result := &firing.results[index]
+		result.Ended = time.Now()
+		result.Notes = notes
+		result.Artifacts = artifacts
+		if err != nil {
+			result.Error = err.Error()
+		}
+		firing.mu.Unlock()
+	}
+	firing.mu.Lock()
+	firing.running = false
+	firing.ended = time.Now()
+	firing.mu.Unlock()
+	e.mu.Lock()
+	state.running = false
+	callbacks := slices.Clone(e.onFired)
+	e.mu.Unlock()
+	status := firing.Status()
+	for _, fn := range callbacks {
+		fn(status)
+	}
+}
+
+// Rules 返回所有规则的状态。
+func (e *TriggerEngine) Rules() []TriggerRuleStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	statuses := make([]TriggerRuleStatus, 0, len(e.rules))
+	for _, state := range e.rules {
+		statuses = append(statuses, TriggerRuleStatus{
+			Rule:        state.rule,
+			Description: state.rule.Describe(),
+			Fired:       state.fired,
+			Suppressed:  state.suppressed,
+			LastFired:   state.lastFired,
+			Running:     state.running,
+		})
+	}
+	return statuses
+}
+
+// Firings 返回触发记录，最近的在前。
+func (e *TriggerEngine) Firings() []*TriggerFiring {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	firings := make([]*TriggerFiring, 0, len(e.firings))
+	for i := len(e.firings) - 1; i >= 0; i-- {
+		firings = append(firings, e.firings[i])
+	}
+	return firings
+}
+
+func (e *TriggerEngine) Firing(id string) *TriggerFiring {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, firing := range e.firings {
+		if firing.ID == id {
+			return firing
+		}
+	}
+	return nil
+}
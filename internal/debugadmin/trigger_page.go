@@ -0,0 +1,215 @@
+package debugadmin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed triggers.html.tpl
+var triggersHTMLContent string
+
+var triggersHTMLTemplate = template.Must(template.New("triggers.html").Parse(triggersHTMLContent))
+
+type triggersPageData struct {
+	TargetQuery string
+	Rules       []triggerRuleView
+	Firings     []triggerFiringView
+}
+
+type triggerRuleView struct {
+	Name       string
+	Condition  string
+	Actions    string
+	Cooldown   string
+	Limit      string
+	Fired      int
+	Suppressed int
+	LastFired  string
+	Running    bool
+	FireURL    string
+}
+
+type triggerFiringView struct {
+	ID      string
+	Time    string
+	Rule    string
+	Reason  string
+	PID     int
+	Running bool
+	Results []triggerResultView
+}
+
+type triggerResultView struct {
+	Action    string
+	Duration  string
+	Running   bool
+	Error     string
+	Notes     string
+	Artifacts []triggerArtifactView
+}
+
+type triggerArtifactView struct {
+	Name string
+	URL  string
+	Size string
+}
+
+// triggersAPIResponse 是 /api/triggers 的返回值。
+type triggersAPIResponse struct {
+	Rules   []TriggerRuleStatus   `json:"rules"`
+	Firings []TriggerFiringStatus `json:"firings"`
+}
+
+// handleTriggers 展示触发规则以及每次触发的结果。
+func (h *AdminHandler) handleTriggers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	firings := h.triggers.Firings()
+	statuses := make([]TriggerFiringStatus, 0, len(firings))
+	for _, firing := range firings {
+		statuses = append(statuses, firing.Status())
+	}
+	rules := h.triggers.Rules()
+	data := buildTriggersPageData(rules, statuses, time.Now())
+	data.TargetQuery = h.targetQuery("?")
+	for i, status := range rules {
+		data.Rules[i].FireURL = "/triggers/fire?rule=" + url.QueryEscape(status.Rule.Name) + h.targetQuery("&")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := triggersHTMLTemplate.Execute(w, data); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "render triggers page failed: %v\n", err)
+	}
+}
+
+// handleTriggerFire 手动触发一条规则，不受 cooldown 与 limit 限制，用于验证规则的动作。
+func (h *AdminHandler) handleTriggerFire(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.triggers.Fire(r.URL.Query().Get("rule")); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/triggers"+h.targetQuery("?"), http.StatusSeeOther)
+}
+
+// handleTriggerArtifact 下载触发动作生成的文件，只允许下载触发记录中登记过的文件。
+func (h *AdminHandler) handleTriggerArtifact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	firing := h.triggers.Firing(query.Get("id"))
+	if firing == nil {
+		http.NotFound(w, r)
+		return
+	}
+	artifact, ok := firing.Artifact(query.Get("name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(artifact.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", firing.ID+"-"+filepath.Base(artifact.Path)))
+	http.ServeContent(w, r, artifact.Name, info.ModTime(), file)
+}
+
+// handleTriggersAPI 以 JSON 返回规则的状态与触发记录。
+func (h *AdminHandler) handleTriggersAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	response := triggersAPIResponse{Rules: h.triggers.Rules(), Firings: []TriggerFiringStatus{}}
+	for _, firing := range h.triggers.Firings() {
+		response.Firings = append(response.Firings, firing.Status())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func buildTriggersPageData(rules []TriggerRuleStatus, firings []TriggerFiringStatus, now time.Time) triggersPageData {
+	var data triggersPageData
+	for _, status := range rules {
+		actions := make([]string, 0, len(status.Rule.Actions))
+		for _, action := range status.Rule.Actions {
+			actions = append(actions, action.Describe())
+		}
+		view := triggerRuleView{
+			Name:       html.EscapeString(status.Rule.Name),
+			Condition:  html.EscapeString(status.Description),
+			Actions:    html.EscapeString(strings.Join(actions, ", ")),
+			Cooldown:   status.Rule.Cooldown.String(),
+			Limit:      "unlimited",
+			Fired:      status.Fired,
+			Suppressed: status.Suppressed,
+			LastFired:  "-",
+			Running:    status.Running,
+		}
+		if status.Rule.Limit > 0 {
+			view.Limit = fmt.Sprintf("%d", status.Rule.Limit)
+		}
+		if !status.LastFired.IsZero() {
+			view.LastFired = status.LastFired.Format("2006-01-02 15:04:05")
+		}
+		data.Rules = append(data.Rules, view)
+	}
+	for _, firing := range firings {
+		view := triggerFiringView{
+			ID:      html.EscapeString(firing.ID),
+			Time:    firing.Time.Format("2006-01-02 15:04:05"),
+			Rule:    html.EscapeString(firing.Rule),
+			Reason:  html.EscapeString(firing.Reason),
+			PID:     firing.PID,
+			Running: firing.Running,
+		}
+		for _, result := range firing.Results {
+			ended := result.Ended
+			if ended.IsZero() {
+				ended = now
+			}
+			resultView := triggerResultView{
+				Action:   html.EscapeString(result.Action.Describe()),
+				Duration: ended.Sub(result.Started).Round(100 * time.Millisecond).String(),
+				Running:  result.Ended.IsZero(),
+				Error:    html.EscapeString(result.Error),
+				Notes:    html.EscapeString(strings.Join(result.Notes, "; ")),
+			}
+			for _, artifact := range result.Artifacts {
+				artifactView := triggerArtifactView{Name: html.EscapeString(artifact.Name), URL: artifact.URL}
+				if artifact.Size > 0 {
+					artifactView.Size = formatBytes(uint64(artifact.Size))
+				}
+				resultView.Artifacts = append(resultView.Artifacts, artifactView)
+			}
+			view.Results = append(view.Results, resultView)
+		}
+		data.Firings = append(data.Firings, view)
+	}
+	return data
+}
@@ -0,0 +1,250 @@
+package debugadmin
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 触发条件的种类。
+const (
+	TriggerConditionCPU          = "cpu"
+	TriggerConditionRSS          = "rss"
+	TriggerConditionLog          = "log"
+	TriggerConditionExceptions   = "exceptions"
+	TriggerConditionThreadGrowth = "thread_growth"
+)
+
+// 触发后执行的动作。
+const (
+	TriggerActionTrace   = "trace"   // 后台 trace，见 TraceJobManager
+	TriggerActionStack   = "stack"   // 用 netcoredbg 抓一次所有线程的调用栈
+	TriggerActionGCDump  = "gcdump"  // dotnet-gcdump 采集托管堆
+	TriggerActionDump    = "dump"    // dotnet-dump 生成 core dump
+	TriggerActionWebhook = "webhook" // POST 一条 JSON 通知
+)
+
+const (
+	defaultTriggerCooldown     = 5 * time.Minute
+	defaultThreadGrowthWindow  = 5 * time.Minute
+	defaultTriggerTraceSeconds = 30
+	defaultTriggerDumpType     = "Full"
+)
+
+var triggerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// TriggerRule 是一条 "当 <条件> 时执行 <动作>" 的规则，来自配置文件的 triggers:。
+type TriggerRule struct {
+	Name      string          `json:"name"`
+	Target    string          `json:"target,omitempty"` // 为空时对所有目标进程生效
+	Condition string          `json:"condition"`        // TriggerCondition*
+	Threshold float64         `json:"threshold"`        // cpu 为百分比，rss 为字节数，exceptions 为每分钟次数，thread_growth 为线程数
+	Log       *regexp.Regexp  `json:"-"`
+	For       time.Duration   `json:"for,omitempty"`    // cpu / rss / exceptions 持续超过阈值多久才触发
+	Window    time.Duration   `json:"window,omitempty"` // thread_growth 统计增长的时间窗口
+	Actions   []TriggerAction `json:"actions"`
+	Cooldown  time.Duration   `json:"cooldown"`
+	Limit     int             `json:"limit,omitempty"` // 最多触发的次数，0 表示不限制
+}
+
+// TriggerAction 是规则触发后执行的一个动作。
+type TriggerAction struct {
+	Type     string       `json:"type"`
+	Trace    TraceRequest `json:"trace,omitzero"`      // trace
+	DumpType string       `json:"dump_type,omitempty"` // dump：Mini / Heap / Triage / Full
+	URL      string       `json:"url,omitempty"`       // webhook
+}
+
+// Describe 返回条件的说明，例如 "cpu >= 90% for 30s"。
+func (r TriggerRule) Describe() string {
+	var text string
+	switch r.Condition {
+	case TriggerConditionCPU:
+		text = fmt.Sprintf("cpu >= %g%%", r.Threshold)
+	case TriggerConditionRSS:
+		text = "rss >= " + formatBytes(uint64(r.Threshold))
+	case TriggerConditionLog:
+		return fmt.Sprintf("log matches /%s/", r.Log)
+	case TriggerConditionExceptions:
+		text = fmt.Sprintf("exceptions >= %g/min", r.Threshold)
+	case TriggerConditionThreadGrowth:
+		return fmt.Sprintf("threads grow by %g within %s", r.Threshold, r.Window)
+	}
+	if r.For > 0 {
+		text += " for " + r.For.String()
+	}
+	return text
+}
+
+// Describe 返回动作的说明，例如 "trace(cpu, 30s)"。
+func (a TriggerAction) Describe() string {
+	switch a.Type {
+	case TriggerActionTrace:
+		return "trace(" + a.Trace.Describe() + ")"
+	case TriggerActionDump:
+		return "dump(" + a.DumpType + ")"
+	case TriggerActionWebhook:
+		if parsed, err := url.Parse(a.URL); err == nil {
+			return "webhook(" + parsed.Host + ")"
+		}
+	}
+	return a.Type
+}
+
+// newTriggerRule 校验配置文件中的一条规则并填上默认值。
+func newTriggerRule(item configFileTrigger) (TriggerRule, error) {
+	rule := TriggerRule{Name: strings.TrimSpace(item.Name), Target: strings.TrimSpace(item.Target), Limit: item.Limit}
+	if !triggerNamePattern.MatchString(rule.Name) {
+		return TriggerRule{}, fmt.Errorf("invalid trigger name %q", item.Name)
+	}
+	fail := func(format string, args ...any) (TriggerRule, error) {
+		return TriggerRule{}, fmt.Errorf("trigger %q: %s", rule.Name, fmt.Sprintf(format, args...))
+	}
+	when := item.When
+	conditions := 0
+	if when.CPU != 0 {
+		conditions++
+		rule.Condition, rule.Threshold = TriggerConditionCPU, when.CPU
+	}
+	if when.RSS != "" {
+		conditions++
+		size, err := parseByteSize(when.RSS)
+		if err != nil {
+			return fail("when.rss: %v", err)
+		}
+		rule.Condition, rule.Threshold = TriggerConditionRSS, float64(size)
+	}
+	if when.Log != "" {
+		conditions++
+		pattern, err := regexp.Compile(when.Log)
+		if err != nil {
+			return fail("when.log: %v", err)
+		}
+		rule.Condition, rule.Log = TriggerConditionLog, pattern
+	}
+	if when.ExceptionsPerMinute != 0 {
+		conditions++
+		rule.Condition, rule.Threshold = TriggerConditionExceptions, float64(when.ExceptionsPerMinute)
+	}
+	if when.ThreadGrowth != 0 {
+		conditions++
+		rule.Condition, rule.Threshold, rule.Window = TriggerConditionThreadGrowth, float64(when.ThreadGrowth), defaultThreadGrowthWindow
+	}
+	if conditions != 1 {
+		return fail("when needs exactly one of cpu, rss, log, exceptions_per_minute or thread_growth")
+	}
+	if rule.Threshold < 0 {
+		return fail("threshold should be positive")
+	}
+	var err error
+	if when.For != "" {
+		if rule.Condition == TriggerConditionLog || rule.Condition == TriggerConditionThreadGrowth {
+			return fail("when.for only applies to cpu, rss and exceptions_per_minute")
+		}
+		if rule.For, err = time.ParseDuration(when.For); err != nil || rule.For < 0 {
+			return fail("invalid when.for %q", when.For)
+		}
+	}
+	if when.Window != "" {
+		if rule.Condition != TriggerConditionThreadGrowth {
+			return fail("when.window only applies to thread_growth")
+		}
+		if rule.Window, err = time.ParseDuration(when.Window); err != nil || rule.Window <= 0 {
+			return fail("invalid when.window %q", when.Window)
+		}
+	}
+	rule.Cooldown = defaultTriggerCooldown
+	if item.Cooldown != "" {
+		if rule.Cooldown, err = time.ParseDuration(item.Cooldown); err != nil || rule.Cooldown < 0 {
+			return fail("invalid cooldown %q", item.Cooldown)
+		}
+	}
+	if rule.Limit < 0 {
+		return fail("limit should not be negative")
+	}
+	if len(item.Actions) == 0 {
+		return fail("no actions")
+	}
+	for i, raw := range item.Actions {
+		action, err := newTriggerAction(raw)
+		if err != nil {
+			return fail("actions[%d]: %v", i, err)
+		}
+		rule.Actions = append(rule.Actions, action)
+	}
+	return rule, nil
+}
+
+func newTriggerAction(item configFileTriggerAction) (TriggerAction, error) {
+	action := TriggerAction{Type: strings.TrimSpace(item.Type)}
+	if action.Type != TriggerActionTrace && (item.Kind != "" || item.Duration != "") {
+		return TriggerAction{}, errors.New("kind and duration only apply to trace")
+	}
+	if action.Type != TriggerActionDump && item.DumpType != "" {
+		return TriggerAction{}, errors.New("dump_type only applies to dump")
+	}
+	if action.Type != TriggerActionWebhook && item.URL != "" {
+		return TriggerAction{}, errors.New("url only applies to webhook")
+	}
+	switch action.Type {
+	case TriggerActionTrace:
+		kind, err := ParseTraceKind(strings.TrimSpace(item.Kind))
+		if err != nil {
+			return TriggerAction{}, err
+		}
+		action.Trace = TraceRequest{Kind: kind, Duration: defaultTriggerTraceSeconds * time.Second}
+		if kind == TraceKindPerf {
+			action.Trace.Frequency = defaultPerfFrequency
+		}
+		if item.Duration != "" {
+			if action.Trace.Duration, err = parseTraceDuration(item.Duration); err != nil {
+				return TriggerAction{}, err
+			}
+			if action.Trace.Duration == 0 {
+				return TriggerAction{}, errors.New("trace duration should be positive")
+			}
+		}
+	case TriggerActionStack, TriggerActionGCDump:
+	case TriggerActionDump:
+		action.DumpType = defaultTriggerDumpType
+		if item.DumpType != "" {
+			action.DumpType = item.DumpType
+		}
+		switch action.DumpType {
+		case "Mini", "Heap", "Triage", "Full":
+		default:
+			return TriggerAction{}, fmt.Errorf("unknown dump_type %q, expected Mini, Heap, Triage or Full", item.DumpType)
+		}
+	case TriggerActionWebhook:
+		parsed, err := url.Parse(item.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return TriggerAction{}, fmt.Errorf("webhook needs an http(s) url, got %q", item.URL)
+		}
+		action.URL = item.URL
+	default:
+		return TriggerAction{}, fmt.Errorf("unknown action type %q, expected trace, stack, gcdump, dump or webhook", item.Type)
+	}
+	return action, nil
+}
+
+// parseByteSize 解析 "512MB"、"2GiB"、"1073741824" 这样的大小，单位按 1024 进位，与 formatBytes 一致。
+func parseByteSize(raw string) (uint64, error) {
+	text := strings.ToUpper(strings.TrimSpace(raw))
+	number := strings.TrimRightFunc(text, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	unit := strings.TrimSpace(text[len(number):])
+	multiplier := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+	}[unit]
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || multiplier == 0 || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+	return uint64(value * multiplier), nil
+}
@@ -0,0 +1,324 @@
+package debugadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadOptionsTriggers(t *testing.T) {
+	path := writeConfigFile(t, `
+targets:
+  - name: api
+    args: [/app/Api.dll]
+  - name: worker
+    args: [/app/Worker.dll]
+triggers:
+  - name: high-cpu
+    target: api
+    when: {cpu: 90, for: 30s}
+    actions:
+      - {type: trace, kind: perf, duration: 1m}
+      - {type: webhook, url: "https://hooks.example.com/x?token=secret"}
+    cooldown: 10m
+    limit: 3
+  - name: oom-soon
+    when: {rss: 2GiB}
+    actions: [{type: gcdump}, {type: dump}]
+  - name: deadlock
+    when: {thread_growth: 50}
+    actions: [{type: stack}]
+`)
+	opts, err := loadOptions([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("loadOptions() error = %v", err)
+	}
+	if len(opts.Triggers) != 3 {
+		t.Fatalf("Triggers = %+v", opts.Triggers)
+	}
+	cpu := opts.Triggers[0]
+	if cpu.Target != "api" || cpu.Condition != TriggerConditionCPU || cpu.Threshold != 90 || cpu.For != 30*time.Second ||
+		cpu.Cooldown != 10*time.Minute || cpu.Limit != 3 || len(cpu.Actions) != 2 {
+		t.Errorf("cpu rule = %+v", cpu)
+	}
+	if got := cpu.Actions[0].Trace; got != (TraceRequest{Kind: TraceKindPerf, Duration: time.Minute, Frequency: defaultPerfFrequency}) {
+		t.Errorf("trace action = %+v", got)
+	}
+	if got := cpu.Actions[1].Describe(); got != "webhook(hooks.example.com)" {
+		t.Errorf("webhook action = %s", got)
+	}
+	rss := opts.Triggers[1]
+	if rss.Describe() != "rss >= 2.00 GB" || rss.Cooldown != defaultTriggerCooldown || rss.Actions[1].DumpType != defaultTriggerDumpType {
+		t.Errorf("rss rule = %+v (%s)", rss, rss.Describe())
+	}
+	if got := opts.Triggers[2].Describe(); got != "threads grow by 50 within 5m0s" {
+		t.Errorf("thread rule = %s", got)
+	}
+}
+
+func TestLoadOptionsRejectsInvalidTriggers(t *testing.T) {
+	for name, trigger := range map[string]string{
+		"two conditions": `{name: a, when: {cpu: 90, rss: 1GB}, actions: [{type: stack}]}`,
+		"no actions":     `{name: a, when: {cpu: 90}}`,
+		"unknown field":  `{name: a, when: {cpu: 90}, actions: [{type: stack}], retries: 3}`,
+		"unknown action": `{name: a, when: {cpu: 90}, actions: [{type: reboot}]}`,
+		"unknown target": `{name: a, target: web, when: {cpu: 90}, actions: [{type: stack}]}`,
+		"bad regexp":     `{name: a, when: {log: "("}, actions: [{type: stack}]}`,
+		"for on log":     `{name: a, when: {log: "x", for: 1s}, actions: [{type: stack}]}`,
+		"bad webhook":    `{name: a, when: {cpu: 90}, actions: [{type: webhook, url: "ftp://x"}]}`,
+		"misplaced kind": `{name: a, when: {cpu: 90}, actions: [{type: stack, kind: cpu}]}`,
+	} {
+		path := writeConfigFile(t, "targets: [{name: api, args: [/app/Api.dll]}]\ntriggers:\n  - "+trigger+"\n")
+		if _, err := loadOptions([]string{"-config", path}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for raw, want := range map[string]uint64{"1048576": 1 << 20, "512MB": 512 << 20, "1.5g": 3 << 29, "2 GiB": 2 << 30, "64k": 64 << 10} {
+		if got, err := parseByteSize(raw); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "MB", "1TB", "-1", "abc"} {
+		if _, err := parseByteSize(raw); err == nil {
+			t.Errorf("parseByteSize(%q) expected an error", raw)
+		}
+	}
+}
+
+func useTempTriggerArtifactDir(t *testing.T) {
+	previous := triggerArtifactDir
+	triggerArtifactDir = t.TempDir()
+	t.Cleanup(func() { triggerArtifactDir = previous })
+}
+
+// recordingActions 记录执行过的动作，用来代替 AdminHandler.runTriggerAction。
+type recordingActions struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (r *recordingActions) run(ctx context.Context, firing *TriggerFiring, action TriggerAction) ([]TriggerArtifact, []string, error) {
+	r.mu.Lock()
+	r.actions = append(r.actions, firing.Rule+":"+action.Type)
+	r.mu.Unlock()
+	if action.Type == TriggerActionDump {
+		return nil, nil, errors.New("no dotnet-dump")
+	}
+	return nil, []string{"ok"}, nil
+}
+
+func (r *recordingActions) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.actions...)
+}
+
+func waitTriggerFirings(t *testing.T, engine *TriggerEngine, count int) []*TriggerFiring {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		firings := engine.Firings()
+		done := len(firings) >= count
+		for _, firing := range firings {
+			done = done && !firing.Status().Running
+		}
+		if done {
+			return firings
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d finished firings, got %d", count, len(engine.Firings()))
+	return nil
+}
+
+func TestTriggerEngine(t *testing.T) {
+	useTempTriggerArtifactDir(t)
+	actions := &recordingActions{}
+	rules := []TriggerRule{
+		{Name: "cpu", Condition: TriggerConditionCPU, Threshold: 90, For: 2 * time.Second, Cooldown: time.Hour,
+			Actions: []TriggerAction{{Type: TriggerActionStack}, {Type: TriggerActionDump, DumpType: "Full"}}},
+		{Name: "rss", Target: "api", Condition: TriggerConditionRSS, Threshold: 1 << 30, Limit: 1, Actions: []TriggerAction{{Type: TriggerActionGCDump}}},
+		{Name: "threads", Condition: TriggerConditionThreadGrowth, Threshold: 50, Window: time.Minute, Actions: []TriggerAction{{Type: TriggerActionStack}}},
+		{Name: "exceptions", Condition: TriggerConditionExceptions, Threshold: 100, Actions: []TriggerAction{{Type: TriggerActionStack}}},
+		{Name: "other", Target: "worker", Condition: TriggerConditionCPU, Threshold: 1, Actions: []TriggerAction{{Type: TriggerActionStack}}},
+	}
+	var exceptions int64
+	engine := NewTriggerEngine("api", rules, func() int { return 42 }, nil, func() int64 { return exceptions }, actions.run)
+	if len(engine.Rules()) != 4 || !engine.NeedsExceptions() {
+		t.Fatalf("rules = %+v", engine.Rules())
+	}
+	var cpuTime time.Duration
+	engine.cpu.read = func(pid int) (time.Duration, error) { return cpuTime, nil }
+	var rss uint64
+	engine.readRSS = func(pid int) uint64 { return rss }
+	threads := 20
+	engine.readThreads = func(pid int) int { return threads }
+
+	base := time.Now()
+	step := func(second int, cpuPercent int) {
+		cpuTime += time.Duration(cpuPercent) * 10 * time.Millisecond
+		engine.poll(base.Add(time.Duration(second) * time.Second))
+	}
+	step(0, 0)
+	step(1, 95)
+	step(2, 95)
+	if len(engine.Firings()) != 0 {
+		t.Fatal("cpu rule fired before staying above the threshold for 2s")
+	}
+	rss = 2 << 30
+	threads = 75
+	exceptions = 150
+	step(3, 95)
+	firings := waitTriggerFirings(t, engine, 4)
+	byRule := make(map[string]TriggerFiringStatus)
+	for _, firing := range firings {
+		byRule[firing.Rule] = firing.Status()
+	}
+	if reason := byRule["cpu"].Reason; reason != "cpu 95% >= 90% for 2s" {
+		t.Errorf("cpu reason = %q", reason)
+	}
+	if reason := byRule["threads"].Reason; reason != "threads 20 -> 75 within 3s" {
+		t.Errorf("threads reason = %q", reason)
+	}
+	if results := byRule["cpu"].Results; len(results) != 2 || results[0].Notes[0] != "ok" || results[1].Error != "no dotnet-dump" {
+		t.Errorf("cpu results = %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(triggerArtifactDir, "api", byRule["rss"].ID)); err != nil {
+		t.Errorf("artifact dir: %v", err)
+	}
+
+	// cpu 在 cooldown 中、rss 达到 limit、线程数没有再增长，都不会再次触发
+	exceptions = 0
+	for second := 4; second < 10; second++ {
+		step(second, 95)
+	}
+	if got := len(engine.Firings()); got != 4 {
+		t.Errorf("firings = %d, want 4", got)
+	}
+	exceptions = 150
+	step(10, 95)
+	if got := len(engine.Firings()); got != 5 {
+		t.Errorf("firings = %d, want 5 (exceptions has no cooldown)", got)
+	}
+	for _, status := range engine.Rules() {
+		if status.Rule.Name == "cpu" && (status.Fired != 1 || status.Suppressed == 0) {
+			t.Errorf("cpu status = %+v", status)
+		}
+		if status.Rule.Name == "rss" && (status.Fired != 1 || status.Suppressed == 0) {
+			t.Errorf("rss status = %+v", status)
+		}
+	}
+	waitTriggerFirings(t, engine, 5)
+	if _, err := engine.Fire("cpu"); err != nil {
+		t.Errorf("manual fire ignores cooldown, got %v", err)
+	}
+	if _, err := engine.Fire("missing"); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+	waitTriggerFirings(t, engine, 6)
+	if got := len(actions.list()); got != 8 {
+		t.Errorf("actions = %v", actions.list())
+	}
+}
+
+func TestTriggerEngineLogRule(t *testing.T) {
+	useTempTriggerArtifactDir(t)
+	broker := NewLogBroker()
+	actions := &recordingActions{}
+	rules := []TriggerRule{{Name: "panic", Condition: TriggerConditionLog, Log: regexp.MustCompile(`Unhandled exception`), Cooldown: time.Hour,
+		Actions: []TriggerAction{{Type: TriggerActionStack}}}}
+	engine := NewTriggerEngine("main", rules, func() int { return 42 }, broker, nil, actions.run)
+	engine.interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go engine.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(engine.Firings()) == 0 && time.Now().Before(deadline) {
+		broker.Broadcast("info: started")
+		broker.Broadcast("fail: Unhandled exception. System.InvalidOperationException")
+		time.Sleep(10 * time.Millisecond)
+	}
+	firings := waitTriggerFirings(t, engine, 1)
+	if len(firings) != 1 || firings[0].Reason != "log: fail: Unhandled exception. System.InvalidOperationException" {
+		t.Errorf("firings = %+v", firings)
+	}
+}
+
+func TestTriggersPageAndArtifacts(t *testing.T) {
+	useTempTriggerArtifactDir(t)
+	var webhook TriggerFiringStatus
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&webhook)
+	}))
+	defer receiver.Close()
+
+	h := &AdminHandler{name: "api", targetNames: []string{"api", "worker"}, traces: NewTraceStore(), mux: http.NewServeMux()}
+	rules := []TriggerRule{{Name: "snapshot", Condition: TriggerConditionCPU, Threshold: 90, Cooldown: time.Hour,
+		Actions: []TriggerAction{{Type: TriggerActionStack}, {Type: TriggerActionWebhook, URL: receiver.URL}}}}
+	h.triggers = NewTriggerEngine(h.name, rules, func() int { return 42 }, nil, nil,
+		func(ctx context.Context, firing *TriggerFiring, action TriggerAction) ([]TriggerArtifact, []string, error) {
+			if action.Type == TriggerActionStack {
+				artifact, err := h.writeTriggerArtifact(firing, "stack.txt", []byte("Thread #1 <main>"))
+				return []TriggerArtifact{artifact}, nil, err
+			}
+			return h.runTriggerAction(ctx, firing, action)
+		})
+	h.Register(h.mux)
+
+	response := httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("POST", "/triggers/fire?rule=snapshot&target=api", nil))
+	if response.Code != http.StatusSeeOther {
+		t.Fatalf("fire status = %d, body = %s", response.Code, response.Body.String())
+	}
+	firing := waitTriggerFirings(t, h.triggers, 1)[0]
+	status := firing.Status()
+	if len(status.Results) != 2 || status.Results[1].Error != "" {
+		t.Fatalf("results = %+v", status.Results)
+	}
+	artifact := status.Results[0].Artifacts[0]
+	if webhook.Rule != "snapshot" || len(webhook.Results) != 2 || webhook.Results[0].Artifacts[0].URL != artifact.URL {
+		t.Errorf("webhook payload = %+v", webhook)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/triggers", nil))
+	page := response.Body.String()
+	if !strings.Contains(page, "cpu &gt;= 90%") || !strings.Contains(page, artifact.URL) || !strings.Contains(page, "/triggers/fire?rule=snapshot&target=api") {
+		t.Errorf("triggers page = %s", page)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", artifact.URL, nil))
+	if body, _ := io.ReadAll(response.Body); response.Code != http.StatusOK || string(body) != "Thread #1 <main>" {
+		t.Errorf("artifact status = %d, body = %s", response.Code, body)
+	}
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/triggers/artifact?id="+firing.ID+"&name=../../etc/passwd", nil))
+	if response.Code != http.StatusNotFound {
+		t.Errorf("unlisted artifact status = %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	h.mux.ServeHTTP(response, httptest.NewRequest("GET", "/api/triggers", nil))
+	var api triggersAPIResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &api); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.Rules) != 1 || api.Rules[0].Fired != 1 || len(api.Firings) != 1 {
+		t.Errorf("api = %+v", api)
+	}
+}
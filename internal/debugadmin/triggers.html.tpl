@@ -0,0 +1,55 @@
+<!doctype html>
+<html>
+<head>
+<meta charset="utf-8"/>
+<title>Triggers</title>
+<style>
+body{margin:0;padding:24px;background:#f3f4f6;color:#111827;font-family:Consolas,Monaco,monospace;}
+.wrap{max-width:1400px;margin:0 auto;background:#ffffff;border:1px solid #d1d5db;border-radius:12px;padding:18px 20px;}
+h1{margin:0 0 12px 0;font-size:20px;}
+h2{margin:18px 0 8px 0;font-size:14px;color:#1f2937;}
+.meta{color:#6b7280;font-size:12px;}
+.running{color:#b45309;font-weight:700;}
+.ok{color:#15803d;}
+table{border-collapse:collapse;width:100%;font-size:12px;margin-top:8px;}
+th,td{border-bottom:1px solid #e5e7eb;padding:4px 6px;text-align:left;vertical-align:top;}
+th{background:#f9fafb;}
+td.num{text-align:right;}
+.rule{font-weight:700;color:#1d4ed8;}
+.result{margin:2px 0;}
+.error{white-space:pre-wrap;color:#991b1b;margin:2px 0 2px 12px;}
+.artifact{margin-left:12px;}
+</style>
+</head>
+<body>
+<div class="wrap">
+<h1>Triggers</h1>
+<div class="meta">Rules come from <code>triggers:</code> in the config file. Conditions are checked every second; a rule does not fire again within its cooldown, after reaching its limit, or while its previous firing is still running. Full data at <a href="/api/triggers{{.TargetQuery}}" target="_blank">/api/triggers</a>. <a href="/triggers{{.TargetQuery}}">refresh</a></div>
+{{if not .Rules}}
+<div class="meta" style="margin-top:12px;">No trigger rule applies to this target. Add <code>triggers:</code> to the file given by <code>-config</code>.</div>
+{{else}}
+<h2>Rules</h2>
+<table>
+<thead><tr><th>name</th><th>when</th><th>actions</th><th>cooldown</th><th>limit</th><th>fired</th><th>suppressed</th><th>last fired</th><th></th></tr></thead>
+<tbody>
+{{range .Rules}}<tr><td class="rule">{{.Name}}</td><td>{{.Condition}}</td><td>{{.Actions}}</td><td>{{.Cooldown}}</td><td>{{.Limit}}</td><td class="num">{{.Fired}}</td><td class="num">{{.Suppressed}}</td><td>{{.LastFired}}</td>
+<td>{{if .Running}}<span class="running">running</span>{{else}}<form method="post" action="{{.FireURL}}" style="display:inline;"><button type="submit" title="run the actions now, ignoring cooldown and limit">Fire now</button></form>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+<h2>History</h2>
+{{if not .Firings}}<div class="meta">No rule has fired yet.</div>{{else}}
+<table>
+<thead><tr><th>time</th><th>rule</th><th>reason</th><th>pid</th><th>actions</th></tr></thead>
+<tbody>
+{{range .Firings}}<tr><td>{{.Time}}</td><td class="rule">{{.Rule}}</td><td>{{.Reason}}</td><td>{{.PID}}</td><td>
+{{range .Results}}<div class="result">{{.Action}} <span class="meta">{{.Duration}}</span> {{if .Running}}<span class="running">running</span>{{else if .Error}}<b>failed</b>{{else}}<span class="ok">done</span>{{end}}{{if .Notes}} <span class="meta">{{.Notes}}</span>{{end}}
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{range .Artifacts}}<div class="artifact"><a href="{{.URL}}" target="_blank" rel="noopener">{{.Name}}</a>{{if .Size}} <span class="meta">{{.Size}}</span>{{end}}</div>{{end}}</div>
+{{end}}{{if .Running}}<div class="meta">running...</div>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{end}}
+</div>
+</body>
+</html>